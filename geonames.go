@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"family-tree-app/internal/config"
+	"family-tree-app/internal/database"
+	"family-tree-app/internal/geonames"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+)
+
+const geonamesUsage = `Использование: family-tree-app geonames <RU.txt|RU.zip|allCountries.zip> [-country RU,UA,BY] [флаги конфигурации]`
+
+// geonamesBatch - столько записей справочника пишется одной транзакцией
+const geonamesBatch = 5000
+
+// runGeonames загружает выгрузку geonames.org в офлайн-справочник мест.
+// Повторная загрузка обновляет уже известные записи.
+func runGeonames(args []string) {
+	if len(args) < 1 {
+		log.Fatal(geonamesUsage)
+	}
+	path, rest := args[0], args[1:]
+
+	countries := map[string]bool{}
+	if len(rest) >= 2 && rest[0] == "-country" {
+		for _, c := range strings.Split(rest[1], ",") {
+			if c = strings.ToUpper(strings.TrimSpace(c)); c != "" {
+				countries[c] = true
+			}
+		}
+		rest = rest[2:]
+	}
+
+	cfg, err := config.Load(rest)
+	if err != nil {
+		log.Fatal("Ошибка конфигурации: ", err)
+	}
+
+	database.InitDB(cfg.DBPath, cfg.AutoMigrate)
+	defer database.DB.Close()
+	st := store.NewSQLite(database.DB)
+
+	ctx := context.Background()
+	batch := make([]models.GazetteerEntry, 0, geonamesBatch)
+	imported := 0
+	flush := func() error {
+		if err := st.ImportGazetteer(ctx, batch); err != nil {
+			return err
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err = geonames.ReadFile(path, func(e models.GazetteerEntry) error {
+		if len(countries) > 0 && !countries[e.CountryCode] {
+			return nil
+		}
+		batch = append(batch, e)
+		if len(batch) < geonamesBatch {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		log.Fatalf("Ошибка загрузки справочника (загружено %d): %v", imported, err)
+	}
+	fmt.Printf("Загружено записей GeoNames: %d\n", imported)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"family-tree-app/internal/config"
+	"family-tree-app/internal/database"
+	"family-tree-app/internal/gedcom"
+	"family-tree-app/internal/store"
+)
+
+const importUsage = `Использование: family-tree-app import <email> <файл.ged> [флаги конфигурации]`
+
+// runImport импортирует файл GEDCOM в дерево по умолчанию пользователя с указанным email
+func runImport(args []string) {
+	if len(args) < 2 {
+		log.Fatal(importUsage)
+	}
+	email, path := args[0], args[1]
+
+	cfg, err := config.Load(args[2:])
+	if err != nil {
+		log.Fatal("Ошибка конфигурации: ", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	doc, err := gedcom.Decode(data, "")
+	if err != nil {
+		log.Fatal("Ошибка разбора GEDCOM: ", err)
+	}
+
+	database.InitDB(cfg.DBPath, cfg.AutoMigrate)
+	defer database.DB.Close()
+	st := store.NewSQLite(database.DB)
+
+	ctx := context.Background()
+	user, err := st.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Fatalf("Пользователь %s не найден: %v", email, err)
+	}
+
+	tree, err := st.EnsureDefaultTree(ctx, user.ID)
+	if err != nil {
+		log.Fatal("Ошибка чтения БД: ", err)
+	}
+
+	people, relationships, report := gedcom.BuildTree(doc)
+	ctx = store.WithActor(ctx, user.ID)
+	if err := st.ImportTree(ctx, tree.ID, people, relationships); err != nil {
+		log.Fatal("Ошибка записи в БД: ", err)
+	}
+
+	fmt.Printf("Кодировка: %s\n", report.Charset)
+	fmt.Printf("Импортировано людей: %d, связей: %d\n", report.PeopleImported, report.RelationshipsImported)
+	for _, s := range report.Skipped {
+		fmt.Printf("Пропущено %s: %s\n", s.XRef, s.Reason)
+	}
+	tags := make([]string, 0, len(report.Unmapped))
+	for tag := range report.Unmapped {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		fmt.Printf("Не перенесено %s: %d\n", tag, report.Unmapped[tag])
+	}
+}
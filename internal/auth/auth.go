@@ -1,14 +1,21 @@
 package auth
 
 import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Секретный ключ для подписи токенов (в продакшене хранить в .env!)
-var JwtKey = []byte("super_secret_key_change_me")
+// Секретный ключ для подписи токенов. Задаётся из конфигурации через Configure.
+var JwtKey []byte
+
+// BcryptCost - сложность хеширования паролей. Меняется через SetBcryptCost.
+var BcryptCost = 14
 
 // Структура данных внутри токена
 type Claims struct {
@@ -16,9 +23,27 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// Configure применяет настройки из конфигурации: ключ подписи токенов и сложность bcrypt
+func Configure(jwtSecret string, bcryptCost int) error {
+	if jwtSecret == "" {
+		return fmt.Errorf("пустой ключ подписи токенов")
+	}
+	JwtKey = []byte(jwtSecret)
+	return SetBcryptCost(bcryptCost)
+}
+
+// SetBcryptCost задаёт сложность bcrypt с проверкой допустимого диапазона
+func SetBcryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("сложность bcrypt должна быть от %d до %d, получено %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	BcryptCost = cost
+	return nil
+}
+
 // HashPassword превращает "123456" в "$2a$14$..."
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
 	return string(bytes), err
 }
 
@@ -28,6 +53,56 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// IsBcryptHash отличает bcrypt-хеш от пароля, сохранённого в открытом виде
+// (так хранились аккаунты до перехода на bcrypt).
+func IsBcryptHash(stored string) bool {
+	if !strings.HasPrefix(stored, "$2a$") && !strings.HasPrefix(stored, "$2b$") && !strings.HasPrefix(stored, "$2y$") {
+		return false
+	}
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// VerifyPassword сверяет пароль с тем, что лежит в users.password_hash.
+// Старые записи в открытом виде сравниваются за постоянное время.
+func VerifyPassword(password, stored string) bool {
+	if IsBcryptHash(stored) {
+		return CheckPasswordHash(password, stored)
+	}
+	return subtle.ConstantTimeCompare([]byte(password), []byte(stored)) == 1
+}
+
+// Хеш-заглушка для входа с незнакомым email. Строится лениво с текущей
+// сложностью, чтобы время ответа не отличалось от проверки настоящего пароля.
+var (
+	dummyMu   sync.Mutex
+	dummyHash []byte
+)
+
+// CompareDummyPassword тратит на пароль столько же времени, сколько
+// VerifyPassword на настоящий bcrypt-хеш. Результат всегда false: по времени
+// ответа нельзя узнать, зарегистрирован ли email.
+func CompareDummyPassword(password string) bool {
+	dummyMu.Lock()
+	if cost, err := bcrypt.Cost(dummyHash); err != nil || cost != BcryptCost {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), BcryptCost)
+	}
+	hash := dummyHash
+	dummyMu.Unlock()
+	bcrypt.CompareHashAndPassword(hash, []byte(password))
+	return false
+}
+
+// NeedsRehash сообщает, что сохранённое значение нужно перехешировать:
+// это пароль в открытом виде или хеш с устаревшей сложностью.
+func NeedsRehash(stored string) bool {
+	if !IsBcryptHash(stored) {
+		return true
+	}
+	cost, _ := bcrypt.Cost([]byte(stored))
+	return cost != BcryptCost
+}
+
 // GenerateToken создает JWT токен для пользователя
 func GenerateToken(userID int) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour) // Токен живет 1 сутки
@@ -40,4 +115,4 @@ func GenerateToken(userID int) (string, error) {
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(JwtKey)
-}
\ No newline at end of file
+}
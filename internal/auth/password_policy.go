@@ -0,0 +1,100 @@
+package auth
+
+import (
+	_ "embed"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения на длину пароля. Верхняя граница - предел bcrypt (72 байта).
+const (
+	MinPasswordLength = 8
+	MaxPasswordBytes  = 72
+)
+
+// Коды нарушений политики паролей (фронтенд может на них опираться)
+const (
+	ViolationTooShort    = "too_short"
+	ViolationTooLong     = "too_long"
+	ViolationCommon      = "common_password"
+	ViolationMatchesMail = "matches_email"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsRaw string
+
+// commonPasswords - набор популярных паролей из common_passwords.txt (в нижнем регистре)
+var commonPasswords = parseCommonPasswords(commonPasswordsRaw)
+
+// PolicyViolation - одно нарушение политики паролей
+type PolicyViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError возвращается, если пароль не прошёл проверку.
+// Содержит все найденные нарушения, а не только первое.
+type PasswordPolicyError struct {
+	Violations []PolicyViolation `json:"violations"`
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidatePassword проверяет пароль на соответствие политике.
+// Возвращает *PasswordPolicyError или nil.
+func ValidatePassword(password, email string) error {
+	var violations []PolicyViolation
+
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		violations = append(violations, PolicyViolation{
+			Code:    ViolationTooShort,
+			Message: "Пароль должен содержать не менее 8 символов",
+		})
+	}
+	if len(password) > MaxPasswordBytes {
+		violations = append(violations, PolicyViolation{
+			Code:    ViolationTooLong,
+			Message: "Пароль слишком длинный (максимум 72 байта)",
+		})
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := commonPasswords[lower]; ok {
+		violations = append(violations, PolicyViolation{
+			Code:    ViolationCommon,
+			Message: "Пароль слишком распространён, придумайте другой",
+		})
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	localPart, _, _ := strings.Cut(email, "@")
+	if email != "" && (lower == email || lower == localPart) {
+		violations = append(violations, PolicyViolation{
+			Code:    ViolationMatchesMail,
+			Message: "Пароль не должен совпадать с email",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func parseCommonPasswords(raw string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// shareAudience отличает токены публичных ссылок от токенов входа
+const shareAudience = "share"
+
+// ShareClaims - содержимое токена публичной ссылки на дерево
+type ShareClaims struct {
+	LinkID int `json:"lid"`
+	TreeID int `json:"tid"`
+	jwt.RegisteredClaims
+}
+
+// shareKey - отдельный ключ подписи, выведенный из JwtKey, чтобы токен
+// ссылки нельзя было подложить вместо токена входа и наоборот
+func shareKey() []byte {
+	mac := hmac.New(sha256.New, JwtKey)
+	mac.Write([]byte("share-links"))
+	return mac.Sum(nil)
+}
+
+// GenerateShareToken подписывает токен ссылки. Нулевой expiresAt - бессрочно.
+// Токен детерминирован: для одной ссылки всегда получается одна и та же строка,
+// поэтому его не нужно хранить - достаточно записи о ссылке.
+func GenerateShareToken(linkID, treeID int, expiresAt time.Time) (string, error) {
+	claims := &ShareClaims{
+		LinkID: linkID,
+		TreeID: treeID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience: jwt.ClaimStrings{shareAudience},
+		},
+	}
+	if !expiresAt.IsZero() {
+		claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(shareKey())
+}
+
+// ParseShareToken проверяет подпись и срок токена ссылки.
+// Отозвана ли ссылка, проверяет вызывающий по LinkID.
+func ParseShareToken(tokenString string) (*ShareClaims, error) {
+	claims := &ShareClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return shareKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(shareAudience))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.LinkID == 0 {
+		return nil, errors.New("недействительный токен ссылки")
+	}
+	return claims, nil
+}
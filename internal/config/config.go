@@ -0,0 +1,281 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Режимы запуска приложения
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Где хранить загруженные файлы
+const (
+	MediaLocal = "local" // каталог на диске сервера
+	MediaS3    = "s3"    // S3-совместимое хранилище
+)
+
+// DefaultJWTSecret - ключ для локальной разработки. В production с ним сервер не стартует.
+const DefaultJWTSecret = "super_secret_key_change_me"
+
+// Config - все настройки приложения.
+// Приоритет источников: значения по умолчанию < файл конфигурации < переменные окружения < флаги.
+type Config struct {
+	Env            string   `json:"env"`             // development | production
+	Port           string   `json:"port"`            // порт HTTP-сервера
+	DBPath         string   `json:"db_path"`         // путь к файлу SQLite
+	JWTSecret      string   `json:"jwt_secret"`      // ключ подписи токенов
+	CookieSecure   bool     `json:"cookie_secure"`   // флаг Secure у куки (нужен HTTPS)
+	BcryptCost     int      `json:"bcrypt_cost"`     // сложность хеширования паролей
+	AllowedOrigins []string `json:"allowed_origins"` // разрешённые источники для CORS
+	AutoMigrate    bool     `json:"auto_migrate"`    // применять миграции при старте сервера
+	// StrictTreeChecks - отклонять изменения людей и связей, добавляющие генеалогические ошибки
+	StrictTreeChecks bool `json:"strict_tree_checks"`
+	// TrashRetentionDays - через сколько дней удалённое стирается из корзины; 0 - хранить всегда
+	TrashRetentionDays int `json:"trash_retention_days"`
+
+	MediaStorage string `json:"media_storage"` // local | s3
+	MediaDir     string `json:"media_dir"`     // каталог файлов при local
+	MediaMaxMB   int    `json:"media_max_mb"`  // наибольший размер загружаемого файла, МБ
+	// Подключение к S3 при MEDIA_STORAGE=s3
+	S3Endpoint        string `json:"s3_endpoint"`
+	S3Region          string `json:"s3_region"`
+	S3Bucket          string `json:"s3_bucket"`
+	S3AccessKeyID     string `json:"s3_access_key_id"`
+	S3SecretAccessKey string `json:"s3_secret_access_key"`
+}
+
+// Default возвращает настройки для локальной разработки
+func Default() *Config {
+	return &Config{
+		Env:            EnvDevelopment,
+		Port:           "8080",
+		DBPath:         "./family_tree.db",
+		JWTSecret:      DefaultJWTSecret,
+		CookieSecure:   false,
+		BcryptCost:     14,
+		AllowedOrigins: []string{"*"},
+		AutoMigrate:    true,
+
+		TrashRetentionDays: 30,
+
+		MediaStorage: MediaLocal,
+		MediaDir:     "./media",
+		MediaMaxMB:   20,
+	}
+}
+
+// Load собирает конфигурацию из файла, окружения и флагов командной строки и проверяет её.
+// Путь к файлу задаётся флагом -config или переменной CONFIG_FILE; файл необязателен.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("family-tree-app", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "путь к JSON-файлу конфигурации")
+	env := fs.String("env", "", "режим запуска: development или production")
+	port := fs.String("port", "", "порт HTTP-сервера")
+	dbPath := fs.String("db", "", "путь к файлу базы данных SQLite")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	// Флаги перекрывают всё остальное, но только если заданы явно
+	if *env != "" {
+		cfg.Env = *env
+	}
+	if *port != "" {
+		cfg.Port = *port
+	}
+	if *dbPath != "" {
+		cfg.DBPath = *dbPath
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// IsProduction - запущено ли приложение в боевом режиме
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// Validate проверяет согласованность настроек
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		errs = append(errs, fmt.Errorf("неизвестный режим %q (ожидается %s или %s)", c.Env, EnvDevelopment, EnvProduction))
+	}
+	if p, err := strconv.Atoi(c.Port); err != nil || p < 1 || p > 65535 {
+		errs = append(errs, fmt.Errorf("некорректный порт %q", c.Port))
+	}
+	if c.DBPath == "" {
+		errs = append(errs, errors.New("не задан путь к базе данных"))
+	}
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("не задан JWT_SECRET"))
+	}
+	if c.BcryptCost < 4 || c.BcryptCost > 31 {
+		errs = append(errs, fmt.Errorf("сложность bcrypt должна быть от 4 до 31, получено %d", c.BcryptCost))
+	}
+	if c.TrashRetentionDays < 0 {
+		errs = append(errs, fmt.Errorf("срок хранения корзины не может быть отрицательным, получено %d", c.TrashRetentionDays))
+	}
+
+	switch c.MediaStorage {
+	case MediaLocal:
+		if c.MediaDir == "" {
+			errs = append(errs, errors.New("не задан каталог для файлов MEDIA_DIR"))
+		}
+	case MediaS3:
+		if c.S3Endpoint == "" || c.S3Bucket == "" || c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			errs = append(errs, errors.New("для MEDIA_STORAGE=s3 нужны S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID и S3_SECRET_ACCESS_KEY"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("неизвестное хранилище файлов %q (ожидается %s или %s)", c.MediaStorage, MediaLocal, MediaS3))
+	}
+	if c.MediaMaxMB < 1 {
+		errs = append(errs, fmt.Errorf("наибольший размер файла должен быть не меньше 1 МБ, получено %d", c.MediaMaxMB))
+	}
+
+	if c.IsProduction() {
+		if c.JWTSecret == DefaultJWTSecret {
+			errs = append(errs, errors.New("в production нельзя использовать JWT-ключ по умолчанию, задайте JWT_SECRET"))
+		} else if len(c.JWTSecret) < 32 {
+			errs = append(errs, errors.New("в production JWT_SECRET должен быть не короче 32 символов"))
+		}
+		for _, origin := range c.AllowedOrigins {
+			if origin == "*" {
+				errs = append(errs, errors.New("в production нельзя разрешать CORS для всех источников, задайте CORS_ORIGINS"))
+				break
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("чтение файла конфигурации: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("разбор файла конфигурации %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	if v := os.Getenv("APP_ENV"); v != "" {
+		c.Env = v
+	}
+	if v := os.Getenv("PORT"); v != "" {
+		c.Port = v
+	}
+	if v := os.Getenv("DB_PATH"); v != "" {
+		c.DBPath = v
+	}
+	if v := os.Getenv("JWT_SECRET"); v != "" {
+		c.JWTSecret = v
+	}
+	if err := envBool("COOKIE_SECURE", &c.CookieSecure); err != nil {
+		return err
+	}
+	if v := os.Getenv("BCRYPT_COST"); v != "" {
+		cost, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("BCRYPT_COST должен быть числом: %w", err)
+		}
+		c.BcryptCost = cost
+	}
+	if err := envBool("AUTO_MIGRATE", &c.AutoMigrate); err != nil {
+		return err
+	}
+	if err := envBool("STRICT_TREE_CHECKS", &c.StrictTreeChecks); err != nil {
+		return err
+	}
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("TRASH_RETENTION_DAYS должен быть числом: %w", err)
+		}
+		c.TrashRetentionDays = days
+	}
+	if v := os.Getenv("MEDIA_STORAGE"); v != "" {
+		c.MediaStorage = v
+	}
+	if v := os.Getenv("MEDIA_DIR"); v != "" {
+		c.MediaDir = v
+	}
+	if v := os.Getenv("MEDIA_MAX_MB"); v != "" {
+		mb, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("MEDIA_MAX_MB должен быть числом: %w", err)
+		}
+		c.MediaMaxMB = mb
+	}
+	if v := os.Getenv("S3_ENDPOINT"); v != "" {
+		c.S3Endpoint = v
+	}
+	if v := os.Getenv("S3_REGION"); v != "" {
+		c.S3Region = v
+	}
+	if v := os.Getenv("S3_BUCKET"); v != "" {
+		c.S3Bucket = v
+	}
+	if v := os.Getenv("S3_ACCESS_KEY_ID"); v != "" {
+		c.S3AccessKeyID = v
+	}
+	if v := os.Getenv("S3_SECRET_ACCESS_KEY"); v != "" {
+		c.S3SecretAccessKey = v
+	}
+	if v := os.Getenv("CORS_ORIGINS"); v != "" {
+		c.AllowedOrigins = splitList(v)
+	}
+	return nil
+}
+
+// envBool читает логическую переменную окружения, если она задана
+func envBool(name string, dst *bool) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%s должен быть true или false: %w", name, err)
+	}
+	*dst = b
+	return nil
+}
+
+// splitList разбирает список через запятую, отбрасывая пустые элементы
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -3,77 +3,66 @@ package database
 import (
 	"database/sql"
 	"log"
+	"strings"
 
 	_ "github.com/glebarez/go-sqlite" // Драйвер Pure Go
 )
 
 var DB *sql.DB
 
-func InitDB() {
-	var err error
-	// Подключаемся к файлу
-	DB, err = sql.Open("sqlite", "./family_tree.db")
+// Open подключается к файлу базы без применения миграций (нужно для команды migrate).
+// Внешние ключи включаются для каждого соединения пула через параметр DSN.
+func Open(path string) (*sql.DB, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", path+sep+"_pragma=foreign_keys(1)")
 	if err != nil {
-		log.Fatal("Ошибка открытия БД: ", err)
+		return nil, err
 	}
-
-	if err = DB.Ping(); err != nil {
-		log.Fatal("Ошибка соединения с БД: ", err)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
 	}
-
-	createTables()
-	log.Println("База данных успешно подключена.")
+	return db, nil
 }
 
-func createTables() {
-	usersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		email TEXT UNIQUE NOT NULL,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- <--- НОВОЕ ПОЛЕ
-		is_verified BOOLEAN DEFAULT 0,
-		link_to_person_id INTEGER,
-		FOREIGN KEY(link_to_person_id) REFERENCES people(id)
-	);`
+// InitDB открывает файл базы по пути из конфигурации и проверяет версию схемы.
+// При autoMigrate недостающие миграции применяются автоматически,
+// иначе сервер откажется стартовать на устаревшей схеме.
+// На схеме новее бинарника сервер не стартует никогда.
+func InitDB(path string, autoMigrate bool) {
+	var err error
+	// Подключаемся к файлу
+	DB, err = Open(path)
+	if err != nil {
+		log.Fatal("Ошибка соединения с БД: ", err)
+	}
 
-	peopleTable := `
-	CREATE TABLE IF NOT EXISTS people (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER, 
-		first_name TEXT NOT NULL,
-		middle_name TEXT,
-		last_name TEXT NOT NULL,
-		birth_date TEXT,
-		death_date TEXT,
-		gender TEXT,
-		photo_url TEXT,
-		position_x REAL DEFAULT 0,
-		position_y REAL DEFAULT 0,
-		FOREIGN KEY(user_id) REFERENCES users(id)
-	);`
+	current, err := SchemaVersion(DB)
+	if err != nil {
+		log.Fatal("Ошибка чтения версии схемы: ", err)
+	}
+	latest, err := LatestVersion()
+	if err != nil {
+		log.Fatal("Ошибка чтения вшитых миграций: ", err)
+	}
 
-	relationshipsTable := `
-	CREATE TABLE IF NOT EXISTS relationships (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER,
-		from_person_id INTEGER NOT NULL,
-		to_person_id INTEGER NOT NULL,
-		type TEXT NOT NULL,
-		description TEXT,
-		FOREIGN KEY(from_person_id) REFERENCES people(id),
-		FOREIGN KEY(to_person_id) REFERENCES people(id),
-		FOREIGN KEY(user_id) REFERENCES users(id)
-	);`
+	switch {
+	case current > latest:
+		log.Fatalf("%v: версия БД %d, последняя известная %d", ErrSchemaAhead, current, latest)
+	case current < latest && !autoMigrate:
+		log.Fatalf("Схема БД устарела (версия %d, нужна %d). Выполните: migrate up", current, latest)
+	case current < latest:
+		applied, err := MigrateUp(DB)
+		if err != nil {
+			log.Fatal("Ошибка применения миграций: ", err)
+		}
+		for _, m := range applied {
+			log.Printf("Применена миграция %04d_%s", m.Version, m.Name)
+		}
+	}
 
-	mustExec(usersTable)
-	mustExec(peopleTable)
-	mustExec(relationshipsTable)
+	log.Println("База данных успешно подключена.")
 }
-
-func mustExec(query string) {
-	_, err := DB.Exec(query)
-	if err != nil {
-		log.Fatalf("Ошибка при создании таблицы (%s): %v", query, err)
-	}
-}
\ No newline at end of file
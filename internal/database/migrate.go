@@ -0,0 +1,303 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Миграции лежат в migrations/ и вшиваются в бинарник.
+// Имя файла: NNNN_описание.up.sql / NNNN_описание.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationNameRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrSchemaAhead - схема БД новее, чем знает бинарник (БД обновили более новой версией)
+var ErrSchemaAhead = errors.New("схема базы данных новее, чем поддерживает эта версия приложения")
+
+// Migration - одна версия схемы
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState - состояние миграции для команды migrate status
+type MigrationState struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+}
+
+// Migrations возвращает все вшитые миграции по возрастанию версии
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		m := migrationNameRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("некорректное имя файла миграции: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("у миграции %d разные имена: %s и %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s нет up- или down-файла", mig.Version, mig.Name)
+		}
+		list = append(list, *mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	for i, mig := range list {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("пропущена миграция перед версией %d", mig.Version)
+		}
+	}
+	return list, nil
+}
+
+// LatestVersion - номер последней миграции, известной бинарнику.
+// Ошибка означает повреждённый набор вшитых миграций.
+func LatestVersion() (int, error) {
+	list, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	return list[len(list)-1].Version, nil
+}
+
+// SchemaVersion возвращает текущую версию схемы (0 - пустая база)
+func SchemaVersion(db *sql.DB) (int, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// MigrateUp применяет все недостающие миграции, каждую в своей транзакции
+func MigrateUp(db *sql.DB) ([]Migration, error) {
+	list, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if current > len(list) {
+		return nil, fmt.Errorf("%w: версия БД %d, последняя известная %d", ErrSchemaAhead, current, len(list))
+	}
+
+	var applied []Migration
+	for _, mig := range list[current:] {
+		err := inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(mig.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", mig.Version, mig.Name)
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("миграция %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		applied = append(applied, mig)
+	}
+	return applied, nil
+}
+
+// MigrateDown откатывает steps последних применённых миграций
+func MigrateDown(db *sql.DB, steps int) ([]Migration, error) {
+	list, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if current > len(list) {
+		return nil, fmt.Errorf("%w: версия БД %d, последняя известная %d", ErrSchemaAhead, current, len(list))
+	}
+
+	var reverted []Migration
+	for v := current; v > 0 && len(reverted) < steps; v-- {
+		mig := list[v-1]
+		err := inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(mig.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", mig.Version)
+			return err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("откат %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		reverted = append(reverted, mig)
+	}
+	return reverted, nil
+}
+
+// Status возвращает список всех миграций с отметкой о применении.
+// Версии из БД, неизвестные бинарнику, тоже попадают в список (с пустым именем файла).
+func Status(db *sql.DB) ([]MigrationState, error) {
+	list, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]MigrationState{}
+	for rows.Next() {
+		var st MigrationState
+		if err := rows.Scan(&st.Version, &st.Name, &st.AppliedAt); err != nil {
+			return nil, err
+		}
+		st.Applied = true
+		applied[st.Version] = st
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(list))
+	for _, mig := range list {
+		st, ok := applied[mig.Version]
+		if !ok {
+			st = MigrationState{Version: mig.Version, Name: mig.Name}
+		}
+		delete(applied, mig.Version)
+		states = append(states, st)
+	}
+	for _, st := range applied {
+		states = append(states, st)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// ensureMigrationsTable создаёт schema_migrations. Если это база, созданная
+// старой версией приложения (таблицы есть, учёта версий нет), она помечается
+// как находящаяся на версии 1.
+func ensureMigrationsTable(db *sql.DB) error {
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations'").Scan(&exists)
+	if err != nil || exists > 0 {
+		return err
+	}
+
+	return inTx(db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		CREATE TABLE schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`)
+		if err != nil {
+			return err
+		}
+
+		var legacy int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='users'").Scan(&legacy); err != nil {
+			return err
+		}
+		if legacy == 0 {
+			return nil
+		}
+
+		// Старые базы могли быть созданы до появления users.created_at.
+		// ALTER TABLE в SQLite не принимает CURRENT_TIMESTAMP как значение по умолчанию,
+		// поэтому заполняем колонку отдельно.
+		hasCreatedAt, err := columnExists(tx, "users", "created_at")
+		if err != nil {
+			return err
+		}
+		if !hasCreatedAt {
+			if _, err := tx.Exec("ALTER TABLE users ADD COLUMN created_at DATETIME"); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE users SET created_at = CURRENT_TIMESTAMP"); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (1, 'init')")
+		return err
+	})
+}
+
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,3 @@
+DROP TABLE relationships;
+DROP TABLE people;
+DROP TABLE users;
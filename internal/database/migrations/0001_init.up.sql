@@ -0,0 +1,36 @@
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	is_verified BOOLEAN DEFAULT 0,
+	link_to_person_id INTEGER,
+	FOREIGN KEY(link_to_person_id) REFERENCES people(id)
+);
+
+CREATE TABLE people (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER,
+	first_name TEXT NOT NULL,
+	middle_name TEXT,
+	last_name TEXT NOT NULL,
+	birth_date TEXT,
+	death_date TEXT,
+	gender TEXT,
+	photo_url TEXT,
+	position_x REAL DEFAULT 0,
+	position_y REAL DEFAULT 0,
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE relationships (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER,
+	from_person_id INTEGER NOT NULL,
+	to_person_id INTEGER NOT NULL,
+	type TEXT NOT NULL,
+	description TEXT,
+	FOREIGN KEY(from_person_id) REFERENCES people(id),
+	FOREIGN KEY(to_person_id) REFERENCES people(id),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
@@ -0,0 +1,4 @@
+DROP INDEX idx_relationships_to;
+DROP INDEX idx_relationships_from;
+DROP INDEX idx_relationships_user;
+DROP INDEX idx_people_user;
@@ -0,0 +1,4 @@
+CREATE INDEX idx_people_user ON people(user_id);
+CREATE INDEX idx_relationships_user ON relationships(user_id);
+CREATE INDEX idx_relationships_from ON relationships(from_person_id);
+CREATE INDEX idx_relationships_to ON relationships(to_person_id);
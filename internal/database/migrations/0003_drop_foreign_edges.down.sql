@@ -0,0 +1,2 @@
+-- Удалённые некорректные связи не восстанавливаются.
+SELECT 1;
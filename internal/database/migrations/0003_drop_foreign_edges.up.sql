@@ -0,0 +1,5 @@
+-- До включения внешних ключей связь могла указывать на несуществующего
+-- человека или на человека из чужого дерева. Такие связи удаляем.
+DELETE FROM relationships
+WHERE NOT EXISTS (SELECT 1 FROM people p WHERE p.id = relationships.from_person_id AND p.user_id = relationships.user_id)
+   OR NOT EXISTS (SELECT 1 FROM people p WHERE p.id = relationships.to_person_id AND p.user_id = relationships.user_id);
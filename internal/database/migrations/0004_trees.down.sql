@@ -0,0 +1,7 @@
+-- Все деревья пользователя сливаются обратно в одно (по user_id)
+DROP INDEX idx_relationships_tree;
+DROP INDEX idx_people_tree;
+ALTER TABLE relationships DROP COLUMN tree_id;
+ALTER TABLE people DROP COLUMN tree_id;
+DROP INDEX idx_trees_user;
+DROP TABLE trees;
@@ -0,0 +1,25 @@
+-- Несколько деревьев у одного аккаунта. Люди и связи теперь принадлежат дереву,
+-- user_id в них остаётся владельцем дерева.
+CREATE TABLE trees (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_trees_user ON trees(user_id);
+
+ALTER TABLE people ADD COLUMN tree_id INTEGER;
+ALTER TABLE relationships ADD COLUMN tree_id INTEGER;
+
+-- Существующие данные переезжают в дерево по умолчанию
+INSERT INTO trees (user_id, name)
+SELECT DISTINCT user_id, 'Моё дерево' FROM people WHERE user_id IS NOT NULL
+UNION
+SELECT DISTINCT user_id, 'Моё дерево' FROM relationships WHERE user_id IS NOT NULL;
+
+UPDATE people SET tree_id = (SELECT t.id FROM trees t WHERE t.user_id = people.user_id);
+UPDATE relationships SET tree_id = (SELECT t.id FROM trees t WHERE t.user_id = relationships.user_id);
+
+CREATE INDEX idx_people_tree ON people(tree_id);
+CREATE INDEX idx_relationships_tree ON relationships(tree_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_tree_invites_email;
+DROP INDEX IF EXISTS idx_tree_invites_tree;
+DROP TABLE IF EXISTS tree_invites;
+DROP INDEX IF EXISTS idx_tree_members_user;
+DROP TABLE IF EXISTS tree_members;
@@ -0,0 +1,35 @@
+-- Совместная работа над деревом: участники с ролями и приглашения.
+CREATE TABLE tree_members (
+	tree_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (tree_id, user_id),
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE,
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_tree_members_user ON tree_members(user_id);
+
+-- Владельцы существующих деревьев становятся их участниками
+INSERT INTO tree_members (tree_id, user_id, role)
+SELECT id, user_id, 'owner' FROM trees;
+
+-- Приглашение одноразовое. Хранится только SHA-256 токена, сам токен
+-- показывается один раз при создании. email задан - принять может только этот адрес.
+CREATE TABLE tree_invites (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	email TEXT,
+	role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+	created_by INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME,
+	used_by INTEGER,
+	used_at DATETIME,
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE,
+	FOREIGN KEY(created_by) REFERENCES users(id),
+	FOREIGN KEY(used_by) REFERENCES users(id)
+);
+CREATE INDEX idx_tree_invites_tree ON tree_invites(tree_id);
+CREATE INDEX idx_tree_invites_email ON tree_invites(email);
@@ -0,0 +1,14 @@
+-- Публичные ссылки на дерево только для чтения. Сам токен не хранится:
+-- он подписан и восстанавливается из id, tree_id и expires_at.
+CREATE TABLE share_links (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	label TEXT NOT NULL DEFAULT '',
+	created_by INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME,
+	revoked_at DATETIME,
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE,
+	FOREIGN KEY(created_by) REFERENCES users(id)
+);
+CREATE INDEX idx_share_links_tree ON share_links(tree_id);
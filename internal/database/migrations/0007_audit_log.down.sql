@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS audit_log_no_delete;
+DROP TRIGGER IF EXISTS audit_log_no_update;
+DROP INDEX IF EXISTS idx_audit_log_cause;
+DROP INDEX IF EXISTS idx_audit_log_to;
+DROP INDEX IF EXISTS idx_audit_log_from;
+DROP INDEX IF EXISTS idx_audit_log_entity;
+DROP INDEX IF EXISTS idx_audit_log_tree;
+DROP TABLE IF EXISTS audit_log;
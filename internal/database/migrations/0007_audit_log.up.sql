@@ -0,0 +1,36 @@
+-- Журнал изменений людей и связей. Только дописывается: строки нельзя
+-- изменить или удалить (см. триггеры), поэтому внешнего ключа на trees нет -
+-- история переживает удаление дерева.
+CREATE TABLE audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	user_id INTEGER,
+	entity_type TEXT NOT NULL CHECK (entity_type IN ('person', 'relationship')),
+	entity_id INTEGER NOT NULL,
+	action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+	before_json TEXT,
+	after_json TEXT,
+	-- для связей: оба конца, чтобы история человека включала и его связи
+	from_person_id INTEGER,
+	to_person_id INTEGER,
+	-- изменение, из-за которого случилось это (связи, удалённые вместе с человеком)
+	cause_id INTEGER REFERENCES audit_log(id),
+	-- изменение, которое это отменяет
+	revert_of INTEGER REFERENCES audit_log(id),
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_audit_log_tree ON audit_log(tree_id, id);
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_log_from ON audit_log(from_person_id);
+CREATE INDEX idx_audit_log_to ON audit_log(to_person_id);
+CREATE INDEX idx_audit_log_cause ON audit_log(cause_id);
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log только дописывается');
+END;
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log только дописывается');
+END;
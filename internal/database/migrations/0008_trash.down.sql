@@ -0,0 +1,11 @@
+-- Без корзины её содержимое стирается окончательно
+DELETE FROM relationships
+WHERE deleted_at IS NOT NULL
+   OR from_person_id IN (SELECT id FROM people WHERE deleted_at IS NOT NULL)
+   OR to_person_id IN (SELECT id FROM people WHERE deleted_at IS NOT NULL);
+UPDATE users SET link_to_person_id = NULL WHERE link_to_person_id IN (SELECT id FROM people WHERE deleted_at IS NOT NULL);
+DELETE FROM people WHERE deleted_at IS NOT NULL;
+DROP INDEX idx_relationships_deleted;
+DROP INDEX idx_people_deleted;
+ALTER TABLE relationships DROP COLUMN deleted_at;
+ALTER TABLE people DROP COLUMN deleted_at;
@@ -0,0 +1,6 @@
+-- Корзина: удалённые люди и связи остаются в таблицах с отметкой deleted_at,
+-- пока их не восстановят или не сотрут окончательно
+ALTER TABLE people ADD COLUMN deleted_at DATETIME;
+ALTER TABLE relationships ADD COLUMN deleted_at DATETIME;
+CREATE INDEX idx_people_deleted ON people(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_relationships_deleted ON relationships(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TRIGGER people_fts_delete;
+DROP TABLE people_fts;
+ALTER TABLE people DROP COLUMN notes;
@@ -0,0 +1,13 @@
+-- Заметки о человеке и полнотекстовый поиск по людям.
+-- Строка people_fts с rowid = people.id: имена как есть, заметки, имена после
+-- транслитерации (genealogy.FoldName) и коды Дейча-Мокотова. Последние два
+-- столбца считает приложение, поэтому записи добавляет и обновляет хранилище,
+-- а уже существующих людей индексирует сервер при старте.
+ALTER TABLE people ADD COLUMN notes TEXT;
+CREATE VIRTUAL TABLE people_fts USING fts5(
+    names, notes, folded, phonetic,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+CREATE TRIGGER people_fts_delete AFTER DELETE ON people BEGIN
+    DELETE FROM people_fts WHERE rowid = old.id;
+END;
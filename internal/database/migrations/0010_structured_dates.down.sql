@@ -0,0 +1,5 @@
+DROP INDEX idx_people_birth_from;
+ALTER TABLE people DROP COLUMN death_to;
+ALTER TABLE people DROP COLUMN death_from;
+ALTER TABLE people DROP COLUMN birth_to;
+ALTER TABLE people DROP COLUMN birth_from;
@@ -0,0 +1,12 @@
+-- Диапазоны дат рождения и смерти (см. models.Date): самый ранний и самый
+-- поздний возможный день в виде "YYYY-MM-DD". По ним сортируют, текст даты
+-- остаётся в birth_date и death_date как есть. Уже записанные даты разбирают
+-- функции date_from и date_to, которые регистрирует приложение.
+ALTER TABLE people ADD COLUMN birth_from TEXT;
+ALTER TABLE people ADD COLUMN birth_to TEXT;
+ALTER TABLE people ADD COLUMN death_from TEXT;
+ALTER TABLE people ADD COLUMN death_to TEXT;
+UPDATE people SET
+    birth_from = date_from(birth_date), birth_to = date_to(birth_date),
+    death_from = date_from(death_date), death_to = date_to(death_date);
+CREATE INDEX idx_people_birth_from ON people(tree_id, birth_from);
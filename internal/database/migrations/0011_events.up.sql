@@ -0,0 +1,26 @@
+-- События жизни: у человека (рождение, крещение, смерть, погребение, переезд)
+-- или у пары (помолвка, брак, развод). Даты - как у людей: текст как есть и
+-- диапазон date_from..date_to для сортировки (см. 0010_structured_dates).
+-- Корзина событий не касается: они остаются при человеке или связи и
+-- стираются вместе с ними.
+CREATE TABLE events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	person_id INTEGER,
+	relationship_id INTEGER,
+	type TEXT NOT NULL,
+	date TEXT NOT NULL DEFAULT '',
+	date_from TEXT,
+	date_to TEXT,
+	place TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	sources TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	CHECK ((person_id IS NULL) != (relationship_id IS NULL)),
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE,
+	FOREIGN KEY(person_id) REFERENCES people(id) ON DELETE CASCADE,
+	FOREIGN KEY(relationship_id) REFERENCES relationships(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_events_person ON events(person_id);
+CREATE INDEX idx_events_relationship ON events(relationship_id);
+CREATE INDEX idx_events_tree ON events(tree_id);
@@ -0,0 +1,7 @@
+DROP TABLE gazetteer_fts;
+DROP TABLE gazetteer;
+ALTER TABLE events DROP COLUMN place_id;
+ALTER TABLE people DROP COLUMN death_place_id;
+ALTER TABLE people DROP COLUMN birth_place_id;
+DROP TABLE place_names;
+DROP TABLE places;
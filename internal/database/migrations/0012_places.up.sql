@@ -0,0 +1,54 @@
+-- Справочник мест дерева. parent_id - место, в которое это входит
+-- (деревня -> волость -> уезд -> губерния). Место с вложенными местами,
+-- людьми или событиями не удаляется - его сначала сливают с другим.
+CREATE TABLE places (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	parent_id INTEGER,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL DEFAULT '',
+	latitude REAL,
+	longitude REAL,
+	geonames_id INTEGER,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE,
+	FOREIGN KEY(parent_id) REFERENCES places(id)
+);
+CREATE INDEX idx_places_tree ON places(tree_id);
+CREATE INDEX idx_places_parent ON places(parent_id);
+
+-- Другие названия места: прежние, на других языках
+CREATE TABLE place_names (
+	place_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	lang TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (place_id, name, lang),
+	FOREIGN KEY(place_id) REFERENCES places(id) ON DELETE CASCADE
+);
+
+-- Ссылки на места. У людей в корзине ссылка на удалённое место обнуляется.
+ALTER TABLE people ADD COLUMN birth_place_id INTEGER REFERENCES places(id) ON DELETE SET NULL;
+ALTER TABLE people ADD COLUMN death_place_id INTEGER REFERENCES places(id) ON DELETE SET NULL;
+ALTER TABLE events ADD COLUMN place_id INTEGER REFERENCES places(id) ON DELETE SET NULL;
+
+-- Офлайн-справочник GeoNames, общий для всех деревьев. Заполняется
+-- командой family-tree-app geonames из выгрузки geonames.org.
+-- gazetteer_fts: rowid = geonames_id, названия как есть и после
+-- транслитерации (genealogy.FoldName) - их считает приложение.
+CREATE TABLE gazetteer (
+	geonames_id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	ascii_name TEXT NOT NULL DEFAULT '',
+	alternate_names TEXT NOT NULL DEFAULT '',
+	latitude REAL NOT NULL,
+	longitude REAL NOT NULL,
+	feature_class TEXT NOT NULL DEFAULT '',
+	feature_code TEXT NOT NULL DEFAULT '',
+	country_code TEXT NOT NULL DEFAULT '',
+	admin1 TEXT NOT NULL DEFAULT '',
+	population INTEGER NOT NULL DEFAULT 0
+);
+CREATE VIRTUAL TABLE gazetteer_fts USING fts5(
+	names, folded,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
@@ -0,0 +1,44 @@
+-- Источники сведений (архивное дело, книга, сайт, интервью) и ссылки на них.
+-- Ссылка подтверждает одно из: человека, связь или событие - и стирается
+-- вместе с ним. Источник, на который есть ссылки, не удаляется.
+CREATE TABLE sources (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	type TEXT NOT NULL DEFAULT '',
+	title TEXT NOT NULL,
+	author TEXT NOT NULL DEFAULT '',
+	publication TEXT NOT NULL DEFAULT '',
+	archive TEXT NOT NULL DEFAULT '',
+	fond TEXT NOT NULL DEFAULT '',
+	opis TEXT NOT NULL DEFAULT '',
+	delo TEXT NOT NULL DEFAULT '',
+	url TEXT NOT NULL DEFAULT '',
+	notes TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_sources_tree ON sources(tree_id);
+
+CREATE TABLE citations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	source_id INTEGER NOT NULL,
+	person_id INTEGER,
+	relationship_id INTEGER,
+	event_id INTEGER,
+	page TEXT NOT NULL DEFAULT '',
+	text TEXT NOT NULL DEFAULT '',
+	confidence TEXT NOT NULL DEFAULT '',
+	notes TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	CHECK ((person_id IS NOT NULL) + (relationship_id IS NOT NULL) + (event_id IS NOT NULL) = 1),
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE,
+	FOREIGN KEY(source_id) REFERENCES sources(id) ON DELETE CASCADE,
+	FOREIGN KEY(person_id) REFERENCES people(id) ON DELETE CASCADE,
+	FOREIGN KEY(relationship_id) REFERENCES relationships(id) ON DELETE CASCADE,
+	FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_citations_source ON citations(source_id);
+CREATE INDEX idx_citations_person ON citations(person_id);
+CREATE INDEX idx_citations_relationship ON citations(relationship_id);
+CREATE INDEX idx_citations_event ON citations(event_id);
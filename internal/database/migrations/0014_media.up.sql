@@ -0,0 +1,38 @@
+-- Загруженные файлы (фотографии, сканы документов) и их связи с людьми,
+-- связями и событиями. Содержимое хранится вне БД под ключом hash: файл
+-- с тем же содержимым в дереве заводится один раз, а между деревьями
+-- (например, в копии) содержимое общее.
+CREATE TABLE media (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	hash TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	file_name TEXT NOT NULL DEFAULT '',
+	title TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(tree_id, hash),
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_media_hash ON media(hash);
+
+-- Связь файла с одним из: человеком, связью или событием. Стирается вместе с ним.
+CREATE TABLE media_links (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tree_id INTEGER NOT NULL,
+	media_id INTEGER NOT NULL,
+	person_id INTEGER,
+	relationship_id INTEGER,
+	event_id INTEGER,
+	CHECK ((person_id IS NOT NULL) + (relationship_id IS NOT NULL) + (event_id IS NOT NULL) = 1),
+	FOREIGN KEY(tree_id) REFERENCES trees(id) ON DELETE CASCADE,
+	FOREIGN KEY(media_id) REFERENCES media(id) ON DELETE CASCADE,
+	FOREIGN KEY(person_id) REFERENCES people(id) ON DELETE CASCADE,
+	FOREIGN KEY(relationship_id) REFERENCES relationships(id) ON DELETE CASCADE,
+	FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_media_links_person ON media_links(person_id, media_id);
+CREATE UNIQUE INDEX idx_media_links_relationship ON media_links(relationship_id, media_id);
+CREATE UNIQUE INDEX idx_media_links_event ON media_links(event_id, media_id);
+CREATE INDEX idx_media_links_media ON media_links(media_id);
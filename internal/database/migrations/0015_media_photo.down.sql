@@ -0,0 +1,4 @@
+ALTER TABLE media DROP COLUMN has_gps;
+ALTER TABLE media DROP COLUMN taken_at;
+ALTER TABLE media DROP COLUMN height;
+ALTER TABLE media DROP COLUMN width;
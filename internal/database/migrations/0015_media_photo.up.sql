@@ -0,0 +1,7 @@
+-- Сведения о фотографии из её содержимого: размеры, дата съёмки из EXIF
+-- (предлагается как дата события) и есть ли в EXIF координаты съёмки.
+-- У файлов, загруженных раньше, они остаются пустыми.
+ALTER TABLE media ADD COLUMN width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE media ADD COLUMN height INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE media ADD COLUMN taken_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE media ADD COLUMN has_gps INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Границы дат по старому стилю остаются посчитанными: прежние версии их не читают иначе.
+SELECT 1;
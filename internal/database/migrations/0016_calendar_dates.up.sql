@@ -0,0 +1,7 @@
+-- Даты по старому стилю (@#DJULIAN@, "ст. ст.", двойной год 1890/91) раньше
+-- не разбирались, и их границы оставались пустыми. Пересчитываем границы
+-- всех дат: теперь они переводятся на григорианский календарь.
+UPDATE people SET
+    birth_from = date_from(birth_date), birth_to = date_to(birth_date),
+    death_from = date_from(death_date), death_to = date_to(death_date);
+UPDATE events SET date_from = date_from(date), date_to = date_to(date);
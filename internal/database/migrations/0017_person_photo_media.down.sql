@@ -0,0 +1,2 @@
+UPDATE people SET photo_url = '/api/media/' || photo_media_id || '/file' WHERE photo_media_id IS NOT NULL;
+ALTER TABLE people DROP COLUMN photo_media_id;
@@ -0,0 +1,12 @@
+-- Портрет человека - ссылка на файл дерева, а не адрес /api/media/{id}/file:
+-- адрес не открывается по публичной ссылке и указывает на чужой файл
+-- в копии дерева. Такие адреса переносятся в ссылку, если файл из того же дерева.
+ALTER TABLE people ADD COLUMN photo_media_id INTEGER REFERENCES media(id) ON DELETE SET NULL;
+
+UPDATE people SET photo_media_id = (
+	SELECT m.id FROM media m
+	WHERE m.tree_id = people.tree_id AND people.photo_url = '/api/media/' || m.id || '/file'
+)
+WHERE photo_url LIKE '/api/media/%/file';
+
+UPDATE people SET photo_url = '' WHERE photo_media_id IS NOT NULL;
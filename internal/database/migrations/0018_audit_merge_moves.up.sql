@@ -0,0 +1,5 @@
+-- Что слияние людей перенесло с удалённого на оставшегося: события, ссылки
+-- на источники, связи файлов и аккаунты (JSON). Пишется в запись об удалении
+-- второго человека или выброшенного повтора связи; отмена удаления
+-- переносит всё обратно.
+ALTER TABLE audit_log ADD COLUMN moved_json TEXT;
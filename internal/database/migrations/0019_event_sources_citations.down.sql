@@ -0,0 +1,18 @@
+ALTER TABLE events ADD COLUMN sources TEXT NOT NULL DEFAULT '';
+
+UPDATE events SET sources = (
+	SELECT group_concat(s.title, '; ')
+	FROM citations c JOIN sources s ON s.id = c.source_id
+	WHERE c.event_id = events.id AND s.notes = 'Перенесено из источников события'
+)
+WHERE id IN (
+	SELECT c.event_id FROM citations c JOIN sources s ON s.id = c.source_id
+	WHERE s.notes = 'Перенесено из источников события'
+);
+
+DELETE FROM citations WHERE event_id IS NOT NULL AND source_id IN (
+	SELECT id FROM sources WHERE notes = 'Перенесено из источников события'
+);
+DELETE FROM sources
+WHERE notes = 'Перенесено из источников события'
+	AND id NOT IN (SELECT source_id FROM citations);
@@ -0,0 +1,14 @@
+-- Текст «откуда известно» у события (events.sources) становится ссылкой на
+-- источник: одинаковый текст в дереве - один источник с таким названием.
+-- Заметка у источника помечает перенесённые, чтобы откат нашёл их.
+INSERT INTO sources (tree_id, title, notes)
+SELECT DISTINCT tree_id, trim(sources), 'Перенесено из источников события'
+FROM events WHERE trim(sources) != '';
+
+INSERT INTO citations (tree_id, source_id, event_id)
+SELECT e.tree_id, s.id, e.id
+FROM events e
+JOIN sources s ON s.tree_id = e.tree_id AND s.title = trim(e.sources) AND s.notes = 'Перенесено из источников события'
+WHERE trim(e.sources) != '';
+
+ALTER TABLE events DROP COLUMN sources;
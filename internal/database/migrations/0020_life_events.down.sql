@@ -0,0 +1,2 @@
+-- Заведённые события не отличить от записанных вручную: они остаются.
+SELECT 1;
@@ -0,0 +1,13 @@
+-- Дата и место рождения и смерти человека всегда есть и в его событии birth
+-- и death. Людям, записанным до этого, недостающие события заводятся по их датам.
+INSERT INTO events (tree_id, person_id, type, date, date_from, date_to, place_id)
+SELECT p.tree_id, p.id, 'birth', p.birth_date, p.birth_from, p.birth_to, p.birth_place_id
+FROM people p
+WHERE (p.birth_date != '' OR p.birth_place_id IS NOT NULL)
+	AND NOT EXISTS (SELECT 1 FROM events e WHERE e.person_id = p.id AND e.type = 'birth');
+
+INSERT INTO events (tree_id, person_id, type, date, date_from, date_to, place_id)
+SELECT p.tree_id, p.id, 'death', COALESCE(p.death_date, ''), p.death_from, p.death_to, p.death_place_id
+FROM people p
+WHERE (COALESCE(p.death_date, '') != '' OR p.death_place_id IS NOT NULL)
+	AND NOT EXISTS (SELECT 1 FROM events e WHERE e.person_id = p.id AND e.type = 'death');
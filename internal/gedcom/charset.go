@@ -0,0 +1,175 @@
+package gedcom
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Поддерживаемые кодировки (значения тега HEAD.CHAR и параметра charset)
+const (
+	CharsetUTF8    = "UTF-8"
+	CharsetUnicode = "UNICODE" // UTF-16 с BOM
+	CharsetANSEL   = "ANSEL"
+	CharsetASCII   = "ASCII"
+	CharsetCP1251  = "WINDOWS-1251"
+	CharsetCP1252  = "WINDOWS-1252"
+)
+
+var headCharRe = regexp.MustCompile(`(?m)^\s*1\s+CHAR\s+(\S+)`)
+
+// DecodeText переводит файл GEDCOM в UTF-8. Кодировка берётся из override,
+// иначе из BOM, иначе из HEAD.CHAR. "ANSI" считается windows-1251: такие файлы
+// у нас приходят из русских версий Windows-программ.
+func DecodeText(data []byte, override string) (string, string, error) {
+	charset := strings.ToUpper(strings.TrimSpace(override))
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+		if charset == "" {
+			charset = CharsetUTF8
+		}
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		// UTF-16 определяется только по BOM, override здесь не имеет смысла
+		text, err := decodeUTF16(data)
+		return text, CharsetUnicode, err
+	}
+
+	if charset == "" {
+		charset = CharsetUTF8
+		if m := headCharRe.FindSubmatch(data[:min(len(data), 4096)]); m != nil {
+			charset = strings.ToUpper(string(m[1]))
+		}
+	}
+
+	switch charset {
+	case CharsetUTF8, "UTF8", CharsetASCII:
+		if !utf8.Valid(data) {
+			return "", charset, fmt.Errorf("файл объявлен как %s, но содержит некорректные байты UTF-8", charset)
+		}
+		return string(data), charset, nil
+	case CharsetANSEL:
+		return decodeANSEL(data), charset, nil
+	case "ANSI", CharsetCP1251, "CP1251":
+		return decodeSingleByte(data, &cp1251), CharsetCP1251, nil
+	case CharsetCP1252, "CP1252", "LATIN1", "ISO-8859-1":
+		return decodeSingleByte(data, &cp1252), CharsetCP1252, nil
+	}
+	return "", charset, fmt.Errorf("неподдерживаемая кодировка %q", charset)
+}
+
+func decodeUTF16(data []byte) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("нечётная длина файла в UTF-16")
+	}
+	bigEndian := data[0] == 0xFE
+	units := make([]uint16, 0, len(data)/2-1)
+	for i := 2; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// decodeSingleByte декодирует однобайтовую кодировку: 0x00-0x7F - ASCII,
+// 0x80-0xFF - по таблице
+func decodeSingleByte(data []byte, table *[128]rune) string {
+	var sb strings.Builder
+	sb.Grow(len(data) * 2)
+	for _, b := range data {
+		if b < 0x80 {
+			sb.WriteByte(b)
+		} else {
+			sb.WriteRune(table[b-0x80])
+		}
+	}
+	return sb.String()
+}
+
+// decodeANSEL декодирует ANSEL (ANSI Z39.47). Диакритические знаки в ANSEL стоят
+// перед буквой, а в Unicode - после, поэтому они копятся и выводятся после базового символа.
+// Результат - в декомпозированной форме (буква + комбинируемый знак).
+func decodeANSEL(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(data) * 2)
+	var pending []rune
+
+	for _, b := range data {
+		if b < 0x80 {
+			sb.WriteByte(b)
+			for _, r := range pending {
+				sb.WriteRune(r)
+			}
+			pending = pending[:0]
+			continue
+		}
+		if r, ok := anselCombining[b]; ok {
+			pending = append(pending, r)
+			continue
+		}
+		r, ok := anselSpacing[b]
+		if !ok {
+			r = utf8.RuneError
+		}
+		sb.WriteRune(r)
+		for _, p := range pending {
+			sb.WriteRune(p)
+		}
+		pending = pending[:0]
+	}
+	for _, r := range pending {
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+var anselSpacing = map[byte]rune{
+	0xA1: 'Ł', 0xA2: 'Ø', 0xA3: 'Đ', 0xA4: 'Þ', 0xA5: 'Æ', 0xA6: 'Œ', 0xA7: 'ʹ',
+	0xA8: '·', 0xA9: '♭', 0xAA: '®', 0xAB: '±', 0xAC: 'Ơ', 0xAD: 'Ư', 0xAE: 'ʼ',
+	0xB0: 'ʻ', 0xB1: 'ł', 0xB2: 'ø', 0xB3: 'đ', 0xB4: 'þ', 0xB5: 'æ', 0xB6: 'œ',
+	0xB7: 'ʺ', 0xB8: 'ı', 0xB9: '£', 0xBA: 'ð', 0xBC: 'ơ', 0xBD: 'ư',
+	0xBE: '□', 0xBF: '■', // расширения GEDCOM
+	0xC0: '°', 0xC1: 'ℓ', 0xC2: '℗', 0xC3: '©', 0xC4: '♯', 0xC5: '¿', 0xC6: '¡',
+	0xC7: 'ß', 0xC8: '€', 0xCF: 'ß',
+}
+
+// anselCombining - комбинируемые диакритические знаки (ударение, умляут, седиль...)
+var anselCombining = map[byte]rune{
+	0xE0: '\u0309', 0xE1: '\u0300', 0xE2: '\u0301', 0xE3: '\u0302', 0xE4: '\u0303',
+	0xE5: '\u0304', 0xE6: '\u0306', 0xE7: '\u0307', 0xE8: '\u0308', 0xE9: '\u030c',
+	0xEA: '\u030a', 0xEB: '\ufe20', 0xEC: '\ufe21', 0xED: '\u0315', 0xEE: '\u030b',
+	0xEF: '\u0310', 0xF0: '\u0327', 0xF1: '\u0328', 0xF2: '\u0323', 0xF3: '\u0324',
+	0xF4: '\u0325', 0xF5: '\u0333', 0xF6: '\u0332', 0xF7: '\u0326', 0xF8: '\u031c',
+	0xF9: '\u032e', 0xFA: '\ufe22', 0xFB: '\ufe23', 0xFE: '\u0313',
+}
+
+// cp1251 - байты 0x80-0xFF кодировки windows-1251
+var cp1251 = [128]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', '\ufffd', '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	'\u00a0', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '\u00ad', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+	'А', 'Б', 'В', 'Г', 'Д', 'Е', 'Ж', 'З', 'И', 'Й', 'К', 'Л', 'М', 'Н', 'О', 'П',
+	'Р', 'С', 'Т', 'У', 'Ф', 'Х', 'Ц', 'Ч', 'Ш', 'Щ', 'Ъ', 'Ы', 'Ь', 'Э', 'Ю', 'Я',
+	'а', 'б', 'в', 'г', 'д', 'е', 'ж', 'з', 'и', 'й', 'к', 'л', 'м', 'н', 'о', 'п',
+	'р', 'с', 'т', 'у', 'ф', 'х', 'ц', 'ч', 'ш', 'щ', 'ъ', 'ы', 'ь', 'э', 'ю', 'я',
+}
+
+// cp1252 - байты 0x80-0xFF кодировки windows-1252 (0xA0-0xFF совпадают с Latin-1)
+var cp1252 = func() [128]rune {
+	t := [128]rune{
+		'€', '\ufffd', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\ufffd', 'Ž', '\ufffd',
+		'\ufffd', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\ufffd', 'ž', 'Ÿ',
+	}
+	for i := 0x20; i < 0x80; i++ {
+		t[i] = rune(0x80 + i)
+	}
+	return t
+}()
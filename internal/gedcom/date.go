@@ -0,0 +1,148 @@
+package gedcom
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var gedcomMonths = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// NormalizeDate переводит точную дату GEDCOM в формат приложения:
+// "12 MAR 1890" -> "1890-03-12", "MAR 1890" -> "1890-03", "1890" -> "1890".
+// Приблизительные даты ("ABT 1890", "BET 1880 AND 1885") возвращаются как есть.
+func NormalizeDate(value string) string {
+	value = strings.TrimSpace(value)
+	parts := strings.Fields(strings.ToUpper(value))
+
+	switch len(parts) {
+	case 1:
+		if year, ok := parseYear(parts[0]); ok {
+			return fmt.Sprintf("%04d", year)
+		}
+	case 2:
+		month := monthIndex(parts[0])
+		if year, ok := parseYear(parts[1]); ok && month > 0 {
+			return fmt.Sprintf("%04d-%02d", year, month)
+		}
+	case 3:
+		day, err := strconv.Atoi(parts[0])
+		month := monthIndex(parts[1])
+		if year, ok := parseYear(parts[2]); ok && err == nil && month > 0 && day >= 1 && day <= 31 {
+			return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+		}
+	}
+	return value
+}
+
+// FormatDate - обратное преобразование: "1890-03-12" -> "12 MAR 1890".
+// Строки в другом формате возвращаются без изменений.
+func FormatDate(value string) string {
+	value = strings.TrimSpace(value)
+	parts := strings.Split(value, "-")
+	year, ok := parseYear(parts[0])
+	if !ok || len(parts) > 3 {
+		return value
+	}
+	if len(parts) == 1 {
+		return fmt.Sprintf("%d", year)
+	}
+	month, err := strconv.Atoi(parts[1])
+	if err != nil || month < 1 || month > 12 {
+		return value
+	}
+	if len(parts) == 2 {
+		return fmt.Sprintf("%s %d", gedcomMonths[month-1], year)
+	}
+	day, err := strconv.Atoi(parts[2])
+	if err != nil || day < 1 || day > 31 {
+		return value
+	}
+	return fmt.Sprintf("%d %s %d", day, gedcomMonths[month-1], year)
+}
+
+var (
+	// Пометки календаря в записи даты: @#DJULIAN@ из GEDCOM 5.5.1, JULIAN из GEDCOM 7
+	calendarMarkRe = regexp.MustCompile(`(?i)@#D(?:JULIAN|GREGORIAN)@|\b(?:JULIAN|GREGORIAN)\b`)
+	// Одна дата без уточнений: [день] [месяц] год, год может быть двойным (1890/91)
+	plainDateRe = regexp.MustCompile(`^(?:(\d{1,2}) )?(?:([A-Z]{3}) )?(\d{3,4})(?:/(\d{1,4}))?$`)
+)
+
+// dateKeywords - уточнения GEDCOM, после которых начинается очередная дата
+var dateKeywords = map[string]bool{
+	"ABT": true, "CAL": true, "EST": true, "BEF": true, "AFT": true,
+	"BET": true, "AND": true, "FROM": true, "TO": true,
+}
+
+// FormatJulianDate записывает дату по старому стилю (см. models.Date.Calendar)
+// для GEDCOM: "@#DJULIAN@ 12 MAR 1890" в 5.5.1, "JULIAN 12 MAR 1890" в 7.
+// Двойной год в 5.5.1 пишется без пометки ("12 FEB 1890/91"), в 7 его нет -
+// пишется второй год с JULIAN. ok = false, если дату не записать без фразы
+// (например, с русским названием месяца).
+func FormatJulianDate(value string, v7 bool) (string, bool) {
+	words := strings.Fields(strings.ToUpper(calendarMarkRe.ReplaceAllString(value, " ")))
+	prefix := "@#DJULIAN@"
+	if v7 {
+		prefix = "JULIAN"
+	}
+
+	var out, part []string
+	flush := func() bool {
+		if len(part) == 0 {
+			return true
+		}
+		date := strings.Join(part, " ")
+		if len(part) == 1 {
+			date = FormatDate(date)
+		}
+		m := plainDateRe.FindStringSubmatch(date)
+		if m == nil || (m[2] != "" && monthIndex(m[2]) == 0) || (m[1] != "" && m[2] == "") {
+			return false
+		}
+		switch {
+		case m[4] == "":
+			out = append(out, prefix, date)
+		case v7:
+			year, _ := strconv.Atoi(m[3])
+			out = append(out, prefix, strings.TrimSuffix(date, m[3]+"/"+m[4])+strconv.Itoa(year+1))
+		default:
+			// В 5.5.1 двойной год - часть григорианской записи, без пометки и из двух цифр
+			year, _ := strconv.Atoi(m[3])
+			out = append(out, strings.TrimSuffix(date, m[4])+fmt.Sprintf("%02d", (year+1)%100))
+		}
+		part = nil
+		return true
+	}
+	for _, w := range words {
+		if dateKeywords[w] {
+			if !flush() {
+				return "", false
+			}
+			out = append(out, w)
+			continue
+		}
+		part = append(part, w)
+	}
+	if !flush() || len(out) == 0 {
+		return "", false
+	}
+	return strings.Join(out, " "), true
+}
+
+func parseYear(s string) (int, bool) {
+	if len(s) < 3 || len(s) > 4 {
+		return 0, false
+	}
+	year, err := strconv.Atoi(s)
+	return year, err == nil && year > 0
+}
+
+func monthIndex(s string) int {
+	for i, m := range gedcomMonths {
+		if m == s {
+			return i + 1
+		}
+	}
+	return 0
+}
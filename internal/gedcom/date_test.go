@@ -0,0 +1,30 @@
+package gedcom
+
+import "testing"
+
+func TestFormatJulianDate(t *testing.T) {
+	tests := []struct {
+		in       string
+		v551, v7 string
+	}{
+		{"@#DJULIAN@ 12 MAR 1890", "@#DJULIAN@ 12 MAR 1890", "JULIAN 12 MAR 1890"},
+		{"JULIAN 1890-03-12", "@#DJULIAN@ 12 MAR 1890", "JULIAN 12 MAR 1890"},
+		{"ABT @#DJULIAN@ 1890", "ABT @#DJULIAN@ 1890", "ABT JULIAN 1890"},
+		{"BET @#DJULIAN@ 1880 AND @#DJULIAN@ 1885", "BET @#DJULIAN@ 1880 AND @#DJULIAN@ 1885", "BET JULIAN 1880 AND JULIAN 1885"},
+		{"12 FEB 1890/91", "12 FEB 1890/91", "JULIAN 12 FEB 1891"},
+		{"1699/1700", "1699/00", "JULIAN 1700"},
+	}
+	for _, tt := range tests {
+		if got, ok := FormatJulianDate(tt.in, false); !ok || got != tt.v551 {
+			t.Errorf("FormatJulianDate(%q, 5.5.1) = %q, %v, ожидалось %q", tt.in, got, ok, tt.v551)
+		}
+		if got, ok := FormatJulianDate(tt.in, true); !ok || got != tt.v7 {
+			t.Errorf("FormatJulianDate(%q, 7) = %q, %v, ожидалось %q", tt.in, got, ok, tt.v7)
+		}
+	}
+
+	// Русскую запись не передать без фразы
+	if got, ok := FormatJulianDate("12 марта 1890 ст. ст.", false); ok {
+		t.Errorf("FormatJulianDate по-русски = %q, ожидалась фраза", got)
+	}
+}
@@ -0,0 +1,659 @@
+package gedcom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+// Версии формата для экспорта
+const (
+	Version551 = "5.5.1"
+	Version7   = "7.0"
+)
+
+// Максимальная длина строки в GEDCOM 5.5.1 (длинные значения режутся через CONC)
+const maxLineLength = 255
+
+// Даты, которые уже являются корректными фразами GEDCOM ("ABT 1890", "BET 1880 AND 1885")
+var gedcomDateRe = regexp.MustCompile(`^(?i)(ABT|CAL|EST|BEF|AFT|BET|FROM|TO|INT)\b|^(\d{1,2} )?([A-Z]{3} )?\d{3,4}$`)
+
+// ExportOptions - параметры экспорта
+type ExportOptions struct {
+	Version   string            // Version551 (по умолчанию) или Version7
+	Now       time.Time         // дата в заголовке; нулевая - текущее время
+	Places    []models.Place    // места дерева для PLAC у событий (с FullName)
+	Events    []models.Event    // события людей и пар
+	Sources   []models.Source   // источники для записей SOUR
+	Citations []models.Citation // ссылки на источники у людей, связей и событий
+}
+
+type family struct {
+	xref     string
+	husband  int
+	wife     int
+	children []int
+	notes    []string
+	rels     []int // связи, из которых собрана семья: события и ссылки на источники берутся у них
+}
+
+type exporter struct {
+	w      *bufio.Writer
+	v7     bool
+	err    error
+	objs   []string // URL фотографий для записей OBJE (только GEDCOM 7)
+	places map[int]models.Place
+
+	personEvents map[int][]models.Event
+	relEvents    map[int][]models.Event
+	sources      map[int]models.Source
+	personCites  map[int][]models.Citation
+	relCites     map[int][]models.Citation
+	eventCites   map[int][]models.Citation
+}
+
+// Export записывает дерево в формате GEDCOM. Семьи (FAM) строятся из связей
+// "родитель" и "супруг": у каждой пары родителей - одна семья. Прочие связи
+// (брат/сестра, дядя, крёстный) выгружаются как ASSO.
+func Export(out io.Writer, people []models.Person, relationships []models.Relationship, opts ExportOptions) error {
+	if opts.Version == "" {
+		opts.Version = Version551
+	}
+	if opts.Version != Version551 && opts.Version != Version7 {
+		return fmt.Errorf("неподдерживаемая версия GEDCOM %q", opts.Version)
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	g := genealogy.NewGraph(people, relationships)
+	families, famsOf, famcOf := buildFamilies(g)
+
+	e := newExporter(out, opts)
+	e.header(opts)
+
+	for _, id := range g.SortedIDs() {
+		e.individual(g.People[id], famsOf[id], famcOf[id], associations(g, id))
+	}
+	for _, fam := range families {
+		e.family(fam)
+	}
+	e.sourceRecords(opts.Sources)
+	for i, url := range e.objs {
+		e.line(0, fmt.Sprintf("@O%d@", i+1), "OBJE", "")
+		e.line(1, "", "FILE", url)
+		e.line(2, "", "FORM", mediaType(url))
+	}
+	e.line(0, "", "TRLR", "")
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+func newExporter(out io.Writer, opts ExportOptions) *exporter {
+	e := &exporter{
+		w:            bufio.NewWriter(out),
+		v7:           opts.Version == Version7,
+		places:       map[int]models.Place{},
+		personEvents: map[int][]models.Event{},
+		relEvents:    map[int][]models.Event{},
+		sources:      map[int]models.Source{},
+		personCites:  map[int][]models.Citation{},
+		relCites:     map[int][]models.Citation{},
+		eventCites:   map[int][]models.Citation{},
+	}
+	for _, p := range opts.Places {
+		e.places[p.ID] = p
+	}
+	for _, ev := range opts.Events {
+		if ev.PersonID != nil {
+			e.personEvents[*ev.PersonID] = append(e.personEvents[*ev.PersonID], ev)
+		} else if ev.RelationshipID != nil {
+			e.relEvents[*ev.RelationshipID] = append(e.relEvents[*ev.RelationshipID], ev)
+		}
+	}
+	for _, src := range opts.Sources {
+		e.sources[src.ID] = src
+	}
+	for _, c := range opts.Citations {
+		switch {
+		case c.PersonID != nil:
+			e.personCites[*c.PersonID] = append(e.personCites[*c.PersonID], c)
+		case c.RelationshipID != nil:
+			e.relCites[*c.RelationshipID] = append(e.relCites[*c.RelationshipID], c)
+		case c.EventID != nil:
+			e.eventCites[*c.EventID] = append(e.eventCites[*c.EventID], c)
+		}
+	}
+	return e
+}
+
+// buildFamilies группирует детей по паре родителей и добавляет бездетные пары супругов
+func buildFamilies(g *genealogy.Graph) ([]*family, map[int][]string, map[int][]string) {
+	byKey := map[[2]int]*family{}
+	var families []*family
+
+	get := func(a, b int) *family {
+		husband, wife := a, b
+		// HUSB/WIFE по полу, если он известен; иначе - в порядке ID
+		if g.People[a].Gender == "female" || g.People[b].Gender == "male" {
+			husband, wife = b, a
+		}
+		key := [2]int{min(a, b), max(a, b)}
+		if fam, ok := byKey[key]; ok {
+			return fam
+		}
+		fam := &family{husband: husband, wife: wife}
+		byKey[key] = fam
+		families = append(families, fam)
+		return fam
+	}
+
+	for _, childID := range g.SortedIDs() {
+		parents := g.Parents(childID)
+		var fam *family
+		switch len(parents) {
+		case 0:
+			continue
+		case 1:
+			fam = get(parents[0], 0)
+		default:
+			// Больше двух родителей GEDCOM не выражает: берём первую пару
+			fam = get(parents[0], parents[1])
+		}
+		fam.children = append(fam.children, childID)
+		for _, edge := range g.ParentEdges(childID) {
+			if edge.Parent == fam.husband || edge.Parent == fam.wife {
+				fam.rels = append(fam.rels, edge.RelationshipID)
+			}
+		}
+	}
+
+	for _, rel := range g.Edges {
+		if genealogy.KindOf(rel.Type) != genealogy.KindSpouse {
+			continue
+		}
+		if _, ok := g.People[rel.FromPersonID]; !ok {
+			continue
+		}
+		if _, ok := g.People[rel.ToPersonID]; !ok || rel.FromPersonID == rel.ToPersonID {
+			continue
+		}
+		fam := get(rel.FromPersonID, rel.ToPersonID)
+		fam.rels = append(fam.rels, rel.ID)
+		if rel.Description != "" {
+			fam.notes = append(fam.notes, rel.Description)
+		}
+	}
+
+	famsOf, famcOf := map[int][]string{}, map[int][]string{}
+	for i, fam := range families {
+		fam.xref = fmt.Sprintf("F%d", i+1)
+		for _, spouse := range []int{fam.husband, fam.wife} {
+			if spouse != 0 {
+				famsOf[spouse] = append(famsOf[spouse], fam.xref)
+			}
+		}
+		for _, child := range fam.children {
+			famcOf[child] = append(famcOf[child], fam.xref)
+		}
+	}
+	return families, famsOf, famcOf
+}
+
+type association struct {
+	to    int
+	role  string
+	relID int
+}
+
+// associations - связи человека, которые не укладываются в FAM
+func associations(g *genealogy.Graph, id int) []association {
+	var out []association
+	for _, rel := range g.Edges {
+		if rel.FromPersonID != id {
+			continue
+		}
+		if _, ok := g.People[rel.ToPersonID]; !ok {
+			continue
+		}
+		switch genealogy.KindOf(rel.Type) {
+		case genealogy.KindSibling, genealogy.KindOther:
+			out = append(out, association{to: rel.ToPersonID, role: rel.Type, relID: rel.ID})
+		}
+	}
+	return out
+}
+
+func (e *exporter) header(opts ExportOptions) {
+	e.line(0, "", "HEAD", "")
+	e.line(1, "", "GEDC", "")
+	e.line(2, "", "VERS", opts.Version)
+	if !e.v7 {
+		e.line(2, "", "FORM", "LINEAGE-LINKED")
+		e.line(1, "", "CHAR", "UTF-8")
+	}
+	e.line(1, "", "SOUR", "FAMILY-TREE-APP")
+	e.line(2, "", "NAME", "Family Tree App")
+	e.line(1, "", "DATE", strings.ToUpper(opts.Now.Format("2 Jan 2006")))
+	if !e.v7 {
+		e.line(1, "", "SUBM", "@U1@")
+		e.line(0, "@U1@", "SUBM", "")
+		e.line(1, "", "NAME", "Family Tree App")
+	}
+}
+
+func (e *exporter) individual(p models.Person, fams, famc []string, assoc []association) {
+	e.line(0, xrefPerson(p.ID), "INDI", "")
+
+	given := strings.TrimSpace(p.FirstName + " " + p.MiddleName)
+	e.line(1, "", "NAME", strings.TrimSpace(given+" /"+p.LastName+"/"))
+	if given != "" {
+		e.line(2, "", "GIVN", given)
+	}
+	if p.LastName != "" {
+		e.line(2, "", "SURN", p.LastName)
+	}
+
+	switch p.Gender {
+	case "male":
+		e.line(1, "", "SEX", "M")
+	case "female":
+		e.line(1, "", "SEX", "F")
+	case "other":
+		if e.v7 {
+			e.line(1, "", "SEX", "X")
+		} else {
+			e.line(1, "", "SEX", "U")
+		}
+	}
+
+	// Дата и место первого события рождения и смерти совпадают с датой и местом
+	// человека: из события берутся только подробности
+	events := e.personEvents[p.ID]
+	birth, death := firstEvent(events, models.EventBirth), firstEvent(events, models.EventDeath)
+	if p.BirthDate != "" || p.BirthPlaceID != nil || birth != nil {
+		e.line(1, "", "BIRT", "")
+		if p.BirthDate != "" {
+			e.date(2, p.BirthDate)
+		}
+		e.place(2, p.BirthPlaceID)
+		e.eventDetails(2, birth)
+	}
+	if p.DeathDate != nil || p.DeathPlaceID != nil || death != nil {
+		if (p.DeathDate == nil || *p.DeathDate == "") && p.DeathPlaceID == nil && !e.hasDetails(death) {
+			e.line(1, "", "DEAT", "Y")
+		} else {
+			e.line(1, "", "DEAT", "")
+			if p.DeathDate != nil && *p.DeathDate != "" {
+				e.date(2, *p.DeathDate)
+			}
+			e.place(2, p.DeathPlaceID)
+			e.eventDetails(2, death)
+		}
+	}
+	for _, ev := range events {
+		if (birth == nil || ev.ID != birth.ID) && (death == nil || ev.ID != death.ID) {
+			e.event(1, ev)
+		}
+	}
+
+	if p.PhotoURL != "" {
+		if e.v7 {
+			e.objs = append(e.objs, p.PhotoURL)
+			e.line(1, "", "OBJE", fmt.Sprintf("@O%d@", len(e.objs)))
+		} else {
+			e.line(1, "", "OBJE", "")
+			e.line(2, "", "FILE", p.PhotoURL)
+			e.line(3, "", "FORM", formatOf(p.PhotoURL))
+		}
+	}
+
+	if p.Notes != "" {
+		e.line(1, "", "NOTE", p.Notes)
+	}
+	e.citations(1, e.personCites[p.ID])
+
+	for _, x := range famc {
+		e.line(1, "", "FAMC", "@"+x+"@")
+	}
+	for _, x := range fams {
+		e.line(1, "", "FAMS", "@"+x+"@")
+	}
+	for _, a := range assoc {
+		e.line(1, "", "ASSO", xrefPerson(a.to))
+		if e.v7 {
+			e.line(2, "", "ROLE", "OTHER")
+			e.line(3, "", "PHRASE", a.role)
+		} else {
+			e.line(2, "", "RELA", a.role)
+		}
+		e.citations(2, e.relCites[a.relID])
+	}
+}
+
+func (e *exporter) family(f *family) {
+	e.line(0, "@"+f.xref+"@", "FAM", "")
+	if f.husband != 0 {
+		e.line(1, "", "HUSB", xrefPerson(f.husband))
+	}
+	if f.wife != 0 {
+		e.line(1, "", "WIFE", xrefPerson(f.wife))
+	}
+	for _, c := range f.children {
+		e.line(1, "", "CHIL", xrefPerson(c))
+	}
+	for _, relID := range f.rels {
+		for _, ev := range e.relEvents[relID] {
+			e.event(1, ev)
+		}
+	}
+	for _, n := range f.notes {
+		e.line(1, "", "NOTE", n)
+	}
+	for _, relID := range f.rels {
+		e.citations(1, e.relCites[relID])
+	}
+}
+
+// eventTags - теги GEDCOM для типов событий; остальные события - EVEN с TYPE
+var eventTags = map[string]string{
+	models.EventBirth:      "BIRT",
+	models.EventBaptism:    "BAPM",
+	models.EventDeath:      "DEAT",
+	models.EventBurial:     "BURI",
+	models.EventResidence:  "RESI",
+	models.EventEngagement: "ENGA",
+	models.EventMarriage:   "MARR",
+	models.EventDivorce:    "DIV",
+}
+
+// eventTypeNames - TYPE у событий без своего тега
+var eventTypeNames = map[string]string{
+	models.EventMigration: "Переезд",
+}
+
+// firstEvent - событие типа eventType с наименьшим ID или nil
+func firstEvent(events []models.Event, eventType string) *models.Event {
+	var first *models.Event
+	for i, ev := range events {
+		if ev.Type == eventType && (first == nil || ev.ID < first.ID) {
+			first = &events[i]
+		}
+	}
+	return first
+}
+
+func (e *exporter) event(level int, ev models.Event) {
+	tag, ok := eventTags[ev.Type]
+	if !ok {
+		tag = "EVEN"
+	}
+	e.line(level, "", tag, "")
+	if !ok {
+		name := eventTypeNames[ev.Type]
+		if name == "" {
+			name = ev.Type
+		}
+		e.line(level+1, "", "TYPE", name)
+	}
+	if ev.Date != "" {
+		e.date(level+1, ev.Date)
+	}
+	e.place(level+1, ev.PlaceID)
+	e.eventDetails(level+1, &ev)
+}
+
+// hasDetails - у события есть что записать сверх даты и места из справочника
+func (e *exporter) hasDetails(ev *models.Event) bool {
+	return ev != nil && ((ev.PlaceID == nil && ev.Place != "") || ev.Description != "" || len(e.eventCites[ev.ID]) > 0)
+}
+
+// eventDetails пишет подробности события: место как в документе (если нет места
+// из справочника), описание и ссылки на источники
+func (e *exporter) eventDetails(level int, ev *models.Event) {
+	if !e.hasDetails(ev) {
+		return
+	}
+	if ev.PlaceID == nil && ev.Place != "" {
+		e.line(level, "", "PLAC", ev.Place)
+	}
+	if ev.Description != "" {
+		e.line(level, "", "NOTE", ev.Description)
+	}
+	e.citations(level, e.eventCites[ev.ID])
+}
+
+// quality - QUAY для оценки ссылки на источник
+var quality = map[string]string{
+	models.ConfidencePrimary:      "3",
+	models.ConfidenceSecondary:    "2",
+	models.ConfidenceQuestionable: "1",
+	models.ConfidenceUnreliable:   "0",
+}
+
+// citations пишет ссылки на записи SOUR: где в источнике, выписку, заметки и оценку
+func (e *exporter) citations(level int, cites []models.Citation) {
+	for _, c := range cites {
+		if _, ok := e.sources[c.SourceID]; !ok {
+			continue
+		}
+		e.line(level, "", "SOUR", xrefSource(c.SourceID))
+		if c.Page != "" {
+			e.line(level+1, "", "PAGE", c.Page)
+		}
+		if c.Text != "" {
+			e.line(level+1, "", "DATA", "")
+			e.line(level+2, "", "TEXT", c.Text)
+		}
+		if c.Notes != "" {
+			e.line(level+1, "", "NOTE", c.Notes)
+		}
+		if q, ok := quality[c.Confidence]; ok {
+			e.line(level+1, "", "QUAY", q)
+		}
+	}
+}
+
+// sourceRecords пишет записи SOUR, а для архивов - записи REPO (по одной
+// на архив). Фонд, опись и дело становятся шифром CALN.
+func (e *exporter) sourceRecords(sources []models.Source) {
+	repos := map[string]string{}
+	var archives []string
+	for _, src := range sources {
+		if src.Archive != "" && repos[src.Archive] == "" {
+			archives = append(archives, src.Archive)
+			repos[src.Archive] = fmt.Sprintf("@R%d@", len(archives))
+		}
+	}
+
+	for _, src := range sources {
+		e.line(0, xrefSource(src.ID), "SOUR", "")
+		if src.Title != "" {
+			e.line(1, "", "TITL", src.Title)
+		}
+		if src.Author != "" {
+			e.line(1, "", "AUTH", src.Author)
+		}
+		if src.Publication != "" {
+			e.line(1, "", "PUBL", src.Publication)
+		}
+		if caln := callNumber(src); src.Archive != "" || caln != "" {
+			repo := repos[src.Archive]
+			if repo == "" && e.v7 {
+				repo = "@VOID@"
+			}
+			e.line(1, "", "REPO", repo)
+			if caln != "" {
+				e.line(2, "", "CALN", caln)
+			}
+		}
+		if note := strings.TrimSpace(src.URL + "\n" + src.Notes); note != "" {
+			e.line(1, "", "NOTE", note)
+		}
+	}
+	for i, name := range archives {
+		e.line(0, fmt.Sprintf("@R%d@", i+1), "REPO", "")
+		e.line(1, "", "NAME", name)
+	}
+}
+
+// callNumber - архивный шифр: "ф. 19, оп. 124, д. 1520"
+func callNumber(src models.Source) string {
+	var parts []string
+	for _, part := range []struct{ prefix, value string }{{"ф. ", src.Fond}, {"оп. ", src.Opis}, {"д. ", src.Delo}} {
+		if part.value != "" {
+			parts = append(parts, part.prefix+part.value)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// date пишет DATE. Даты приложения ("1890-03-12") переводятся в формат GEDCOM,
+// фразы GEDCOM ("ABT 1890") остаются как есть, прочий текст становится фразой.
+// Даты по старому стилю пишутся с пометкой календаря (см. FormatJulianDate).
+func (e *exporter) date(level int, value string) {
+	if d, err := models.ParseDate(value); err == nil && d.Calendar != models.CalendarGregorian {
+		if formatted, ok := FormatJulianDate(value, e.v7); ok {
+			e.line(level, "", "DATE", formatted)
+			return
+		}
+	}
+	formatted := FormatDate(value)
+	if gedcomDateRe.MatchString(strings.ToUpper(formatted)) {
+		e.line(level, "", "DATE", strings.ToUpper(formatted))
+		return
+	}
+	if e.v7 {
+		e.line(level, "", "DATE", "")
+		e.line(level+1, "", "PHRASE", value)
+	} else {
+		e.line(level, "", "DATE", "("+value+")")
+	}
+}
+
+// place пишет PLAC с полным названием места и координатами (MAP)
+func (e *exporter) place(level int, placeID *int) {
+	if placeID == nil {
+		return
+	}
+	p, ok := e.places[*placeID]
+	if !ok {
+		return
+	}
+	name := p.FullName
+	if name == "" {
+		name = p.Name
+	}
+	e.line(level, "", "PLAC", name)
+	if p.Latitude != nil && p.Longitude != nil {
+		e.line(level+1, "", "MAP", "")
+		e.line(level+2, "", "LATI", coordinate(*p.Latitude, "N", "S"))
+		e.line(level+2, "", "LONG", coordinate(*p.Longitude, "E", "W"))
+	}
+}
+
+// coordinate - координата в записи GEDCOM: N56.8584, W3.1
+func coordinate(v float64, positive, negative string) string {
+	prefix := positive
+	if v < 0 {
+		prefix, v = negative, -v
+	}
+	return prefix + strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// line пишет строку, разбивая многострочные значения через CONT,
+// а слишком длинные (только 5.5.1) - через CONC
+func (e *exporter) line(level int, xref, tag, value string) {
+	if e.err != nil {
+		return
+	}
+	for i, part := range strings.Split(value, "\n") {
+		curTag, curLevel := tag, level
+		if i > 0 {
+			curTag, curLevel = "CONT", level+1
+		}
+		chunks := []string{part}
+		if !e.v7 {
+			chunks = splitChunks(part, maxLineLength-len(tag)-len(xref)-8)
+		}
+		for j, chunk := range chunks {
+			if j > 0 {
+				curTag, curLevel = "CONC", level+1
+			}
+			prefix := fmt.Sprint(curLevel)
+			if xref != "" && i == 0 && j == 0 {
+				prefix += " " + xref
+			}
+			text := prefix + " " + curTag
+			if chunk != "" {
+				text += " " + chunk
+			}
+			if _, err := e.w.WriteString(text + "\n"); err != nil {
+				e.err = err
+				return
+			}
+		}
+	}
+}
+
+// splitChunks режет строку на куски не длиннее limit байт, не разрывая символы UTF-8
+// и не оставляя пробел на границе (CONC склеивает куски без разделителя)
+func splitChunks(s string, limit int) []string {
+	if len(s) <= limit {
+		return []string{s}
+	}
+	var chunks []string
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && (!utf8.RuneStart(s[cut]) || s[cut] == ' ' || s[cut-1] == ' ') {
+			cut--
+		}
+		if cut == 0 {
+			cut = limit
+			for !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+		}
+		chunks = append(chunks, s[:cut])
+		s = s[cut:]
+	}
+	return append(chunks, s)
+}
+
+func xrefPerson(id int) string {
+	return fmt.Sprintf("@I%d@", id)
+}
+
+func xrefSource(id int) string {
+	return fmt.Sprintf("@S%d@", id)
+}
+
+// formatOf - значение FORM для GEDCOM 5.5.1 (расширение файла)
+func formatOf(url string) string {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(strings.SplitN(url, "?", 2)[0])), ".")
+	if ext == "" {
+		return "jpg"
+	}
+	return ext
+}
+
+// mediaType - значение FORM для GEDCOM 7 (MIME-тип)
+func mediaType(url string) string {
+	if t := mime.TypeByExtension("." + formatOf(url)); t != "" {
+		return strings.SplitN(t, ";", 2)[0]
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,343 @@
+package gedcom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"family-tree-app/internal/models"
+)
+
+// Individual - запись INDI, приведённая к полям приложения
+type Individual struct {
+	XRef       string
+	FirstName  string
+	MiddleName string
+	LastName   string
+	Gender     string
+	BirthDate  string
+	DeathDate  *string
+	PhotoURL   string
+	Notes      string // встроенные NOTE, ссылки на записи NOTE пропускаются
+	// Associations - связи ASSO (крёстный, брат и т.п.): ссылка и роль
+	Associations []Association
+}
+
+// Association - связь ASSO из записи INDI
+type Association struct {
+	XRef string
+	Role string
+}
+
+// Family - запись FAM: супруги и дети
+type Family struct {
+	XRef         string
+	Husband      string
+	Wife         string
+	Children     []string
+	MarriageDate string
+	Note         string
+}
+
+// Document - разобранный файл GEDCOM
+type Document struct {
+	Charset     string
+	Individuals []Individual
+	Families    []Family
+	// Unmapped - сколько раз встретились теги, которые приложение не хранит
+	// ("SOUR", "INDI.OCCU"...)
+	Unmapped map[string]int
+}
+
+// Skipped - запись, которую не удалось импортировать
+type Skipped struct {
+	XRef   string `json:"xref"`
+	Reason string `json:"reason"`
+}
+
+// Report - итог импорта
+type Report struct {
+	Charset               string         `json:"charset"`
+	PeopleImported        int            `json:"people_imported"`
+	RelationshipsImported int            `json:"relationships_imported"`
+	Skipped               []Skipped      `json:"skipped"`
+	Unmapped              map[string]int `json:"unmapped"`
+}
+
+// Теги INDI и FAM, которые переносятся в приложение
+var (
+	indiMapped = map[string]bool{"NAME": true, "SEX": true, "BIRT": true, "DEAT": true, "OBJE": true, "NOTE": true, "FAMS": true, "FAMC": true, "ASSO": true}
+	famMapped  = map[string]bool{"HUSB": true, "WIFE": true, "CHIL": true, "MARR": true, "NOTE": true}
+)
+
+// Decode декодирует и разбирает файл. charset - необязательное переопределение кодировки.
+func Decode(data []byte, charset string) (*Document, error) {
+	text, detected, err := DecodeText(data, charset)
+	if err != nil {
+		return nil, err
+	}
+	records, err := ParseRecords(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || records[0].Tag != "HEAD" {
+		return nil, fmt.Errorf("это не GEDCOM: файл должен начинаться с 0 HEAD")
+	}
+
+	doc := &Document{Charset: detected, Unmapped: map[string]int{}}
+	for _, rec := range records {
+		switch rec.Tag {
+		case "HEAD", "TRLR":
+		case "INDI":
+			doc.Individuals = append(doc.Individuals, parseIndividual(rec, doc.Unmapped))
+		case "FAM":
+			doc.Families = append(doc.Families, parseFamily(rec, doc.Unmapped))
+		default:
+			doc.Unmapped[rec.Tag]++
+		}
+	}
+	return doc, nil
+}
+
+func parseIndividual(rec *Line, unmapped map[string]int) Individual {
+	ind := Individual{XRef: rec.XRef}
+
+	if name := rec.Child("NAME"); name != nil {
+		ind.FirstName, ind.MiddleName, ind.LastName = splitName(name)
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(rec.ChildValue("SEX"))) {
+	case "M":
+		ind.Gender = "male"
+	case "F":
+		ind.Gender = "female"
+	case "":
+	default:
+		ind.Gender = "other"
+	}
+
+	if birth := rec.Child("BIRT"); birth != nil {
+		ind.BirthDate = NormalizeDate(birth.ChildValue("DATE"))
+	}
+	if death := rec.Child("DEAT"); death != nil {
+		if date := NormalizeDate(death.ChildValue("DATE")); date != "" {
+			ind.DeathDate = &date
+		}
+	}
+
+	// Фото переносим только по внешней ссылке: локальные пути из чужого компьютера бесполезны
+	for _, obje := range rec.ChildrenByTag("OBJE") {
+		file := obje.ChildValue("FILE")
+		if strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://") {
+			ind.PhotoURL = file
+			break
+		}
+	}
+
+	var notes []string
+	for _, note := range rec.ChildrenByTag("NOTE") {
+		if text := strings.TrimSpace(note.Value); text != "" && !strings.HasPrefix(text, "@") {
+			notes = append(notes, text)
+		}
+	}
+	ind.Notes = strings.Join(notes, "\n\n")
+
+	// Роль ASSO: RELA в 5.5.1, ROLE/PHRASE в GEDCOM 7
+	for _, asso := range rec.ChildrenByTag("ASSO") {
+		role := asso.ChildValue("RELA")
+		if roleLine := asso.Child("ROLE"); role == "" && roleLine != nil {
+			role = roleLine.ChildValue("PHRASE")
+			if role == "" {
+				role = strings.ToLower(roleLine.Value)
+			}
+		}
+		if role != "" {
+			ind.Associations = append(ind.Associations, Association{XRef: pointer(asso.Value), Role: role})
+		}
+	}
+
+	for _, c := range rec.Children {
+		if !indiMapped[c.Tag] {
+			unmapped["INDI."+c.Tag]++
+		}
+	}
+	return ind
+}
+
+// splitName разбирает "Иван Петрович /Иванов/". Если в GIVN несколько слов,
+// второе считается отчеством (русская традиция).
+func splitName(name *Line) (first, middle, last string) {
+	given := name.ChildValue("GIVN")
+	last = name.ChildValue("SURN")
+
+	if given == "" || last == "" {
+		value := name.Value
+		if i := strings.Index(value, "/"); i >= 0 {
+			rest := value[i+1:]
+			surname, after, _ := strings.Cut(rest, "/")
+			if last == "" {
+				last = surname
+			}
+			if given == "" {
+				given = strings.TrimSpace(value[:i] + " " + after)
+			}
+		} else if given == "" {
+			given = value
+		}
+	}
+
+	words := strings.Fields(given)
+	switch len(words) {
+	case 0:
+	case 1:
+		first = words[0]
+	default:
+		first = words[0]
+		middle = strings.Join(words[1:], " ")
+	}
+	return first, middle, strings.TrimSpace(last)
+}
+
+func parseFamily(rec *Line, unmapped map[string]int) Family {
+	fam := Family{
+		XRef:    rec.XRef,
+		Husband: pointer(rec.ChildValue("HUSB")),
+		Wife:    pointer(rec.ChildValue("WIFE")),
+	}
+	for _, c := range rec.ChildrenByTag("CHIL") {
+		fam.Children = append(fam.Children, pointer(c.Value))
+	}
+	if marr := rec.Child("MARR"); marr != nil {
+		fam.MarriageDate = NormalizeDate(marr.ChildValue("DATE"))
+	}
+	// Ссылки на записи NOTE (@N1@) не разыменовываем
+	if note := rec.ChildValue("NOTE"); !strings.HasPrefix(note, "@") {
+		fam.Note = note
+	}
+
+	for _, c := range rec.Children {
+		if !famMapped[c.Tag] {
+			unmapped["FAM."+c.Tag]++
+		}
+	}
+	return fam
+}
+
+// BuildTree превращает документ в людей и связи приложения.
+// ID людей - локальные (1..N), связи ссылаются на них; настоящие ID назначает хранилище.
+// Из FAM получаются связи "spouse" (муж - жена) и "parent" (родитель -> ребёнок).
+func BuildTree(doc *Document) ([]models.Person, []models.Relationship, *Report) {
+	report := &Report{Charset: doc.Charset, Skipped: []Skipped{}, Unmapped: doc.Unmapped}
+	localID := map[string]int{}
+	var people []models.Person
+
+	for _, ind := range doc.Individuals {
+		if ind.XRef == "" {
+			report.Skipped = append(report.Skipped, Skipped{Reason: "запись INDI без идентификатора"})
+			continue
+		}
+		if _, dup := localID[ind.XRef]; dup {
+			report.Skipped = append(report.Skipped, Skipped{XRef: ind.XRef, Reason: "повторный идентификатор INDI"})
+			continue
+		}
+		if ind.FirstName == "" && ind.LastName == "" {
+			ind.FirstName = "Неизвестно"
+		}
+
+		p := models.Person{
+			ID:         len(people) + 1,
+			FirstName:  ind.FirstName,
+			MiddleName: ind.MiddleName,
+			LastName:   ind.LastName,
+			BirthDate:  ind.BirthDate,
+			DeathDate:  ind.DeathDate,
+			Gender:     ind.Gender,
+			PhotoURL:   ind.PhotoURL,
+			Notes:      ind.Notes,
+		}
+		localID[ind.XRef] = p.ID
+		people = append(people, p)
+	}
+
+	type edge struct {
+		kind     string
+		from, to int
+	}
+	seen := map[edge]bool{}
+	var relationships []models.Relationship
+	addEdge := func(kind string, from, to int, description string) {
+		if kind == "spouse" && from > to {
+			from, to = to, from
+		}
+		e := edge{kind, from, to}
+		if from == to || seen[e] {
+			return
+		}
+		seen[e] = true
+		relationships = append(relationships, models.Relationship{
+			FromPersonID: from,
+			ToPersonID:   to,
+			Type:         kind,
+			Description:  description,
+		})
+	}
+
+	resolve := func(fam Family, xref, role string) (int, bool) {
+		if xref == "" {
+			return 0, false
+		}
+		id, ok := localID[xref]
+		if !ok {
+			report.Skipped = append(report.Skipped, Skipped{XRef: fam.XRef, Reason: fmt.Sprintf("%s ссылается на несуществующую запись %s", role, xref)})
+		}
+		return id, ok
+	}
+
+	for _, fam := range doc.Families {
+		var parents []int
+		if id, ok := resolve(fam, fam.Husband, "HUSB"); ok {
+			parents = append(parents, id)
+		}
+		if id, ok := resolve(fam, fam.Wife, "WIFE"); ok {
+			parents = append(parents, id)
+		}
+
+		if len(parents) == 2 {
+			description := fam.Note
+			if description == "" && fam.MarriageDate != "" {
+				description = "Брак: " + fam.MarriageDate
+			}
+			addEdge("spouse", parents[0], parents[1], description)
+		}
+
+		for _, childXRef := range fam.Children {
+			childID, ok := resolve(fam, childXRef, "CHIL")
+			if !ok {
+				continue
+			}
+			for _, parentID := range parents {
+				addEdge("parent", parentID, childID, "")
+			}
+		}
+	}
+
+	for _, ind := range doc.Individuals {
+		fromID, ok := localID[ind.XRef]
+		if !ok {
+			continue
+		}
+		for _, asso := range ind.Associations {
+			toID, ok := localID[asso.XRef]
+			if !ok {
+				report.Skipped = append(report.Skipped, Skipped{XRef: ind.XRef, Reason: fmt.Sprintf("ASSO ссылается на несуществующую запись %s", asso.XRef)})
+				continue
+			}
+			addEdge(asso.Role, fromID, toID, "")
+		}
+	}
+
+	report.PeopleImported = len(people)
+	report.RelationshipsImported = len(relationships)
+	sort.SliceStable(report.Skipped, func(i, j int) bool { return report.Skipped[i].XRef < report.Skipped[j].XRef })
+	return people, relationships, report
+}
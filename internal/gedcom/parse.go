@@ -0,0 +1,119 @@
+// Package gedcom - чтение и запись файлов GEDCOM 5.5.1 (и экспорт в GEDCOM 7).
+package gedcom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Line - строка GEDCOM вместе с вложенными строками.
+// Продолжения CONC/CONT уже склеены в Value.
+type Line struct {
+	Level    int
+	XRef     string // "@I1@" без собачек: "I1"
+	Tag      string
+	Value    string
+	Children []*Line
+}
+
+// Child - первая вложенная строка с тегом (или nil)
+func (l *Line) Child(tag string) *Line {
+	for _, c := range l.Children {
+		if c.Tag == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+// ChildValue - значение первой вложенной строки с тегом
+func (l *Line) ChildValue(tag string) string {
+	if c := l.Child(tag); c != nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ChildrenByTag - все вложенные строки с тегом
+func (l *Line) ChildrenByTag(tag string) []*Line {
+	var out []*Line
+	for _, c := range l.Children {
+		if c.Tag == tag {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// ParseRecords разбирает текст GEDCOM на записи верхнего уровня (HEAD, INDI, FAM...)
+func ParseRecords(text string) ([]*Line, error) {
+	var records []*Line
+	var stack []*Line // stack[i] - последняя строка уровня i
+
+	for n, raw := range strings.Split(text, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		line, err := parseLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", n+1, err)
+		}
+
+		if line.Level > len(stack) {
+			return nil, fmt.Errorf("строка %d: уровень %d после уровня %d", n+1, line.Level, len(stack)-1)
+		}
+		stack = stack[:line.Level]
+
+		if line.Level == 0 {
+			records = append(records, line)
+			stack = append(stack, line)
+			continue
+		}
+
+		parent := stack[line.Level-1]
+		switch line.Tag {
+		case "CONC":
+			parent.Value += line.Value
+			continue
+		case "CONT":
+			parent.Value += "\n" + line.Value
+			continue
+		}
+		parent.Children = append(parent.Children, line)
+		stack = append(stack, line)
+	}
+	return records, nil
+}
+
+// parseLine разбирает "уровень [@xref@] тег [значение]"
+func parseLine(raw string) (*Line, error) {
+	raw = strings.TrimLeft(raw, " \t\ufeff")
+	levelStr, rest, _ := strings.Cut(raw, " ")
+	level, err := strconv.Atoi(levelStr)
+	if err != nil || level < 0 {
+		return nil, fmt.Errorf("некорректный уровень %q", levelStr)
+	}
+
+	line := &Line{Level: level}
+	rest = strings.TrimLeft(rest, " ")
+	if strings.HasPrefix(rest, "@") {
+		xref, after, _ := strings.Cut(rest, " ")
+		line.XRef = strings.Trim(xref, "@")
+		rest = strings.TrimLeft(after, " ")
+	}
+
+	tag, value, _ := strings.Cut(rest, " ")
+	if tag == "" {
+		return nil, fmt.Errorf("нет тега")
+	}
+	line.Tag = strings.ToUpper(tag)
+	line.Value = value
+	return line, nil
+}
+
+// pointer убирает собачки из ссылки: "@I1@" -> "I1"
+func pointer(value string) string {
+	return strings.Trim(strings.TrimSpace(value), "@")
+}
@@ -0,0 +1,92 @@
+package genealogy
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+// ApproxYears - насколько может ошибаться приблизительная дата (около, оценка,
+// расчёт): в проверках она считается промежутком на столько лет в обе стороны
+const ApproxYears = 2
+
+// dateSpan - дата как промежуток, в который она точно попадает (см. models.Date).
+// Нулевая граница - не ограничено: у "до 1900" нет начала, у "после 1880" - конца.
+type dateSpan struct {
+	from, to time.Time
+	nominal  time.Time // сама дата без поправки на приблизительность: по ней сортируют
+}
+
+var looseYearRe = regexp.MustCompile(`\d{4}`)
+
+// parseSpan разбирает дату человека. ok = false, если ни одной границы нет.
+// Старые записи, которые ParseDate не понимает ("1890?", "весной 1890"),
+// считаются приблизительными по первому году в строке.
+func parseSpan(s string) (dateSpan, bool) {
+	d, err := models.ParseDate(s)
+	if err != nil {
+		y, _ := strconv.Atoi(looseYearRe.FindString(s))
+		if y == 0 {
+			return dateSpan{}, false
+		}
+		d = models.Date{Qualifier: models.DateAbout, From: strconv.Itoa(y) + "-01-01", To: strconv.Itoa(y) + "-12-31"}
+	}
+	if !d.Known() {
+		return dateSpan{}, false
+	}
+
+	var sp dateSpan
+	sp.from, sp.to = d.Range()
+	sp.nominal = sp.from
+	if sp.nominal.IsZero() {
+		sp.nominal = sp.to
+	}
+	if d.Approximate() {
+		sp.from, sp.to = sp.from.AddDate(-ApproxYears, 0, 0), sp.to.AddDate(ApproxYears, 0, 0)
+	}
+	return sp, true
+}
+
+// DateYear - год даты в любой записи ("1890", "1890-03-12", "ABT 1890", "до 1900");
+// для промежутка - год его начала. ok = false, если года нет.
+func DateYear(s string) (year int, ok bool) {
+	sp, ok := parseSpan(s)
+	return sp.nominal.Year(), ok
+}
+
+// definitelyBefore - d точно раньше o: конец одного промежутка раньше начала другого
+func (d dateSpan) definitelyBefore(o dateSpan) bool {
+	return !d.to.IsZero() && !o.from.IsZero() && d.to.Before(o.from)
+}
+
+// overlaps - промежутки пересекаются, то есть это может быть одна и та же дата
+func (d dateSpan) overlaps(o dateSpan) bool {
+	return !d.definitelyBefore(o) && !o.definitelyBefore(d)
+}
+
+// minYears - сколько полных лет прошло от from до to как минимум; ok = false, если не ограничено
+func minYears(from, to dateSpan) (int, bool) {
+	if from.to.IsZero() || to.from.IsZero() {
+		return 0, false
+	}
+	return yearsBetween(from.to, to.from), true
+}
+
+// maxYears - сколько полных лет прошло от from до to как максимум
+func maxYears(from, to dateSpan) (int, bool) {
+	if from.from.IsZero() || to.to.IsZero() {
+		return 0, false
+	}
+	return yearsBetween(from.from, to.to), true
+}
+
+// yearsBetween - полное число лет между датами
+func yearsBetween(from, to time.Time) int {
+	years := to.Year() - from.Year()
+	if to.Month() < from.Month() || (to.Month() == from.Month() && to.Day() < from.Day()) {
+		years--
+	}
+	return years
+}
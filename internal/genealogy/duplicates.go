@@ -0,0 +1,315 @@
+package genealogy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"family-tree-app/internal/models"
+)
+
+// DefaultDuplicateScore - с какой оценки пара считается возможным дублем
+const DefaultDuplicateScore = 0.6
+
+// Веса составляющих оценки дубля
+const (
+	dupNameWeight      = 0.6  // имя целиком даёт не больше 0.6
+	dupDateMatch       = 0.15 // совпал год рождения или смерти
+	dupDateClose       = 0.08 // разница не больше dupDateTolerance лет
+	dupDateConflict    = 0.25 // штраф за явно разные даты
+	dupRelativeBonus   = 0.1  // за каждого общего родственника
+	dupRelativesMax    = 0.2
+	dupDateTolerance   = 2
+	dupMinNameScore    = 0.7 // пары с менее похожими именами не рассматриваются
+	dupRelativeNameMin = 0.9 // родственники разных копий считаются общими, если имена настолько похожи
+)
+
+// Duplicate - пара людей, которые, возможно, один и тот же человек
+type Duplicate struct {
+	People  []models.Person `json:"people"` // двое, меньший ID первым
+	Score   float64         `json:"score"`  // от 0 до 1
+	Reasons []string        `json:"reasons"`
+}
+
+// nameKey - имя, подготовленное к сравнению (см. FoldName)
+type nameKey struct {
+	first, middle, last string
+}
+
+func nameKeyOf(p models.Person) nameKey {
+	return nameKey{
+		first:  FoldName(p.FirstName),
+		middle: patronymicStem(FoldName(p.MiddleName)),
+		last:   surnameStem(FoldName(p.LastName)),
+	}
+}
+
+// nameScore - похожесть имён от 0 до 1. Отчество учитывается, только если оно есть у обоих,
+// фамилия - тоже: у одного из дублей её часто просто не записали.
+func nameScore(a, b nameKey) float64 {
+	score, weight := 0.45*similarity(a.first, b.first), 0.45
+	if a.last != "" && b.last != "" {
+		score += 0.35 * similarity(a.last, b.last)
+		weight += 0.35
+	}
+	if a.middle != "" && b.middle != "" {
+		score += 0.2 * similarity(a.middle, b.middle)
+		weight += 0.2
+	}
+	return score / weight
+}
+
+// FindDuplicates ищет в дереве пары возможных дублей с оценкой не ниже minScore,
+// лучшие первыми. Сравниваются только люди, у которых ключ имени начинается
+// с одной буквы, и не бывают дублями люди разного пола или родитель с ребёнком.
+func FindDuplicates(g *Graph, minScore float64) []Duplicate {
+	keys := make(map[int]nameKey, len(g.People))
+	blocks := map[byte][]int{}
+	for _, id := range g.SortedIDs() {
+		k := nameKeyOf(g.People[id])
+		if k.first == "" {
+			continue
+		}
+		keys[id] = k
+		blocks[k.first[0]] = append(blocks[k.first[0]], id)
+	}
+
+	var found []Duplicate
+	for _, ids := range blocks {
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				if d, ok := scoreDuplicate(g, keys, a, b); ok && d.Score >= minScore {
+					found = append(found, d)
+				}
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Score != found[j].Score {
+			return found[i].Score > found[j].Score
+		}
+		if found[i].People[0].ID != found[j].People[0].ID {
+			return found[i].People[0].ID < found[j].People[0].ID
+		}
+		return found[i].People[1].ID < found[j].People[1].ID
+	})
+	return found
+}
+
+func scoreDuplicate(g *Graph, keys map[int]nameKey, a, b int) (Duplicate, bool) {
+	pa, pb := g.People[a], g.People[b]
+	if knownGender(pa.Gender) && knownGender(pb.Gender) && !strings.EqualFold(pa.Gender, pb.Gender) {
+		return Duplicate{}, false
+	}
+	if contains(g.Parents(a), b) || contains(g.Parents(b), a) {
+		return Duplicate{}, false
+	}
+
+	name := nameScore(keys[a], keys[b])
+	if name < dupMinNameScore {
+		return Duplicate{}, false
+	}
+	d := Duplicate{People: []models.Person{pa, pb}, Score: dupNameWeight * name}
+	if name == 1 {
+		d.Reasons = append(d.Reasons, "одинаковые имена")
+	} else {
+		d.Reasons = append(d.Reasons, fmt.Sprintf("похожие имена (%.0f%%)", name*100))
+	}
+
+	var death, deathB string
+	if pa.DeathDate != nil {
+		death = *pa.DeathDate
+	}
+	if pb.DeathDate != nil {
+		deathB = *pb.DeathDate
+	}
+	for _, dates := range []struct{ a, b, what string }{
+		{pa.BirthDate, pb.BirthDate, "рождения"},
+		{death, deathB, "смерти"},
+	} {
+		da, okA := parseSpan(dates.a)
+		db, okB := parseSpan(dates.b)
+		if !okA || !okB {
+			continue
+		}
+		// Приблизительные и неполные даты не штрафуются, пока их промежутки пересекаются
+		switch diff := abs(da.nominal.Year() - db.nominal.Year()); {
+		case diff == 0:
+			d.Score += dupDateMatch
+			d.Reasons = append(d.Reasons, "совпадает год "+dates.what)
+		case diff <= dupDateTolerance || da.overlaps(db):
+			d.Score += dupDateClose
+			d.Reasons = append(d.Reasons, fmt.Sprintf("год %s отличается на %d", dates.what, diff))
+		default:
+			d.Score -= dupDateConflict
+			d.Reasons = append(d.Reasons, fmt.Sprintf("разные годы %s: %d и %d", dates.what, da.nominal.Year(), db.nominal.Year()))
+		}
+	}
+
+	if shared := sharedRelatives(g, keys, a, b); shared > 0 {
+		d.Score += min(float64(shared)*dupRelativeBonus, dupRelativesMax)
+		d.Reasons = append(d.Reasons, fmt.Sprintf("общие родственники: %d", shared))
+	}
+
+	d.Score = max(0, min(1, d.Score))
+	d.Score = float64(int(d.Score*100+0.5)) / 100
+	return d, true
+}
+
+// sharedRelatives - сколько родителей, детей, супругов и братьев у a и b общие.
+// При импорте от разных родственников общие люди тоже бывают дублями,
+// поэтому совпадением считается и родственник с очень похожим именем.
+func sharedRelatives(g *Graph, keys map[int]nameKey, a, b int) int {
+	relativesOf := func(id int) []int {
+		var ids []int
+		for _, group := range [][]int{g.Parents(id), g.Children(id), g.Spouses(id), g.Siblings(id)} {
+			for _, r := range group {
+				if r != a && r != b {
+					ids = appendUnique(ids, r)
+				}
+			}
+		}
+		return ids
+	}
+
+	relB := relativesOf(b)
+	used := map[int]bool{}
+	shared := 0
+	for _, ra := range relativesOf(a) {
+		for _, rb := range relB {
+			if used[rb] {
+				continue
+			}
+			if ra == rb || nameScore(keys[ra], keys[rb]) >= dupRelativeNameMin {
+				used[rb] = true
+				shared++
+				break
+			}
+		}
+	}
+	return shared
+}
+
+func knownGender(g string) bool {
+	g = strings.ToLower(g)
+	return g == "male" || g == "female"
+}
+
+func contains(ids []int, id int) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeFields - поля человека, которые можно выбрать при слиянии
+var MergeFields = []string{"first_name", "middle_name", "last_name", "birth_date", "death_date", "gender", "photo_url", "notes",
+	"birth_place_id", "death_place_id", "photo_media_id"}
+
+// Стороны при выборе поля
+const (
+	MergeKeep  = "keep"
+	MergeOther = "merge"
+)
+
+// MergePerson собирает одного человека из двух. Для каждого поля берётся
+// значение keep, а если оно пустое - значение other. prefer задаёт сторону
+// явно: {"birth_date": "merge"}. ID и координаты остаются от keep.
+func MergePerson(keep, other models.Person, prefer map[string]string) (models.Person, error) {
+	fields := func(p *models.Person) map[string]*string {
+		if p.DeathDate == nil {
+			p.DeathDate = new(string)
+		}
+		return map[string]*string{
+			"first_name": &p.FirstName, "middle_name": &p.MiddleName, "last_name": &p.LastName,
+			"birth_date": &p.BirthDate, "death_date": p.DeathDate, "gender": &p.Gender, "photo_url": &p.PhotoURL, "notes": &p.Notes,
+		}
+	}
+
+	// Ссылки на места и файл портрета
+	refs := func(p *models.Person) map[string]**int {
+		return map[string]**int{"birth_place_id": &p.BirthPlaceID, "death_place_id": &p.DeathPlaceID, "photo_media_id": &p.PhotoMediaID}
+	}
+
+	merged := keep
+	if keep.DeathDate != nil {
+		death := *keep.DeathDate
+		merged.DeathDate = &death
+	}
+	mf, of := fields(&merged), fields(&other)
+	mp, op := refs(&merged), refs(&other)
+
+	for field, side := range prefer {
+		_, isText := mf[field]
+		_, isRef := mp[field]
+		if !isText && !isRef {
+			return models.Person{}, fmt.Errorf("неизвестное поле %q", field)
+		}
+		if side != MergeKeep && side != MergeOther {
+			return models.Person{}, fmt.Errorf("для поля %s можно выбрать только %s или %s", field, MergeKeep, MergeOther)
+		}
+	}
+	for _, field := range MergeFields {
+		if _, ok := mp[field]; ok {
+			if prefer[field] == MergeOther || (prefer[field] == "" && *mp[field] == nil) {
+				*mp[field] = *op[field]
+			}
+			continue
+		}
+		switch prefer[field] {
+		case MergeOther:
+			*mf[field] = *of[field]
+		case "":
+			if strings.TrimSpace(*mf[field]) == "" {
+				*mf[field] = *of[field]
+			}
+		}
+	}
+	if *merged.DeathDate == "" {
+		merged.DeathDate = nil
+	}
+	return merged, nil
+}
+
+// ApplyMerge повторяет слияние на копии дерева (для проверок до записи):
+// связи other переходят к keep, связи между ними самими и повторы
+// уже существующих связей пропадают, other удаляется.
+func ApplyMerge(people []models.Person, relationships []models.Relationship, merged models.Person, otherID int) ([]models.Person, []models.Relationship) {
+	var outPeople []models.Person
+	for _, p := range people {
+		switch p.ID {
+		case otherID:
+			continue
+		case merged.ID:
+			p = merged
+		}
+		outPeople = append(outPeople, p)
+	}
+
+	var outRels []models.Relationship
+	for _, rel := range relationships {
+		if rel.FromPersonID == otherID {
+			rel.FromPersonID = merged.ID
+		}
+		if rel.ToPersonID == otherID {
+			rel.ToPersonID = merged.ID
+		}
+		if rel.FromPersonID == rel.ToPersonID {
+			continue
+		}
+		duplicate := false
+		for _, kept := range outRels {
+			if SameEdge(kept, rel) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			outRels = append(outRels, rel)
+		}
+	}
+	return outPeople, outRels
+}
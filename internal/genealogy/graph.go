@@ -0,0 +1,151 @@
+// Package genealogy - алгоритмы над деревом: проверки согласованности, обход предков и т.п.
+// Работает с уже загруженными models.Person и models.Relationship и не зависит от хранилища.
+package genealogy
+
+import (
+	"sort"
+	"strings"
+
+	"family-tree-app/internal/models"
+)
+
+// Kind - нормализованный тип связи. Типы в БД - свободный текст
+// ("parent", "Родитель", "Мать"...), см. также web/src/utils/relationshipTypes.js.
+type Kind int
+
+const (
+	KindOther   Kind = iota
+	KindParent       // from - родитель to
+	KindChild        // from - ребёнок to
+	KindSpouse       // супруги
+	KindSibling      // брат/сестра
+)
+
+// KindOf приводит свободный тип связи к Kind
+func KindOf(relType string) Kind {
+	switch strings.ToLower(strings.TrimSpace(relType)) {
+	case "parent", "родитель", "отец", "мать":
+		return KindParent
+	case "child", "ребенок", "ребёнок", "сын", "дочь":
+		return KindChild
+	case "spouse", "супруг", "супруга", "жена", "муж":
+		return KindSpouse
+	case "sibling", "brother", "sister", "брат", "сестра":
+		return KindSibling
+	}
+	return KindOther
+}
+
+// ParentEdge - ребро "родитель -> ребёнок" с ID исходной связи
+type ParentEdge struct {
+	Parent, Child  int
+	RelationshipID int
+}
+
+// Graph - дерево, разложенное по индексам для быстрых обходов
+type Graph struct {
+	People   map[int]models.Person
+	parents  map[int][]ParentEdge // ребёнок -> рёбра к родителям
+	children map[int][]ParentEdge // родитель -> рёбра к детям
+	spouses  map[int][]int
+	siblings map[int][]int
+	Edges    []models.Relationship
+}
+
+// NewGraph строит граф. Связи "child" переворачиваются в "parent",
+// связи на отсутствующих людей игнорируются.
+func NewGraph(people []models.Person, relationships []models.Relationship) *Graph {
+	g := &Graph{
+		People:   make(map[int]models.Person, len(people)),
+		parents:  map[int][]ParentEdge{},
+		children: map[int][]ParentEdge{},
+		spouses:  map[int][]int{},
+		siblings: map[int][]int{},
+		Edges:    relationships,
+	}
+	for _, p := range people {
+		g.People[p.ID] = p
+	}
+
+	for _, rel := range relationships {
+		if _, ok := g.People[rel.FromPersonID]; !ok {
+			continue
+		}
+		if _, ok := g.People[rel.ToPersonID]; !ok {
+			continue
+		}
+
+		switch KindOf(rel.Type) {
+		case KindParent:
+			g.addParent(ParentEdge{Parent: rel.FromPersonID, Child: rel.ToPersonID, RelationshipID: rel.ID})
+		case KindChild:
+			g.addParent(ParentEdge{Parent: rel.ToPersonID, Child: rel.FromPersonID, RelationshipID: rel.ID})
+		case KindSpouse:
+			g.spouses[rel.FromPersonID] = appendUnique(g.spouses[rel.FromPersonID], rel.ToPersonID)
+			g.spouses[rel.ToPersonID] = appendUnique(g.spouses[rel.ToPersonID], rel.FromPersonID)
+		case KindSibling:
+			g.siblings[rel.FromPersonID] = appendUnique(g.siblings[rel.FromPersonID], rel.ToPersonID)
+			g.siblings[rel.ToPersonID] = appendUnique(g.siblings[rel.ToPersonID], rel.FromPersonID)
+		}
+	}
+	return g
+}
+
+func (g *Graph) addParent(e ParentEdge) {
+	g.parents[e.Child] = append(g.parents[e.Child], e)
+	g.children[e.Parent] = append(g.children[e.Parent], e)
+}
+
+// Parents - уникальные ID родителей по возрастанию
+func (g *Graph) Parents(id int) []int {
+	var ids []int
+	for _, e := range g.parents[id] {
+		ids = appendUnique(ids, e.Parent)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Children - уникальные ID детей по возрастанию
+func (g *Graph) Children(id int) []int {
+	var ids []int
+	for _, e := range g.children[id] {
+		ids = appendUnique(ids, e.Child)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// ParentEdges - все рёбра к родителям (включая дубликаты связей)
+func (g *Graph) ParentEdges(id int) []ParentEdge {
+	return g.parents[id]
+}
+
+// Spouses - супруги человека
+func (g *Graph) Spouses(id int) []int {
+	return g.spouses[id]
+}
+
+// Siblings - явно указанные братья и сёстры (без вычисления через общих родителей)
+func (g *Graph) Siblings(id int) []int {
+	return g.siblings[id]
+}
+
+// SortedIDs - ID всех людей по возрастанию (для детерминированного вывода)
+func (g *Graph) SortedIDs() []int {
+	ids := make([]int, 0, len(g.People))
+	for id := range g.People {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func appendUnique(ids []int, id int) []int {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,197 @@
+package genealogy
+
+// Виды родства в ответе Kinship
+const (
+	KinshipSelf   = "self"
+	KinshipBlood  = "blood"
+	KinshipSpouse = "spouse"
+	KinshipInLaw  = "in_law"
+	KinshipNone   = "none"
+)
+
+// Kinship - кем человек B приходится человеку A
+type Kinship struct {
+	PersonA int    `json:"person_a"`
+	PersonB int    `json:"person_b"`
+	Kind    string `json:"kind"`
+	// EN и RU - название родства B по отношению к A ("second cousin once removed", "троюродный брат")
+	EN string `json:"en"`
+	RU string `json:"ru"`
+	// GenerationsA и GenerationsB - число поколений от A и от B до общего предка
+	GenerationsA    int   `json:"generations_a"`
+	GenerationsB    int   `json:"generations_b"`
+	Half            bool  `json:"half"`
+	CommonAncestors []int `json:"common_ancestors"`
+	// Path - цепочка ID от A до B, обосновывающая родство
+	Path []int `json:"path"`
+}
+
+// bloodLink - кровное родство через ближайших общих предков
+type bloodLink struct {
+	up, down  int   // поколений от A вверх и от общего предка вниз до B
+	ancestors []int // ближайшие общие предки
+	path      []int // A ... предок ... B
+	half      bool
+}
+
+// ComputeKinship находит родство B по отношению к A. Кровное родство ищется через
+// ближайших общих предков по связям "родитель"; если его нет - свойство через
+// одну связь "супруг" (супруг родственника или родственник супруга).
+func ComputeKinship(g *Graph, a, b int) Kinship {
+	k := Kinship{PersonA: a, PersonB: b, CommonAncestors: []int{}, Path: []int{}}
+	pb := g.People[b]
+
+	if a == b {
+		k.Kind, k.EN, k.RU, k.Path = KinshipSelf, "self", "это один и тот же человек", []int{a}
+		return k
+	}
+
+	if link, ok := g.blood(a, b); ok {
+		k.Kind = KinshipBlood
+		k.GenerationsA, k.GenerationsB = link.up, link.down
+		k.Half, k.CommonAncestors, k.Path = link.half, link.ancestors, link.path
+		k.EN = bloodNameEN(link.up, link.down, link.half, pb.Gender)
+		k.RU = bloodNameRU(link.up, link.down, link.half, pb.Gender, g.halfSide(link))
+		return k
+	}
+
+	for _, s := range g.Spouses(a) {
+		if s == b {
+			k.Kind, k.Path = KinshipSpouse, []int{a, b}
+			k.EN = pick(pb.Gender, "husband", "wife", "spouse")
+			k.RU = pick(pb.Gender, "муж", "жена", "супруг(а)")
+			return k
+		}
+	}
+
+	// Свойство: выбираем самую короткую цепочку из двух вариантов
+	var best *Kinship
+	consider := func(c Kinship) {
+		if best == nil || len(c.Path) < len(best.Path) {
+			best = &c
+		}
+	}
+
+	// B - супруг(а) кровного родственника R
+	for _, r := range g.Spouses(b) {
+		if link, ok := g.blood(a, r); ok {
+			c := k
+			c.Kind, c.GenerationsA, c.GenerationsB = KinshipInLaw, link.up, link.down
+			c.CommonAncestors, c.Path = link.ancestors, append(link.path, b)
+			c.EN, c.RU = spouseOfRelativeNames(g, link, r, b)
+			consider(c)
+		}
+	}
+	// B - кровный родственник супруга(и) S
+	for _, s := range g.Spouses(a) {
+		if link, ok := g.blood(s, b); ok {
+			c := k
+			c.Kind, c.GenerationsA, c.GenerationsB = KinshipInLaw, link.up, link.down
+			c.CommonAncestors, c.Path = link.ancestors, append([]int{a}, link.path...)
+			c.EN, c.RU = relativeOfSpouseNames(g, link, a, s, b)
+			consider(c)
+		}
+	}
+	if best != nil {
+		return *best
+	}
+
+	k.Kind, k.EN, k.RU = KinshipNone, "not related", "не родственники"
+	return k
+}
+
+// ancestorsByDistance - все предки с минимальным числом поколений до них
+// и указателем "через кого дошли" для восстановления пути
+func (g *Graph) ancestorsByDistance(id int) (map[int]int, map[int]int) {
+	dist := map[int]int{id: 0}
+	via := map[int]int{}
+	queue := []int{id}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, p := range g.Parents(cur) {
+			if _, seen := dist[p]; seen {
+				continue
+			}
+			dist[p] = dist[cur] + 1
+			via[p] = cur
+			queue = append(queue, p)
+		}
+	}
+	return dist, via
+}
+
+func (g *Graph) blood(a, b int) (bloodLink, bool) {
+	distA, viaA := g.ancestorsByDistance(a)
+	distB, viaB := g.ancestorsByDistance(b)
+
+	best := -1
+	for anc, da := range distA {
+		if db, ok := distB[anc]; ok && (best < 0 || da+db < best) {
+			best = da + db
+		}
+	}
+	if best < 0 {
+		return bloodLink{}, false
+	}
+
+	// Ближайшие общие предки: минимальная сумма, при равенстве - минимальный up
+	var link bloodLink
+	link.up = -1
+	for _, anc := range g.SortedIDs() {
+		da, okA := distA[anc]
+		db, okB := distB[anc]
+		if !okA || !okB || da+db != best {
+			continue
+		}
+		if link.up < 0 || da < link.up {
+			link.up, link.down, link.ancestors = da, db, nil
+		}
+		if da == link.up {
+			link.ancestors = append(link.ancestors, anc)
+		}
+	}
+
+	// Путь: A вверх до общего предка, затем вниз до B
+	top := link.ancestors[0]
+	chain := []int{top}
+	for cur := top; cur != a; {
+		cur = viaA[cur]
+		chain = append(chain, cur)
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	for cur := top; cur != b; {
+		cur = viaB[cur]
+		chain = append(chain, cur)
+	}
+	link.path = chain
+
+	// Неполнородство: у ветвей под общим предком по два известных родителя, но общий - один
+	if link.up > 0 && link.down > 0 && len(link.ancestors) == 1 {
+		ca, cb := link.path[link.up-1], link.path[link.up+1]
+		pa, pb := g.Parents(ca), g.Parents(cb)
+		link.half = len(pa) == 2 && len(pb) == 2
+	}
+	return link, true
+}
+
+// halfSide - пол общего предка у неполнородных (для "единокровный"/"единоутробный")
+func (g *Graph) halfSide(link bloodLink) string {
+	if !link.half {
+		return ""
+	}
+	return g.People[link.ancestors[0]].Gender
+}
+
+// pick выбирает форму слова по полу
+func pick(gender, male, female, neutral string) string {
+	switch gender {
+	case "male":
+		return male
+	case "female":
+		return female
+	}
+	return neutral
+}
@@ -0,0 +1,241 @@
+package genealogy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- Английские названия ---
+
+var ordinalsEN = []string{"", "first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth", "tenth"}
+
+// bloodNameEN - название кровного родства B для A: up - поколений от A до общего предка,
+// down - от общего предка до B
+func bloodNameEN(up, down int, half bool, gender string) string {
+	halfPrefix := ""
+	if half {
+		halfPrefix = "half-"
+	}
+
+	switch {
+	case up == 0: // B - потомок A
+		if down == 1 {
+			return pick(gender, "son", "daughter", "child")
+		}
+		return greats(down-2) + pick(gender, "grandson", "granddaughter", "grandchild")
+	case down == 0: // B - предок A
+		if up == 1 {
+			return pick(gender, "father", "mother", "parent")
+		}
+		return greats(up-2) + pick(gender, "grandfather", "grandmother", "grandparent")
+	case up == 1 && down == 1:
+		return halfPrefix + pick(gender, "brother", "sister", "sibling")
+	case up == 1: // потомок брата или сестры
+		if down == 2 {
+			return halfPrefix + pick(gender, "nephew", "niece", "nephew/niece")
+		}
+		return halfPrefix + greats(down-3) + pick(gender, "grandnephew", "grandniece", "grandnephew/grandniece")
+	case down == 1: // брат или сестра предка
+		return halfPrefix + greats(up-2) + pick(gender, "uncle", "aunt", "uncle/aunt")
+	}
+
+	degree := min(up, down) - 1
+	name := ordinalEN(degree) + " cousin"
+	if half {
+		name = "half " + name
+	}
+	switch removed := abs(up - down); removed {
+	case 0:
+	case 1:
+		name += " once removed"
+	case 2:
+		name += " twice removed"
+	case 3:
+		name += " thrice removed"
+	default:
+		name += fmt.Sprintf(" %d times removed", removed)
+	}
+	return name
+}
+
+func greats(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("great-", n)
+}
+
+func ordinalEN(n int) string {
+	if n < len(ordinalsEN) {
+		return ordinalsEN[n]
+	}
+	return fmt.Sprintf("%dth", n)
+}
+
+// --- Русские названия ---
+
+var cousinStemsRU = []string{"", "двоюродн", "троюродн", "четвероюродн", "пятиюродн", "шестиюродн", "семиюродн", "восьмиюродн", "девятиюродн", "десятиюродн"}
+
+// bloodNameRU - то же, что bloodNameEN, по-русски. Для неизвестного пола
+// возвращаются обе формы через косую черту. halfSide - пол общего предка
+// у неполнородных братьев и сестёр ("единокровный" или "единоутробный").
+func bloodNameRU(up, down int, half bool, gender, halfSide string) string {
+	if gender != "male" && gender != "female" {
+		return bloodNameRU(up, down, half, "male", halfSide) + " / " + bloodNameRU(up, down, half, "female", halfSide)
+	}
+	f := gender == "female"
+	word := func(male, female string) string {
+		if f {
+			return female
+		}
+		return male
+	}
+
+	switch {
+	case up == 0:
+		switch down {
+		case 1:
+			return word("сын", "дочь")
+		case 2:
+			return word("внук", "внучка")
+		}
+		return pra(down-2) + word("внук", "внучка")
+	case down == 0:
+		switch up {
+		case 1:
+			return word("отец", "мать")
+		}
+		return pra(up-2) + word("дедушка", "бабушка")
+	case up == down:
+		if up == 1 {
+			name := word("брат", "сестра")
+			if half {
+				switch halfSide {
+				case "male":
+					name = word("единокровный ", "единокровная ") + name
+				case "female":
+					name = word("единоутробный ", "единоутробная ") + name
+				default:
+					name = word("неполнородный ", "неполнородная ") + name
+				}
+			}
+			return name
+		}
+		return cousinRU(up-1, f) + " " + word("брат", "сестра")
+	case up > down: // B - старшего поколения: дяди, тёти, двоюродные деды
+		gap, degree := up-down, down-1
+		if gap == 1 {
+			return join(cousinRU(degree, f), word("дядя", "тётя"))
+		}
+		return join(cousinRU(degree+1, f), pra(gap-2)+word("дедушка", "бабушка"))
+	default: // B - младшего поколения: племянники
+		gap, degree := down-up, up-1
+		noun := word("племянник", "племянница")
+		switch gap {
+		case 1:
+		case 2:
+			noun = word("внучатый ", "внучатая ") + noun
+		default:
+			noun = pra(gap-3) + word("правнучатый ", "правнучатая ") + noun
+		}
+		return join(cousinRU(degree, f), noun)
+	}
+}
+
+// cousinRU - прилагательное степени родства: 1 - двоюродный, 2 - троюродный...
+func cousinRU(degree int, female bool) string {
+	if degree <= 0 {
+		return ""
+	}
+	stem := fmt.Sprintf("%d-юродн", degree+1)
+	if degree < len(cousinStemsRU) {
+		stem = cousinStemsRU[degree]
+	}
+	if female {
+		return stem + "ая"
+	}
+	return stem + "ый"
+}
+
+func pra(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("пра", n)
+}
+
+func join(adj, noun string) string {
+	if adj == "" {
+		return noun
+	}
+	return adj + " " + noun
+}
+
+// --- Свойство ---
+
+// spouseOfRelativeNames - B приходится супругом(ой) кровному родственнику R человека A
+func spouseOfRelativeNames(g *Graph, link bloodLink, r, b int) (string, string) {
+	gb, gr := g.People[b].Gender, g.People[r].Gender
+
+	switch {
+	case link.up == 0 && link.down == 1:
+		return pick(gb, "son-in-law", "daughter-in-law", "child-in-law"),
+			pick(gb, "зять", "невестка", "зять / невестка")
+	case link.up == 1 && link.down == 1:
+		return pick(gb, "brother-in-law", "sister-in-law", "sibling-in-law"),
+			pick(gb, "зять", "невестка", "зять / невестка")
+	case link.up == 1 && link.down == 0:
+		return pick(gb, "stepfather", "stepmother", "step-parent"),
+			pick(gb, "отчим", "мачеха", "отчим / мачеха")
+	}
+
+	en := bloodNameEN(link.up, link.down, link.half, gr) + "'s " + pick(gb, "husband", "wife", "spouse")
+	// "жена (муж — племянник)": кто B, затем кто R для B и кем R приходится A
+	ru := fmt.Sprintf("%s (%s — %s)",
+		pick(gb, "муж", "жена", "супруг(а)"),
+		pick(gr, "муж", "жена", "супруг(а)"),
+		bloodNameRU(link.up, link.down, link.half, gr, g.halfSide(link)),
+	)
+	return en, ru
+}
+
+// relativeOfSpouseNames - B приходится кровным родственником супругу(е) S человека A.
+// Русские названия зависят от пола A: тесть и шурин - у мужа, свёкор и деверь - у жены.
+func relativeOfSpouseNames(g *Graph, link bloodLink, a, s, b int) (string, string) {
+	ga, gb, gs := g.People[a].Gender, g.People[b].Gender, g.People[s].Gender
+
+	switch {
+	case link.up == 1 && link.down == 0:
+		en := pick(gb, "father-in-law", "mother-in-law", "parent-in-law")
+		switch ga {
+		case "male":
+			return en, pick(gb, "тесть", "тёща", "тесть / тёща")
+		case "female":
+			return en, pick(gb, "свёкор", "свекровь", "свёкор / свекровь")
+		}
+		return en, pick(gb, "отец", "мать", "родитель") + " супруга(и)"
+	case link.up == 1 && link.down == 1:
+		en := pick(gb, "brother-in-law", "sister-in-law", "sibling-in-law")
+		switch ga {
+		case "male":
+			return en, pick(gb, "шурин", "свояченица", "шурин / свояченица")
+		case "female":
+			return en, pick(gb, "деверь", "золовка", "деверь / золовка")
+		}
+		return en, pick(gb, "брат", "сестра", "брат / сестра") + " супруга(и)"
+	case link.up == 0 && link.down == 1:
+		return pick(gb, "stepson", "stepdaughter", "stepchild"),
+			pick(gb, "пасынок", "падчерица", "пасынок / падчерица")
+	}
+
+	en := pick(gs, "husband's ", "wife's ", "spouse's ") + bloodNameEN(link.up, link.down, link.half, gb)
+	ru := bloodNameRU(link.up, link.down, link.half, gb, g.halfSide(link)) + " " + pick(gs, "мужа", "жены", "супруга(и)")
+	return en, ru
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
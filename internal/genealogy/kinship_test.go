@@ -0,0 +1,145 @@
+package genealogy
+
+import (
+	"testing"
+
+	"family-tree-app/internal/models"
+)
+
+func TestBloodNames(t *testing.T) {
+	tests := []struct {
+		up, down int
+		half     bool
+		gender   string
+		halfSide string
+		en, ru   string
+	}{
+		{0, 1, false, "male", "", "son", "сын"},
+		{0, 2, false, "female", "", "granddaughter", "внучка"},
+		{0, 3, false, "male", "", "great-grandson", "правнук"},
+		{0, 4, false, "", "", "great-great-grandchild", "праправнук / праправнучка"},
+		{1, 0, false, "female", "", "mother", "мать"},
+		{2, 0, false, "male", "", "grandfather", "дедушка"},
+		{3, 0, false, "female", "", "great-grandmother", "прабабушка"},
+		{1, 1, false, "male", "", "brother", "брат"},
+		{1, 1, true, "male", "male", "half-brother", "единокровный брат"},
+		{1, 1, true, "female", "female", "half-sister", "единоутробная сестра"},
+		{1, 1, true, "female", "", "half-sister", "неполнородная сестра"},
+		{2, 1, false, "male", "", "uncle", "дядя"},
+		{3, 1, false, "male", "", "great-uncle", "двоюродный дедушка"},
+		{4, 1, false, "female", "", "great-great-aunt", "двоюродная прабабушка"},
+		{1, 2, false, "female", "", "niece", "племянница"},
+		{1, 3, false, "male", "", "grandnephew", "внучатый племянник"},
+		{1, 4, false, "female", "", "great-grandniece", "правнучатая племянница"},
+		{2, 2, false, "male", "", "first cousin", "двоюродный брат"},
+		{2, 2, false, "", "", "first cousin", "двоюродный брат / двоюродная сестра"},
+		{2, 2, true, "female", "", "half first cousin", "двоюродная сестра"},
+		{3, 3, false, "female", "", "second cousin", "троюродная сестра"},
+		{4, 4, false, "male", "", "third cousin", "четвероюродный брат"},
+		{3, 2, false, "female", "", "first cousin once removed", "двоюродная тётя"},
+		{2, 3, false, "male", "", "first cousin once removed", "двоюродный племянник"},
+		{4, 2, false, "male", "", "first cousin twice removed", "троюродный дедушка"},
+		{2, 4, false, "female", "", "first cousin twice removed", "двоюродная внучатая племянница"},
+		{3, 5, false, "male", "", "second cousin twice removed", "троюродный внучатый племянник"},
+		{2, 7, false, "male", "", "first cousin 5 times removed", "двоюродный прапраправнучатый племянник"},
+		{12, 12, false, "male", "", "11th cousin", "12-юродный брат"},
+	}
+	for _, tt := range tests {
+		if got := bloodNameEN(tt.up, tt.down, tt.half, tt.gender); got != tt.en {
+			t.Errorf("bloodNameEN(%d, %d, %v, %q) = %q, ожидалось %q", tt.up, tt.down, tt.half, tt.gender, got, tt.en)
+		}
+		if got := bloodNameRU(tt.up, tt.down, tt.half, tt.gender, tt.halfSide); got != tt.ru {
+			t.Errorf("bloodNameRU(%d, %d, %v, %q) = %q, ожидалось %q", tt.up, tt.down, tt.half, tt.gender, got, tt.ru)
+		}
+	}
+}
+
+// Семья для проверки свойства:
+//
+//	7 Пётр + 8 Анна      3 Борис + 4 Вера        7 Пётр + 12 Зоя
+//	    |                    |                       |
+//	1 Иван, 6 Ольга  +  2 Мария, 5 Олег          11 Юрий
+//	    |
+//	9 Никита + 10 Дарья
+func kinshipFamily() *Graph {
+	person := func(id int, name, gender string) models.Person {
+		return models.Person{ID: id, FirstName: name, Gender: gender}
+	}
+	people := []models.Person{
+		person(1, "Иван", "male"), person(2, "Мария", "female"), person(3, "Борис", "male"),
+		person(4, "Вера", "female"), person(5, "Олег", "male"), person(6, "Ольга", "female"),
+		person(7, "Пётр", "male"), person(8, "Анна", "female"), person(9, "Никита", "male"),
+		person(10, "Дарья", "female"), person(11, "Юрий", "male"), person(12, "Зоя", "female"),
+	}
+	var rels []models.Relationship
+	parent := func(p, c int) {
+		rels = append(rels, models.Relationship{ID: len(rels) + 1, FromPersonID: p, ToPersonID: c, Type: "parent"})
+	}
+	spouse := func(a, b int) {
+		rels = append(rels, models.Relationship{ID: len(rels) + 1, FromPersonID: a, ToPersonID: b, Type: "spouse"})
+	}
+	for _, c := range []int{1, 6} {
+		parent(7, c)
+		parent(8, c)
+	}
+	for _, c := range []int{2, 5} {
+		parent(3, c)
+		parent(4, c)
+	}
+	parent(7, 11)
+	parent(12, 11)
+	parent(1, 9)
+	parent(2, 9)
+	spouse(1, 2)
+	spouse(9, 10)
+	return NewGraph(people, rels)
+}
+
+func TestComputeKinship(t *testing.T) {
+	g := kinshipFamily()
+	tests := []struct {
+		a, b     int
+		kind     string
+		en, ru   string
+		wantHalf bool
+	}{
+		{1, 1, KinshipSelf, "self", "это один и тот же человек", false},
+		{1, 2, KinshipSpouse, "wife", "жена", false},
+		{9, 3, KinshipBlood, "grandfather", "дедушка", false},
+		{1, 11, KinshipBlood, "half-brother", "единокровный брат", true},
+		{6, 9, KinshipBlood, "nephew", "племянник", false},
+		{1, 3, KinshipInLaw, "father-in-law", "тесть", false},
+		{2, 7, KinshipInLaw, "father-in-law", "свёкор", false},
+		{2, 8, KinshipInLaw, "mother-in-law", "свекровь", false},
+		{1, 5, KinshipInLaw, "brother-in-law", "шурин", false},
+		{2, 6, KinshipInLaw, "sister-in-law", "золовка", false},
+		{6, 2, KinshipInLaw, "sister-in-law", "невестка", false},
+		{1, 10, KinshipInLaw, "daughter-in-law", "невестка", false},
+		{7, 2, KinshipInLaw, "daughter-in-law", "невестка", false},
+		{6, 10, KinshipInLaw, "nephew's wife", "жена (муж — племянник)", false},
+		{3, 11, KinshipNone, "not related", "не родственники", false},
+	}
+	for _, tt := range tests {
+		k := ComputeKinship(g, tt.a, tt.b)
+		if k.Kind != tt.kind || k.EN != tt.en || k.RU != tt.ru || k.Half != tt.wantHalf {
+			t.Errorf("ComputeKinship(%d, %d) = %s %q %q half=%v, ожидалось %s %q %q half=%v",
+				tt.a, tt.b, k.Kind, k.EN, k.RU, k.Half, tt.kind, tt.en, tt.ru, tt.wantHalf)
+		}
+	}
+}
+
+func TestComputeKinshipPath(t *testing.T) {
+	k := ComputeKinship(kinshipFamily(), 6, 9)
+	want := []int{6, 7, 1, 9}
+	if len(k.Path) != len(want) {
+		t.Fatalf("путь %v, ожидался %v", k.Path, want)
+	}
+	for i := range want {
+		if k.Path[i] != want[i] {
+			t.Fatalf("путь %v, ожидался %v", k.Path, want)
+		}
+	}
+	if k.GenerationsA != 1 || k.GenerationsB != 2 {
+		t.Errorf("поколений %d и %d, ожидалось 1 и 2", k.GenerationsA, k.GenerationsB)
+	}
+}
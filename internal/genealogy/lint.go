@@ -0,0 +1,371 @@
+package genealogy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"family-tree-app/internal/models"
+)
+
+// Уровни серьёзности проблем
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Коды проверок. Фронтенд и скрипты чистки могут фильтровать по ним.
+const (
+	CodeAncestorCycle       = "ancestor_cycle"
+	CodeTooManyParents      = "too_many_parents"
+	CodeParentBornAfter     = "parent_born_after_child"
+	CodeParentTooYoung      = "parent_too_young"
+	CodeParentTooOld        = "parent_too_old"
+	CodeBornAfterMotherDied = "born_after_mother_death"
+	CodeBornAfterFatherDied = "born_after_father_death"
+	CodeDeathBeforeBirth    = "death_before_birth"
+	CodeImplausibleLifespan = "implausible_lifespan"
+	CodeSameGenderParents   = "same_gender_parents"
+	CodeDuplicateEdge       = "duplicate_relationship"
+	CodeSpouseIsRelative    = "spouse_is_ancestor"
+)
+
+// Пороги правдоподобия (в годах)
+const (
+	minParentAge   = 12
+	maxMotherAge   = 60
+	maxFatherAge   = 90
+	maxLifespan    = 120
+	maxBiolParents = 2
+)
+
+// Issue - одна найденная проблема в дереве
+type Issue struct {
+	Severity        string `json:"severity"`
+	Code            string `json:"code"`
+	Message         string `json:"message"`
+	PersonIDs       []int  `json:"person_ids"`
+	RelationshipIDs []int  `json:"relationship_ids,omitempty"`
+}
+
+// Key - идентификатор проблемы для сравнения двух прогонов проверки
+func (i Issue) Key() string {
+	return fmt.Sprintf("%s:%v", i.Code, i.PersonIDs)
+}
+
+// Lint проверяет дерево на генеалогические противоречия.
+// Результат отсортирован: сначала ошибки, затем предупреждения.
+func Lint(g *Graph) []Issue {
+	var issues []Issue
+
+	for _, id := range g.SortedIDs() {
+		p := g.People[id]
+		issues = append(issues, lintLifespan(p)...)
+		issues = append(issues, lintParents(g, p)...)
+	}
+	issues = append(issues, lintCycles(g)...)
+	issues = append(issues, lintDuplicateEdges(g)...)
+	issues = append(issues, lintSpouses(g)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Severity == SeverityError && issues[j].Severity != SeverityError
+	})
+	return issues
+}
+
+// LintPeople - то же, что Lint, но сразу по спискам из хранилища
+func LintPeople(people []models.Person, relationships []models.Relationship) []Issue {
+	return Lint(NewGraph(people, relationships))
+}
+
+func lintLifespan(p models.Person) []Issue {
+	birth, hasBirth := parseSpan(p.BirthDate)
+	if !hasBirth || p.DeathDate == nil {
+		return nil
+	}
+	death, hasDeath := parseSpan(*p.DeathDate)
+	if !hasDeath {
+		return nil
+	}
+
+	if death.definitelyBefore(birth) {
+		return []Issue{{
+			Severity:  SeverityError,
+			Code:      CodeDeathBeforeBirth,
+			Message:   fmt.Sprintf("%s: дата смерти раньше даты рождения", fullName(p)),
+			PersonIDs: []int{p.ID},
+		}}
+	}
+	if years, ok := minYears(birth, death); ok && years > maxLifespan {
+		return []Issue{{
+			Severity:  SeverityWarning,
+			Code:      CodeImplausibleLifespan,
+			Message:   fmt.Sprintf("%s: продолжительность жизни больше %d лет", fullName(p), maxLifespan),
+			PersonIDs: []int{p.ID},
+		}}
+	}
+	return nil
+}
+
+func lintParents(g *Graph, child models.Person) []Issue {
+	var issues []Issue
+	parentIDs := g.Parents(child.ID)
+
+	if len(parentIDs) > maxBiolParents {
+		issues = append(issues, Issue{
+			Severity:        SeverityError,
+			Code:            CodeTooManyParents,
+			Message:         fmt.Sprintf("%s: указано родителей - %d", fullName(child), len(parentIDs)),
+			PersonIDs:       append([]int{child.ID}, parentIDs...),
+			RelationshipIDs: edgeIDs(g.ParentEdges(child.ID)),
+		})
+	}
+
+	if len(parentIDs) == 2 {
+		a, b := g.People[parentIDs[0]], g.People[parentIDs[1]]
+		if a.Gender != "" && a.Gender != "other" && a.Gender == b.Gender {
+			issues = append(issues, Issue{
+				Severity:  SeverityWarning,
+				Code:      CodeSameGenderParents,
+				Message:   fmt.Sprintf("%s: оба родителя одного пола", fullName(child)),
+				PersonIDs: []int{child.ID, a.ID, b.ID},
+			})
+		}
+	}
+
+	childBirth, hasChildBirth := parseSpan(child.BirthDate)
+	if !hasChildBirth {
+		return issues
+	}
+
+	for _, parentID := range parentIDs {
+		parent := g.People[parentID]
+		ids := []int{parent.ID, child.ID}
+
+		if parentBirth, ok := parseSpan(parent.BirthDate); ok {
+			// Возраст родителя при рождении ребёнка - промежуток: предупреждаем,
+			// только если он весь за пределами нормы
+			youngest, hasYoungest := minYears(parentBirth, childBirth)
+			oldest, hasOldest := maxYears(parentBirth, childBirth)
+			switch {
+			case !parentBirth.from.IsZero() && !childBirth.to.IsZero() && parentBirth.from.Year() >= childBirth.to.Year():
+				issues = append(issues, Issue{
+					Severity:  SeverityError,
+					Code:      CodeParentBornAfter,
+					Message:   fmt.Sprintf("%s родился(ась) не раньше своего ребёнка %s", fullName(parent), fullName(child)),
+					PersonIDs: ids,
+				})
+			case hasOldest && oldest < minParentAge:
+				issues = append(issues, Issue{
+					Severity:  SeverityWarning,
+					Code:      CodeParentTooYoung,
+					Message:   fmt.Sprintf("%s: возраст при рождении ребёнка %s - %d лет", fullName(parent), fullName(child), oldest),
+					PersonIDs: ids,
+				})
+			case hasYoungest && ((parent.Gender == "female" && youngest > maxMotherAge) || youngest > maxFatherAge):
+				issues = append(issues, Issue{
+					Severity:  SeverityWarning,
+					Code:      CodeParentTooOld,
+					Message:   fmt.Sprintf("%s: возраст при рождении ребёнка %s - %d лет", fullName(parent), fullName(child), youngest),
+					PersonIDs: ids,
+				})
+			}
+		}
+
+		if parent.DeathDate == nil {
+			continue
+		}
+		parentDeath, ok := parseSpan(*parent.DeathDate)
+		if !ok {
+			continue
+		}
+		// Мать не может родить после смерти. Отец может умереть до рождения ребёнка,
+		// но не больше чем примерно за год.
+		if parent.Gender == "female" && parentDeath.definitelyBefore(childBirth) {
+			issues = append(issues, Issue{
+				Severity:  SeverityError,
+				Code:      CodeBornAfterMotherDied,
+				Message:   fmt.Sprintf("%s родился(ась) после смерти матери %s", fullName(child), fullName(parent)),
+				PersonIDs: ids,
+			})
+		} else if parent.Gender == "male" && !parentDeath.to.IsZero() && !childBirth.from.IsZero() &&
+			childBirth.from.Year()-parentDeath.to.Year() > 1 {
+			issues = append(issues, Issue{
+				Severity:  SeverityWarning,
+				Code:      CodeBornAfterFatherDied,
+				Message:   fmt.Sprintf("%s родился(ась) больше чем через год после смерти отца %s", fullName(child), fullName(parent)),
+				PersonIDs: ids,
+			})
+		}
+	}
+	return issues
+}
+
+// lintCycles ищет циклы по связям родитель -> ребёнок (человек - собственный предок).
+// Каждый цикл сообщается один раз.
+func lintCycles(g *Graph) []Issue {
+	const (
+		white = iota
+		grey
+		black
+	)
+	color := map[int]int{}
+	var stack []int
+	var issues []Issue
+
+	var visit func(id int)
+	visit = func(id int) {
+		color[id] = grey
+		stack = append(stack, id)
+		for _, childID := range g.Children(id) {
+			switch color[childID] {
+			case white:
+				visit(childID)
+			case grey:
+				// Цикл - это хвост стека начиная с childID
+				start := len(stack) - 1
+				for stack[start] != childID {
+					start--
+				}
+				cycle := append([]int(nil), stack[start:]...)
+				issues = append(issues, Issue{
+					Severity:  SeverityError,
+					Code:      CodeAncestorCycle,
+					Message:   fmt.Sprintf("%s оказывается собственным предком", fullName(g.People[childID])),
+					PersonIDs: cycle,
+				})
+			}
+		}
+		stack = stack[:len(stack)-1]
+		color[id] = black
+	}
+
+	for _, id := range g.SortedIDs() {
+		if color[id] == white {
+			visit(id)
+		}
+	}
+	return issues
+}
+
+// lintDuplicateEdges ищет повторяющиеся связи одного вида между одной парой людей
+func lintDuplicateEdges(g *Graph) []Issue {
+	seen := map[edgeKey][]int{}
+	var order []edgeKey
+
+	for _, rel := range g.Edges {
+		key, ok := edgeKeyOf(rel)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[key]; !ok {
+			order = append(order, key)
+		}
+		seen[key] = append(seen[key], rel.ID)
+	}
+
+	var issues []Issue
+	for _, key := range order {
+		if ids := seen[key]; len(ids) > 1 {
+			issues = append(issues, Issue{
+				Severity:        SeverityWarning,
+				Code:            CodeDuplicateEdge,
+				Message:         fmt.Sprintf("Связь между %s и %s указана %d раз(а)", fullName(g.People[key.a]), fullName(g.People[key.b]), len(ids)),
+				PersonIDs:       []int{key.a, key.b},
+				RelationshipIDs: ids,
+			})
+		}
+	}
+	return issues
+}
+
+// edgeKey - связь без направления там, где оно не важно: "ребёнок" становится
+// "родителем" в обратную сторону, супруги и братья упорядочены по ID
+type edgeKey struct {
+	kind Kind
+	a, b int
+}
+
+// edgeKeyOf возвращает ключ связи; для связей неизвестного типа ok = false
+func edgeKeyOf(rel models.Relationship) (key edgeKey, ok bool) {
+	kind := KindOf(rel.Type)
+	a, b := rel.FromPersonID, rel.ToPersonID
+	switch kind {
+	case KindChild:
+		kind, a, b = KindParent, b, a
+	case KindSpouse, KindSibling:
+		if a > b {
+			a, b = b, a
+		}
+	case KindOther:
+		return edgeKey{}, false
+	}
+	return edgeKey{kind, a, b}, true
+}
+
+// SameEdge сообщает, что две связи означают одно и то же: "A родитель B"
+// и "B ребёнок A", "A супруг B" и "B супруг A". Связи неизвестного типа
+// совпадают, только если совпадают тип и направление.
+func SameEdge(x, y models.Relationship) bool {
+	kx, okX := edgeKeyOf(x)
+	ky, okY := edgeKeyOf(y)
+	if okX || okY {
+		return okX && okY && kx == ky
+	}
+	return strings.EqualFold(strings.TrimSpace(x.Type), strings.TrimSpace(y.Type)) &&
+		x.FromPersonID == y.FromPersonID && x.ToPersonID == y.ToPersonID
+}
+
+// lintSpouses предупреждает о браке с собственным предком
+func lintSpouses(g *Graph) []Issue {
+	var issues []Issue
+	for _, id := range g.SortedIDs() {
+		ancestors := g.ancestorSet(id)
+		for _, spouseID := range g.Spouses(id) {
+			if spouseID < id {
+				continue // пару проверяем один раз
+			}
+			if ancestors[spouseID] || g.ancestorSet(spouseID)[id] {
+				issues = append(issues, Issue{
+					Severity:  SeverityWarning,
+					Code:      CodeSpouseIsRelative,
+					Message:   fmt.Sprintf("%s и %s указаны супругами, но один из них - предок другого", fullName(g.People[id]), fullName(g.People[spouseID])),
+					PersonIDs: []int{id, spouseID},
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// ancestorSet - все предки человека (устойчиво к циклам)
+func (g *Graph) ancestorSet(id int) map[int]bool {
+	seen := map[int]bool{}
+	queue := g.Parents(id)
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+		queue = append(queue, g.Parents(cur)...)
+	}
+	return seen
+}
+
+func edgeIDs(edges []ParentEdge) []int {
+	ids := make([]int, len(edges))
+	for i, e := range edges {
+		ids[i] = e.RelationshipID
+	}
+	return ids
+}
+
+func fullName(p models.Person) string {
+	name := p.LastName + " " + p.FirstName
+	if p.MiddleName != "" {
+		name += " " + p.MiddleName
+	}
+	return name
+}
@@ -0,0 +1,144 @@
+package genealogy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// translitRU - кириллица в латиницу. Схема простая и нужна только для
+// сравнения: "Иванов", "Ivanov" и "Iwanow" должны дать одну и ту же строку.
+var translitRU = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+	'я': "ia", 'і': "i", 'ї': "i", 'є': "e", 'ґ': "g", 'ў': "u",
+}
+
+// latinDiacritics - латинские буквы с диакритикой в простые:
+// Wiśniewski, Müller и Dvořák сравниваются с записями без неё
+var latinDiacritics = map[rune]string{
+	'ą': "a", 'ć': "c", 'ę': "e", 'ł': "l", 'ń': "n", 'ó': "o", 'ś': "s", 'ź': "z", 'ż': "z",
+	'ä': "a", 'ö': "o", 'ü': "u", 'ß': "ss", 'á': "a", 'é': "e", 'í': "i", 'ú': "u", 'ý': "y",
+	'č': "c", 'ď': "d", 'ě': "e", 'ň': "n", 'ř': "r", 'š': "s", 'ť': "t", 'ů': "u", 'ž': "z",
+}
+
+// asciiLetters - строчные латинские буквы слова: кириллица транслитерируется,
+// диакритика снимается, всё прочее отбрасывается
+func asciiLetters(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if t, ok := translitRU[r]; ok {
+			b.WriteString(t)
+		} else if t, ok := latinDiacritics[r]; ok {
+			b.WriteString(t)
+		} else if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// latinFolds сводит разные латинские записи одного звука к одной:
+// Yakov/Jakov/Iakov, Iwanow/Ivanov, Schmidt/Shmidt, Khariton/Hariton, Nicolai/Nikolai
+var latinFolds = strings.NewReplacer(
+	"shch", "sh", "tsch", "ch", "sch", "sh", "tch", "ch",
+	"kh", "h", "ph", "f", "ck", "k", "tz", "ts", "cz", "ch",
+	"ch", "ch", "c", "k",
+	"w", "v", "j", "i", "y", "i", "q", "k", "x", "ks",
+)
+
+// FoldName приводит имя к латинскому ключу для сравнения: нижний регистр,
+// транслитерация кириллицы, одинаковые звуки - одинаковыми буквами,
+// без повторов букв и без всего, кроме букв.
+func FoldName(s string) string {
+	folded := latinFolds.Replace(asciiLetters(s))
+
+	out := make([]byte, 0, len(folded))
+	for i := 0; i < len(folded); i++ {
+		if i > 0 && folded[i] == folded[i-1] {
+			continue // Анна/Ана, Филипп/Филип
+		}
+		out = append(out, folded[i])
+	}
+	return string(out)
+}
+
+// surnameEndings - окончания фамилий после FoldName. Мужская и женская формы
+// одной фамилии сводятся к общей основе: Иванов/Иванова -> ivanov,
+// Вишневский/Вишневская -> vishnevsk, Толстой/Толстая -> tolst.
+// Порядок важен: сначала длинные окончания.
+var surnameEndings = []struct{ suffix, stem string }{
+	{"aia", ""}, {"ska", "sk"},
+	{"ova", "ov"}, {"eva", "ev"}, {"ina", "in"},
+	{"oi", ""}, {"i", ""},
+}
+
+// surnameStem - общая основа мужской и женской формы фамилии. Применяется
+// к обеим сравниваемым фамилиям, поэтому редкие мужские фамилии на -ина
+// (Калина) сравниваются правильно: обе стороны укорачиваются одинаково.
+func surnameStem(folded string) string {
+	for _, e := range surnameEndings {
+		if strings.HasSuffix(folded, e.suffix) && len(folded) > len(e.suffix)+1 {
+			return strings.TrimSuffix(folded, e.suffix) + e.stem
+		}
+	}
+	return folded
+}
+
+// SurnameKey - ключ фамилии для поиска и отбора: одна и та же фамилия
+// в любой записи и любой форме (Иванов, Иванова, Ivanov, Iwanowa) даёт один ключ
+func SurnameKey(s string) string {
+	return surnameStem(FoldName(s))
+}
+
+// patronymicEndings - окончания отчеств: мужское и женское отчество
+// от одного имени дают одну основу (Иванович/Ивановна -> ivanov)
+var patronymicEndings = []struct{ suffix, stem string }{
+	{"ovich", "ov"}, {"ovna", "ov"}, {"evich", "ev"}, {"evna", "ev"},
+	{"inichna", "ich"}, {"ichna", "ich"},
+}
+
+func patronymicStem(folded string) string {
+	for _, e := range patronymicEndings {
+		if strings.HasSuffix(folded, e.suffix) {
+			return strings.TrimSuffix(folded, e.suffix) + e.stem
+		}
+	}
+	return folded
+}
+
+// similarity - похожесть строк от 0 до 1 по расстоянию Левенштейна
+func similarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
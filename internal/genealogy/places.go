@@ -0,0 +1,235 @@
+package genealogy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"family-tree-app/internal/models"
+)
+
+// PlaceDuplicateKm - места с общим названием дальше друг от друга
+// не считаются одним местом
+const PlaceDuplicateKm = 25
+
+// placeWords - слова, которыми в документах обозначают вид места:
+// "с. Горки", "Тверская губ.", "Gubernia płocka". Названия сравниваются без них.
+var placeWords = map[string]bool{
+	"д": true, "дер": true, "деревня": true, "с": true, "село": true, "сельцо": true,
+	"пос": true, "посёлок": true, "поселок": true, "м": true, "местечко": true,
+	"г": true, "гор": true, "город": true, "вол": true, "волость": true,
+	"у": true, "уезд": true, "губ": true, "губерния": true,
+	"р": true, "н": true, "район": true, "обл": true, "область": true, "край": true,
+	"village": true, "town": true, "city": true, "county": true, "district": true, "region": true,
+	"oblast": true, "volost": true, "uyezd": true, "uezd": true, "guberniya": true, "gubernia": true,
+	"wieś": true, "gmina": true, "powiat": true,
+}
+
+// PlaceKey - ключ названия места для сравнения: без слов вида места, в латинице
+// (см. FoldName) и без окончаний прилагательных, поэтому "Тверская губерния",
+// "Тверской уезд" и "Twerskaja" дают общее "tversk"
+func PlaceKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	keys := make([]string, 0, len(words))
+	for _, w := range words {
+		if placeWords[w] {
+			continue
+		}
+		if k := surnameStem(FoldName(w)); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return strings.Join(keys, " ")
+}
+
+// placeKeys - ключи всех названий места: основного и других
+func placeKeys(p models.Place) map[string]string {
+	keys := map[string]string{}
+	for _, name := range append([]string{p.Name}, placeNames(p)...) {
+		if k := PlaceKey(name); k != "" {
+			if _, ok := keys[k]; !ok {
+				keys[k] = name
+			}
+		}
+	}
+	return keys
+}
+
+func placeNames(p models.Place) []string {
+	names := make([]string, len(p.Names))
+	for i, n := range p.Names {
+		names[i] = n.Name
+	}
+	return names
+}
+
+// commonName - название, общее у a и b, или пустая строка
+func commonName(a, b map[string]string) string {
+	best := ""
+	for k, name := range a {
+		if _, ok := b[k]; ok && (best == "" || name < best) {
+			best = name
+		}
+	}
+	return best
+}
+
+func typesAgree(a, b models.Place) bool {
+	return a.Type == "" || b.Type == "" || a.Type == b.Type
+}
+
+// SamePlace - b повторяет уже заведённое место a: одно из названий совпадает,
+// вышестоящее место то же, тип не противоречит
+func SamePlace(a, b models.Place) bool {
+	if !sameParent(a.ParentID, b.ParentID) || !typesAgree(a, b) {
+		return false
+	}
+	return commonName(placeKeys(a), placeKeys(b)) != ""
+}
+
+func sameParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// FillFullNames записывает каждому месту полное название через вышестоящие:
+// "Горки, Бежецкий уезд, Тверская губерния"
+func FillFullNames(places []models.Place) {
+	byID := make(map[int]models.Place, len(places))
+	for _, p := range places {
+		byID[p.ID] = p
+	}
+	for i := range places {
+		parts := []string{places[i].Name}
+		seen := map[int]bool{places[i].ID: true}
+		for parent := places[i].ParentID; parent != nil && !seen[*parent]; {
+			p, ok := byID[*parent]
+			if !ok {
+				break
+			}
+			seen[p.ID] = true
+			parts = append(parts, p.Name)
+			parent = p.ParentID
+		}
+		places[i].FullName = strings.Join(parts, ", ")
+	}
+}
+
+// SortPlaces - по полному названию, при равенстве по ID
+func SortPlaces(places []models.Place) {
+	sort.SliceStable(places, func(i, j int) bool {
+		a, b := strings.ToLower(places[i].FullName), strings.ToLower(places[j].FullName)
+		if a != b {
+			return a < b
+		}
+		return places[i].ID < places[j].ID
+	})
+}
+
+// PlaceDuplicate - пара мест, которые, возможно, одно и то же место
+type PlaceDuplicate struct {
+	Places  []models.Place `json:"places"` // два места, меньший ID первым
+	Reasons []string       `json:"reasons"`
+}
+
+// FindPlaceDuplicates ищет пары мест с общим названием или общей записью
+// GeoNames. Не считаются дублями места разных видов, места в разных
+// вышестоящих (если и те не называются одинаково) и места дальше PlaceDuplicateKm.
+func FindPlaceDuplicates(places []models.Place) []PlaceDuplicate {
+	sorted := append([]models.Place(nil), places...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	keys := make(map[int]map[string]string, len(sorted))
+	for _, p := range sorted {
+		keys[p.ID] = placeKeys(p)
+	}
+
+	found := []PlaceDuplicate{}
+	for i, a := range sorted {
+		for _, b := range sorted[i+1:] {
+			if reasons, ok := comparePlaces(a, b, keys); ok {
+				found = append(found, PlaceDuplicate{Places: []models.Place{a, b}, Reasons: reasons})
+			}
+		}
+	}
+	return found
+}
+
+func comparePlaces(a, b models.Place, keys map[int]map[string]string) ([]string, bool) {
+	var reasons []string
+	if name := commonName(keys[a.ID], keys[b.ID]); name != "" {
+		reasons = append(reasons, fmt.Sprintf("совпадает название «%s»", name))
+	}
+	if a.GeoNamesID != nil && b.GeoNamesID != nil && *a.GeoNamesID == *b.GeoNamesID {
+		reasons = append(reasons, fmt.Sprintf("одна запись GeoNames %d", *a.GeoNamesID))
+	}
+	if len(reasons) == 0 || !typesAgree(a, b) {
+		return nil, false
+	}
+
+	switch {
+	case sameParent(a.ParentID, b.ParentID) && a.ParentID != nil:
+		reasons = append(reasons, "входят в одно место")
+	case a.ParentID == nil || b.ParentID == nil:
+	case commonName(keys[*a.ParentID], keys[*b.ParentID]) != "":
+		reasons = append(reasons, fmt.Sprintf("вышестоящие места называются одинаково (%d и %d)", *a.ParentID, *b.ParentID))
+	default:
+		return nil, false
+	}
+
+	if a.Latitude != nil && a.Longitude != nil && b.Latitude != nil && b.Longitude != nil {
+		km := DistanceKm(*a.Latitude, *a.Longitude, *b.Latitude, *b.Longitude)
+		if km > PlaceDuplicateKm {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("координаты в %.1f км друг от друга", km))
+	}
+	return reasons, true
+}
+
+// DistanceKm - расстояние по поверхности Земли между двумя точками (градусы)
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371
+	rad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat, dLon := rad(lat2-lat1), rad(lon2-lon1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// GazetteerKeys - слова названий после FoldName через пробел, без повторов:
+// по ним запись справочника находится в любой транслитерации
+func GazetteerKeys(names []string) string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, name := range names {
+		for _, w := range searchWords(name) {
+			if k := FoldName(w); k != "" && !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return strings.Join(keys, " ")
+}
+
+// MatchPlaceNames - каждое слово запроса начинает слово одного из названий,
+// как есть или после транслитерации. Повторяет правила индекса справочника
+// для хранилищ без полнотекстового поиска.
+func MatchPlaceNames(names []string, terms []SearchTerm) bool {
+	var words []string
+	for _, name := range names {
+		words = append(words, searchWords(name)...)
+	}
+	folded := strings.Fields(GazetteerKeys(names))
+	for _, t := range terms {
+		if !hasWordPrefix(words, t.Text) && (t.Folded == "" || !hasWordPrefix(folded, t.Folded)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package genealogy
+
+import (
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+// LivingYears - человек без даты смерти, родившийся меньше стольких лет назад, считается живым
+const LivingYears = 100
+
+// LivingName - имя, которое показывается вместо имени живого человека
+const LivingName = "Living"
+
+// IsLiving сообщает, что человека нужно считать живым: даты смерти нет,
+// а родился он, возможно, меньше LivingYears лет назад. Без даты рождения
+// (или с датой вроде "после 1900") человек тоже считается живым - лучше
+// скрыть лишнее, чем показать живого.
+func IsLiving(p models.Person, now time.Time) bool {
+	if p.DeathDate != nil && *p.DeathDate != "" {
+		return false
+	}
+	birth, ok := parseSpan(p.BirthDate)
+	if !ok {
+		return true
+	}
+	age, ok := minYears(birth, dateSpan{from: now, to: now})
+	return !ok || age < LivingYears
+}
+
+// RedactLiving заменяет живых людей заглушкой "Living" без дат и фото.
+// ID, пол и положение на графе сохраняются, чтобы дерево не распалось.
+// Описания связей с живыми людьми тоже очищаются: там бывают даты и подробности.
+func RedactLiving(people []models.Person, relationships []models.Relationship, now time.Time) ([]models.Person, []models.Relationship) {
+	living := map[int]bool{}
+	redacted := make([]models.Person, len(people))
+	for i, p := range people {
+		if !IsLiving(p, now) {
+			redacted[i] = p
+			continue
+		}
+		living[p.ID] = true
+		redacted[i] = models.Person{
+			ID:        p.ID,
+			FirstName: LivingName,
+			Gender:    p.Gender,
+			PositionX: p.PositionX,
+			PositionY: p.PositionY,
+		}
+	}
+
+	rels := make([]models.Relationship, len(relationships))
+	for i, rel := range relationships {
+		if living[rel.FromPersonID] || living[rel.ToPersonID] {
+			rel.Description = ""
+		}
+		rels[i] = rel
+	}
+	return redacted, rels
+}
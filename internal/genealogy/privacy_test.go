@@ -0,0 +1,79 @@
+package genealogy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+func TestIsLiving(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	died, empty := "1980", ""
+	tests := []struct {
+		birth string
+		death *string
+		want  bool
+	}{
+		{"1990", nil, true},
+		{"1890", &died, false},
+		{"2000", &died, false}, // дата смерти важнее возраста
+		{"1990", &empty, true}, // пустая дата смерти - её нет
+		{"", nil, true},
+		{"весной", nil, true}, // года нет - лучше скрыть
+		{"1900", nil, false},
+		{"1926-06-16", nil, true},  // 100 лет исполнится завтра
+		{"1926-06-15", nil, false}, // 100 лет сегодня
+		{"ABT 1926", nil, true},
+		{"ABT 1920", nil, false},
+		{"1890?", nil, false}, // старая запись - приблизительно по году
+		{"после 1900", nil, true},
+		{"до 1900", nil, false},
+	}
+	for _, tt := range tests {
+		p := models.Person{BirthDate: tt.birth, DeathDate: tt.death}
+		if got := IsLiving(p, now); got != tt.want {
+			death := "нет"
+			if tt.death != nil {
+				death = *tt.death
+			}
+			t.Errorf("IsLiving(рождение %q, смерть %s) = %v, ожидалось %v", tt.birth, death, got, tt.want)
+		}
+	}
+}
+
+func TestRedactLiving(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	died, photo := "1960", 7
+	people := []models.Person{
+		{ID: 1, FirstName: "Иван", LastName: "Петров", BirthDate: "1890", DeathDate: &died, Gender: "male", PhotoURL: "/ivan.jpg", Notes: "кузнец"},
+		{ID: 2, FirstName: "Мария", LastName: "Петрова", MiddleName: "Ивановна", BirthDate: "1990-03-08", Gender: "female",
+			PhotoURL: "/maria.jpg", PhotoMediaID: &photo, Notes: "живёт в Казани", PositionX: 10, PositionY: 20},
+	}
+	relationships := []models.Relationship{
+		{ID: 1, FromPersonID: 1, ToPersonID: 1, Type: "spouse", Description: "венчание в 1915"},
+		{ID: 2, FromPersonID: 1, ToPersonID: 2, Type: "parent", Description: "удочерена в 1995"},
+	}
+
+	gotPeople, gotRels := RedactLiving(people, relationships, now)
+
+	wantPeople := []models.Person{
+		people[0],
+		{ID: 2, FirstName: LivingName, Gender: "female", PositionX: 10, PositionY: 20},
+	}
+	if !reflect.DeepEqual(gotPeople, wantPeople) {
+		t.Errorf("люди %+v, ожидалось %+v", gotPeople, wantPeople)
+	}
+	wantRels := []models.Relationship{
+		relationships[0],
+		{ID: 2, FromPersonID: 1, ToPersonID: 2, Type: "parent"},
+	}
+	if !reflect.DeepEqual(gotRels, wantRels) {
+		t.Errorf("связи %+v, ожидалось %+v", gotRels, wantRels)
+	}
+	// Входные данные не меняются
+	if people[1].FirstName != "Мария" || relationships[1].Description == "" {
+		t.Error("RedactLiving изменил исходные данные")
+	}
+}
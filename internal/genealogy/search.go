@@ -0,0 +1,117 @@
+package genealogy
+
+import (
+	"strings"
+	"unicode"
+
+	"family-tree-app/internal/models"
+)
+
+// minPhoneticLength - слова короче не ищутся по звучанию: у двух-трёх букв
+// код Дейча-Мокотова совпадает со слишком многими фамилиями
+const minPhoneticLength = 3
+
+// SearchTerm - одно слово поискового запроса в трёх видах
+type SearchTerm struct {
+	Text     string   // как введено, в нижнем регистре: ищется префиксом в именах и заметках
+	Folded   string   // основа после FoldName без родовых окончаний: префикс имени в любой записи
+	Phonetic []string // коды Дейча-Мокотова
+}
+
+// searchWords делит строку на слова из букв и цифр
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ParseSearchQuery разбирает запрос на слова. Человек подходит, если
+// подходит каждое слово. "Ivanova" ищется по основе ivanov и потому
+// находит и Иванова, и Иванову, и Iwanow.
+func ParseSearchQuery(q string) []SearchTerm {
+	var terms []SearchTerm
+	for _, word := range searchWords(q) {
+		t := SearchTerm{Text: word, Folded: surnameStem(patronymicStem(FoldName(word)))}
+		if len(asciiLetters(word)) >= minPhoneticLength {
+			t.Phonetic = DaitchMokotoff(word)
+		}
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// personNameWords - слова имени, отчества и фамилии
+func personNameWords(p models.Person) []string {
+	return searchWords(p.FirstName + " " + p.MiddleName + " " + p.LastName)
+}
+
+// SearchKeys - поисковые ключи человека через пробел: имена после FoldName
+// и все коды Дейча-Мокотова этих имён
+func SearchKeys(p models.Person) (folded, phonetic string) {
+	var foldedWords, codes []string
+	for _, word := range personNameWords(p) {
+		if f := FoldName(word); f != "" {
+			foldedWords = append(foldedWords, f)
+		}
+		codes = append(codes, DaitchMokotoff(word)...)
+	}
+	return strings.Join(foldedWords, " "), strings.Join(codes, " ")
+}
+
+// Веса совпадений: точное написание важнее транслитерации, та - звучания
+const (
+	searchWeightName     = 10
+	searchWeightFolded   = 5
+	searchWeightPhonetic = 2
+	searchWeightNotes    = 1
+)
+
+// MatchPerson оценивает, насколько человек подходит под запрос (больше - лучше,
+// 0 - не подходит). Нужна хранилищам без полнотекстового индекса и повторяет
+// его правила: каждое слово запроса - префикс имени как есть, после
+// транслитерации, совпадение по звучанию или слово из заметок.
+func MatchPerson(p models.Person, terms []SearchTerm) float64 {
+	names := personNameWords(p)
+	notes := searchWords(p.Notes)
+	folded, phonetic := SearchKeys(p)
+	foldedWords, codes := strings.Fields(folded), strings.Fields(phonetic)
+
+	total := 0.0
+	for _, t := range terms {
+		best := 0.0
+		if hasWordPrefix(names, t.Text) {
+			best = searchWeightName
+		} else if t.Folded != "" && hasWordPrefix(foldedWords, t.Folded) {
+			best = searchWeightFolded
+		} else if sharesCode(codes, t.Phonetic) {
+			best = searchWeightPhonetic
+		} else if hasWordPrefix(notes, t.Text) {
+			best = searchWeightNotes
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total
+}
+
+func hasWordPrefix(words []string, prefix string) bool {
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func sharesCode(codes, wanted []string) bool {
+	for _, c := range codes {
+		for _, w := range wanted {
+			if c == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package genealogy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Фонетический код Дейча-Мокотова (Daitch-Mokotoff Soundex). В отличие от
+// обычного Soundex он рассчитан на славянские, немецкие и еврейские фамилии:
+// Шварц, Schwarz и Szwarc получают один код, а у неоднозначных сочетаний
+// (CH, CK, RZ, J) кодов несколько - по одному на каждое прочтение.
+
+// dmRule - правило кодирования: код в начале слова, перед гласной и в остальных случаях.
+// "|" разделяет варианты прочтения, пустая строка - буквы не кодируются.
+type dmRule struct {
+	pattern                     string
+	atStart, beforeVowel, other string
+}
+
+var dmRules = []dmRule{
+	{"ai", "0", "1", ""}, {"aj", "0", "1", ""}, {"ay", "0", "1", ""},
+	{"au", "0", "7", ""},
+	{"a", "0", "", ""},
+	{"b", "7", "7", "7"},
+	{"chs", "5", "54", "54"},
+	{"ch", "5|4", "5|4", "5|4"},
+	{"ck", "5|45", "5|45", "5|45"},
+	{"csz", "4", "4", "4"}, {"czs", "4", "4", "4"}, {"cz", "4", "4", "4"}, {"cs", "4", "4", "4"},
+	{"c", "5|4", "5|4", "5|4"},
+	{"drz", "4", "4", "4"}, {"drs", "4", "4", "4"},
+	{"dsh", "4", "4", "4"}, {"dsz", "4", "4", "4"}, {"ds", "4", "4", "4"},
+	{"dzh", "4", "4", "4"}, {"dzs", "4", "4", "4"}, {"dz", "4", "4", "4"},
+	{"dt", "3", "3", "3"}, {"d", "3", "3", "3"},
+	{"ei", "0", "1", ""}, {"ej", "0", "1", ""}, {"ey", "0", "1", ""},
+	{"eu", "1", "1", ""},
+	{"e", "0", "", ""},
+	{"fb", "7", "7", "7"}, {"f", "7", "7", "7"},
+	{"g", "5", "5", "5"},
+	{"h", "5", "5", ""},
+	{"ia", "1", "", ""}, {"ie", "1", "", ""}, {"io", "1", "", ""}, {"iu", "1", "", ""},
+	{"i", "0", "", ""},
+	{"j", "1|4", "|4", "|4"},
+	{"ks", "5", "54", "54"},
+	{"kh", "5", "5", "5"},
+	{"k", "5", "5", "5"},
+	{"l", "8", "8", "8"},
+	{"mn", "66", "66", "66"}, {"m", "6", "6", "6"},
+	{"nm", "66", "66", "66"}, {"n", "6", "6", "6"},
+	{"oi", "0", "1", ""}, {"oj", "0", "1", ""}, {"oy", "0", "1", ""},
+	{"o", "0", "", ""},
+	{"pf", "7", "7", "7"}, {"ph", "7", "7", "7"}, {"p", "7", "7", "7"},
+	{"q", "5", "5", "5"},
+	{"rz", "94|4", "94|4", "94|4"}, {"rs", "94|4", "94|4", "94|4"},
+	{"r", "9", "9", "9"},
+	{"schtsch", "2", "4", "4"}, {"schtsh", "2", "4", "4"}, {"schtch", "2", "4", "4"},
+	{"shtch", "2", "4", "4"}, {"shtsh", "2", "4", "4"}, {"stsch", "2", "4", "4"},
+	{"shch", "2", "4", "4"}, {"stch", "2", "4", "4"}, {"strz", "2", "4", "4"},
+	{"strs", "2", "4", "4"}, {"stsh", "2", "4", "4"}, {"szcz", "2", "4", "4"},
+	{"szcs", "2", "4", "4"},
+	{"scht", "2", "43", "43"}, {"schd", "2", "43", "43"},
+	{"sch", "4", "4", "4"},
+	{"sht", "2", "43", "43"}, {"szt", "2", "43", "43"}, {"shd", "2", "43", "43"},
+	{"szd", "2", "43", "43"}, {"sd", "2", "43", "43"}, {"st", "2", "43", "43"},
+	{"sc", "2", "4", "4"},
+	{"sh", "4", "4", "4"}, {"sz", "4", "4", "4"},
+	{"s", "4", "4", "4"},
+	{"ttsch", "4", "4", "4"}, {"ttch", "4", "4", "4"}, {"tsch", "4", "4", "4"},
+	{"ttsz", "4", "4", "4"}, {"tch", "4", "4", "4"}, {"trz", "4", "4", "4"},
+	{"trs", "4", "4", "4"}, {"tsh", "4", "4", "4"}, {"tts", "4", "4", "4"},
+	{"ttz", "4", "4", "4"}, {"tzs", "4", "4", "4"}, {"tsz", "4", "4", "4"},
+	{"ts", "4", "4", "4"}, {"tc", "4", "4", "4"}, {"tz", "4", "4", "4"},
+	{"th", "3", "3", "3"},
+	{"t", "3", "3", "3"},
+	{"ui", "0", "1", ""}, {"uj", "0", "1", ""}, {"uy", "0", "1", ""},
+	{"ue", "0", "", ""}, {"u", "0", "", ""},
+	{"v", "7", "7", "7"},
+	{"w", "7", "7", "7"},
+	{"x", "5", "54", "54"},
+	{"y", "1", "", ""},
+	{"zhdzh", "2", "4", "4"}, {"zdzh", "2", "4", "4"}, {"zdz", "2", "4", "4"},
+	{"zsch", "4", "4", "4"}, {"zhd", "2", "43", "43"}, {"zsh", "4", "4", "4"},
+	{"zd", "2", "43", "43"}, {"zh", "4", "4", "4"}, {"zs", "4", "4", "4"},
+	{"z", "4", "4", "4"},
+}
+
+func init() {
+	// На каждой позиции срабатывает самое длинное подходящее правило
+	sort.SliceStable(dmRules, func(i, j int) bool { return len(dmRules[i].pattern) > len(dmRules[j].pattern) })
+}
+
+const dmCodeLength = 6
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiouy", c) >= 0
+}
+
+// DaitchMokotoff возвращает коды слова (по одному на вариант прочтения,
+// без повторов, по возрастанию). Кириллица транслитерируется.
+// Для слова без букв - nil.
+func DaitchMokotoff(word string) []string {
+	s := asciiLetters(word)
+	if s == "" {
+		return nil
+	}
+
+	type branch struct {
+		code string
+		last string // код предыдущего правила: одинаковые соседние коды пишутся один раз
+	}
+	branches := []branch{{}}
+
+	for i := 0; i < len(s); {
+		rule := dmRule{pattern: s[i : i+1]} // буква без правила не кодируется
+		for _, r := range dmRules {
+			if strings.HasPrefix(s[i:], r.pattern) {
+				rule = r
+				break
+			}
+		}
+		codes := rule.other
+		next := i + len(rule.pattern)
+		switch {
+		case i == 0:
+			codes = rule.atStart
+		case next < len(s) && isVowel(s[next]):
+			codes = rule.beforeVowel
+		}
+
+		// Ветки, которые дальше кодируются одинаково, сливаются: иначе
+		// на длинных фамилиях число вариантов растёт экспоненциально
+		var grown []branch
+		seen := map[branch]bool{}
+		for _, b := range branches {
+			for _, code := range strings.Split(codes, "|") {
+				nb := b
+				if code == "" || b.last == "" || !strings.HasSuffix(b.last, code) {
+					nb.code += code
+				}
+				nb.last = code
+				if len(nb.code) >= dmCodeLength {
+					nb.code, nb.last = nb.code[:dmCodeLength], ""
+				}
+				if !seen[nb] {
+					seen[nb] = true
+					grown = append(grown, nb)
+				}
+			}
+		}
+		branches = grown
+		i = next
+	}
+
+	seen := map[string]bool{}
+	var result []string
+	for _, b := range branches {
+		code := (b.code + strings.Repeat("0", dmCodeLength))[:dmCodeLength]
+		if !seen[code] {
+			seen[code] = true
+			result = append(result, code)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,53 @@
+package genealogy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDaitchMokotoff(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"Moskowitz", []string{"645740"}},
+		{"Auerbach", []string{"097400", "097500"}},
+		{"Peters", []string{"734000", "739400"}},
+		{"Jackson", []string{"145460", "154600", "445460", "454600"}},
+		{"Rosochowaciec", []string{"944744", "944745", "944754", "944755", "945744", "945745", "945754", "945755"}},
+		{"Schwartz", []string{"479400"}},
+		{"Lipshitz", []string{"874400"}},
+		{"Иванов", []string{"076700"}},
+		{"", nil},
+		{"123", nil},
+	}
+	for _, tt := range tests {
+		if got := DaitchMokotoff(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DaitchMokotoff(%q) = %v, ожидалось %v", tt.word, got, tt.want)
+		}
+	}
+}
+
+// Одна фамилия в разных написаниях должна иметь общий код
+func TestDaitchMokotoffSpellings(t *testing.T) {
+	groups := [][]string{
+		{"Schwartz", "Szwarc", "Шварц"},
+		{"Moskowitz", "Moskovitz", "Московиц"},
+		{"Kowalski", "Ковальский"},
+	}
+	for _, group := range groups {
+		common := map[string]int{}
+		for _, word := range group {
+			for _, code := range DaitchMokotoff(word) {
+				common[code]++
+			}
+		}
+		found := false
+		for _, n := range common {
+			found = found || n == len(group)
+		}
+		if !found {
+			t.Errorf("у %v нет общего кода", group)
+		}
+	}
+}
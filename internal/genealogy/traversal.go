@@ -0,0 +1,189 @@
+package genealogy
+
+import (
+	"sort"
+	"strconv"
+
+	"family-tree-app/internal/models"
+)
+
+// MaxAncestorDepth - предел глубины для предков: номера Ahnentafel растут как 2^n
+const MaxAncestorDepth = 60
+
+// MaxNumbersPerPerson - сколько номеров получает один человек при обходе.
+// При родственных браках и циклах в данных число путей растёт экспоненциально,
+// поэтому дальше этого предела номера не выдаются, а ответ помечается как усечённый.
+const MaxNumbersPerPerson = 32
+
+// AncestorEntry - предок с номерами по системе Ahnentafel (Соса-Страдонис).
+// При родственных браках один человек получает несколько номеров.
+type AncestorEntry struct {
+	Person     models.Person `json:"person"`
+	Generation int           `json:"generation"`
+	Numbers    []int64       `json:"ahnentafel"`
+}
+
+// DescendantEntry - потомок с номерами по системе д'Абовиля ("1.2.1")
+type DescendantEntry struct {
+	Person     models.Person `json:"person"`
+	Generation int           `json:"generation"`
+	Numbers    []string      `json:"daboville"`
+}
+
+// Ancestors обходит предков root не глубже depth поколений.
+// Номер root - 1, отца человека с номером n - 2n, матери - 2n+1.
+// Возвращает предков по возрастанию номера, связи между ними и признак того,
+// что часть номеров отброшена (см. MaxNumbersPerPerson).
+func Ancestors(g *Graph, root, depth int) ([]AncestorEntry, []models.Relationship, bool) {
+	depth = min(depth, MaxAncestorDepth)
+	entries := map[int]*AncestorEntry{}
+	truncated := false
+
+	// Все пройденные шаги лежат в items, parent - индекс шага-потомка.
+	// По этой цепочке проверяется, не встречался ли человек уже на текущем пути.
+	type item struct {
+		id, gen int
+		number  int64
+		parent  int
+	}
+	items := []item{{root, 0, 1, -1}}
+	onPath := func(i, id int) bool {
+		for ; i >= 0; i = items[i].parent {
+			if items[i].id == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	for next := 0; next < len(items); next++ {
+		cur := items[next]
+
+		e, ok := entries[cur.id]
+		if !ok {
+			e = &AncestorEntry{Person: g.People[cur.id], Generation: cur.gen}
+			entries[cur.id] = e
+		}
+		if len(e.Numbers) >= MaxNumbersPerPerson {
+			truncated = true
+			continue // предки этого человека уже пройдены через его первые номера
+		}
+		e.Numbers = append(e.Numbers, cur.number)
+
+		if cur.gen >= depth {
+			continue
+		}
+		father, mother := g.fatherAndMother(cur.id)
+		if father != 0 && !onPath(next, father) {
+			items = append(items, item{father, cur.gen + 1, cur.number * 2, next})
+		}
+		if mother != 0 && !onPath(next, mother) {
+			items = append(items, item{mother, cur.gen + 1, cur.number*2 + 1, next})
+		}
+	}
+
+	list := make([]AncestorEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Numbers[0] < list[j].Numbers[0] })
+	return list, g.edgesWithin(func(id int) bool { return entries[id] != nil }), truncated
+}
+
+// Descendants обходит потомков root не глубже depth поколений.
+// Номер root - "1", его дети - "1.1", "1.2"... по дате рождения.
+// Третье значение - признак усечения номеров, как у Ancestors.
+func Descendants(g *Graph, root, depth int) ([]DescendantEntry, []models.Relationship, bool) {
+	entries := map[int]*DescendantEntry{}
+	var order []int
+	truncated := false
+
+	var visit func(id, gen int, number string, path map[int]bool)
+	visit = func(id, gen int, number string, path map[int]bool) {
+		e, ok := entries[id]
+		if !ok {
+			e = &DescendantEntry{Person: g.People[id], Generation: gen}
+			entries[id] = e
+			order = append(order, id)
+		}
+		if len(e.Numbers) >= MaxNumbersPerPerson {
+			truncated = true
+			return
+		}
+		e.Numbers = append(e.Numbers, number)
+
+		if gen >= depth || path[id] {
+			return // path защищает от циклов в данных
+		}
+		path[id] = true
+		for i, child := range g.childrenByBirth(id) {
+			visit(child, gen+1, number+"."+strconv.Itoa(i+1), path)
+		}
+		delete(path, id)
+	}
+	visit(root, 0, "1", map[int]bool{})
+
+	list := make([]DescendantEntry, 0, len(order))
+	for _, id := range order {
+		list = append(list, *entries[id])
+	}
+	return list, g.edgesWithin(func(id int) bool { return entries[id] != nil }), truncated
+}
+
+// fatherAndMother раскладывает двух первых родителей на отца и мать.
+// Если пол не указан, родитель занимает свободное место (сначала отца).
+func (g *Graph) fatherAndMother(id int) (int, int) {
+	var father, mother int
+	var unknown []int
+	for _, p := range g.Parents(id) {
+		switch g.People[p].Gender {
+		case "male":
+			if father == 0 {
+				father = p
+				continue
+			}
+		case "female":
+			if mother == 0 {
+				mother = p
+				continue
+			}
+		}
+		unknown = append(unknown, p)
+	}
+	for _, p := range unknown {
+		if father == 0 {
+			father = p
+		} else if mother == 0 {
+			mother = p
+		}
+	}
+	return father, mother
+}
+
+// childrenByBirth - дети по дате рождения; без даты - в конце, по ID
+func (g *Graph) childrenByBirth(id int) []int {
+	children := g.Children(id)
+	sort.SliceStable(children, func(i, j int) bool {
+		a, okA := parseSpan(g.People[children[i]].BirthDate)
+		b, okB := parseSpan(g.People[children[j]].BirthDate)
+		switch {
+		case okA && okB:
+			return a.nominal.Before(b.nominal)
+		case okA != okB:
+			return okA
+		}
+		return false
+	})
+	return children
+}
+
+// edgesWithin - связи, оба конца которых входят в подграф
+func (g *Graph) edgesWithin(in func(id int) bool) []models.Relationship {
+	edges := []models.Relationship{}
+	for _, rel := range g.Edges {
+		if in(rel.FromPersonID) && in(rel.ToPersonID) {
+			edges = append(edges, rel)
+		}
+	}
+	return edges
+}
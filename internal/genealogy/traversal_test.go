@@ -0,0 +1,101 @@
+package genealogy
+
+import (
+	"testing"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+func parentRel(id, parent, child int) models.Relationship {
+	return models.Relationship{ID: id, FromPersonID: parent, ToPersonID: child, Type: "parent"}
+}
+
+func TestAncestorsNumbering(t *testing.T) {
+	people := []models.Person{
+		{ID: 1, FirstName: "Я"},
+		{ID: 2, FirstName: "Отец", Gender: "male"},
+		{ID: 3, FirstName: "Мать", Gender: "female"},
+		{ID: 4, FirstName: "Дед", Gender: "male"},
+	}
+	rels := []models.Relationship{parentRel(1, 2, 1), parentRel(2, 3, 1), parentRel(3, 4, 2)}
+
+	list, edges, truncated := Ancestors(NewGraph(people, rels), 1, 10)
+	if truncated {
+		t.Fatal("обход без родственных браков не должен усекаться")
+	}
+	want := map[int]int64{1: 1, 2: 2, 3: 3, 4: 4}
+	if len(list) != len(want) {
+		t.Fatalf("предков %d, ожидалось %d", len(list), len(want))
+	}
+	for _, e := range list {
+		if len(e.Numbers) != 1 || e.Numbers[0] != want[e.Person.ID] {
+			t.Errorf("человек %d: номера %v, ожидался %d", e.Person.ID, e.Numbers, want[e.Person.ID])
+		}
+	}
+	if len(edges) != 3 {
+		t.Errorf("связей %d, ожидалось 3", len(edges))
+	}
+}
+
+// Цикл в родителях (данные из GEDCOM без строгих проверок) не должен
+// приводить к экспоненциальному обходу даже на максимальной глубине.
+func TestTraversalParentCycle(t *testing.T) {
+	people := []models.Person{{ID: 1}, {ID: 2}, {ID: 3}}
+	rels := []models.Relationship{
+		parentRel(1, 2, 1), parentRel(2, 3, 1),
+		parentRel(3, 3, 2), parentRel(4, 1, 2),
+		parentRel(5, 1, 3), parentRel(6, 2, 3),
+	}
+	g := NewGraph(people, rels)
+
+	start := time.Now()
+	ancestors, _, _ := Ancestors(g, 1, MaxAncestorDepth)
+	descendants, _, _ := Descendants(g, 1, 100)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("обход цикла занял %v", elapsed)
+	}
+
+	for _, e := range ancestors {
+		if len(e.Numbers) > MaxNumbersPerPerson {
+			t.Errorf("предок %d: %d номеров", e.Person.ID, len(e.Numbers))
+		}
+	}
+	for _, e := range descendants {
+		if len(e.Numbers) > MaxNumbersPerPerson {
+			t.Errorf("потомок %d: %d номеров", e.Person.ID, len(e.Numbers))
+		}
+	}
+	if len(ancestors) != 3 || len(descendants) != 3 {
+		t.Errorf("предков %d, потомков %d, ожидалось по 3", len(ancestors), len(descendants))
+	}
+}
+
+// Родственные браки дают экспоненциальное число путей и без циклов
+func TestAncestorsPedigreeCollapseTruncated(t *testing.T) {
+	// Каждое поколение - одна и та же пара родителей у обоих супругов
+	var people []models.Person
+	var rels []models.Relationship
+	const generations = 40
+	for gen := 0; gen <= generations; gen++ {
+		people = append(people,
+			models.Person{ID: gen*2 + 1, Gender: "male"},
+			models.Person{ID: gen*2 + 2, Gender: "female"})
+	}
+	for gen := 0; gen < generations; gen++ {
+		for _, child := range []int{gen*2 + 1, gen*2 + 2} {
+			rels = append(rels,
+				parentRel(len(rels)+1, gen*2+3, child),
+				parentRel(len(rels)+2, gen*2+4, child))
+		}
+	}
+
+	list, _, truncated := Ancestors(NewGraph(people, rels), 1, MaxAncestorDepth)
+	if !truncated {
+		t.Error("ожидался признак усечения")
+	}
+	// Все, кроме сестры root из нулевого поколения
+	if len(list) != len(people)-1 {
+		t.Errorf("предков %d, ожидалось %d", len(list), len(people)-1)
+	}
+}
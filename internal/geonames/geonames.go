@@ -0,0 +1,147 @@
+// Package geonames - чтение выгрузок geonames.org (allCountries.txt, RU.txt,
+// cities15000.txt и их zip-архивов) для офлайн-справочника мест.
+package geonames
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"family-tree-app/internal/models"
+)
+
+// Столбцы выгрузки (см. readme.txt на download.geonames.org/export/dump)
+const (
+	colID = iota
+	colName
+	colASCIIName
+	colAlternateNames
+	colLatitude
+	colLongitude
+	colFeatureClass
+	colFeatureCode
+	colCountryCode
+	colCC2
+	colAdmin1
+	colAdmin2
+	colAdmin3
+	colAdmin4
+	colPopulation
+	columns = 19
+)
+
+// Read читает выгрузку построчно и передаёт каждую запись в fn. Ошибка
+// разбора останавливает чтение и сообщает номер строки.
+func Read(r io.Reader, fn func(models.GazetteerEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024) // у столиц тысячи других названий
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		e, err := parseLine(text)
+		if err != nil {
+			return fmt.Errorf("строка %d: %w", line, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func parseLine(text string) (models.GazetteerEntry, error) {
+	var e models.GazetteerEntry
+	f := strings.Split(text, "\t")
+	if len(f) < columns {
+		return e, fmt.Errorf("ожидалось %d столбцов через табуляцию, а не %d", columns, len(f))
+	}
+	var err error
+	if e.GeoNamesID, err = strconv.Atoi(f[colID]); err != nil {
+		return e, fmt.Errorf("неверный geonameid %q", f[colID])
+	}
+	if e.Latitude, err = strconv.ParseFloat(f[colLatitude], 64); err != nil {
+		return e, fmt.Errorf("неверная широта %q", f[colLatitude])
+	}
+	if e.Longitude, err = strconv.ParseFloat(f[colLongitude], 64); err != nil {
+		return e, fmt.Errorf("неверная долгота %q", f[colLongitude])
+	}
+	if f[colPopulation] != "" {
+		if e.Population, err = strconv.ParseInt(f[colPopulation], 10, 64); err != nil {
+			return e, fmt.Errorf("неверное население %q", f[colPopulation])
+		}
+	}
+	e.Name, e.ASCIIName = f[colName], f[colASCIIName]
+	e.AlternateNames = []string{}
+	for _, name := range strings.Split(f[colAlternateNames], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			e.AlternateNames = append(e.AlternateNames, name)
+		}
+	}
+	e.FeatureClass, e.FeatureCode = f[colFeatureClass], f[colFeatureCode]
+	e.CountryCode, e.Admin1 = f[colCountryCode], f[colAdmin1]
+	return e, nil
+}
+
+// ReadFile читает выгрузку из файла .txt или из zip-архива, в котором
+// она лежит вместе с readme.txt (как RU.zip с сайта)
+func ReadFile(path string, fn func(models.GazetteerEntry) error) error {
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return Read(f, fn)
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		if !strings.EqualFold(filepath.Ext(file.Name), ".txt") || strings.EqualFold(filepath.Base(file.Name), "readme.txt") {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return Read(r, fn)
+	}
+	return fmt.Errorf("в архиве %s нет выгрузки .txt", path)
+}
+
+// cityPopulation - населённый пункт с таким населением считается городом
+const cityPopulation = 10000
+
+// PlaceType - тип места по коду объекта GeoNames; пусто - не подобрать
+func PlaceType(e models.GazetteerEntry) string {
+	switch {
+	case strings.HasPrefix(e.FeatureCode, "PCL"):
+		return models.PlaceCountry
+	case e.FeatureCode == "ADM1":
+		return models.PlaceRegion
+	case e.FeatureCode == "ADM2":
+		return models.PlaceDistrict
+	case e.FeatureCode == "CMTY":
+		return models.PlaceCemetery
+	case e.FeatureCode == "PPLC" || strings.HasPrefix(e.FeatureCode, "PPLA"):
+		return models.PlaceCity
+	case e.FeatureClass == "P" && e.Population >= cityPopulation:
+		return models.PlaceCity
+	case e.FeatureClass == "P":
+		return models.PlaceVillage
+	}
+	return ""
+}
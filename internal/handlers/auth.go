@@ -2,28 +2,53 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"family-tree-app/internal/auth"
-	"family-tree-app/internal/database"
+	"family-tree-app/internal/store"
+	"log"
 	"net/http"
 )
 
+// AuthHandler - регистрация, вход и выход. Хранит настройки куки из конфигурации.
+type AuthHandler struct {
+	Users        store.UserStore
+	CookieSecure bool // флаг Secure у куки с токеном (включать при HTTPS)
+}
+
+// NewAuthHandler создаёт обработчики авторизации
+func NewAuthHandler(users store.UserStore, cookieSecure bool) *AuthHandler {
+	return &AuthHandler{Users: users, CookieSecure: cookieSecure}
+}
+
 type Credentials struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
-func Register(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var creds Credentials
 	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
 		http.Error(w, "Неверный формат", http.StatusBadRequest)
 		return
 	}
 
-	// Пароль хранится в открытом виде (тестовое приложение)
-	query := `INSERT INTO users (email, password_hash) VALUES (?, ?)`
-	_, err := database.DB.Exec(query, creds.Email, creds.Password)
+	if err := auth.ValidatePassword(creds.Password, creds.Email); err != nil {
+		writePasswordPolicyError(w, err)
+		return
+	}
+
+	hash, err := auth.HashPassword(creds.Password)
 	if err != nil {
-		http.Error(w, "Пользователь с таким email уже существует", http.StatusConflict)
+		http.Error(w, "Ошибка хеширования пароля", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.Users.CreateUser(r.Context(), creds.Email, hash); err != nil {
+		if errors.Is(err, store.ErrEmailTaken) {
+			http.Error(w, "Пользователь с таким email уже существует", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -31,29 +56,39 @@ func Register(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "registered"})
 }
 
-func Login(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var creds Credentials
 	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
 		http.Error(w, "Неверный формат", http.StatusBadRequest)
 		return
 	}
 
-	var storedPassword string
-	var userID int
-	err := database.DB.QueryRow(
-		"SELECT id, password_hash FROM users WHERE email = ?", creds.Email,
-	).Scan(&userID, &storedPassword)
+	// PasswordHash - bcrypt-хеш или пароль в открытом виде у старых аккаунтов
+	user, err := h.Users.GetUserByEmail(r.Context(), creds.Email)
 	if err != nil {
+		// Сравниваем с заглушкой, чтобы незнакомый email отвечал так же долго, как неверный пароль
+		auth.CompareDummyPassword(creds.Password)
 		http.Error(w, "Неверный email или пароль", http.StatusUnauthorized)
 		return
 	}
 
-	if creds.Password != storedPassword {
+	if !auth.VerifyPassword(creds.Password, user.PasswordHash) {
 		http.Error(w, "Неверный email или пароль", http.StatusUnauthorized)
 		return
 	}
 
-	token, err := auth.GenerateToken(userID)
+	// Старые аккаунты хранили пароль в открытом виде — перехешируем при первом входе.
+	// Ошибка здесь не мешает входу: попробуем снова в следующий раз.
+	if auth.NeedsRehash(user.PasswordHash) {
+		hash, err := auth.HashPassword(creds.Password)
+		if err != nil {
+			log.Printf("Не удалось захешировать пароль пользователя %d: %v", user.ID, err)
+		} else if err := h.Users.SetPasswordHash(r.Context(), user.ID, hash); err != nil {
+			log.Printf("Не удалось перехешировать пароль пользователя %d: %v", user.ID, err)
+		}
+	}
+
+	token, err := auth.GenerateToken(user.ID)
 	if err != nil {
 		http.Error(w, "Ошибка создания токена", http.StatusInternalServerError)
 		return
@@ -67,14 +102,14 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		Path:     "/",
 		MaxAge:   86400, // 24 часа
 		SameSite: http.SameSiteLaxMode,
-		// Secure: true — раскомментировать при деплое на HTTPS
+		Secure:   h.CookieSecure,
 	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"email": creds.Email})
 }
 
-func Logout(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "token",
 		Value:    "",
@@ -82,17 +117,17 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 		Path:     "/",
 		MaxAge:   -1,
 		SameSite: http.SameSiteLaxMode,
+		Secure:   h.CookieSecure,
 	})
 	w.WriteHeader(http.StatusOK)
 }
 
 // Me — проверка авторизации и получение данных текущего пользователя.
 // Дополнительно делает запрос в БД, поэтому невалидный user_id (удалённая БД) вернёт 401.
-func Me(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(auth.UserIDKey).(int)
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	userID := getUserID(r)
 
-	var email string
-	err := database.DB.QueryRow("SELECT email FROM users WHERE id = ?", userID).Scan(&email)
+	user, err := h.Users.GetUserByID(r.Context(), userID)
 	if err != nil {
 		// Токен валиден, но пользователь не найден в БД (например, БД была удалена)
 		http.Error(w, "Пользователь не найден", http.StatusUnauthorized)
@@ -102,6 +137,23 @@ func Me(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"user_id": userID,
-		"email":   email,
+		"email":   user.Email,
+	})
+}
+
+// writePasswordPolicyError отдаёт нарушения политики паролей в виде JSON:
+// {"error": "...", "violations": [{"code": "too_short", "message": "..."}]}
+func writePasswordPolicyError(w http.ResponseWriter, err error) {
+	var policyErr *auth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      "Пароль не соответствует требованиям",
+		"violations": policyErr.Violations,
 	})
 }
@@ -0,0 +1,55 @@
+package handlers
+
+import "family-tree-app/internal/models"
+
+// personResponse - человек в ответе API вместе с разобранными датами
+// (см. models.ParseDate). У дат по старому стилю в них обе формы:
+// from/to по григорианскому календарю и julian_from/julian_to как в записи.
+type personResponse struct {
+	models.Person
+	BirthDateParsed *models.Date `json:"birth_date_parsed,omitempty"`
+	DeathDateParsed *models.Date `json:"death_date_parsed,omitempty"`
+}
+
+// eventResponse - событие вместе с разобранной датой, как у personResponse
+type eventResponse struct {
+	models.Event
+	DateParsed *models.Date `json:"date_parsed,omitempty"`
+}
+
+// parsedDate - разобранная дата для ответа; nil, если разбирать нечего
+func parsedDate(s string) *models.Date {
+	d, err := models.ParseDate(s)
+	if err != nil || !d.Known() {
+		return nil
+	}
+	return &d
+}
+
+func withPersonDates(p models.Person) personResponse {
+	resp := personResponse{Person: p, BirthDateParsed: parsedDate(p.BirthDate)}
+	if p.DeathDate != nil {
+		resp.DeathDateParsed = parsedDate(*p.DeathDate)
+	}
+	return resp
+}
+
+func withPeopleDates(people []models.Person) []personResponse {
+	list := make([]personResponse, 0, len(people))
+	for _, p := range people {
+		list = append(list, withPersonDates(p))
+	}
+	return list
+}
+
+func withEventDate(e models.Event) eventResponse {
+	return eventResponse{Event: e, DateParsed: parsedDate(e.Date)}
+}
+
+func withEventsDates(events []models.Event) []eventResponse {
+	list := make([]eventResponse, 0, len(events))
+	for _, e := range events {
+		list = append(list, withEventDate(e))
+	}
+	return list
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"net/http"
+	"strconv"
+)
+
+// DuplicatesHandler - поиск и слияние дублей людей
+type DuplicatesHandler struct {
+	People        store.PeopleStore
+	Relationships store.RelationshipStore
+	Guard         *ConsistencyGuard // nil - проверки согласованности выключены
+}
+
+// NewDuplicatesHandler создаёт обработчики поиска и слияния дублей
+func NewDuplicatesHandler(people store.PeopleStore, relationships store.RelationshipStore) *DuplicatesHandler {
+	return &DuplicatesHandler{People: people, Relationships: relationships}
+}
+
+// Find - GET /api/people/duplicates?min_score=0.6: возможные дубли, самые вероятные первыми
+func (h *DuplicatesHandler) Find(w http.ResponseWriter, r *http.Request) {
+	minScore := genealogy.DefaultDuplicateScore
+	if raw := r.URL.Query().Get("min_score"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v > 1 {
+			http.Error(w, "min_score должен быть числом от 0 до 1", http.StatusBadRequest)
+			return
+		}
+		minScore = v
+	}
+
+	people, relationships, err := loadTree(r.Context(), h.People, h.Relationships, getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	duplicates := genealogy.FindDuplicates(genealogy.NewGraph(people, relationships), minScore)
+	if duplicates == nil {
+		duplicates = []genealogy.Duplicate{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(duplicates)
+}
+
+// mergeRequest - тело POST /api/people/merge
+type mergeRequest struct {
+	KeepID  int               `json:"keep_id"`  // кто остаётся
+	MergeID int               `json:"merge_id"` // кто вливается в keep_id и уходит в корзину
+	Prefer  map[string]string `json:"prefer"`   // поле -> "keep" или "merge"; по умолчанию непустое значение keep_id
+}
+
+// Merge - POST /api/people/merge: сливает двух людей в одного. Связи второго
+// переходят к первому, повторяющиеся связи уходят в корзину вместе со вторым.
+func (h *DuplicatesHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+
+	var req mergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка данных", http.StatusBadRequest)
+		return
+	}
+	if req.KeepID == req.MergeID {
+		http.Error(w, "Нельзя слить человека с самим собой", http.StatusUnprocessableEntity)
+		return
+	}
+
+	people, _, err := loadTree(r.Context(), h.People, h.Relationships, treeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byID := map[int]models.Person{}
+	for _, p := range people {
+		byID[p.ID] = p
+	}
+	keep, okKeep := byID[req.KeepID]
+	other, okOther := byID[req.MergeID]
+	if !okKeep || !okOther {
+		http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+		return
+	}
+
+	merged, err := genealogy.MergePerson(keep, other, req.Prefer)
+	if err != nil {
+		http.Error(w, "Ошибка данных: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if h.Guard != nil {
+		issues, err := h.Guard.Check(r.Context(), treeID, func(people []models.Person, rels []models.Relationship) ([]models.Person, []models.Relationship) {
+			return genealogy.ApplyMerge(people, rels, merged, other.ID)
+		})
+		if err != nil {
+			http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(issues) > 0 {
+			writeConsistencyError(w, issues)
+			return
+		}
+	}
+
+	result, err := h.People.MergePeople(r.Context(), treeID, merged, other.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrInvalidReference) {
+			http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка слияния: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
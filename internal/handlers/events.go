@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// EventsHandler - события жизни людей (/people/{id}/events) и пар (/relationships/{id}/events)
+type EventsHandler struct {
+	Store store.EventStore
+	Guard *ConsistencyGuard // nil - проверки согласованности выключены
+}
+
+// NewEventsHandler создаёт обработчики событий
+func NewEventsHandler(s store.EventStore) *EventsHandler {
+	return &EventsHandler{Store: s}
+}
+
+// Маршруты для человека и для связи отличаются только владельцем событий
+func (h *EventsHandler) ListPersonEvents(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, personOwner)
+}
+func (h *EventsHandler) CreatePersonEvent(w http.ResponseWriter, r *http.Request) {
+	h.create(w, r, personOwner)
+}
+func (h *EventsHandler) UpdatePersonEvent(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, personOwner)
+}
+func (h *EventsHandler) DeletePersonEvent(w http.ResponseWriter, r *http.Request) {
+	h.delete(w, r, personOwner)
+}
+func (h *EventsHandler) ListRelationshipEvents(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, relationshipOwner)
+}
+func (h *EventsHandler) CreateRelationshipEvent(w http.ResponseWriter, r *http.Request) {
+	h.create(w, r, relationshipOwner)
+}
+func (h *EventsHandler) UpdateRelationshipEvent(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, relationshipOwner)
+}
+func (h *EventsHandler) DeleteRelationshipEvent(w http.ResponseWriter, r *http.Request) {
+	h.delete(w, r, relationshipOwner)
+}
+
+// ownerFunc превращает {id} из пути во владельца событий
+type ownerFunc func(id int) store.EventOwner
+
+func personOwner(id int) store.EventOwner       { return store.EventOwner{PersonID: id} }
+func relationshipOwner(id int) store.EventOwner { return store.EventOwner{RelationshipID: id} }
+
+// list - GET .../events: события по дате, без даты - в конце
+func (h *EventsHandler) list(w http.ResponseWriter, r *http.Request, ownerOf ownerFunc) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.Store.ListEvents(r.Context(), getTreeID(r), ownerOf(id))
+	if err != nil {
+		writeEventError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(withEventsDates(events))
+}
+
+// create - POST .../events: {type, date, place, description}.
+// Событие birth или death меняет и дату рождения или смерти человека.
+func (h *EventsHandler) create(w http.ResponseWriter, r *http.Request, ownerOf ownerFunc) {
+	treeID := getTreeID(r)
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+	owner := ownerOf(id)
+
+	e, ok := decodeEvent(w, r, owner)
+	if !ok || !h.check(w, r, treeID, owner, e) {
+		return
+	}
+
+	if err := h.Store.CreateEvent(r.Context(), treeID, owner, &e); err != nil {
+		writeEventError(w, err, "Ошибка записи в БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(withEventDate(e))
+}
+
+// update - PUT .../events/{eventID}: меняет событие целиком
+func (h *EventsHandler) update(w http.ResponseWriter, r *http.Request, ownerOf ownerFunc) {
+	treeID := getTreeID(r)
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+	eventID, err := urlID(r, "eventID")
+	if err != nil {
+		http.Error(w, "Неверный ID события", http.StatusBadRequest)
+		return
+	}
+	owner := ownerOf(id)
+
+	e, ok := decodeEvent(w, r, owner)
+	if !ok || !h.check(w, r, treeID, owner, e) {
+		return
+	}
+	e.ID = eventID
+
+	if err := h.Store.UpdateEvent(r.Context(), treeID, owner, e); err != nil {
+		writeEventError(w, err, "Ошибка обновления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// delete - DELETE .../events/{eventID}. Событие стирается сразу, без корзины.
+func (h *EventsHandler) delete(w http.ResponseWriter, r *http.Request, ownerOf ownerFunc) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+	eventID, err := urlID(r, "eventID")
+	if err != nil {
+		http.Error(w, "Неверный ID события", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.DeleteEvent(r.Context(), getTreeID(r), ownerOf(id), eventID); err != nil {
+		writeEventError(w, err, "Ошибка удаления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// decodeEvent читает событие из тела и проверяет тип и дату
+func decodeEvent(w http.ResponseWriter, r *http.Request, owner store.EventOwner) (models.Event, bool) {
+	var e models.Event
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return e, false
+	}
+	e.Type = strings.TrimSpace(e.Type)
+
+	allowed := models.PersonEventTypes
+	if owner.RelationshipID != 0 {
+		allowed = models.RelationshipEventTypes
+	}
+	if !slices.Contains(allowed, e.Type) {
+		http.Error(w, "Неизвестный тип события, допустимы: "+strings.Join(allowed, ", "), http.StatusBadRequest)
+		return e, false
+	}
+	if _, err := models.ParseDate(e.Date); err != nil {
+		http.Error(w, "Неверная дата события: "+err.Error(), http.StatusBadRequest)
+		return e, false
+	}
+	return e, true
+}
+
+// check - при строгих проверках дата рождения или смерти из события не должна
+// противоречить дереву, как и при правке самого человека
+func (h *EventsHandler) check(w http.ResponseWriter, r *http.Request, treeID int, owner store.EventOwner, e models.Event) bool {
+	if h.Guard == nil || owner.PersonID == 0 || e.Date == "" {
+		return true
+	}
+	if e.Type != models.EventBirth && e.Type != models.EventDeath {
+		return true
+	}
+
+	issues, err := h.Guard.Check(r.Context(), treeID, func(people []models.Person, rels []models.Relationship) ([]models.Person, []models.Relationship) {
+		for i := range people {
+			if people[i].ID != owner.PersonID {
+				continue
+			}
+			if e.Type == models.EventBirth {
+				people[i].BirthDate = e.Date
+			} else {
+				date := e.Date
+				people[i].DeathDate = &date
+			}
+		}
+		return people, rels
+	})
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if len(issues) > 0 {
+		writeConsistencyError(w, issues)
+		return false
+	}
+	return true
+}
+
+func writeEventError(w http.ResponseWriter, err error, prefix string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Человек, связь или событие не найдены", http.StatusNotFound)
+	case errors.Is(err, store.ErrInvalidPlace):
+		http.Error(w, "Место события не найдено в дереве", http.StatusBadRequest)
+	case errors.Is(err, store.ErrNotSpouses):
+		http.Error(w, "Помолвка, брак и развод бывают только у связи супругов", http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, prefix+err.Error(), http.StatusInternalServerError)
+	}
+}
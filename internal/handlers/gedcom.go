@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"family-tree-app/internal/gedcom"
+	"family-tree-app/internal/store"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxGedcomSize - предельный размер загружаемого файла GEDCOM
+const maxGedcomSize = 50 << 20
+
+// GedcomHandler - импорт и экспорт дерева в формате GEDCOM
+type GedcomHandler struct {
+	People        store.PeopleStore
+	Relationships store.RelationshipStore
+	Importer      store.TreeImporter
+	Places        store.PlaceStore
+	Events        store.EventStore
+	Sources       store.SourceStore
+}
+
+// NewGedcomHandler создаёт обработчики GEDCOM
+func NewGedcomHandler(people store.PeopleStore, relationships store.RelationshipStore, importer store.TreeImporter, places store.PlaceStore, events store.EventStore, sources store.SourceStore) *GedcomHandler {
+	return &GedcomHandler{People: people, Relationships: relationships, Importer: importer, Places: places, Events: events, Sources: sources}
+}
+
+// Import - POST /api/import/gedcom
+// Файл передаётся телом запроса или полем "file" в multipart/form-data.
+// Параметры: charset - переопределить кодировку, dry_run=true - только отчёт без записи.
+func (h *GedcomHandler) Import(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	r.Body = http.MaxBytesReader(w, r.Body, maxGedcomSize)
+
+	data, err := readUpload(r, "file")
+	if err != nil {
+		http.Error(w, "Не удалось прочитать файл: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	doc, err := gedcom.Decode(data, r.URL.Query().Get("charset"))
+	if err != nil {
+		http.Error(w, "Ошибка разбора GEDCOM: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	people, relationships, report := gedcom.BuildTree(doc)
+	if r.URL.Query().Get("dry_run") != "true" {
+		if err := h.Importer.ImportTree(r.Context(), treeID, people, relationships); err != nil {
+			http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Export - GET /api/export/gedcom?version=5.5.1|7
+// Отдаёт текущее дерево файлом .ged (по умолчанию GEDCOM 5.5.1).
+func (h *GedcomHandler) Export(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+
+	version := gedcom.Version551
+	switch r.URL.Query().Get("version") {
+	case "", "5", "5.5.1":
+	case "7", "7.0":
+		version = gedcom.Version7
+	default:
+		http.Error(w, "Поддерживаются версии 5.5.1 и 7", http.StatusBadRequest)
+		return
+	}
+
+	people, relationships, err := loadTree(r.Context(), h.People, h.Relationships, treeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	opts, err := h.exportOptions(r.Context(), treeID, version)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Собираем файл целиком в памяти, чтобы ошибка не оборвала ответ на середине
+	var buf bytes.Buffer
+	if err := gedcom.Export(&buf, people, relationships, opts); err != nil {
+		http.Error(w, "Ошибка экспорта: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := "application/x-gedcom; charset=utf-8"
+	if version == gedcom.Version7 {
+		contentType = "text/vnd.familysearch.gedcom; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="family-tree.ged"`)
+	w.Write(buf.Bytes())
+}
+
+// exportOptions собирает для экспорта места, события и источники дерева
+func (h *GedcomHandler) exportOptions(ctx context.Context, treeID int, version string) (gedcom.ExportOptions, error) {
+	opts := gedcom.ExportOptions{Version: version}
+	var err error
+	if opts.Places, err = h.Places.ListPlaces(ctx, treeID); err != nil {
+		return opts, err
+	}
+	if opts.Events, err = h.Events.ListTreeEvents(ctx, treeID); err != nil {
+		return opts, err
+	}
+	if opts.Sources, err = h.Sources.ListSources(ctx, treeID); err != nil {
+		return opts, err
+	}
+	opts.Citations, err = h.Sources.ListTreeCitations(ctx, treeID)
+	return opts, err
+}
+
+// readUpload читает файл из multipart-поля или, если это не multipart, всё тело запроса
+func readUpload(r *http.Request, field string) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return io.ReadAll(r.Body)
+	}
+
+	file, _, err := r.FormFile(field)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"net/http"
+	"strconv"
+)
+
+// maxHistoryLimit - больше записей журнала за один запрос не отдаём
+const maxHistoryLimit = 1000
+
+// HistoryHandler - журнал изменений и отмена
+type HistoryHandler struct {
+	Store store.HistoryStore
+}
+
+// NewHistoryHandler создаёт обработчики журнала изменений
+func NewHistoryHandler(s store.HistoryStore) *HistoryHandler {
+	return &HistoryHandler{Store: s}
+}
+
+// TreeHistory - GET /api/history?limit=N&before=ID: все изменения дерева, новые первыми
+func (h *HistoryHandler) TreeHistory(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, store.ChangeFilter{})
+}
+
+// PersonHistory - GET /api/people/{id}/history?limit=N&before=ID: изменения человека и его связей
+func (h *HistoryHandler) PersonHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+	h.list(w, r, store.ChangeFilter{PersonID: id})
+}
+
+func (h *HistoryHandler) list(w http.ResponseWriter, r *http.Request, f store.ChangeFilter) {
+	for name, dst := range map[string]*int{"limit": &f.Limit, "before": &f.BeforeID} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			http.Error(w, "Параметр "+name+" должен быть положительным числом", http.StatusBadRequest)
+			return
+		}
+		*dst = v
+	}
+	if f.Limit > maxHistoryLimit {
+		f.Limit = maxHistoryLimit
+	}
+
+	changes, err := h.Store.ListChanges(r.Context(), getTreeID(r), f)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// Revert - POST /api/history/{changeID}/revert: отменяет одно изменение.
+// Отмена сама попадает в журнал (revert_of), поэтому её тоже можно отменить.
+func (h *HistoryHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	changeID, err := urlID(r, "changeID")
+	if err != nil {
+		http.Error(w, "Неверный ID изменения", http.StatusBadRequest)
+		return
+	}
+	h.revert(w, r, changeID)
+}
+
+// RestorePerson - POST /api/people/{id}/restore: возвращает удалённого человека
+// вместе со связями, которые были удалены вместе с ним
+func (h *HistoryHandler) RestorePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	deletions, err := h.Store.ListChanges(r.Context(), getTreeID(r), store.ChangeFilter{
+		EntityType: models.EntityPerson,
+		EntityID:   id,
+		Action:     models.ActionDelete,
+		Limit:      1,
+	})
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(deletions) == 0 {
+		http.Error(w, "Удаление этого человека не найдено в журнале", http.StatusNotFound)
+		return
+	}
+	h.revert(w, r, deletions[0].ID)
+}
+
+func (h *HistoryHandler) revert(w http.ResponseWriter, r *http.Request, changeID int) {
+	changes, err := h.Store.RevertChange(r.Context(), getTreeID(r), changeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, "Изменение не найдено", http.StatusNotFound)
+		case errors.Is(err, store.ErrRevertConflict):
+			http.Error(w, "Изменение нельзя отменить: данные с тех пор изменились", http.StatusConflict)
+		case errors.Is(err, store.ErrInvalidPlace):
+			http.Error(w, "Изменение нельзя отменить: место из него с тех пор удалено", http.StatusConflict)
+		case errors.Is(err, store.ErrInvalidMedia):
+			http.Error(w, "Изменение нельзя отменить: файл из него с тех пор удалён", http.StatusConflict)
+		default:
+			http.Error(w, "Ошибка отмены: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
@@ -0,0 +1,655 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/media"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// multipartOverhead - сколько тела запроса на загрузку может уйти сверх
+// самого файла: заголовки частей, название и описание
+const multipartOverhead = 1 << 20
+
+// maxMediaFieldLength - предел длины названия и описания в форме загрузки, байт
+const maxMediaFieldLength = 64 << 10
+
+// defaultThumbnailSize - миниатюра, которую отдают без ?size
+const defaultThumbnailSize = 256
+
+// immutableCache - ответ по этому адресу никогда не меняется: содержимое файла
+// задано его хешем, а ID файлов не используются повторно
+const immutableCache = "private, max-age=31536000, immutable"
+
+// MediaHandler - загруженные файлы дерева (/media) и их связи с людьми,
+// связями и событиями (/people/{id}/media, /relationships/{id}/media,
+// /events/{id}/media). Содержимое хранит Files, описание - Store.
+type MediaHandler struct {
+	Store   store.MediaStore
+	Files   media.Storage
+	MaxSize int64 // наибольший размер файла, байт
+
+	contentLocks hashLocks
+}
+
+// NewMediaHandler создаёт обработчики файлов
+func NewMediaHandler(s store.MediaStore, files media.Storage, maxSize int64) *MediaHandler {
+	return &MediaHandler{Store: s, Files: files, MaxSize: maxSize}
+}
+
+// List - GET /api/media: файлы дерева, новые первыми
+func (h *MediaHandler) List(w http.ResponseWriter, r *http.Request) {
+	files, err := h.Store.ListMedia(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files)
+}
+
+// Get - GET /api/media/{id}: описание файла и с кем он связан
+func (h *MediaHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.Store.GetMedia(r.Context(), getTreeID(r), id)
+	if err != nil {
+		writeMediaError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
+
+// Upload - POST /api/media: multipart/form-data с файлом в поле file
+// и необязательными полями title и description. Тип файла определяется
+// по содержимому (см. media.AllowedTypes). Если такой же файл в дереве уже
+// есть, новый не заводится: возвращается существующий со статусом 200.
+//
+// strip_gps=true стирает из фотографии координаты съёмки до сохранения.
+// Из EXIF берутся размеры и дата съёмки (taken_at - подсказка для даты
+// события), для JPEG, PNG и GIF сразу строятся миниатюры.
+func (h *MediaHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxSize+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Ожидается multipart/form-data с файлом в поле file", http.StatusBadRequest)
+		return
+	}
+
+	var m models.Media
+	var upload *media.Upload
+	var stripGPS bool
+	defer func() {
+		if upload != nil {
+			upload.Close()
+		}
+	}()
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			h.writeUploadError(w, err)
+			return
+		}
+
+		switch part.FormName() {
+		case "file":
+			if upload != nil {
+				http.Error(w, "За один запрос загружается один файл", http.StatusBadRequest)
+				return
+			}
+			if upload, err = media.Receive(part, h.MaxSize); err != nil {
+				h.writeUploadError(w, err)
+				return
+			}
+			m.FileName = part.FileName()
+		case "title", "description":
+			value, err := io.ReadAll(io.LimitReader(part, maxMediaFieldLength+1))
+			if err != nil {
+				h.writeUploadError(w, err)
+				return
+			}
+			if len(value) > maxMediaFieldLength {
+				http.Error(w, "Слишком длинное название или описание", http.StatusBadRequest)
+				return
+			}
+			if part.FormName() == "title" {
+				m.Title = strings.TrimSpace(string(value))
+			} else {
+				m.Description = strings.TrimSpace(string(value))
+			}
+		case "strip_gps":
+			value, err := io.ReadAll(io.LimitReader(part, 16))
+			if err != nil {
+				h.writeUploadError(w, err)
+				return
+			}
+			stripGPS, _ = strconv.ParseBool(strings.TrimSpace(string(value)))
+		}
+		part.Close()
+	}
+	if upload == nil {
+		http.Error(w, "Не передан файл (поле file)", http.StatusBadRequest)
+		return
+	}
+	// Координаты стираются до поиска дубликата: хеш у файла уже другой
+	if stripGPS {
+		if err := media.StripGPS(upload); err != nil {
+			http.Error(w, "Не удалось стереть координаты из файла: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// До записи в базу содержимое никому не принадлежит: удаление того же
+	// содержимого в другом дереве должно подождать
+	unlock := h.contentLocks.lock(upload.Hash)
+	defer unlock()
+
+	existing, err := h.Store.FindMedia(r.Context(), treeID, upload.Hash)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+		return
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Содержимое, общее с другими деревьями, просто перезаписывается тем же самым.
+	// Хранилищу отдаётся отдельный читатель: файл ещё нужен для Inspect и миниатюр.
+	content := io.NewSectionReader(upload.File, 0, upload.Size)
+	if err := h.Files.Put(r.Context(), upload.Hash, content, upload.Size, upload.ContentType); err != nil {
+		http.Error(w, "Ошибка записи файла: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m.Hash, m.Size, m.ContentType = upload.Hash, upload.Size, upload.ContentType
+	info := media.Inspect(upload.File, upload.Size, upload.ContentType)
+	m.Width, m.Height, m.TakenAt, m.HasGPS = info.Width, info.Height, info.TakenAt, info.HasGPS
+	if media.CanThumbnail(m.ContentType) {
+		// Не получилось - не беда: миниатюры построятся при первом запросе
+		if _, err := h.makeThumbnails(r.Context(), &m, upload.File); err != nil {
+			log.Printf("Не удалось построить миниатюры файла %s: %v", m.Hash, err)
+		}
+	}
+	if err := h.Store.CreateMedia(r.Context(), treeID, &m); err != nil {
+		h.dropUnusedContent(r.Context(), m.Hash)
+		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(m)
+}
+
+// Download - GET /api/media/{id}/file: содержимое файла, только участникам
+// дерева. ?download=1 - сохранить файлом, а не открыть в браузере.
+func (h *MediaHandler) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.Store.GetMedia(r.Context(), getTreeID(r), id)
+	if err != nil {
+		writeMediaError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+	h.serveFile(w, r, m, immutableCache)
+}
+
+// serveFile отдаёт содержимое файла m с заголовком Cache-Control: cache
+func (h *MediaHandler) serveFile(w http.ResponseWriter, r *http.Request, m *models.Media, cache string) {
+	content, err := h.Files.Open(r.Context(), m.Hash)
+	if err != nil {
+		if errors.Is(err, media.ErrNotExist) {
+			http.Error(w, "Содержимое файла не найдено в хранилище", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка чтения файла: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "1" {
+		disposition = "attachment"
+	}
+	if m.FileName != "" {
+		disposition = mime.FormatMediaType(disposition, map[string]string{"filename": m.FileName})
+	}
+	w.Header().Set("Content-Type", m.ContentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", cache)
+	w.Header().Set("ETag", `"`+m.Hash+`"`)
+
+	// С диска - с поддержкой Range (перемотка аудио и видео), из S3 - потоком
+	if rs, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", time.Time{}, rs)
+		return
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), `"`+m.Hash+`"`) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(m.Size, 10))
+	io.Copy(w, content)
+}
+
+// Thumbnail - GET /api/media/{id}/thumbnail?size=256: уменьшенная копия
+// фотографии в JPEG, size - из media.ThumbnailSizes. Для графа и карточек
+// людей вместо многомегабайтных сканов. Кешируется надолго, с ETag.
+func (h *MediaHandler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+	size, ok := thumbnailSize(w, r)
+	if !ok {
+		return
+	}
+
+	m, err := h.Store.GetMedia(r.Context(), getTreeID(r), id)
+	if err != nil {
+		writeMediaError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+	h.serveThumbnail(w, r, m, size, immutableCache)
+}
+
+// thumbnailSize читает ?size= (без него - defaultThumbnailSize). false - ответ
+// с ошибкой уже отправлен.
+func thumbnailSize(w http.ResponseWriter, r *http.Request) (int, bool) {
+	s := r.URL.Query().Get("size")
+	if s == "" {
+		return defaultThumbnailSize, true
+	}
+	size, err := strconv.Atoi(s)
+	if err != nil || !slices.Contains(media.ThumbnailSizes, size) {
+		http.Error(w, fmt.Sprintf("Неверный размер миниатюры, допустимы: %v", media.ThumbnailSizes), http.StatusBadRequest)
+		return 0, false
+	}
+	return size, true
+}
+
+// serveThumbnail отдаёт миниатюру m размера size с заголовком Cache-Control: cache
+func (h *MediaHandler) serveThumbnail(w http.ResponseWriter, r *http.Request, m *models.Media, size int, cache string) {
+	if !media.CanThumbnail(m.ContentType) {
+		http.Error(w, "У файла этого типа нет миниатюр", http.StatusNotFound)
+		return
+	}
+
+	// Проверяем ETag до хранилища: повторный запрос не должен его трогать
+	etag := fmt.Sprintf(`"%s-%d"`, m.Hash, size)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cache)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, err := h.thumbnail(r.Context(), m, size)
+	if err != nil {
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+		switch {
+		case errors.Is(err, media.ErrNotExist):
+			http.Error(w, "Содержимое файла не найдено в хранилище", http.StatusNotFound)
+			return
+		case errors.Is(err, media.ErrImageTooLarge):
+			http.Error(w, "Изображение слишком большое для миниатюр", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Не удалось построить миниатюру: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+// thumbnail читает миниатюру из хранилища. Если её там нет (файл загружен
+// до появления миниатюр или построить их при загрузке не удалось), строит
+// все размеры из исходника и сохраняет.
+func (h *MediaHandler) thumbnail(ctx context.Context, m *models.Media, size int) ([]byte, error) {
+	content, err := h.Files.Open(ctx, media.ThumbnailKey(m.Hash, size))
+	if err == nil {
+		defer content.Close()
+		return io.ReadAll(content)
+	}
+	if !errors.Is(err, media.ErrNotExist) {
+		return nil, err
+	}
+
+	original, err := h.Files.Open(ctx, m.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer original.Close()
+	// Из S3 исходник приходит потоком, а декодеру нужен произвольный доступ
+	src, ok := original.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(original)
+		if err != nil {
+			return nil, err
+		}
+		src = bytes.NewReader(data)
+	}
+	thumbs, err := h.makeThumbnails(ctx, m, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range thumbs {
+		if t.Size == size {
+			return t.Data, nil
+		}
+	}
+	return nil, media.ErrNotExist
+}
+
+// makeThumbnails строит миниатюры всех размеров и кладёт их в хранилище
+// рядом с исходником
+func (h *MediaHandler) makeThumbnails(ctx context.Context, m *models.Media, src io.ReaderAt) ([]media.Thumbnail, error) {
+	thumbs, err := media.MakeThumbnails(src, m.Size, m.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range thumbs {
+		if err := h.Files.Put(ctx, media.ThumbnailKey(m.Hash, t.Size), bytes.NewReader(t.Data), int64(len(t.Data)), "image/jpeg"); err != nil {
+			return nil, err
+		}
+	}
+	return thumbs, nil
+}
+
+// etagMatches - совпадает ли etag с одним из перечисленных в If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Update - PUT /api/media/{id}: {title, description}
+func (h *MediaHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	var m models.Media
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "Ошибка данных", http.StatusBadRequest)
+		return
+	}
+	m.ID = id
+	m.Title, m.Description = strings.TrimSpace(m.Title), strings.TrimSpace(m.Description)
+
+	if err := h.Store.UpdateMedia(r.Context(), getTreeID(r), m); err != nil {
+		writeMediaError(w, err, "Ошибка обновления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// Delete - DELETE /api/media/{id}: удаляет файл вместе со всеми связями.
+// Содержимое стирается из хранилища, если оно не нужно другим деревьям.
+func (h *MediaHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.Store.DeleteMedia(r.Context(), getTreeID(r), id)
+	if err != nil {
+		writeMediaError(w, err, "Ошибка удаления: ")
+		return
+	}
+	h.dropContent(r.Context(), m.Hash)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// Маршруты файлов человека, связи и события отличаются только владельцем
+func (h *MediaHandler) ListPersonMedia(w http.ResponseWriter, r *http.Request) {
+	h.listOwnerMedia(w, r, personMediaOwner)
+}
+func (h *MediaHandler) LinkPersonMedia(w http.ResponseWriter, r *http.Request) {
+	h.link(w, r, personMediaOwner)
+}
+func (h *MediaHandler) UnlinkPersonMedia(w http.ResponseWriter, r *http.Request) {
+	h.unlink(w, r, personMediaOwner)
+}
+func (h *MediaHandler) ListRelationshipMedia(w http.ResponseWriter, r *http.Request) {
+	h.listOwnerMedia(w, r, relationshipMediaOwner)
+}
+func (h *MediaHandler) LinkRelationshipMedia(w http.ResponseWriter, r *http.Request) {
+	h.link(w, r, relationshipMediaOwner)
+}
+func (h *MediaHandler) UnlinkRelationshipMedia(w http.ResponseWriter, r *http.Request) {
+	h.unlink(w, r, relationshipMediaOwner)
+}
+func (h *MediaHandler) ListEventMedia(w http.ResponseWriter, r *http.Request) {
+	h.listOwnerMedia(w, r, eventMediaOwner)
+}
+func (h *MediaHandler) LinkEventMedia(w http.ResponseWriter, r *http.Request) {
+	h.link(w, r, eventMediaOwner)
+}
+func (h *MediaHandler) UnlinkEventMedia(w http.ResponseWriter, r *http.Request) {
+	h.unlink(w, r, eventMediaOwner)
+}
+
+// mediaOwnerFunc превращает {id} из пути во владельца файлов
+type mediaOwnerFunc func(id int) store.MediaOwner
+
+func personMediaOwner(id int) store.MediaOwner {
+	return store.MediaOwner{PersonID: id}
+}
+func relationshipMediaOwner(id int) store.MediaOwner {
+	return store.MediaOwner{RelationshipID: id}
+}
+func eventMediaOwner(id int) store.MediaOwner {
+	return store.MediaOwner{EventID: id}
+}
+
+// listOwnerMedia - GET .../media: файлы, связанные с владельцем
+func (h *MediaHandler) listOwnerMedia(w http.ResponseWriter, r *http.Request, ownerOf mediaOwnerFunc) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	files, err := h.Store.ListOwnerMedia(r.Context(), getTreeID(r), ownerOf(id))
+	if err != nil {
+		writeMediaError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files)
+}
+
+// link - POST .../media {"media_id": N}: связывает загруженный файл с владельцем
+// и возвращает файл со всеми связями. Повторная связь ничего не меняет.
+func (h *MediaHandler) link(w http.ResponseWriter, r *http.Request, ownerOf mediaOwnerFunc) {
+	treeID := getTreeID(r)
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		MediaID int `json:"media_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if req.MediaID == 0 {
+		http.Error(w, "Не указан файл (media_id)", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.LinkMedia(r.Context(), treeID, ownerOf(id), req.MediaID); err != nil {
+		writeMediaError(w, err, "Ошибка записи в БД: ")
+		return
+	}
+	m, err := h.Store.GetMedia(r.Context(), treeID, req.MediaID)
+	if err != nil {
+		writeMediaError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
+
+// unlink - DELETE .../media/{mediaID}: убирает связь, сам файл остаётся
+func (h *MediaHandler) unlink(w http.ResponseWriter, r *http.Request, ownerOf mediaOwnerFunc) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+	mediaID, err := urlID(r, "mediaID")
+	if err != nil {
+		http.Error(w, "Неверный ID файла", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.UnlinkMedia(r.Context(), getTreeID(r), ownerOf(id), mediaID); err != nil {
+		writeMediaError(w, err, "Ошибка удаления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// dropContent стирает содержимое и миниатюры из хранилища, если файлов с ним
+// не осталось ни в одном дереве. Ошибка только пишется в лог: лишний файл
+// в хранилище ничему не мешает.
+func (h *MediaHandler) dropContent(ctx context.Context, hash string) {
+	unlock := h.contentLocks.lock(hash)
+	defer unlock()
+	h.dropUnusedContent(ctx, hash)
+}
+
+// dropUnusedContent - dropContent под уже взятой блокировкой хеша
+func (h *MediaHandler) dropUnusedContent(ctx context.Context, hash string) {
+	used, err := h.Store.MediaInUse(ctx, hash)
+	if err == nil && !used {
+		err = h.Files.Delete(ctx, hash)
+		for _, size := range media.ThumbnailSizes {
+			if err == nil {
+				err = h.Files.Delete(ctx, media.ThumbnailKey(hash, size))
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Не удалось стереть содержимое файла %s: %v", hash, err)
+	}
+}
+
+// hashLocks - блокировки по хешу содержимого. Загрузка держит блокировку
+// от поиска дубликата до записи файла в базу, а dropContent - от проверки,
+// нужно ли содержимое, до его удаления. Иначе удаление в одном дереве
+// стирает содержимое, которое другое дерево записало, но ещё не сохранило
+// в базе. Блокировки действуют внутри одного процесса сервера.
+type hashLocks struct {
+	mu    sync.Mutex
+	locks map[string]*hashLock
+}
+
+type hashLock struct {
+	sync.Mutex
+	holders int // сколько запросов держат или ждут блокировку
+}
+
+// lock берёт блокировку хеша и возвращает функцию, которая её отпускает
+func (l *hashLocks) lock(hash string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*hashLock{}
+	}
+	hl, ok := l.locks[hash]
+	if !ok {
+		hl = &hashLock{}
+		l.locks[hash] = hl
+	}
+	hl.holders++
+	l.mu.Unlock()
+
+	hl.Lock()
+	return func() {
+		hl.Unlock()
+		l.mu.Lock()
+		if hl.holders--; hl.holders == 0 {
+			delete(l.locks, hash)
+		}
+		l.mu.Unlock()
+	}
+}
+
+func (h *MediaHandler) writeUploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, media.ErrTooLarge), errors.As(err, &tooLarge):
+		http.Error(w, fmt.Sprintf("Файл больше %d МБ", h.MaxSize>>20), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, media.ErrEmpty):
+		http.Error(w, "Файл пуст", http.StatusBadRequest)
+	case errors.Is(err, media.ErrUnsupportedType):
+		http.Error(w, "Неподдерживаемый тип файла, допустимы: "+strings.Join(media.AllowedTypes, ", "), http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, "Не удалось прочитать файл: "+err.Error(), http.StatusBadRequest)
+	}
+}
+
+func writeMediaError(w http.ResponseWriter, err error, prefix string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Файл, человек, связь или событие не найдены", http.StatusNotFound)
+	case errors.Is(err, store.ErrInvalidMedia):
+		http.Error(w, "Файл не найден в дереве", http.StatusBadRequest)
+	default:
+		http.Error(w, prefix+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -2,14 +2,30 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"family-tree-app/internal/auth"
-	"family-tree-app/internal/database"
 	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// PeopleHandler - CRUD для людей текущего дерева
+type PeopleHandler struct {
+	Store store.PeopleStore
+	Guard *ConsistencyGuard // nil - проверки согласованности выключены
+}
+
+// NewPeopleHandler создаёт обработчики для людей
+func NewPeopleHandler(s store.PeopleStore) *PeopleHandler {
+	return &PeopleHandler{Store: s}
+}
+
 // Структура для получения координат с фронтенда
 type PositionUpdate struct {
 	ID int     `json:"id"`
@@ -22,85 +38,283 @@ func getUserID(r *http.Request) int {
 	return r.Context().Value(auth.UserIDKey).(int)
 }
 
+// urlID читает числовой параметр пути (например, {id})
+func urlID(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(chi.URLParam(r, name))
+}
+
+// validatePersonDates проверяет, что даты рождения и смерти записаны понятно
+// (см. models.ParseDate): иначе их не отсортировать и не проверить.
+// Даты, которые совпадают с сохранёнными в stored, не проверяются: старые
+// записи вроде "1890?" не должны мешать править остальные поля человека.
+// stored - nil для нового человека.
+func validatePersonDates(p models.Person, stored *models.Person) error {
+	if stored == nil || p.BirthDate != stored.BirthDate {
+		if _, err := models.ParseDate(p.BirthDate); err != nil {
+			return fmt.Errorf("Неверная дата рождения: %w", err)
+		}
+	}
+	if p.DeathDate != nil && (stored == nil || stored.DeathDate == nil || *p.DeathDate != *stored.DeathDate) {
+		if _, err := models.ParseDate(*p.DeathDate); err != nil {
+			return fmt.Errorf("Неверная дата смерти: %w", err)
+		}
+	}
+	return nil
+}
+
 // CreatePerson
-func CreatePerson(w http.ResponseWriter, r *http.Request) {
-	userID := getUserID(r)
+func (h *PeopleHandler) CreatePerson(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
 
 	var p models.Person
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
 		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
 		return
 	}
+	if err := validatePersonDates(p, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// При создании position_x/y по умолчанию 0 (в БД)
-	query := `INSERT INTO people (user_id, first_name, middle_name, last_name, birth_date, death_date, gender, photo_url) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	
-	result, err := database.DB.Exec(query, userID, p.FirstName, p.MiddleName, p.LastName, p.BirthDate, p.DeathDate, p.Gender, p.PhotoURL)
-	if err != nil {
+	if err := h.Store.CreatePerson(r.Context(), treeID, &p); err != nil {
+		if errors.Is(err, store.ErrInvalidPlace) {
+			http.Error(w, "Место рождения или смерти не найдено в дереве", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, store.ErrInvalidMedia) {
+			http.Error(w, "Файл портрета не найден в дереве", http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	id, _ := result.LastInsertId()
-	p.ID = int(id)
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(p)
+	json.NewEncoder(w).Encode(withPersonDates(p))
 }
 
-// GetAllPeople
-func GetAllPeople(w http.ResponseWriter, r *http.Request) {
-	userID := getUserID(r)
+// maxPeoplePage - больше людей на одной странице не отдаём
+const maxPeoplePage = 1000
+
+// GetAllPeople - GET /api/people: люди дерева. Без параметров - все сразу, как раньше.
+//
+//	limit=N            страница из N человек; ссылка на следующую - в заголовке Link (rel="next")
+//	cursor=...         продолжение из ссылки на следующую страницу
+//	sort=last_name     id (по умолчанию), last_name, first_name, birth_date; "-" в начале - по убыванию
+//	surname=Иванов     фамилия в любой форме и записи: найдутся и Иванова, и Ivanov
+//	gender=female      пол
+//	born_from=1850     год рождения не раньше
+//	born_to=1900       год рождения не позже
+//	living=true        только живые (false - только умершие), как при скрытии живых
+//	has_photo=true     только с фото (false - только без фото)
+//	fields=id,first_name,last_name  только эти поля (id есть всегда)
+func (h *PeopleHandler) GetAllPeople(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+
+	q, fields, err := parsePeopleQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Добавили чтение координат: position_x, position_y
-	query := `SELECT id, first_name, middle_name, last_name, birth_date, death_date, gender, photo_url, position_x, position_y FROM people WHERE user_id = ?`
-	rows, err := database.DB.Query(query, userID)
+	page, err := h.Store.QueryPeople(r.Context(), treeID, q)
 	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) || errors.Is(err, store.ErrInvalidSort) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	people := []models.Person{}
+	if page.NextCursor != "" {
+		next := *r.URL
+		values := next.Query()
+		values.Set("cursor", page.NextCursor)
+		next.RawQuery = values.Encode()
+		w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if fields == nil {
+		json.NewEncoder(w).Encode(withPeopleDates(page.People))
+		return
+	}
+	json.NewEncoder(w).Encode(projectPeople(page.People, fields))
+}
+
+// personFields - имена полей человека в JSON, допустимые в fields=
+var personFields = map[string]bool{
+	"id": true, "first_name": true, "last_name": true, "middle_name": true, "birth_date": true, "death_date": true,
+	"gender": true, "photo_url": true, "notes": true, "birth_place_id": true, "death_place_id": true, "photo_media_id": true,
+	"position_x": true, "position_y": true, "birth_date_parsed": true, "death_date_parsed": true,
+}
+
+// parsePeopleQuery разбирает параметры GetAllPeople. fields - nil, если поля не выбирались.
+func parsePeopleQuery(r *http.Request) (store.PeopleQuery, []string, error) {
+	v := r.URL.Query()
+	q := store.PeopleQuery{
+		Surname: strings.TrimSpace(v.Get("surname")),
+		Gender:  v.Get("gender"),
+		Sort:    strings.TrimPrefix(v.Get("sort"), "-"),
+		Desc:    strings.HasPrefix(v.Get("sort"), "-"),
+		Cursor:  v.Get("cursor"),
+	}
+
+	for name, dst := range map[string]*int{"limit": &q.Limit, "born_from": &q.BornFrom, "born_to": &q.BornTo} {
+		raw := v.Get(name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return q, nil, fmt.Errorf("Параметр %s должен быть положительным числом", name)
+		}
+		*dst = n
+	}
+	if q.Limit > maxPeoplePage {
+		q.Limit = maxPeoplePage
+	}
 
-	for rows.Next() {
-		var p models.Person
-		var photoUrl *string 
-		var middleName *string
-		
-		// Сканируем координаты в p.PositionX, p.PositionY
-		if err := rows.Scan(&p.ID, &p.FirstName, &middleName, &p.LastName, &p.BirthDate, &p.DeathDate, &p.Gender, &photoUrl, &p.PositionX, &p.PositionY); err != nil {
+	for name, dst := range map[string]**bool{"living": &q.Living, "has_photo": &q.HasPhoto} {
+		raw := v.Get(name)
+		if raw == "" {
 			continue
 		}
-		if photoUrl != nil {
-			p.PhotoURL = *photoUrl
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, nil, fmt.Errorf("Параметр %s должен быть true или false", name)
 		}
-		if middleName != nil {
-			p.MiddleName = *middleName
+		*dst = &b
+	}
+
+	var fields []string
+	if raw := v.Get("fields"); raw != "" {
+		fields = []string{"id"}
+		for _, f := range strings.Split(raw, ",") {
+			f = strings.TrimSpace(f)
+			if !personFields[f] {
+				return q, nil, fmt.Errorf("Неизвестное поле %q", f)
+			}
+			if f != "id" {
+				fields = append(fields, f)
+			}
 		}
-		people = append(people, p)
+	}
+	return q, fields, nil
+}
+
+// projectPeople оставляет у людей только поля fields
+func projectPeople(people []models.Person, fields []string) []map[string]json.RawMessage {
+	projected := make([]map[string]json.RawMessage, 0, len(people))
+	for _, p := range people {
+		var all map[string]json.RawMessage
+		b, _ := json.Marshal(withPersonDates(p))
+		json.Unmarshal(b, &all)
+		one := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			one[f] = all[f]
+		}
+		projected = append(projected, one)
+	}
+	return projected
+}
+
+// maxSearchLimit - больше людей за один поиск не отдаём
+const maxSearchLimit = 200
+
+// SearchPeople - GET /api/people/search?q=...&limit=N: поиск по именам и заметкам,
+// лучшие совпадения первыми. Находит имя в другой транслитерации (Iwanow - Иванов),
+// другую форму фамилии (Иванова - Иванов) и похожие по звучанию (Шварц - Szwarc).
+func (h *PeopleHandler) SearchPeople(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if strings.TrimSpace(q) == "" {
+		http.Error(w, "Параметр q обязателен", http.StatusBadRequest)
+		return
+	}
+	limit := store.DefaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			http.Error(w, "Параметр limit должен быть положительным числом", http.StatusBadRequest)
+			return
+		}
+		limit = min(v, maxSearchLimit)
+	}
+
+	people, err := h.Store.SearchPeople(r.Context(), getTreeID(r), q, limit)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(people)
+	json.NewEncoder(w).Encode(withPeopleDates(people))
 }
 
 // UpdatePerson
-func UpdatePerson(w http.ResponseWriter, r *http.Request) {
-	userID := getUserID(r)
-	idStr := chi.URLParam(r, "id")
-	
+func (h *PeopleHandler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
 	var p models.Person
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
 		http.Error(w, "Ошибка данных", http.StatusBadRequest)
 		return
 	}
+	p.ID = id
 
-	query := `UPDATE people SET first_name=?, middle_name=?, last_name=?, birth_date=?, death_date=?, gender=?, photo_url=? WHERE id=? AND user_id=?`
-	
-	_, err := database.DB.Exec(query, p.FirstName, p.MiddleName, p.LastName, p.BirthDate, p.DeathDate, p.Gender, p.PhotoURL, idStr, userID)
+	people, err := h.Store.ListPeople(r.Context(), treeID)
 	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	i := slices.IndexFunc(people, func(stored models.Person) bool { return stored.ID == id })
+	if i < 0 {
+		http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+		return
+	}
+	if err := validatePersonDates(p, &people[i]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if h.Guard != nil {
+		issues, err := h.Guard.Check(r.Context(), treeID, func(people []models.Person, rels []models.Relationship) ([]models.Person, []models.Relationship) {
+			for i := range people {
+				if people[i].ID == p.ID {
+					people[i] = p
+				}
+			}
+			return people, rels
+		})
+		if err != nil {
+			http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(issues) > 0 {
+			writeConsistencyError(w, issues)
+			return
+		}
+	}
+
+	if err := h.Store.UpdatePerson(r.Context(), treeID, p); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, store.ErrInvalidPlace) {
+			http.Error(w, "Место рождения или смерти не найдено в дереве", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, store.ErrInvalidMedia) {
+			http.Error(w, "Файл портрета не найден в дереве", http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Ошибка обновления: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -110,9 +324,9 @@ func UpdatePerson(w http.ResponseWriter, r *http.Request) {
 }
 
 // SaveNodePosition - сохраняет координаты перетащенной карточки
-func SaveNodePosition(w http.ResponseWriter, r *http.Request) {
-	userID := getUserID(r)
-	
+func (h *PeopleHandler) SaveNodePosition(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+
 	var pos PositionUpdate
 	if err := json.NewDecoder(r.Body).Decode(&pos); err != nil {
 		http.Error(w, "Неверный формат", http.StatusBadRequest)
@@ -120,9 +334,7 @@ func SaveNodePosition(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Обновляем только координаты X и Y
-	query := `UPDATE people SET position_x=?, position_y=? WHERE id=? AND user_id=?`
-	_, err := database.DB.Exec(query, pos.X, pos.Y, pos.ID, userID)
-	if err != nil {
+	if err := h.Store.UpdatePersonPosition(r.Context(), treeID, pos.ID, pos.X, pos.Y); err != nil {
 		http.Error(w, "Ошибка сохранения позиции: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -130,25 +342,32 @@ func SaveNodePosition(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// DeletePerson
-func DeletePerson(w http.ResponseWriter, r *http.Request) {
-	userID := getUserID(r)
-	idStr := chi.URLParam(r, "id")
-	
-	_, _ = database.DB.Exec("DELETE FROM relationships WHERE (from_person_id=? OR to_person_id=?) AND user_id=?", idStr, idStr, userID)
-	
-	result, err := database.DB.Exec("DELETE FROM people WHERE id=? AND user_id=?", idStr, userID)
+// deleteResponse - ответ на удаление: что именно перенесено в корзину
+type deleteResponse struct {
+	Status  string          `json:"status"`
+	Removed *models.Removed `json:"removed"`
+}
+
+// DeletePerson - переносит человека в корзину вместе с его связями
+func (h *PeopleHandler) DeletePerson(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	id, err := urlID(r, "id")
 	if err != nil {
-		http.Error(w, "Ошибка удаления: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
 		return
 	}
-	
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+
+	removed, err := h.Store.DeletePerson(r.Context(), treeID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка удаления: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(deleteResponse{Status: "deleted", Removed: removed})
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/geonames"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxGeocodeResults - больше записей справочника за один запрос не отдаём
+const maxGeocodeResults = 100
+
+// PlacesHandler - справочник мест дерева и поиск по офлайн-справочнику GeoNames
+type PlacesHandler struct {
+	Store     store.PlaceStore
+	Gazetteer store.GazetteerStore
+}
+
+// NewPlacesHandler создаёт обработчики мест
+func NewPlacesHandler(places store.PlaceStore, gazetteer store.GazetteerStore) *PlacesHandler {
+	return &PlacesHandler{Store: places, Gazetteer: gazetteer}
+}
+
+// List - GET /api/places?q=...: места дерева по полному названию.
+// q - только места, в названиях которых (любых, в любой транслитерации) есть эти слова.
+func (h *PlacesHandler) List(w http.ResponseWriter, r *http.Request) {
+	places, err := h.Store.ListPlaces(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if terms := genealogy.ParseSearchQuery(r.URL.Query().Get("q")); len(terms) > 0 {
+		found := []models.Place{}
+		for _, p := range places {
+			names := []string{p.Name}
+			for _, n := range p.Names {
+				names = append(names, n.Name)
+			}
+			if genealogy.MatchPlaceNames(names, terms) {
+				found = append(found, p)
+			}
+		}
+		places = found
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(places)
+}
+
+// Get - GET /api/places/{id}
+func (h *PlacesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	place, err := h.Store.GetPlace(r.Context(), getTreeID(r), id)
+	if err != nil {
+		writePlaceError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(place)
+}
+
+// Create - POST /api/places: {name, type, parent_id, latitude, longitude, names, geonames_id}.
+// С geonames_id пустые название, тип, координаты и другие названия берутся из
+// справочника GeoNames. Если такое место уже заведено (то же название в том же
+// вышестоящем месте), новое не создаётся: возвращается существующее со статусом 200.
+func (h *PlacesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+
+	var p models.Place
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if p.GeoNamesID != nil {
+		entry, err := h.Gazetteer.GetGazetteerEntry(r.Context(), *p.GeoNamesID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				http.Error(w, "Запись GeoNames не найдена: загрузите справочник командой geonames", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fillFromGazetteer(&p, *entry)
+	}
+	if err := validatePlace(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	places, err := h.Store.ListPlaces(r.Context(), treeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, existing := range places {
+		if genealogy.SamePlace(existing, p) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(existing)
+			return
+		}
+	}
+
+	if err := h.Store.CreatePlace(r.Context(), treeID, &p); err != nil {
+		writePlaceError(w, err, "Ошибка записи в БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Update - PUT /api/places/{id}: меняет место целиком, вместе с другими названиями
+func (h *PlacesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	var p models.Place
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Ошибка данных", http.StatusBadRequest)
+		return
+	}
+	if err := validatePlace(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.ID = id
+
+	if err := h.Store.UpdatePlace(r.Context(), getTreeID(r), p); err != nil {
+		writePlaceError(w, err, "Ошибка обновления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// Delete - DELETE /api/places/{id}. Место, на которое кто-то ссылается, не удаляется.
+func (h *PlacesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.DeletePlace(r.Context(), getTreeID(r), id); err != nil {
+		writePlaceError(w, err, "Ошибка удаления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// Duplicates - GET /api/places/duplicates: пары мест, которые, возможно, одно и то же
+func (h *PlacesHandler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	places, err := h.Store.ListPlaces(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genealogy.FindPlaceDuplicates(places))
+}
+
+// Merge - POST /api/places/merge {"keep_id", "merge_id"}: люди, события
+// и вложенные места merge_id переходят к keep_id, его названия становятся
+// другими названиями keep_id, а само место удаляется
+func (h *PlacesHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		KeepID  int `json:"keep_id"`
+		MergeID int `json:"merge_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Ошибка данных", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.Store.MergePlaces(r.Context(), getTreeID(r), req.KeepID, req.MergeID)
+	if err != nil {
+		writePlaceError(w, err, "Ошибка слияния: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Geocode - GET /api/places/geocode?q=Тверь&country=RU&limit=10: поиск по
+// офлайн-справочнику GeoNames, крупные места первыми. Найденное можно
+// завести в дерево: POST /api/places с {"geonames_id": ...}.
+func (h *PlacesHandler) Geocode(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 10
+	if raw := q.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxGeocodeResults {
+			http.Error(w, "limit должен быть числом от 1 до "+strconv.Itoa(maxGeocodeResults), http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+
+	entries, err := h.Gazetteer.SearchGazetteer(r.Context(), q.Get("q"), strings.TrimSpace(q.Get("country")), limit)
+	if err != nil {
+		http.Error(w, "Ошибка поиска: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// fillFromGazetteer заполняет пустые поля места из записи GeoNames. Из других
+// названий берутся только кириллические и латинская запись: остальных в выгрузке
+// бывают сотни. Язык названий выгрузка не указывает.
+func fillFromGazetteer(p *models.Place, e models.GazetteerEntry) {
+	if strings.TrimSpace(p.Name) == "" {
+		p.Name = e.Name
+	}
+	if p.Type == "" {
+		p.Type = geonames.PlaceType(e)
+	}
+	if p.Latitude == nil || p.Longitude == nil {
+		lat, lon := e.Latitude, e.Longitude
+		p.Latitude, p.Longitude = &lat, &lon
+	}
+	if len(p.Names) > 0 {
+		return
+	}
+	for _, name := range append([]string{e.Name}, e.AlternateNames...) {
+		if name != p.Name && isCyrillic(name) {
+			p.Names = append(p.Names, models.PlaceName{Name: name})
+		}
+	}
+	if e.ASCIIName != p.Name {
+		p.Names = append(p.Names, models.PlaceName{Name: e.ASCIIName})
+	}
+}
+
+func isCyrillic(s string) bool {
+	for _, r := range s {
+		if r >= 'А' && r <= 'я' || r == 'ё' || r == 'Ё' {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePlace проверяет название, тип и координаты места
+func validatePlace(p *models.Place) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("Название места не может быть пустым")
+	}
+	if p.Type != "" && !slices.Contains(models.PlaceTypes, p.Type) {
+		return errors.New("Неизвестный тип места, допустимы: " + strings.Join(models.PlaceTypes, ", "))
+	}
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return errors.New("Координаты задаются парой: latitude и longitude")
+	}
+	if p.Latitude != nil && (*p.Latitude < -90 || *p.Latitude > 90 || *p.Longitude < -180 || *p.Longitude > 180) {
+		return errors.New("Широта должна быть от -90 до 90, долгота - от -180 до 180")
+	}
+	return nil
+}
+
+func writePlaceError(w http.ResponseWriter, err error, prefix string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Место не найдено", http.StatusNotFound)
+	case errors.Is(err, store.ErrInvalidPlace):
+		http.Error(w, "Вышестоящее место не найдено в дереве", http.StatusBadRequest)
+	case errors.Is(err, store.ErrPlaceCycle):
+		http.Error(w, "Место не может входить само в себя или во вложенное в него место", http.StatusUnprocessableEntity)
+	case errors.Is(err, store.ErrPlaceInUse):
+		http.Error(w, "На место ссылаются люди, события или вложенные места: слейте его с другим (POST /api/places/merge)", http.StatusConflict)
+	default:
+		http.Error(w, prefix+err.Error(), http.StatusInternalServerError)
+	}
+}
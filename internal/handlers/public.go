@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/auth"
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PublicHandler - публичные ссылки на дерево только для чтения
+type PublicHandler struct {
+	Links         store.ShareLinkStore
+	Trees         store.TreeStore
+	People        store.PeopleStore
+	Relationships store.RelationshipStore
+	Media         *MediaHandler // портреты людей; nil - без портретов
+}
+
+// NewPublicHandler создаёт обработчики публичных ссылок
+func NewPublicHandler(links store.ShareLinkStore, trees store.TreeStore, people store.PeopleStore, relationships store.RelationshipStore) *PublicHandler {
+	return &PublicHandler{Links: links, Trees: trees, People: people, Relationships: relationships}
+}
+
+type shareLinkRequest struct {
+	Label         string `json:"label"`
+	ExpiresInDays int    `json:"expires_in_days"` // 0 - бессрочно
+}
+
+// publicTree - ответ по публичной ссылке
+type publicTree struct {
+	Name          string                `json:"name"`
+	People        []personResponse      `json:"people"`
+	Relationships []models.Relationship `json:"relationships"`
+}
+
+// ListShareLinks - GET /api/trees/{treeID}/share-links
+// Токен есть у каждой неотозванной ссылки: он подписан, а не хранится, и
+// восстанавливается заново, поэтому ссылку можно скопировать ещё раз.
+func (h *PublicHandler) ListShareLinks(w http.ResponseWriter, r *http.Request) {
+	links, err := h.Links.ListShareLinks(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for i := range links {
+		if links[i].RevokedAt != nil {
+			continue
+		}
+		if err := signShareLink(&links[i]); err != nil {
+			http.Error(w, "Ошибка подписи ссылки: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// CreateShareLink - POST /api/trees/{treeID}/share-links {"label": "...", "expires_in_days": 30}
+func (h *PublicHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	var req shareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		http.Error(w, "Срок действия не может быть отрицательным", http.StatusUnprocessableEntity)
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresInDays > 0 {
+		// Доли секунды отбрасываем заранее: база хранит время с точностью до секунды,
+		// и токен, собранный из записи заново, совпадёт с выданным
+		expiresAt = time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour).UTC().Truncate(time.Second)
+	}
+
+	link := models.ShareLink{
+		TreeID:    getTreeID(r),
+		Label:     strings.TrimSpace(req.Label),
+		CreatedBy: getUserID(r),
+	}
+	if err := h.Links.CreateShareLink(r.Context(), &link, expiresAt); err != nil {
+		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := signShareLink(&link); err != nil {
+		http.Error(w, "Ошибка подписи ссылки: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+// RevokeShareLink - DELETE /api/trees/{treeID}/share-links/{linkID}
+func (h *PublicHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	linkID, err := urlID(r, "linkID")
+	if err != nil {
+		http.Error(w, "Неверный ID ссылки", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Links.RevokeShareLink(r.Context(), getTreeID(r), linkID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Ссылка не найдена", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка обновления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "revoked"})
+}
+
+// Tree - GET /api/public/{token}/tree, без авторизации.
+// Живые люди заменяются заглушкой (genealogy.RedactLiving).
+// Просроченная, отозванная и поддельная ссылки неразличимы: всегда 404.
+func (h *PublicHandler) Tree(w http.ResponseWriter, r *http.Request) {
+	link, ok := h.shareLink(w, r)
+	if !ok {
+		return
+	}
+
+	tree, err := h.Trees.GetTree(r.Context(), link.TreeID)
+	if err != nil {
+		http.Error(w, "Ссылка недействительна", http.StatusNotFound)
+		return
+	}
+	people, relationships, err := h.redactedTree(r, link.TreeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Файлы дерева закрыты для гостя: портрет отдаётся по адресу ссылки
+	for i, p := range people {
+		if p.PhotoMediaID != nil {
+			people[i].PhotoMediaID = nil
+			people[i].PhotoURL = publicPhotoURL(chi.URLParam(r, "token"), p.ID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(publicTree{Name: tree.Name, People: withPeopleDates(people), Relationships: relationships})
+}
+
+// Photo - GET /api/public/{token}/people/{id}/photo?size=256, без авторизации:
+// портрет человека (photo_media_id). С size - миниатюра, как у
+// /api/media/{id}/thumbnail, без него - исходный файл. Живых людей ссылка
+// не показывает, поэтому и их портретов нет: 404.
+func (h *PublicHandler) Photo(w http.ResponseWriter, r *http.Request) {
+	link, ok := h.shareLink(w, r)
+	if !ok {
+		return
+	}
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	people, _, err := h.redactedTree(r, link.TreeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var mediaID *int
+	for _, p := range people {
+		if p.ID == id {
+			mediaID = p.PhotoMediaID
+		}
+	}
+	if mediaID == nil || h.Media == nil {
+		http.Error(w, "Портрет не найден", http.StatusNotFound)
+		return
+	}
+	m, err := h.Media.Store.GetMedia(r.Context(), link.TreeID, *mediaID)
+	if err != nil {
+		writeMediaError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	// Портрет человека может смениться, а ссылку могут отозвать - не кешируем без проверки
+	if r.URL.Query().Has("size") {
+		size, ok := thumbnailSize(w, r)
+		if !ok {
+			return
+		}
+		h.Media.serveThumbnail(w, r, m, size, "no-cache")
+		return
+	}
+	h.Media.serveFile(w, r, m, "no-cache")
+}
+
+// publicPhotoURL - адрес портрета человека по публичной ссылке (см. Photo)
+func publicPhotoURL(token string, personID int) string {
+	return "/api/public/" + url.PathEscape(token) + "/people/" + strconv.Itoa(personID) + "/photo"
+}
+
+// shareLink проверяет токен из адреса. Просроченная, отозванная и поддельная
+// ссылки неразличимы: 404. false - ответ с ошибкой уже отправлен.
+func (h *PublicHandler) shareLink(w http.ResponseWriter, r *http.Request) (*models.ShareLink, bool) {
+	claims, err := auth.ParseShareToken(chi.URLParam(r, "token"))
+	if err != nil {
+		http.Error(w, "Ссылка недействительна", http.StatusNotFound)
+		return nil, false
+	}
+
+	link, err := h.Links.GetShareLink(r.Context(), claims.LinkID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if link == nil || link.RevokedAt != nil || link.TreeID != claims.TreeID {
+		http.Error(w, "Ссылка недействительна", http.StatusNotFound)
+		return nil, false
+	}
+	return link, true
+}
+
+// redactedTree - люди и связи дерева, какими их видит гость (genealogy.RedactLiving)
+func (h *PublicHandler) redactedTree(r *http.Request, treeID int) ([]models.Person, []models.Relationship, error) {
+	people, relationships, err := loadTree(r.Context(), h.People, h.Relationships, treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	people, relationships = genealogy.RedactLiving(people, relationships, time.Now())
+	return people, relationships, nil
+}
+
+// signShareLink заполняет link.Token подписанным токеном ссылки
+func signShareLink(link *models.ShareLink) error {
+	var expiresAt time.Time
+	if link.ExpiresAt != nil {
+		t, err := parseStoredTime(*link.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		expiresAt = t
+	}
+
+	token, err := auth.GenerateShareToken(link.ID, link.TreeID, expiresAt)
+	if err != nil {
+		return err
+	}
+	link.Token = token
+	return nil
+}
+
+// parseStoredTime разбирает время из базы: драйвер отдаёт RFC 3339,
+// а в тексте SQLite оно хранится как "YYYY-MM-DD HH:MM:SS"
+func parseStoredTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02 15:04:05", s)
+}
@@ -2,18 +2,27 @@ package handlers
 
 import (
 	"encoding/json"
-	"family-tree-app/internal/auth" // Добавлен импорт auth
-	"family-tree-app/internal/database"
+	"errors"
 	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
 	"net/http"
-
-	"github.com/go-chi/chi/v5"
+	"strings"
 )
 
+// RelationshipHandler - CRUD для связей текущего дерева
+type RelationshipHandler struct {
+	Store store.RelationshipStore
+	Guard *ConsistencyGuard // nil - проверки согласованности выключены
+}
+
+// NewRelationshipHandler создаёт обработчики для связей
+func NewRelationshipHandler(s store.RelationshipStore) *RelationshipHandler {
+	return &RelationshipHandler{Store: s}
+}
+
 // CreateRelationship
-func CreateRelationship(w http.ResponseWriter, r *http.Request) {
-	// Достаем userID из контекста (так же, как в people.go)
-	userID := r.Context().Value(auth.UserIDKey).(int)
+func (h *RelationshipHandler) CreateRelationship(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
 
 	var rel models.Relationship
 	err := json.NewDecoder(r.Body).Decode(&rel)
@@ -26,53 +35,62 @@ func CreateRelationship(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Человек не может быть связан сам с собой", http.StatusBadRequest)
 		return
 	}
+	if strings.TrimSpace(rel.Type) == "" {
+		http.Error(w, "Не указан тип связи", http.StatusUnprocessableEntity)
+		return
+	}
 
-	// Добавили user_id
-	query := `INSERT INTO relationships (user_id, from_person_id, to_person_id, type, description) VALUES (?, ?, ?, ?, ?)`
-	result, err := database.DB.Exec(query, userID, rel.FromPersonID, rel.ToPersonID, rel.Type, rel.Description)
-	if err != nil {
+	if h.Guard != nil {
+		issues, err := h.Guard.Check(r.Context(), treeID, func(people []models.Person, rels []models.Relationship) ([]models.Person, []models.Relationship) {
+			return people, append(rels, rel)
+		})
+		if err != nil {
+			http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(issues) > 0 {
+			writeConsistencyError(w, issues)
+			return
+		}
+	}
+
+	if err := h.Store.CreateRelationship(r.Context(), treeID, &rel); err != nil {
+		// Чужой и несуществующий человек неразличимы: не раскрываем чужие ID
+		if errors.Is(err, store.ErrInvalidReference) {
+			http.Error(w, "Человек не найден в вашем дереве", http.StatusUnprocessableEntity)
+			return
+		}
 		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	id, _ := result.LastInsertId()
-	rel.ID = int(id)
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(rel)
 }
 
 // GetAllRelationships
-func GetAllRelationships(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(auth.UserIDKey).(int)
+func (h *RelationshipHandler) GetAllRelationships(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
 
-	// Фильтр WHERE user_id = ?
-	rows, err := database.DB.Query("SELECT id, from_person_id, to_person_id, type, description FROM relationships WHERE user_id = ?", userID)
+	relationships, err := h.Store.ListRelationships(r.Context(), treeID)
 	if err != nil {
 		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	relationships := []models.Relationship{}
-
-	for rows.Next() {
-		var rel models.Relationship
-		if err := rows.Scan(&rel.ID, &rel.FromPersonID, &rel.ToPersonID, &rel.Type, &rel.Description); err != nil {
-			continue
-		}
-		relationships = append(relationships, rel)
-	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(relationships)
 }
 
 // UpdateRelationship — обновляет описание связи
-func UpdateRelationship(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(auth.UserIDKey).(int)
-	idStr := chi.URLParam(r, "id")
+func (h *RelationshipHandler) UpdateRelationship(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
 
 	var rel models.Relationship
 	if err := json.NewDecoder(r.Body).Decode(&rel); err != nil {
@@ -80,43 +98,40 @@ func UpdateRelationship(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := database.DB.Exec(
-		"UPDATE relationships SET description=? WHERE id=? AND user_id=?",
-		rel.Description, idStr, userID,
-	)
-	if err != nil {
+	if err := h.Store.UpdateRelationshipDescription(r.Context(), treeID, id, rel.Description); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Связь не найдена или нет прав", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Ошибка обновления: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Связь не найдена или нет прав", http.StatusNotFound)
-		return
-	}
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 }
 
 // DeleteRelationship
-func DeleteRelationship(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(auth.UserIDKey).(int)
-	idStr := chi.URLParam(r, "id")
-
-	// Удаляем только если принадлежит юзеру
-	result, err := database.DB.Exec("DELETE FROM relationships WHERE id=? AND user_id=?", idStr, userID)
+func (h *RelationshipHandler) DeleteRelationship(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	id, err := urlID(r, "id")
 	if err != nil {
-		http.Error(w, "Ошибка удаления связи: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Связь не найдена или нет прав", http.StatusNotFound)
+	// Удаляем только если принадлежит юзеру
+	removed, err := h.Store.DeleteRelationship(r.Context(), treeID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Связь не найдена или нет прав", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка удаления связи: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(deleteResponse{Status: "deleted", Removed: removed})
+}
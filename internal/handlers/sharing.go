@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultInviteDays - срок действия приглашения, если он не указан
+	defaultInviteDays = 7
+	// maxInviteDays - самый долгий допустимый срок приглашения
+	maxInviteDays = 90
+)
+
+// SharingHandler - участники деревьев и приглашения в них
+type SharingHandler struct {
+	Members store.MembershipStore
+	Users   store.UserStore
+}
+
+// NewSharingHandler создаёт обработчики совместного доступа
+func NewSharingHandler(members store.MembershipStore, users store.UserStore) *SharingHandler {
+	return &SharingHandler{Members: members, Users: users}
+}
+
+type memberRequest struct {
+	Role string `json:"role"`
+}
+
+type inviteRequest struct {
+	Email         string `json:"email"`           // пусто - приглашение по ссылке с токеном
+	Role          string `json:"role"`            // editor или viewer
+	ExpiresInDays int    `json:"expires_in_days"` // 0 - срок по умолчанию
+}
+
+type acceptRequest struct {
+	Token string `json:"token"`
+}
+
+// ListMembers - GET /api/trees/{treeID}/members
+func (h *SharingHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := h.Members.ListMembers(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// UpdateMember - PUT /api/trees/{treeID}/members/{userID} {"role": "editor"|"viewer"}
+func (h *SharingHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	userID, err := urlID(r, "userID")
+	if err != nil {
+		http.Error(w, "Неверный ID пользователя", http.StatusBadRequest)
+		return
+	}
+
+	var req memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if !validInviteRole(req.Role) {
+		http.Error(w, "Роль может быть только editor или viewer", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !h.checkNotOwner(w, r, treeID, userID, "Роль владельца нельзя изменить") {
+		return
+	}
+	if err := h.Members.SetMemberRole(r.Context(), treeID, userID, req.Role); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Участник не найден", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка обновления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// RemoveMember - DELETE /api/trees/{treeID}/members/{userID}
+// Владелец может убрать любого участника, остальные - только себя (выйти из дерева).
+func (h *SharingHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	userID, err := urlID(r, "userID")
+	if err != nil {
+		http.Error(w, "Неверный ID пользователя", http.StatusBadRequest)
+		return
+	}
+
+	if userID != getUserID(r) && getTreeRole(r) != models.RoleOwner {
+		http.Error(w, "Недостаточно прав: нужна роль "+models.RoleOwner, http.StatusForbidden)
+		return
+	}
+	if !h.checkNotOwner(w, r, treeID, userID, "Владельца нельзя убрать из дерева") {
+		return
+	}
+	if err := h.Members.RemoveMember(r.Context(), treeID, userID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Участник не найден", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка удаления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// checkNotOwner отвечает 422, если userID - владелец дерева
+func (h *SharingHandler) checkNotOwner(w http.ResponseWriter, r *http.Request, treeID, userID int, message string) bool {
+	role, err := h.Members.GetMemberRole(r.Context(), treeID, userID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if role == models.RoleOwner {
+		http.Error(w, message, http.StatusUnprocessableEntity)
+		return false
+	}
+	return true
+}
+
+// ListInvites - GET /api/trees/{treeID}/invites: неиспользованные приглашения
+func (h *SharingHandler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.Members.ListInvites(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invites)
+}
+
+// CreateInvite - POST /api/trees/{treeID}/invites {"email": "...", "role": "viewer", "expires_in_days": 7}
+// Токен возвращается только в этом ответе. Приглашение с email принимается
+// тем же токеном, но только пользователем с этим адресом.
+func (h *SharingHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	var req inviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && !strings.Contains(req.Email, "@") {
+		http.Error(w, "Неверный email", http.StatusUnprocessableEntity)
+		return
+	}
+	if !validInviteRole(req.Role) {
+		http.Error(w, "Роль может быть только editor или viewer", http.StatusUnprocessableEntity)
+		return
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxInviteDays {
+		http.Error(w, "Срок приглашения - от 1 до 90 дней", http.StatusUnprocessableEntity)
+		return
+	}
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = defaultInviteDays
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		http.Error(w, "Ошибка генерации токена", http.StatusInternalServerError)
+		return
+	}
+
+	inv := models.TreeInvite{
+		TreeID:    getTreeID(r),
+		Email:     req.Email,
+		Role:      req.Role,
+		Token:     token,
+		CreatedBy: getUserID(r),
+	}
+	expiresAt := time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+	if err := h.Members.CreateInvite(r.Context(), &inv, hashInviteToken(token), expiresAt); err != nil {
+		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(inv)
+}
+
+// DeleteInvite - DELETE /api/trees/{treeID}/invites/{inviteID}: отзыв приглашения
+func (h *SharingHandler) DeleteInvite(w http.ResponseWriter, r *http.Request) {
+	inviteID, err := urlID(r, "inviteID")
+	if err != nil {
+		http.Error(w, "Неверный ID приглашения", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Members.DeleteInvite(r.Context(), getTreeID(r), inviteID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Приглашение не найдено", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка удаления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// AcceptInvite - POST /api/invites/accept {"token": "..."}
+func (h *SharingHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	var req acceptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Не передан токен приглашения", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Users.GetUserByID(r.Context(), getUserID(r))
+	if err != nil {
+		http.Error(w, "Пользователь не найден", http.StatusNotFound)
+		return
+	}
+
+	member, err := h.Members.AcceptInvite(r.Context(), hashInviteToken(req.Token), user.ID, user.Email)
+	if err != nil {
+		if errors.Is(err, store.ErrInviteInvalid) {
+			http.Error(w, "Приглашение недействительно, уже использовано или просрочено", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+func validInviteRole(role string) bool {
+	return role == models.RoleEditor || role == models.RoleViewer
+}
+
+// newInviteToken - 256 случайных бит в base64url
+func newInviteToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashInviteToken - в базе хранится только SHA-256 токена
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SourcesHandler - источники дерева (/sources) и ссылки на них у людей,
+// связей и событий (/people/{id}/citations, /relationships/{id}/citations,
+// /events/{id}/citations)
+type SourcesHandler struct {
+	Store store.SourceStore
+}
+
+// NewSourcesHandler создаёт обработчики источников
+func NewSourcesHandler(s store.SourceStore) *SourcesHandler {
+	return &SourcesHandler{Store: s}
+}
+
+// List - GET /api/sources: источники по названию
+func (h *SourcesHandler) List(w http.ResponseWriter, r *http.Request) {
+	sources, err := h.Store.ListSources(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sources)
+}
+
+// Get - GET /api/sources/{id}
+func (h *SourcesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	src, err := h.Store.GetSource(r.Context(), getTreeID(r), id)
+	if err != nil {
+		writeSourceError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(src)
+}
+
+// Create - POST /api/sources: {type, title, author, publication, archive, fond, opis, delo, url, notes}
+func (h *SourcesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var src models.Source
+	if err := json.NewDecoder(r.Body).Decode(&src); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return
+	}
+	if err := validateSource(&src); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.CreateSource(r.Context(), getTreeID(r), &src); err != nil {
+		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(src)
+}
+
+// Update - PUT /api/sources/{id}: меняет источник целиком
+func (h *SourcesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	var src models.Source
+	if err := json.NewDecoder(r.Body).Decode(&src); err != nil {
+		http.Error(w, "Ошибка данных", http.StatusBadRequest)
+		return
+	}
+	if err := validateSource(&src); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	src.ID = id
+
+	if err := h.Store.UpdateSource(r.Context(), getTreeID(r), src); err != nil {
+		writeSourceError(w, err, "Ошибка обновления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// Delete - DELETE /api/sources/{id}. Источник, на который есть ссылки, не удаляется.
+func (h *SourcesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.DeleteSource(r.Context(), getTreeID(r), id); err != nil {
+		writeSourceError(w, err, "Ошибка удаления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// SourceCitations - GET /api/sources/{id}/citations: что подтверждает источник
+func (h *SourcesHandler) SourceCitations(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	citations, err := h.Store.ListSourceCitations(r.Context(), getTreeID(r), id)
+	if err != nil {
+		writeSourceError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(citations)
+}
+
+// Маршруты ссылок для человека, связи и события отличаются только владельцем
+func (h *SourcesHandler) ListPersonCitations(w http.ResponseWriter, r *http.Request) {
+	h.listCitations(w, r, personCitationOwner)
+}
+func (h *SourcesHandler) CreatePersonCitation(w http.ResponseWriter, r *http.Request) {
+	h.createCitation(w, r, personCitationOwner)
+}
+func (h *SourcesHandler) ListRelationshipCitations(w http.ResponseWriter, r *http.Request) {
+	h.listCitations(w, r, relationshipCitationOwner)
+}
+func (h *SourcesHandler) CreateRelationshipCitation(w http.ResponseWriter, r *http.Request) {
+	h.createCitation(w, r, relationshipCitationOwner)
+}
+func (h *SourcesHandler) ListEventCitations(w http.ResponseWriter, r *http.Request) {
+	h.listCitations(w, r, eventCitationOwner)
+}
+func (h *SourcesHandler) CreateEventCitation(w http.ResponseWriter, r *http.Request) {
+	h.createCitation(w, r, eventCitationOwner)
+}
+
+// citationOwnerFunc превращает {id} из пути в то, что подтверждает ссылка
+type citationOwnerFunc func(id int) store.CitationOwner
+
+func personCitationOwner(id int) store.CitationOwner {
+	return store.CitationOwner{PersonID: id}
+}
+func relationshipCitationOwner(id int) store.CitationOwner {
+	return store.CitationOwner{RelationshipID: id}
+}
+func eventCitationOwner(id int) store.CitationOwner {
+	return store.CitationOwner{EventID: id}
+}
+
+// listCitations - GET .../citations. У человека - вместе со ссылками его
+// событий и связей, у связи - вместе со ссылками её событий: всё, чем
+// подтверждены сведения о нём.
+func (h *SourcesHandler) listCitations(w http.ResponseWriter, r *http.Request, ownerOf citationOwnerFunc) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	citations, err := h.Store.ListCitations(r.Context(), getTreeID(r), ownerOf(id))
+	if err != nil {
+		writeCitationError(w, err, "Ошибка чтения БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(citations)
+}
+
+// createCitation - POST .../citations: {source_id, page, text, confidence, notes}
+func (h *SourcesHandler) createCitation(w http.ResponseWriter, r *http.Request, ownerOf citationOwnerFunc) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	c, ok := decodeCitation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Store.CreateCitation(r.Context(), getTreeID(r), ownerOf(id), &c); err != nil {
+		writeCitationError(w, err, "Ошибка записи в БД: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// UpdateCitation - PUT /api/citations/{id}: меняет ссылку целиком, кроме того, что она подтверждает
+func (h *SourcesHandler) UpdateCitation(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	c, ok := decodeCitation(w, r)
+	if !ok {
+		return
+	}
+	c.ID = id
+
+	if err := h.Store.UpdateCitation(r.Context(), getTreeID(r), c); err != nil {
+		writeCitationError(w, err, "Ошибка обновления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// DeleteCitation - DELETE /api/citations/{id}
+func (h *SourcesHandler) DeleteCitation(w http.ResponseWriter, r *http.Request) {
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.DeleteCitation(r.Context(), getTreeID(r), id); err != nil {
+		writeCitationError(w, err, "Ошибка удаления: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// validateSource проверяет название и тип источника
+func validateSource(src *models.Source) error {
+	src.Title = strings.TrimSpace(src.Title)
+	if src.Title == "" {
+		return errors.New("Название источника не может быть пустым")
+	}
+	if src.Type != "" && !slices.Contains(models.SourceTypes, src.Type) {
+		return errors.New("Неизвестный тип источника, допустимы: " + strings.Join(models.SourceTypes, ", "))
+	}
+	src.Archive, src.Fond, src.Opis, src.Delo = strings.TrimSpace(src.Archive), strings.TrimSpace(src.Fond), strings.TrimSpace(src.Opis), strings.TrimSpace(src.Delo)
+	src.URL = strings.TrimSpace(src.URL)
+	return nil
+}
+
+// decodeCitation читает ссылку из тела и проверяет источник и оценку
+func decodeCitation(w http.ResponseWriter, r *http.Request) (models.Citation, bool) {
+	var c models.Citation
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return c, false
+	}
+	if c.SourceID == 0 {
+		http.Error(w, "Не указан источник (source_id)", http.StatusBadRequest)
+		return c, false
+	}
+	if c.Confidence != "" && !slices.Contains(models.Confidences, c.Confidence) {
+		http.Error(w, "Неизвестная оценка, допустимы: "+strings.Join(models.Confidences, ", "), http.StatusBadRequest)
+		return c, false
+	}
+	c.Page = strings.TrimSpace(c.Page)
+	return c, true
+}
+
+func writeSourceError(w http.ResponseWriter, err error, prefix string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Источник не найден", http.StatusNotFound)
+	case errors.Is(err, store.ErrSourceInUse):
+		http.Error(w, "На источник ссылаются сведения дерева: сначала удалите ссылки", http.StatusConflict)
+	default:
+		http.Error(w, prefix+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeCitationError(w http.ResponseWriter, err error, prefix string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Человек, связь, событие или ссылка не найдены", http.StatusNotFound)
+	case errors.Is(err, store.ErrInvalidSource):
+		http.Error(w, "Источник не найден в дереве", http.StatusBadRequest)
+	default:
+		http.Error(w, prefix+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TrashHandler - корзина удалённых людей и связей
+type TrashHandler struct {
+	Trash store.TrashStore
+}
+
+// NewTrashHandler создаёт обработчики корзины
+func NewTrashHandler(s store.TrashStore) *TrashHandler {
+	return &TrashHandler{Trash: s}
+}
+
+// trashEntities - сегмент пути {entity} и тип записи
+var trashEntities = map[string]string{
+	"people":        models.EntityPerson,
+	"relationships": models.EntityRelationship,
+}
+
+// List - GET /api/trash: удалённые люди и связи, недавние первыми
+func (h *TrashHandler) List(w http.ResponseWriter, r *http.Request) {
+	trash, err := h.Trash.ListTrash(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trash)
+}
+
+// Restore - POST /api/trash/{entity}/{id}/restore, entity - people или relationships.
+// Человек возвращается вместе со связями, удалёнными вместе с ним.
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	entityType, id, ok := trashItem(w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := h.Trash.RestoreFromTrash(r.Context(), getTreeID(r), entityType, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, "Запись не найдена в корзине", http.StatusNotFound)
+		case errors.Is(err, store.ErrRevertConflict):
+			http.Error(w, "Связь ведёт к человеку в корзине: сначала восстановите его", http.StatusConflict)
+		default:
+			http.Error(w, "Ошибка восстановления: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// Purge - DELETE /api/trash/{entity}/{id}: стереть запись окончательно
+func (h *TrashHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	entityType, id, ok := trashItem(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.Trash.PurgeFromTrash(r.Context(), getTreeID(r), entityType, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Запись не найдена в корзине", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка удаления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Empty - DELETE /api/trash: очистить корзину дерева
+func (h *TrashHandler) Empty(w http.ResponseWriter, r *http.Request) {
+	result, err := h.Trash.EmptyTrash(r.Context(), getTreeID(r))
+	if err != nil {
+		http.Error(w, "Ошибка удаления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// trashItem разбирает {entity} и {id} из пути, при ошибке отвечает сам
+func trashItem(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	entityType, ok := trashEntities[chi.URLParam(r, "entity")]
+	if !ok {
+		http.Error(w, "В корзине есть только people и relationships", http.StatusNotFound)
+		return "", 0, false
+	}
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return "", 0, false
+	}
+	return entityType, id, true
+}
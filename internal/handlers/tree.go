@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// TreeHandler - операции над деревом целиком
+type TreeHandler struct {
+	People        store.PeopleStore
+	Relationships store.RelationshipStore
+	Events        store.EventStore
+	Sources       store.SourceStore
+}
+
+// NewTreeHandler создаёт обработчики для дерева
+func NewTreeHandler(people store.PeopleStore, relationships store.RelationshipStore, events store.EventStore, sources store.SourceStore) *TreeHandler {
+	return &TreeHandler{People: people, Relationships: relationships, Events: events, Sources: sources}
+}
+
+// Lint - GET /api/tree/lint: список генеалогических противоречий в текущем дереве
+// со ссылками на источники сведений о людях и связях из них
+func (h *TreeHandler) Lint(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+
+	people, relationships, err := loadTree(r.Context(), h.People, h.Relationships, treeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	issues := genealogy.LintPeople(people, relationships)
+	errorsCount := 0
+	for _, issue := range issues {
+		if issue.Severity == genealogy.SeverityError {
+			errorsCount++
+		}
+	}
+	if issues == nil {
+		issues = []genealogy.Issue{}
+	}
+
+	var personIDs, relIDs []int
+	for _, issue := range issues {
+		personIDs = append(personIDs, issue.PersonIDs...)
+		relIDs = append(relIDs, issue.RelationshipIDs...)
+	}
+	citations, sources, err := h.citationsReport(r.Context(), treeID, personIDs, relIDs)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors":    errorsCount,
+		"warnings":  len(issues) - errorsCount,
+		"issues":    issues,
+		"citations": citations,
+		"sources":   sources,
+	})
+}
+
+// Kinship - GET /api/people/{a}/kinship/{b}: кем B приходится A, с путём по дереву
+func (h *TreeHandler) Kinship(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+	a, errA := urlID(r, "a")
+	b, errB := urlID(r, "b")
+	if errA != nil || errB != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	people, relationships, err := loadTree(r.Context(), h.People, h.Relationships, treeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	g := genealogy.NewGraph(people, relationships)
+	if _, ok := g.People[a]; !ok {
+		http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+		return
+	}
+	if _, ok := g.People[b]; !ok {
+		http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genealogy.ComputeKinship(g, a, b))
+}
+
+// Глубина обхода по умолчанию и верхний предел для потомков
+const (
+	defaultTraversalDepth = 10
+	maxDescendantDepth    = 100
+)
+
+// Ancestors - GET /api/people/{id}/ancestors?depth=N: предки с номерами Ahnentafel
+func (h *TreeHandler) Ancestors(w http.ResponseWriter, r *http.Request) {
+	h.traverse(w, r, genealogy.MaxAncestorDepth, func(g *genealogy.Graph, root, depth int) (interface{}, []int, []models.Relationship, bool) {
+		entries, edges, truncated := genealogy.Ancestors(g, root, depth)
+		ids := make([]int, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.Person.ID
+		}
+		return entries, ids, edges, truncated
+	})
+}
+
+// Descendants - GET /api/people/{id}/descendants?depth=N: потомки с номерами д'Абовиля
+func (h *TreeHandler) Descendants(w http.ResponseWriter, r *http.Request) {
+	h.traverse(w, r, maxDescendantDepth, func(g *genealogy.Graph, root, depth int) (interface{}, []int, []models.Relationship, bool) {
+		entries, edges, truncated := genealogy.Descendants(g, root, depth)
+		ids := make([]int, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.Person.ID
+		}
+		return entries, ids, edges, truncated
+	})
+}
+
+// traverse отдаёт обход walk вместе со ссылками на источники сведений
+// о попавших в него людях, связях и их событиях
+func (h *TreeHandler) traverse(w http.ResponseWriter, r *http.Request, maxDepth int, walk func(g *genealogy.Graph, root, depth int) (interface{}, []int, []models.Relationship, bool)) {
+	treeID := getTreeID(r)
+	id, err := urlID(r, "id")
+	if err != nil {
+		http.Error(w, "Неверный ID", http.StatusBadRequest)
+		return
+	}
+
+	depth := defaultTraversalDepth
+	if v := r.URL.Query().Get("depth"); v != "" {
+		depth, err = strconv.Atoi(v)
+		if err != nil || depth < 0 || depth > maxDepth {
+			http.Error(w, fmt.Sprintf("depth должен быть числом от 0 до %d", maxDepth), http.StatusBadRequest)
+			return
+		}
+	}
+
+	people, relationships, err := loadTree(r.Context(), h.People, h.Relationships, treeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	g := genealogy.NewGraph(people, relationships)
+	if _, ok := g.People[id]; !ok {
+		http.Error(w, "Человек не найден или нет прав", http.StatusNotFound)
+		return
+	}
+
+	entries, personIDs, edges, truncated := walk(g, id, depth)
+	relIDs := make([]int, len(edges))
+	for i, rel := range edges {
+		relIDs[i] = rel.ID
+	}
+	citations, sources, err := h.citationsReport(r.Context(), treeID, personIDs, relIDs)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"root":          id,
+		"depth":         depth,
+		"people":        entries,
+		"relationships": edges,
+		"truncated":     truncated, // часть номеров отброшена, см. genealogy.MaxNumbersPerPerson
+		"citations":     citations,
+		"sources":       sources,
+	})
+}
+
+// citationsReport - ссылки, которыми подтверждены сведения о людях personIDs,
+// связях relIDs и их событиях, и источники этих ссылок
+func (h *TreeHandler) citationsReport(ctx context.Context, treeID int, personIDs, relIDs []int) ([]models.Citation, []models.Source, error) {
+	citations, sources := []models.Citation{}, []models.Source{}
+	all, err := h.Sources.ListTreeCitations(ctx, treeID)
+	if err != nil || len(all) == 0 {
+		return citations, sources, err
+	}
+
+	people, rels, events := map[int]bool{}, map[int]bool{}, map[int]bool{}
+	for _, id := range personIDs {
+		people[id] = true
+	}
+	for _, id := range relIDs {
+		rels[id] = true
+	}
+	treeEvents, err := h.Events.ListTreeEvents(ctx, treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range treeEvents {
+		if (e.PersonID != nil && people[*e.PersonID]) || (e.RelationshipID != nil && rels[*e.RelationshipID]) {
+			events[e.ID] = true
+		}
+	}
+
+	cited := map[int]bool{}
+	for _, c := range all {
+		if (c.PersonID != nil && people[*c.PersonID]) || (c.RelationshipID != nil && rels[*c.RelationshipID]) || (c.EventID != nil && events[*c.EventID]) {
+			citations = append(citations, c)
+			cited[c.SourceID] = true
+		}
+	}
+	if len(citations) == 0 {
+		return citations, sources, nil
+	}
+	treeSources, err := h.Sources.ListSources(ctx, treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, src := range treeSources {
+		if cited[src.ID] {
+			sources = append(sources, src)
+		}
+	}
+	return citations, sources, nil
+}
+
+// ConsistencyGuard не даёт сохранить изменение, которое добавляет в дерево новые ошибки.
+// Подключается к обработчикам при включённой настройке strict_tree_checks.
+type ConsistencyGuard struct {
+	People        store.PeopleStore
+	Relationships store.RelationshipStore
+}
+
+// NewConsistencyGuard создаёт проверку согласованности перед записью
+func NewConsistencyGuard(people store.PeopleStore, relationships store.RelationshipStore) *ConsistencyGuard {
+	return &ConsistencyGuard{People: people, Relationships: relationships}
+}
+
+// treeChange применяет изменение к копии дерева
+type treeChange func(people []models.Person, relationships []models.Relationship) ([]models.Person, []models.Relationship)
+
+// Check прогоняет проверки до и после изменения и возвращает только новые ошибки.
+// Старые проблемы дерева и предупреждения запись не блокируют.
+func (g *ConsistencyGuard) Check(ctx context.Context, treeID int, change treeChange) ([]genealogy.Issue, error) {
+	people, relationships, err := loadTree(ctx, g.People, g.Relationships, treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := map[string]bool{}
+	for _, issue := range genealogy.LintPeople(people, relationships) {
+		before[issue.Key()] = true
+	}
+
+	people, relationships = change(people, relationships)
+
+	var added []genealogy.Issue
+	for _, issue := range genealogy.LintPeople(people, relationships) {
+		if issue.Severity == genealogy.SeverityError && !before[issue.Key()] {
+			added = append(added, issue)
+		}
+	}
+	return added, nil
+}
+
+// writeConsistencyError отвечает 422 со списком ошибок, которые внесло бы изменение
+func writeConsistencyError(w http.ResponseWriter, issues []genealogy.Issue) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "Изменение противоречит данным дерева",
+		"issues": issues,
+	})
+}
+
+func loadTree(ctx context.Context, ps store.PeopleStore, rs store.RelationshipStore, treeID int) ([]models.Person, []models.Relationship, error) {
+	people, err := ps.ListPeople(ctx, treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	relationships, err := rs.ListRelationships(ctx, treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return people, relationships, nil
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTreeNameLength - предельная длина имени дерева в символах
+const maxTreeNameLength = 200
+
+type treeContextKey string
+
+const (
+	// TreeIDKey - ключ контекста с ID дерева, над которым работает запрос
+	TreeIDKey treeContextKey = "treeID"
+	// TreeRoleKey - ключ контекста с ролью пользователя в этом дереве
+	TreeRoleKey treeContextKey = "treeRole"
+)
+
+// getTreeID достаёт ID дерева, положенный в контекст RequireTree или DefaultTree
+func getTreeID(r *http.Request) int {
+	return r.Context().Value(TreeIDKey).(int)
+}
+
+// getTreeRole достаёт роль пользователя в текущем дереве
+func getTreeRole(r *http.Request) string {
+	return r.Context().Value(TreeRoleKey).(string)
+}
+
+// TreesHandler - список деревьев пользователя и операции над ними
+type TreesHandler struct {
+	Store   store.TreeStore
+	Members store.MembershipStore
+	// Media, если задан, после удаления дерева стирает из хранилища
+	// содержимое его файлов, не нужное другим деревьям
+	Media *MediaHandler
+}
+
+// NewTreesHandler создаёт обработчики для деревьев
+func NewTreesHandler(s store.TreeStore, members store.MembershipStore) *TreesHandler {
+	return &TreesHandler{Store: s, Members: members}
+}
+
+type treeRequest struct {
+	Name string `json:"name"`
+}
+
+// RequireTree - middleware для маршрутов /api/trees/{treeID}/...
+// Кладёт в контекст ID дерева, роль пользователя в нём и автора изменений для журнала. Дерево, где
+// пользователь не участник, неотличимо от несуществующего: в обоих случаях 404.
+func (h *TreesHandler) RequireTree(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		treeID, err := urlID(r, "treeID")
+		if err != nil {
+			http.Error(w, "Неверный ID дерева", http.StatusBadRequest)
+			return
+		}
+
+		role, err := h.Members.GetMemberRole(r.Context(), treeID, getUserID(r))
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Дерево не найдено", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), TreeIDKey, treeID)
+		ctx = context.WithValue(ctx, TreeRoleKey, role)
+		ctx = store.WithActor(ctx, getUserID(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireRole пропускает запрос, только если роль в текущем дереве не ниже min.
+// Ставится после RequireTree или DefaultTree.
+func RequireRole(min string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !models.RoleAtLeast(getTreeRole(r), min) {
+				http.Error(w, "Недостаточно прав: нужна роль "+min, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DefaultTree - middleware для старых маршрутов без {treeID} (/api/people и т.д.).
+// Они работают с самым старым собственным деревом пользователя, которое создаётся при первом обращении.
+func (h *TreesHandler) DefaultTree(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tree, err := h.Store.EnsureDefaultTree(r.Context(), getUserID(r))
+		if err != nil {
+			http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Дерево по умолчанию - всегда собственное
+		ctx := context.WithValue(r.Context(), TreeIDKey, tree.ID)
+		ctx = context.WithValue(ctx, TreeRoleKey, models.RoleOwner)
+		ctx = store.WithActor(ctx, getUserID(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ListTrees - GET /api/trees: свои деревья и те, куда пригласили, с ролью в каждом
+func (h *TreesHandler) ListTrees(w http.ResponseWriter, r *http.Request) {
+	trees, err := h.Store.ListTrees(r.Context(), getUserID(r))
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trees)
+}
+
+// CreateTree - POST /api/trees {"name": "..."}
+func (h *TreesHandler) CreateTree(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeTreeName(w, r, "")
+	if !ok {
+		return
+	}
+
+	tree, err := h.Store.CreateTree(r.Context(), getUserID(r), name)
+	if err != nil {
+		http.Error(w, "Ошибка записи в БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tree.Role = models.RoleOwner
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tree)
+}
+
+// RenameTree - PUT /api/trees/{treeID} {"name": "..."}
+func (h *TreesHandler) RenameTree(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeTreeName(w, r, "")
+	if !ok {
+		return
+	}
+
+	if err := h.Store.RenameTree(r.Context(), getTreeID(r), name); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Дерево не найдено", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка обновления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// DeleteTree - DELETE /api/trees/{treeID}: удаляет дерево со всеми людьми и связями
+func (h *TreesHandler) DeleteTree(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+
+	var files []models.Media
+	if h.Media != nil {
+		var err error
+		if files, err = h.Media.Store.ListMedia(r.Context(), treeID); err != nil {
+			http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.Store.DeleteTree(r.Context(), treeID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Дерево не найдено", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка удаления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, m := range files {
+		h.Media.dropContent(r.Context(), m.Hash)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// DuplicateTree - POST /api/trees/{treeID}/duplicate {"name": "..."}
+// Копия всегда принадлежит тому, кто её сделал, - так участник может
+// забрать себе черновик общего дерева. Без имени копия называется "<имя оригинала> (копия)".
+func (h *TreesHandler) DuplicateTree(w http.ResponseWriter, r *http.Request) {
+	treeID := getTreeID(r)
+
+	src, err := h.Store.GetTree(r.Context(), treeID)
+	if err != nil {
+		http.Error(w, "Ошибка чтения БД: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name, ok := decodeTreeName(w, r, src.Name+" (копия)")
+	if !ok {
+		return
+	}
+
+	tree, err := h.Store.DuplicateTree(r.Context(), treeID, getUserID(r), name)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Дерево не найдено", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка копирования: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tree.Role = models.RoleOwner
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tree)
+}
+
+// decodeTreeName читает и проверяет имя дерева из тела запроса.
+// Если fallback не пуст, тело можно не передавать вовсе.
+func decodeTreeName(w http.ResponseWriter, r *http.Request, fallback string) (string, bool) {
+	var req treeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !(fallback != "" && errors.Is(err, io.EOF)) {
+		http.Error(w, "Неверный формат JSON", http.StatusBadRequest)
+		return "", false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = fallback
+	}
+	if name == "" {
+		http.Error(w, "Не указано имя дерева", http.StatusUnprocessableEntity)
+		return "", false
+	}
+	if utf8.RuneCountInString(name) > maxTreeNameLength {
+		http.Error(w, "Слишком длинное имя дерева", http.StatusUnprocessableEntity)
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,407 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"regexp"
+	"time"
+)
+
+// Теги EXIF, которые нужны дереву
+const (
+	tagImageWidth       = 0x0100
+	tagImageLength      = 0x0101
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+)
+
+// maxIFDEntries - больше записей в одном каталоге EXIF не бывает у честных
+// файлов; защищает от испорченных и зацикленных каталогов
+const maxIFDEntries = 512
+
+// exifLayout - дата EXIF: "2006:01:02 15:04:05"
+const exifLayout = "2006:01:02 15:04:05"
+
+// exifData - то, что прочитано из EXIF
+type exifData struct {
+	width, height int
+	orientation   int
+	dateTime      string // DateTimeOriginal, а без неё - DateTime
+	gps           bool   // в каталоге GPS есть записи
+}
+
+// tiffReader читает структуру TIFF (на ней же устроен блок EXIF в JPEG),
+// начинающуюся со смещения base в r, не выходя за его первые limit байт
+type tiffReader struct {
+	r     io.ReaderAt
+	base  int64
+	limit int64
+	order binary.ByteOrder
+}
+
+func newTIFFReader(r io.ReaderAt, base, limit int64) (*tiffReader, uint32, error) {
+	head := make([]byte, 8)
+	if limit < 8 {
+		return nil, 0, errors.New("короткий заголовок TIFF")
+	}
+	if _, err := r.ReadAt(head, base); err != nil {
+		return nil, 0, err
+	}
+	t := &tiffReader{r: r, base: base, limit: limit}
+	switch string(head[:4]) {
+	case "II*\x00":
+		t.order = binary.LittleEndian
+	case "MM\x00*":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, errors.New("неверный заголовок TIFF")
+	}
+	return t, t.order.Uint32(head[4:]), nil
+}
+
+// read читает n байт со смещения off от начала TIFF
+func (t *tiffReader) read(off int64, n int) ([]byte, error) {
+	if off < 0 || n < 0 || off+int64(n) > t.limit {
+		return nil, errors.New("ссылка за пределы блока EXIF")
+	}
+	buf := make([]byte, n)
+	if _, err := t.r.ReadAt(buf, t.base+off); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ifdEntry - запись каталога: тег, тип, число значений и 4 байта
+// значения (или смещение до него, если оно длиннее)
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// typeSizes - размер одного значения по типу TIFF
+var typeSizes = map[uint16]int64{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// size - сколько байт занимают значения записи
+func (e ifdEntry) size() int64 {
+	return typeSizes[e.typ] * int64(e.count)
+}
+
+// entries читает записи каталога по смещению off
+func (t *tiffReader) entries(off uint32) ([]ifdEntry, error) {
+	head, err := t.read(int64(off), 2)
+	if err != nil {
+		return nil, err
+	}
+	n := int(t.order.Uint16(head))
+	if n > maxIFDEntries {
+		return nil, errors.New("слишком много записей в каталоге EXIF")
+	}
+	raw, err := t.read(int64(off)+2, n*12)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]ifdEntry, n)
+	for i := range list {
+		b := raw[i*12:]
+		list[i] = ifdEntry{
+			tag:   t.order.Uint16(b),
+			typ:   t.order.Uint16(b[2:]),
+			count: t.order.Uint32(b[4:]),
+			value: b[8:12],
+		}
+	}
+	return list, nil
+}
+
+// uint возвращает первое целое значение записи SHORT или LONG
+func (t *tiffReader) uint(e ifdEntry) uint32 {
+	switch e.typ {
+	case 3:
+		return uint32(t.order.Uint16(e.value))
+	case 4:
+		return t.order.Uint32(e.value)
+	}
+	return 0
+}
+
+// ascii возвращает строковое значение записи без завершающего нуля
+func (t *tiffReader) ascii(e ifdEntry) string {
+	if e.typ != 2 || e.count == 0 || e.count > 256 {
+		return ""
+	}
+	b := e.value[:min(e.count, 4)]
+	if e.count > 4 {
+		var err error
+		if b, err = t.read(int64(t.order.Uint32(e.value)), int(e.count)); err != nil {
+			return ""
+		}
+	}
+	return string(bytes.TrimRight(b, "\x00 "))
+}
+
+// parse читает нужные теги из первого каталога (IFD0) и каталога Exif
+func (t *tiffReader) parse(ifd0 uint32) (exifData, error) {
+	var d exifData
+	list, err := t.entries(ifd0)
+	if err != nil {
+		return d, err
+	}
+	var exifIFD uint32
+	for _, e := range list {
+		switch e.tag {
+		case tagImageWidth:
+			d.width = int(t.uint(e))
+		case tagImageLength:
+			d.height = int(t.uint(e))
+		case tagOrientation:
+			d.orientation = int(t.uint(e))
+		case tagDateTime:
+			d.dateTime = t.ascii(e)
+		case tagExifIFD:
+			exifIFD = t.uint(e)
+		case tagGPSIFD:
+			if gps, err := t.entries(t.uint(e)); err == nil && len(gps) > 0 {
+				d.gps = true
+			}
+		}
+	}
+	if exifIFD != 0 {
+		if list, err := t.entries(exifIFD); err == nil {
+			for _, e := range list {
+				if e.tag == tagDateTimeOriginal {
+					if s := t.ascii(e); s != "" {
+						d.dateTime = s
+					}
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+// jpegSegment - сегмент-маркер JPEG до начала данных изображения
+type jpegSegment struct {
+	marker byte
+	off    int64 // начало данных сегмента (после маркера и длины)
+	length int64 // длина данных
+}
+
+// jpegSegments перечисляет сегменты JPEG до SOS, после которого идут сжатые данные
+func jpegSegments(r io.ReaderAt, size int64) ([]jpegSegment, error) {
+	var list []jpegSegment
+	head := make([]byte, 4)
+	for off := int64(2); off+4 <= size; {
+		if _, err := r.ReadAt(head, off); err != nil {
+			return list, err
+		}
+		if head[0] != 0xFF {
+			return list, errors.New("испорченная разметка JPEG")
+		}
+		marker := head[1]
+		if marker == 0xFF { // байт-заполнитель перед маркером
+			off++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		length := int64(binary.BigEndian.Uint16(head[2:])) - 2
+		if length < 0 || off+4+length > size {
+			return list, errors.New("испорченная разметка JPEG")
+		}
+		list = append(list, jpegSegment{marker: marker, off: off + 4, length: length})
+		off += 4 + length
+	}
+	return list, nil
+}
+
+var (
+	exifPrefix = []byte("Exif\x00\x00")
+	xmpPrefix  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// segmentHas - начинаются ли данные сегмента с prefix
+func segmentHas(r io.ReaderAt, seg jpegSegment, prefix []byte) bool {
+	if seg.marker != 0xE1 || seg.length < int64(len(prefix)) {
+		return false
+	}
+	head := make([]byte, len(prefix))
+	if _, err := r.ReadAt(head, seg.off); err != nil {
+		return false
+	}
+	return bytes.Equal(head, prefix)
+}
+
+// xmpGPSRe - координаты в XMP: атрибутом exif:GPSLatitude="..." или элементом
+var xmpGPSRe = regexp.MustCompile(`exif:GPS\w+="[^"]*"|<exif:GPS\w+>[^<]*</exif:GPS\w+>`)
+
+// readEXIF читает EXIF из JPEG или TIFF. Файл без EXIF - пустые данные без ошибки.
+func readEXIF(r io.ReaderAt, size int64, contentType string) (exifData, error) {
+	switch contentType {
+	case "image/tiff":
+		t, ifd0, err := newTIFFReader(r, 0, size)
+		if err != nil {
+			return exifData{}, err
+		}
+		return t.parse(ifd0)
+	case "image/jpeg":
+		segments, err := jpegSegments(r, size)
+		if err != nil && len(segments) == 0 {
+			return exifData{}, err
+		}
+		var d exifData
+		for _, seg := range segments {
+			switch {
+			case segmentHas(r, seg, exifPrefix):
+				base := seg.off + int64(len(exifPrefix))
+				t, ifd0, err := newTIFFReader(r, base, seg.length-int64(len(exifPrefix)))
+				if err != nil {
+					return d, err
+				}
+				gps := d.gps
+				if d, err = t.parse(ifd0); err != nil {
+					return d, err
+				}
+				d.gps = d.gps || gps
+			case segmentHas(r, seg, xmpPrefix):
+				data := make([]byte, seg.length)
+				if _, err := r.ReadAt(data, seg.off); err == nil && xmpGPSRe.Match(data) {
+					d.gps = true
+				}
+			}
+		}
+		return d, nil
+	}
+	return exifData{}, nil
+}
+
+// takenAt переводит дату EXIF в "2006-01-02"; пусто, если её нет или она
+// заведомо неверна (0000:00:00 у камер с невыставленными часами)
+func takenAt(s string) string {
+	t, err := time.Parse(exifLayout, s)
+	if err != nil || t.Year() < 1826 {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// StripGPS стирает координаты съёмки из загруженного JPEG или TIFF прямо
+// во временном файле: записи каталога GPS и их значения затираются нулями,
+// сам каталог становится пустым, координаты в XMP - пробелами. Размер
+// файла не меняется, хеш пересчитывается. Прочие форматы не трогает.
+func StripGPS(u *Upload) error {
+	var changed bool
+	var err error
+	switch u.ContentType {
+	case "image/tiff":
+		var t *tiffReader
+		var ifd0 uint32
+		if t, ifd0, err = newTIFFReader(u.File, 0, u.Size); err == nil {
+			changed, err = t.stripGPS(u.File, ifd0)
+		}
+	case "image/jpeg":
+		changed, err = stripJPEGGPS(u.File, u.Size)
+	default:
+		return nil
+	}
+	if err != nil || !changed {
+		return err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(u.File, 0, u.Size)); err != nil {
+		return err
+	}
+	u.Hash = hex.EncodeToString(h.Sum(nil))
+	_, err = u.File.Seek(0, io.SeekStart)
+	return err
+}
+
+// readerWriterAt - файл, который правят на месте
+type readerWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+func stripJPEGGPS(rw readerWriterAt, size int64) (bool, error) {
+	segments, err := jpegSegments(rw, size)
+	if err != nil && len(segments) == 0 {
+		return false, err
+	}
+	var changed bool
+	for _, seg := range segments {
+		switch {
+		case segmentHas(rw, seg, exifPrefix):
+			base := seg.off + int64(len(exifPrefix))
+			t, ifd0, err := newTIFFReader(rw, base, seg.length-int64(len(exifPrefix)))
+			if err != nil {
+				return changed, err
+			}
+			ok, err := t.stripGPS(rw, ifd0)
+			if err != nil {
+				return changed, err
+			}
+			changed = changed || ok
+		case segmentHas(rw, seg, xmpPrefix):
+			data := make([]byte, seg.length)
+			if _, err := rw.ReadAt(data, seg.off); err != nil {
+				return changed, err
+			}
+			blanked := xmpGPSRe.ReplaceAllFunc(data, func(m []byte) []byte {
+				return bytes.Repeat([]byte(" "), len(m))
+			})
+			if !bytes.Equal(blanked, data) {
+				if _, err := rw.WriteAt(blanked, seg.off); err != nil {
+					return changed, err
+				}
+				changed = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+// stripGPS затирает каталог GPS, на который ссылается IFD0
+func (t *tiffReader) stripGPS(w io.WriterAt, ifd0 uint32) (bool, error) {
+	list, err := t.entries(ifd0)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range list {
+		if e.tag != tagGPSIFD {
+			continue
+		}
+		off := t.uint(e)
+		gps, err := t.entries(off)
+		if err != nil || len(gps) == 0 {
+			return false, err
+		}
+		for _, g := range gps {
+			if n := g.size(); n > 4 {
+				valueOff := int64(t.order.Uint32(g.value))
+				if valueOff+n <= t.limit {
+					if _, err := w.WriteAt(make([]byte, n), t.base+valueOff); err != nil {
+						return false, err
+					}
+				}
+			}
+		}
+		// пустой каталог: ноль записей, а на месте записей и ссылки
+		// на следующий каталог - нули
+		n := min(int64(2+len(gps)*12+4), t.limit-int64(off))
+		if _, err := w.WriteAt(make([]byte, n), t.base+int64(off)); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,201 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"slices"
+
+	_ "image/gif" // форматы, из которых строятся миниатюры
+	_ "image/png"
+)
+
+// ThumbnailSizes - размеры миниатюр по длинной стороне: значок в дереве,
+// карточка человека, просмотр. Больше исходника миниатюра не бывает.
+var ThumbnailSizes = []int{64, 256, 1024}
+
+// thumbnailQuality - качество JPEG миниатюр
+const thumbnailQuality = 85
+
+// maxThumbnailPixels - больше этого изображение не раскодируется: 50 Мп
+// в памяти - это уже 200 МБ
+const maxThumbnailPixels = 50_000_000
+
+// thumbnailSlots ограничивает, сколько изображений раскодируется
+// одновременно, чтобы пачка больших сканов не съела память сервера
+var thumbnailSlots = make(chan struct{}, 2)
+
+// ErrImageTooLarge - изображение слишком большое, чтобы строить из него миниатюры
+var ErrImageTooLarge = errors.New("изображение слишком большое для миниатюр")
+
+// CanThumbnail - умеет ли сервер строить миниатюры из файлов этого типа
+func CanThumbnail(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// ThumbnailKey - ключ миниатюры в хранилище рядом с исходником
+func ThumbnailKey(hash string, size int) string {
+	return fmt.Sprintf("%s_%d.jpg", hash, size)
+}
+
+// PhotoInfo - сведения об изображении из его содержимого
+type PhotoInfo struct {
+	Width, Height int    // как изображение показывается, с учётом поворота из EXIF
+	TakenAt       string // дата съёмки из EXIF, "2006-01-02"
+	HasGPS        bool   // в EXIF или XMP есть координаты съёмки
+}
+
+// Inspect читает размеры изображения и его EXIF. Испорченные метаданные
+// не ошибка: чего не удалось прочитать, то остаётся пустым.
+func Inspect(r io.ReaderAt, size int64, contentType string) PhotoInfo {
+	var info PhotoInfo
+	if !slices.Contains([]string{"image/jpeg", "image/png", "image/gif", "image/tiff"}, contentType) {
+		return info
+	}
+	exif, _ := readEXIF(r, size, contentType)
+	info.Width, info.Height = exif.width, exif.height
+	if CanThumbnail(contentType) {
+		if cfg, _, err := image.DecodeConfig(io.NewSectionReader(r, 0, size)); err == nil {
+			info.Width, info.Height = cfg.Width, cfg.Height
+		}
+	}
+	if exif.orientation >= 5 && exif.orientation <= 8 {
+		info.Width, info.Height = info.Height, info.Width
+	}
+	info.TakenAt = takenAt(exif.dateTime)
+	info.HasGPS = exif.gps
+	return info
+}
+
+// Thumbnail - миниатюра в JPEG
+type Thumbnail struct {
+	Size int
+	Data []byte
+}
+
+// MakeThumbnails строит миниатюры всех размеров ThumbnailSizes: поворачивает
+// изображение по EXIF, кладёт прозрачные места на белый фон и уменьшает
+// усреднением пикселей. Тип должен подходить под CanThumbnail.
+func MakeThumbnails(r io.ReaderAt, size int64, contentType string) ([]Thumbnail, error) {
+	if !CanThumbnail(contentType) {
+		return nil, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	thumbnailSlots <- struct{}{}
+	defer func() { <-thumbnailSlots }()
+
+	img, _, err := image.Decode(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	src := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Over)
+	img = nil
+
+	// сначала уменьшаем до самого большого размера, потом поворачиваем:
+	// поворачивать исходник целиком незачем
+	sizes := slices.Clone(ThumbnailSizes)
+	slices.Sort(sizes)
+	slices.Reverse(sizes)
+	exif, _ := readEXIF(r, size, contentType)
+	cur := shrink(src, sizes[0])
+	cur = orient(cur, exif.orientation)
+
+	thumbs := make([]Thumbnail, 0, len(sizes))
+	for _, s := range sizes {
+		cur = shrink(cur, s)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, cur, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return nil, err
+		}
+		thumbs = append(thumbs, Thumbnail{Size: s, Data: buf.Bytes()})
+	}
+	return thumbs, nil
+}
+
+// shrink уменьшает изображение так, чтобы длинная сторона была не больше
+// side: каждый пиксель результата - среднее покрытых им пикселей исходника
+func shrink(src *image.RGBA, side int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw <= side && sh <= side {
+		return src
+	}
+	dw, dh := side, side
+	if sw >= sh {
+		dh = max(1, sh*side/sw)
+	} else {
+		dw = max(1, sw*side/sh)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, b, a = r+int(p[0]), g+int(p[1]), b+int(p[2]), a+int(p[3])
+					n++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// orient поворачивает и отражает изображение по тегу Orientation из EXIF
+// (1 - как есть, 6 - повернуть по часовой стрелке, 8 - против и т.д.)
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:])
+		}
+	}
+	return dst
+}
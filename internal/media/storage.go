@@ -0,0 +1,44 @@
+// Package media хранит содержимое загруженных файлов (фотографий, сканов
+// документов) вне БД: на диске сервера или в S3-совместимом хранилище.
+// Описания файлов и их связи с людьми лежат в БД (store.MediaStore).
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotExist - в хранилище нет содержимого с таким ключом
+var ErrNotExist = errors.New("файл не найден в хранилище")
+
+// Storage - хранилище содержимого файлов по ключу. Ключ - имя без
+// каталогов (см. checkKey); обработчики используют SHA-256 содержимого,
+// поэтому одинаковые файлы хранятся один раз.
+type Storage interface {
+	// Put записывает size байт из r под ключом key, заменяя прежнее содержимое
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open открывает содержимое на чтение, ErrNotExist - если его нет.
+	// Содержимое на диске можно ещё и перематывать (io.Seeker).
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete стирает содержимое. Отсутствующее - не ошибка.
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey не пускает в хранилище ключи, похожие на пути: только латиница,
+// цифры, точка, дефис и подчёркивание, не с точки
+func checkKey(key string) error {
+	if len(key) < 3 || strings.HasPrefix(key, ".") {
+		return fmt.Errorf("недопустимый ключ файла %q", key)
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return fmt.Errorf("недопустимый ключ файла %q", key)
+		}
+	}
+	return nil
+}
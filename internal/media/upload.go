@@ -0,0 +1,94 @@
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// Ошибки приёма файла. Обработчики переводят их в HTTP-статусы.
+var (
+	ErrEmpty           = errors.New("файл пуст")
+	ErrTooLarge        = errors.New("файл слишком большой")
+	ErrUnsupportedType = errors.New("неподдерживаемый тип файла")
+)
+
+// AllowedTypes - что можно загружать: фотографии и сканы, PDF, записи
+// рассказов. Тип определяется по содержимому, а не по имени файла.
+var AllowedTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp", "image/bmp", "image/tiff",
+	"application/pdf",
+	"audio/mpeg", "audio/wave", "video/mp4",
+}
+
+// Upload - принятый файл во временном файле на диске. Содержимое уже
+// посчитано и проверено; после записи в хранилище нужно вызвать Close.
+type Upload struct {
+	File        *os.File
+	Hash        string // SHA-256 содержимого, hex - он же ключ в хранилище
+	Size        int64
+	ContentType string
+}
+
+// Receive читает файл во временный файл, считая хеш, и определяет его
+// тип по первым байтам. ErrTooLarge - больше maxSize байт, ErrEmpty,
+// ErrUnsupportedType - тип не из AllowedTypes.
+func Receive(r io.Reader, maxSize int64) (*Upload, error) {
+	tmp, err := os.CreateTemp("", "media-*")
+	if err != nil {
+		return nil, err
+	}
+	u := &Upload{File: tmp}
+
+	h := sha256.New()
+	u.Size, err = io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, maxSize+1))
+	if err == nil {
+		switch {
+		case u.Size > maxSize:
+			err = ErrTooLarge
+		case u.Size == 0:
+			err = ErrEmpty
+		}
+	}
+	if err != nil {
+		u.Close()
+		return nil, err
+	}
+	u.Hash = hex.EncodeToString(h.Sum(nil))
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		u.Close()
+		return nil, err
+	}
+	u.ContentType = Sniff(head[:n])
+	if !slices.Contains(AllowedTypes, u.ContentType) {
+		u.Close()
+		return nil, ErrUnsupportedType
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		u.Close()
+		return nil, err
+	}
+	return u, nil
+}
+
+// Close удаляет временный файл
+func (u *Upload) Close() error {
+	u.File.Close()
+	return os.Remove(u.File.Name())
+}
+
+// Sniff определяет тип содержимого по первым байтам (до 512). Кроме того,
+// что знает http.DetectContentType, узнаёт TIFF - в нём часто сканируют архивные документы.
+func Sniff(head []byte) string {
+	if len(head) >= 4 && (string(head[:4]) == "II*\x00" || string(head[:4]) == "MM\x00*") {
+		return "image/tiff"
+	}
+	return http.DetectContentType(head)
+}
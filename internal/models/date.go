@@ -0,0 +1,424 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date - генеалогическая дата: запись как есть и диапазон дней, в который
+// она попадает. Понимает фразы GEDCOM и их русские варианты:
+//
+//	1890, 1890-03, 1890-03-12, 12.03.1890, 12 MAR 1890, 12 марта 1890 г.
+//	ABT 1890, около 1890        приблизительно (также EST - оценка, CAL - расчёт)
+//	BEF 1900, до 1900           раньше
+//	AFT 1880, после 1880        позже
+//	BET 1880 AND 1885, между 1880 и 1885, FROM 1880 TO 1885, с 1880 по 1885
+//	(весной, после войны)       фраза без даты
+//
+// Даты по старому стилю (метрические книги до 1918 года) помечаются так:
+//
+//	@#DJULIAN@ 12 MAR 1890      GEDCOM 5.5.1; JULIAN 12 MAR 1890 - GEDCOM 7
+//	12 марта 1890 ст. ст.       также "по ст. ст.", "по старому стилю"
+//	12 FEB 1890/91              двойной год: год начинался 25 марта, день - по старому стилю
+//
+// From и To всегда по григорианскому календарю: по ним сортируют, считают
+// возраст и проверяют дерево. Границы по старому стилю - в JulianFrom и JulianTo.
+type Date struct {
+	Text      string `json:"text"`
+	Qualifier string `json:"qualifier,omitempty"`
+	Calendar  string `json:"calendar,omitempty"` // CalendarJulian или CalendarDual; пусто - григорианский
+	From      string `json:"from,omitempty"`     // самый ранний возможный день, "2006-01-02"; пусто - не ограничено
+	To        string `json:"to,omitempty"`       // самый поздний возможный день
+	// Те же границы по юлианскому календарю - только у дат по старому стилю
+	JulianFrom string `json:"julian_from,omitempty"`
+	JulianTo   string `json:"julian_to,omitempty"`
+}
+
+// Календари даты (Date.Calendar)
+const (
+	CalendarGregorian = ""       // новый стиль
+	CalendarJulian    = "julian" // старый стиль
+	CalendarDual      = "dual"   // старый стиль с двойным годом: 1890/91
+)
+
+// calendarMarkedGregorian - явная пометка нового стиля: с ней нельзя двойной год
+const calendarMarkedGregorian = "gregorian"
+
+// Уточнения даты (Date.Qualifier); пусто - точная дата
+const (
+	DateAbout      = "about"      // ABT: около
+	DateEstimated  = "estimated"  // EST: оценка
+	DateCalculated = "calculated" // CAL: рассчитана по другим датам
+	DateBefore     = "before"     // BEF: раньше
+	DateAfter      = "after"      // AFT: позже
+	DateBetween    = "between"    // BET ... AND ...: в этом промежутке
+	DatePhrase     = "phrase"     // только текст, диапазона нет
+)
+
+// dateLayout - формат границ диапазона: строки сравниваются так же, как даты
+const dateLayout = "2006-01-02"
+
+// dateKeywords - слова в начале даты и уточнения, которые они задают
+var dateKeywords = map[string]string{
+	"abt": DateAbout, "about": DateAbout, "ca": DateAbout, "circa": DateAbout, "c": DateAbout,
+	"около": DateAbout, "ок": DateAbout, "примерно": DateAbout,
+	"est": DateEstimated, "estimated": DateEstimated,
+	"cal": DateCalculated, "calculated": DateCalculated,
+	"bef": DateBefore, "before": DateBefore, "до": DateBefore,
+	"aft": DateAfter, "after": DateAfter, "после": DateAfter,
+	"bet": DateBetween, "between": DateBetween, "между": DateBetween,
+	"from": DateBetween, "с": DateBetween,
+	"to": DateBefore, "по": DateBefore,
+}
+
+// rangeSeparators - слово между началом и концом промежутка
+var rangeSeparators = map[string]bool{"and": true, "и": true, "to": true, "по": true}
+
+// monthNames - месяцы по первым трём буквам: JAN из GEDCOM, March, марта, мая
+var monthNames = map[string]time.Month{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	"янв": 1, "фев": 2, "мар": 3, "апр": 4, "май": 5, "мая": 5, "июн": 6,
+	"июл": 7, "авг": 8, "сен": 9, "окт": 10, "ноя": 11, "дек": 12,
+}
+
+var (
+	isoDateRe    = regexp.MustCompile(`^(\d{3,4})(?:-(\d{1,2})(?:-(\d{1,2}))?)?$`)
+	dottedDateRe = regexp.MustCompile(`^(?:(\d{1,2})\.)?(\d{1,2})\.(\d{3,4})$`)
+	yearRe       = regexp.MustCompile(`^\d{3,4}$`)
+	dayRe        = regexp.MustCompile(`^\d{1,2}$`)
+	dualYearRe   = regexp.MustCompile(`^(\d{3,4})/(\d{1,4})$`)
+	// Пометки календаря в русской записи: "ст. ст.", "по старому стилю", "н. ст."
+	oldStyleRe = regexp.MustCompile(`(?:по\s+)?(?:ст\.\s*ст\.?|старому\s+стилю)`)
+	newStyleRe = regexp.MustCompile(`(?:по\s+)?(?:н\.\s*ст\.?|новому\s+стилю)`)
+)
+
+// ParseDate разбирает дату. Пустая строка - пустая дата без ошибки.
+func ParseDate(s string) (Date, error) {
+	d := Date{Text: s}
+	text := strings.TrimSpace(s)
+	if text == "" {
+		return d, nil
+	}
+	if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+		d.Qualifier = DatePhrase
+		return d, nil
+	}
+
+	calendar, text, err := extractCalendar(strings.ToLower(text))
+	if err != nil {
+		return d, fmt.Errorf("не удалось разобрать дату %q: %w", s, err)
+	}
+
+	// INT 1890 (около 1890) - дата, истолкованная из фразы: фраза не нужна
+	if i := strings.Index(text, "("); i > 0 && strings.HasSuffix(text, ")") {
+		text = text[:i]
+	}
+	var words []string
+	for _, w := range strings.Fields(text) {
+		w = strings.TrimSuffix(w, ".")
+		switch w {
+		case "int", "г", "год", "года", "":
+			continue
+		}
+		// Двойной год 1890/91 - это 1891 год по нынешнему счёту
+		if m := dualYearRe.FindStringSubmatch(w); m != nil {
+			year, ok := resolveDualYear(m[1], m[2])
+			if !ok {
+				return d, fmt.Errorf("не удалось разобрать дату %q: неверный двойной год %q", s, w)
+			}
+			if calendar == calendarMarkedGregorian {
+				return d, fmt.Errorf("в дате %q двойной год у даты по новому стилю", s)
+			}
+			w, calendar = year, CalendarDual
+		}
+		words = append(words, w)
+	}
+	julian := calendar == CalendarJulian || calendar == CalendarDual
+	if calendar == calendarMarkedGregorian {
+		calendar = CalendarGregorian
+	}
+	d.Calendar = calendar
+	if len(words) == 0 {
+		return d, fmt.Errorf("не удалось разобрать дату %q", s)
+	}
+
+	keyword := words[0]
+	qualifier, hasKeyword := dateKeywords[keyword]
+	if hasKeyword {
+		words = words[1:]
+	}
+
+	var first, second []string
+	first = words
+	for i, w := range words {
+		if rangeSeparators[w] {
+			first, second = words[:i], words[i+1:]
+			break
+		}
+	}
+
+	lo, hi, err := parseDatePart(first, julian)
+	if err != nil {
+		return d, fmt.Errorf("не удалось разобрать дату %q: %w", s, err)
+	}
+
+	switch {
+	case second != nil:
+		// BET a AND b, FROM a TO b; промежуток без BET или FROM в начале - ошибка
+		if qualifier != DateBetween {
+			return d, fmt.Errorf("не удалось разобрать дату %q", s)
+		}
+		_, hi2, err := parseDatePart(second, julian)
+		if err != nil {
+			return d, fmt.Errorf("не удалось разобрать дату %q: %w", s, err)
+		}
+		if lo.After(hi2) {
+			return d, fmt.Errorf("в дате %q начало промежутка позже конца", s)
+		}
+		d.Qualifier, d.From, d.To = DateBetween, lo.Format(dateLayout), hi2.Format(dateLayout)
+	case qualifier == DateBetween && (keyword == "from" || keyword == "с"):
+		// FROM 1880 - с этого времени
+		d.Qualifier, d.From = DateAfter, lo.Format(dateLayout)
+	case qualifier == DateBetween:
+		return d, fmt.Errorf("в дате %q нет конца промежутка", s)
+	case qualifier == DateBefore && (keyword == "to" || keyword == "по"):
+		// TO 1885 - по это время включительно
+		d.Qualifier, d.To = DateBefore, hi.Format(dateLayout)
+	case qualifier == DateBefore:
+		d.Qualifier, d.To = DateBefore, lo.AddDate(0, 0, -1).Format(dateLayout)
+	case qualifier == DateAfter:
+		d.Qualifier, d.From = DateAfter, hi.AddDate(0, 0, 1).Format(dateLayout)
+	default:
+		d.Qualifier, d.From, d.To = qualifier, lo.Format(dateLayout), hi.Format(dateLayout)
+	}
+	if julian {
+		d.JulianFrom, d.JulianTo = julianString(d.From), julianString(d.To)
+	}
+	return d, nil
+}
+
+// extractCalendar убирает из даты (уже в нижнем регистре) пометки календаря
+// и возвращает календарь: CalendarJulian, calendarMarkedGregorian или
+// CalendarGregorian, если пометок нет. Разные пометки в одной дате - ошибка.
+func extractCalendar(text string) (string, string, error) {
+	var found []string
+	mark := func(calendar string) string {
+		found = append(found, calendar)
+		return " "
+	}
+	text = oldStyleRe.ReplaceAllStringFunc(text, func(string) string { return mark(CalendarJulian) })
+	text = newStyleRe.ReplaceAllStringFunc(text, func(string) string { return mark(calendarMarkedGregorian) })
+
+	// GEDCOM 5.5.1: @#DJULIAN@, @#DGREGORIAN@; другие календари (@#DHEBREW@...) не поддерживаются
+	for {
+		i := strings.Index(text, "@#d")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(text[i+3:], "@")
+		if j < 0 {
+			return "", text, fmt.Errorf("незакрытая пометка календаря")
+		}
+		switch name := text[i+3 : i+3+j]; name {
+		case "julian":
+			mark(CalendarJulian)
+		case "gregorian":
+			mark(calendarMarkedGregorian)
+		default:
+			return "", text, fmt.Errorf("календарь %q не поддерживается", strings.ToUpper(name))
+		}
+		text = text[:i] + " " + text[i+3+j+1:]
+	}
+
+	// GEDCOM 7: слово JULIAN или GREGORIAN перед датой
+	words := strings.Fields(text)
+	kept := words[:0]
+	for _, w := range words {
+		switch w {
+		case "julian":
+			mark(CalendarJulian)
+		case "gregorian":
+			mark(calendarMarkedGregorian)
+		default:
+			kept = append(kept, w)
+		}
+	}
+	text = strings.Join(kept, " ")
+
+	for _, c := range found[min(1, len(found)):] {
+		if c != found[0] {
+			return "", text, fmt.Errorf("в дате разные календари")
+		}
+	}
+	if len(found) == 0 {
+		return CalendarGregorian, text, nil
+	}
+	return found[0], text, nil
+}
+
+// resolveDualYear - второй год из двойной записи: "1890", "91" -> "1891",
+// "1699", "00" -> "1700". Второй год должен быть следующим за первым.
+func resolveDualYear(first, second string) (string, bool) {
+	if len(second) > len(first) {
+		return "", false
+	}
+	a, _ := strconv.Atoi(first)
+	b, _ := strconv.Atoi(first[:len(first)-len(second)] + second)
+	if b < a {
+		b += int(math.Pow10(len(second))) // переход через век: 1699/00
+	}
+	return strconv.Itoa(b), b == a+1
+}
+
+// parseDatePart разбирает одну дату без уточнений и возвращает её первый и последний день:
+// для "1890" это 1 января и 31 декабря, для "1890-03" - 1 и 31 марта.
+// При julian дата записана по старому стилю; результат всегда по григорианскому.
+func parseDatePart(words []string, julian bool) (lo, hi time.Time, err error) {
+	var year, month, day string
+	switch len(words) {
+	case 1:
+		if m := isoDateRe.FindStringSubmatch(words[0]); m != nil {
+			year, month, day = m[1], m[2], m[3]
+		} else if m := dottedDateRe.FindStringSubmatch(words[0]); m != nil {
+			day, month, year = m[1], m[2], m[3]
+		} else {
+			return lo, hi, fmt.Errorf("нет года")
+		}
+	case 2, 3:
+		// [день] месяц год
+		if len(words) == 3 {
+			day, words = words[0], words[1:]
+			if !dayRe.MatchString(day) {
+				return lo, hi, fmt.Errorf("неверный день %q", day)
+			}
+		}
+		name := []rune(words[0])
+		m, ok := time.Month(0), len(name) >= 3
+		if ok {
+			m, ok = monthNames[string(name[:3])]
+		}
+		if !ok {
+			return lo, hi, fmt.Errorf("неизвестный месяц %q", words[0])
+		}
+		month, year = strconv.Itoa(int(m)), words[1]
+		if !yearRe.MatchString(year) {
+			return lo, hi, fmt.Errorf("неверный год %q", year)
+		}
+	default:
+		return lo, hi, fmt.Errorf("нет года")
+	}
+
+	y, _ := strconv.Atoi(year)
+	if y == 0 {
+		return lo, hi, fmt.Errorf("неверный год %q", year)
+	}
+	at := func(m, d int) time.Time {
+		if julian {
+			return julianToGregorian(y, m, d)
+		}
+		return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	}
+	if month == "" {
+		return at(1, 1), at(12, 31), nil
+	}
+	m, _ := strconv.Atoi(month)
+	if m < 1 || m > 12 {
+		return lo, hi, fmt.Errorf("неверный месяц %q", month)
+	}
+	last := daysInMonth(y, m, julian)
+	if day == "" {
+		return at(m, 1), at(m, last), nil
+	}
+	dd, _ := strconv.Atoi(day)
+	if dd < 1 || dd > last {
+		return lo, hi, fmt.Errorf("в месяце %d нет дня %d", m, dd)
+	}
+	lo = at(m, dd)
+	return lo, lo, nil
+}
+
+// daysInMonth - число дней в месяце. По старому стилю високосен каждый
+// четвёртый год, поэтому 29 февраля 1700 по юлианскому календарю было.
+func daysInMonth(y, m int, julian bool) int {
+	if m == 2 && julian {
+		if y%4 == 0 {
+			return 29
+		}
+		return 28
+	}
+	return time.Date(y, time.Month(m)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// Перевод между календарями идёт через номер юлианского дня (JDN).
+// Разница набегает в конце февраля: 10 дней до 1700 года, 11 - в XVIII веке,
+// 12 - в XIX, 13 - с 1900 по 2100 год.
+const unixEpochJDN = 2440588 // JDN 1 января 1970 года
+
+// julianToGregorian - григорианская дата дня, записанного по старому стилю
+func julianToGregorian(y, m, d int) time.Time {
+	a := (14 - m) / 12
+	yy, mm := y+4800-a, m+12*a-3
+	jdn := d + (153*mm+2)/5 + 365*yy + yy/4 - 32083
+	return time.Unix(int64(jdn-unixEpochJDN)*86400, 0).UTC()
+}
+
+// gregorianToJulian - тот же день по старому стилю
+func gregorianToJulian(t time.Time) (y, m, d int) {
+	jdn := int(t.Unix()/86400) + unixEpochJDN
+	c := jdn + 32082
+	dd := (4*c + 3) / 1461
+	e := c - 1461*dd/4
+	mm := (5*e + 2) / 153
+	d = e - (153*mm+2)/5 + 1
+	m = mm + 3 - 12*(mm/10)
+	y = dd - 4800 + mm/10
+	return y, m, d
+}
+
+// julianString переводит границу "2006-01-02" на старый стиль; пустая - пустая
+func julianString(gregorian string) string {
+	if gregorian == "" {
+		return ""
+	}
+	t, err := time.Parse(dateLayout, gregorian)
+	if err != nil {
+		return ""
+	}
+	y, m, d := gregorianToJulian(t)
+	return fmt.Sprintf("%04d-%02d-%02d", y, m, d)
+}
+
+// Known - у даты есть хотя бы одна граница
+func (d Date) Known() bool {
+	return d.From != "" || d.To != ""
+}
+
+// Approximate - дата приблизительная: около, оценка или расчёт
+func (d Date) Approximate() bool {
+	return d.Qualifier == DateAbout || d.Qualifier == DateEstimated || d.Qualifier == DateCalculated
+}
+
+// SortKey - по чему сортировать: начало диапазона, а если его нет - конец.
+// Пусто у дат без диапазона.
+func (d Date) SortKey() string {
+	if d.From != "" {
+		return d.From
+	}
+	return d.To
+}
+
+// Range - границы диапазона; нулевое время - граница не задана
+func (d Date) Range() (from, to time.Time) {
+	if d.From != "" {
+		from, _ = time.Parse(dateLayout, d.From)
+	}
+	if d.To != "" {
+		to, _ = time.Parse(dateLayout, d.To)
+	}
+	return from, to
+}
@@ -0,0 +1,115 @@
+package models
+
+import "testing"
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in        string
+		qualifier string
+		from, to  string
+	}{
+		{"", "", "", ""},
+		{"1890", "", "1890-01-01", "1890-12-31"},
+		{"1890-03", "", "1890-03-01", "1890-03-31"},
+		{"1900-02", "", "1900-02-01", "1900-02-28"},
+		{"1890-03-12", "", "1890-03-12", "1890-03-12"},
+		{"12.03.1890", "", "1890-03-12", "1890-03-12"},
+		{"03.1890", "", "1890-03-01", "1890-03-31"},
+		{"12 MAR 1890", "", "1890-03-12", "1890-03-12"},
+		{"12 марта 1890 г.", "", "1890-03-12", "1890-03-12"},
+		{"мая 1890", "", "1890-05-01", "1890-05-31"},
+		{"ABT 1890", DateAbout, "1890-01-01", "1890-12-31"},
+		{"около 1890", DateAbout, "1890-01-01", "1890-12-31"},
+		{"EST 1890", DateEstimated, "1890-01-01", "1890-12-31"},
+		{"CAL 1890", DateCalculated, "1890-01-01", "1890-12-31"},
+		{"BEF 1900", DateBefore, "", "1899-12-31"},
+		{"до 1900", DateBefore, "", "1899-12-31"},
+		{"AFT 1880", DateAfter, "1881-01-01", ""},
+		{"после 1880", DateAfter, "1881-01-01", ""},
+		{"BET 1880 AND 1885", DateBetween, "1880-01-01", "1885-12-31"},
+		{"между 1880 и 1885", DateBetween, "1880-01-01", "1885-12-31"},
+		{"FROM 1880 TO 1885", DateBetween, "1880-01-01", "1885-12-31"},
+		{"с 1880 по 1885", DateBetween, "1880-01-01", "1885-12-31"},
+		{"FROM 1880", DateAfter, "1880-01-01", ""},
+		{"TO 1885", DateBefore, "", "1885-12-31"},
+		{"INT 1890 (около 1890)", "", "1890-01-01", "1890-12-31"},
+		{"(весной, после войны)", DatePhrase, "", ""},
+	}
+	for _, tt := range tests {
+		d, err := ParseDate(tt.in)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", tt.in, err)
+			continue
+		}
+		if d.Text != tt.in || d.Qualifier != tt.qualifier || d.From != tt.from || d.To != tt.to {
+			t.Errorf("ParseDate(%q) = %+v, ожидалось %s %s..%s", tt.in, d, tt.qualifier, tt.from, tt.to)
+		}
+	}
+}
+
+func TestParseDateErrors(t *testing.T) {
+	for _, in := range []string{
+		"вчера", "1890?", "31 FEB 1890", "1890-13", "0", "1890 AND 1895",
+		"BET 1890", "BET 1895 AND 1890", "@#DHEBREW@ 5650", "@#DJULIAN@ 1890 н. ст.",
+		"1890/92", "1890/91 н. ст.", "29 FEB 1900",
+	} {
+		if d, err := ParseDate(in); err == nil {
+			t.Errorf("ParseDate(%q) = %+v, ожидалась ошибка", in, d)
+		}
+	}
+}
+
+func TestParseDateCalendar(t *testing.T) {
+	tests := []struct {
+		in                   string
+		calendar             string
+		from, to             string
+		julianFrom, julianTo string
+	}{
+		// Разница календарей: 10 дней до 1700 года, 11 в XVIII веке, 12 в XIX, 13 в XX.
+		// Она растёт после 29 февраля по старому стилю в 1700, 1800 и 1900 годах.
+		{"@#DJULIAN@ 5 OCT 1582", CalendarJulian, "1582-10-15", "1582-10-15", "1582-10-05", "1582-10-05"},
+		{"@#DJULIAN@ 1 JAN 1650", CalendarJulian, "1650-01-11", "1650-01-11", "1650-01-01", "1650-01-01"},
+		{"@#DJULIAN@ 28 FEB 1700", CalendarJulian, "1700-03-10", "1700-03-10", "1700-02-28", "1700-02-28"},
+		{"@#DJULIAN@ 29 FEB 1700", CalendarJulian, "1700-03-11", "1700-03-11", "1700-02-29", "1700-02-29"},
+		{"@#DJULIAN@ 1 MAR 1700", CalendarJulian, "1700-03-12", "1700-03-12", "1700-03-01", "1700-03-01"},
+		{"JULIAN 12 MAR 1799", CalendarJulian, "1799-03-23", "1799-03-23", "1799-03-12", "1799-03-12"},
+		{"12 марта 1890 ст. ст.", CalendarJulian, "1890-03-24", "1890-03-24", "1890-03-12", "1890-03-12"},
+		{"12 марта 1890 (по ст. ст.)", CalendarJulian, "1890-03-24", "1890-03-24", "1890-03-12", "1890-03-12"},
+		{"1 февраля 1918 по старому стилю", CalendarJulian, "1918-02-14", "1918-02-14", "1918-02-01", "1918-02-01"},
+		{"@#DJULIAN@ 1890", CalendarJulian, "1890-01-13", "1891-01-12", "1890-01-01", "1890-12-31"},
+		{"@#DJULIAN@ FEB 1900", CalendarJulian, "1900-02-13", "1900-03-13", "1900-02-01", "1900-02-29"},
+		{"ABT @#DJULIAN@ 1890", CalendarJulian, "1890-01-13", "1891-01-12", "1890-01-01", "1890-12-31"},
+		{"BEF @#DJULIAN@ 1900", CalendarJulian, "", "1900-01-12", "", "1899-12-31"},
+		{"BET @#DJULIAN@ 1880 AND @#DJULIAN@ 1885", CalendarJulian, "1880-01-13", "1886-01-12", "1880-01-01", "1885-12-31"},
+		{"12 FEB 1890/91", CalendarDual, "1891-02-24", "1891-02-24", "1891-02-12", "1891-02-12"},
+		{"1699/00", CalendarDual, "1700-01-11", "1701-01-11", "1700-01-01", "1700-12-31"},
+		{"1699/1700", CalendarDual, "1700-01-11", "1701-01-11", "1700-01-01", "1700-12-31"},
+		{"@#DGREGORIAN@ 12 MAR 1890", CalendarGregorian, "1890-03-12", "1890-03-12", "", ""},
+		{"12 марта 1890 н. ст.", CalendarGregorian, "1890-03-12", "1890-03-12", "", ""},
+	}
+	for _, tt := range tests {
+		d, err := ParseDate(tt.in)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", tt.in, err)
+			continue
+		}
+		if d.Calendar != tt.calendar || d.From != tt.from || d.To != tt.to || d.JulianFrom != tt.julianFrom || d.JulianTo != tt.julianTo {
+			t.Errorf("ParseDate(%q) = %+v,\n ожидалось %q %s..%s (ст. ст. %s..%s)",
+				tt.in, d, tt.calendar, tt.from, tt.to, tt.julianFrom, tt.julianTo)
+		}
+	}
+}
+
+func TestDateSortKey(t *testing.T) {
+	before, _ := ParseDate("BEF 1900")
+	exact, _ := ParseDate("1890-03-12")
+	julian, _ := ParseDate("12 марта 1890 ст. ст.")
+	if before.SortKey() != "1899-12-31" || exact.SortKey() != "1890-03-12" {
+		t.Errorf("ключи сортировки %q и %q", before.SortKey(), exact.SortKey())
+	}
+	// 12 марта по старому стилю - это 24 марта, позже 12 марта по новому
+	if julian.SortKey() <= exact.SortKey() {
+		t.Errorf("дата по старому стилю %q сортируется раньше %q", julian.SortKey(), exact.SortKey())
+	}
+}
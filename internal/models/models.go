@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 // Person - Узел графа. Хранит личные данные.
 type Person struct {
 	ID         int     `json:"id" db:"id"`
@@ -12,6 +14,12 @@ type Person struct {
 	DeathDate  *string `json:"death_date" db:"death_date"` // Указатель, может быть null (жив)
 	Gender     string  `json:"gender" db:"gender"`         // "male", "female", "other"
 	PhotoURL	 string  `json:"photo_url" db:"photo_url"`   // URL фотографии
+	// Портрет из файлов дерева (см. Media); если задан, важнее PhotoURL
+	PhotoMediaID *int `json:"photo_media_id" db:"photo_media_id"`
+	Notes      string  `json:"notes" db:"notes"`           // заметки, участвуют в поиске
+	// Места рождения и смерти из справочника мест дерева (см. Place)
+	BirthPlaceID *int `json:"birth_place_id" db:"birth_place_id"`
+	DeathPlaceID *int `json:"death_place_id" db:"death_place_id"`
 	// Позиция на графе для визуализации
 	PositionX float64 `json:"position_x"`
   PositionY float64 `json:"position_y"`
@@ -40,4 +48,302 @@ type User struct {
 	
 	// LinkToPersonID - Какой Person в дереве соответствует этому юзеру (Я)
 	LinkToPersonID *int   `json:"link_to_person_id" db:"link_to_person_id"`
-}
\ No newline at end of file
+}
+
+// Tree - отдельное дерево пользователя (например, по линии матери или "черновик").
+type Tree struct {
+	ID        int    `json:"id" db:"id"`
+	UserID    int    `json:"user_id" db:"user_id"` // владелец
+	Name      string `json:"name" db:"name"`
+	CreatedAt string `json:"created_at" db:"created_at"`
+	Role      string `json:"role,omitempty"` // роль текущего пользователя в этом дереве
+}
+
+// Роли участников дерева. Каждая следующая включает права предыдущей.
+const (
+	RoleViewer = "viewer" // только просмотр
+	RoleEditor = "editor" // правка людей и связей
+	RoleOwner  = "owner"  // переименование, удаление, управление участниками
+)
+
+// RoleAtLeast сообщает, что роль role даёт права не меньше, чем min
+func RoleAtLeast(role, min string) bool {
+	rank := map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+	return rank[role] > 0 && rank[role] >= rank[min]
+}
+
+// TreeMember - участник дерева с ролью
+type TreeMember struct {
+	TreeID    int    `json:"tree_id" db:"tree_id"`
+	UserID    int    `json:"user_id" db:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role" db:"role"`
+	CreatedAt string `json:"created_at" db:"created_at"`
+}
+
+// TreeInvite - одноразовое приглашение в дерево.
+// Token заполняется только в ответе на создание: в базе хранится его хеш.
+type TreeInvite struct {
+	ID        int     `json:"id" db:"id"`
+	TreeID    int     `json:"tree_id" db:"tree_id"`
+	TreeName  string  `json:"tree_name,omitempty"`
+	Email     string  `json:"email,omitempty" db:"email"` // пусто - принять может любой, у кого есть токен
+	Role      string  `json:"role" db:"role"`
+	Token     string  `json:"token,omitempty"`
+	CreatedBy int     `json:"created_by" db:"created_by"`
+	CreatedAt string  `json:"created_at" db:"created_at"`
+	ExpiresAt *string `json:"expires_at" db:"expires_at"`
+	UsedAt    *string `json:"used_at" db:"used_at"`
+}
+
+// ShareLink - публичная ссылка на дерево только для чтения.
+// Живые люди в ответе по ссылке скрыты (см. genealogy.RedactLiving).
+type ShareLink struct {
+	ID        int     `json:"id" db:"id"`
+	TreeID    int     `json:"tree_id" db:"tree_id"`
+	Label     string  `json:"label" db:"label"` // для себя: "для тёти Гали"
+	Token     string  `json:"token,omitempty"`
+	CreatedBy int     `json:"created_by" db:"created_by"`
+	CreatedAt string  `json:"created_at" db:"created_at"`
+	ExpiresAt *string `json:"expires_at" db:"expires_at"` // null - бессрочно
+	RevokedAt *string `json:"revoked_at" db:"revoked_at"`
+}
+
+// Change - запись журнала изменений человека или связи
+type Change struct {
+	ID         int             `json:"id" db:"id"`
+	TreeID     int             `json:"tree_id" db:"tree_id"`
+	UserID     *int            `json:"user_id" db:"user_id"` // кто изменил; null - импорт из консоли и т.п.
+	UserEmail  string          `json:"user_email,omitempty"`
+	EntityType string          `json:"entity_type" db:"entity_type"` // "person" или "relationship"
+	EntityID   int             `json:"entity_id" db:"entity_id"`
+	Action     string          `json:"action" db:"action"` // "create", "update", "delete"
+	Before     json.RawMessage `json:"before" db:"before_json"` // null для create
+	After      json.RawMessage `json:"after" db:"after_json"`   // null для delete
+	CauseID    *int            `json:"cause_id,omitempty" db:"cause_id"`   // изменение, повлёкшее это
+	RevertOf   *int            `json:"revert_of,omitempty" db:"revert_of"` // изменение, которое это отменяет
+	// Moved - у удаления при слиянии людей: что перенесено на оставшегося
+	// (события, ссылки на источники, файлы, аккаунты) и вернётся при отмене
+	Moved      json.RawMessage `json:"moved,omitempty" db:"moved_json"`
+	CreatedAt  string          `json:"created_at" db:"created_at"`
+}
+
+// Типы сущностей и действия в журнале изменений
+const (
+	EntityPerson       = "person"
+	EntityRelationship = "relationship"
+
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Removed - что именно перенесено в корзину одной операцией удаления
+type Removed struct {
+	People        []Person       `json:"people"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+// MergeResult - итог слияния двух людей в одного
+type MergeResult struct {
+	Person    Person         `json:"person"`    // оставшийся человек после слияния
+	MergedID  int            `json:"merged_id"` // ID второго, он перенесён в корзину
+	Repointed []Relationship `json:"repointed"` // связи второго, перенесённые на оставшегося
+	Removed   []Relationship `json:"removed"`   // повторы и связи между ними двумя - в корзине
+}
+
+// TrashedPerson - человек в корзине
+type TrashedPerson struct {
+	Person
+	DeletedAt string `json:"deleted_at" db:"deleted_at"`
+}
+
+// TrashedRelationship - связь в корзине
+type TrashedRelationship struct {
+	Relationship
+	DeletedAt string `json:"deleted_at" db:"deleted_at"`
+}
+
+// Trash - содержимое корзины дерева, недавно удалённые первыми
+type Trash struct {
+	People        []TrashedPerson       `json:"people"`
+	Relationships []TrashedRelationship `json:"relationships"`
+}
+
+// PurgeResult - сколько записей стёрто окончательно
+type PurgeResult struct {
+	People        int `json:"people"`
+	Relationships int `json:"relationships"`
+}
+
+// Event - событие жизни человека (рождение, крещение, смерть...) или пары (помолвка, брак, развод).
+// Задан ровно один из PersonID и RelationshipID.
+type Event struct {
+	ID             int    `json:"id" db:"id"`
+	PersonID       *int   `json:"person_id,omitempty" db:"person_id"`
+	RelationshipID *int   `json:"relationship_id,omitempty" db:"relationship_id"`
+	Type           string `json:"type" db:"type"`
+	Date           string `json:"date" db:"date"` // в любой записи, которую понимает ParseDate
+	Place          string `json:"place" db:"place"`       // место как записано в документе
+	PlaceID        *int   `json:"place_id" db:"place_id"` // место из справочника мест дерева
+	Description    string `json:"description" db:"description"`
+}
+
+// Типы событий человека
+const (
+	EventBirth     = "birth"     // рождение
+	EventBaptism   = "baptism"   // крещение
+	EventDeath     = "death"     // смерть
+	EventBurial    = "burial"    // погребение
+	EventMigration = "migration" // переезд
+	EventResidence = "residence" // проживание
+)
+
+// Типы событий пары
+const (
+	EventEngagement = "engagement" // помолвка
+	EventMarriage   = "marriage"   // брак
+	EventDivorce    = "divorce"    // развод
+)
+
+// PersonEventTypes и RelationshipEventTypes - допустимые типы событий человека и пары
+var (
+	PersonEventTypes       = []string{EventBirth, EventBaptism, EventDeath, EventBurial, EventMigration, EventResidence}
+	RelationshipEventTypes = []string{EventEngagement, EventMarriage, EventDivorce}
+)
+
+// Place - место из справочника дерева. Места вложены друг в друга по
+// историческому делению (деревня -> волость -> уезд -> губерния) или по
+// нынешнему (село -> район -> область -> страна).
+type Place struct {
+	ID         int         `json:"id" db:"id"`
+	ParentID   *int        `json:"parent_id" db:"parent_id"`
+	Name       string      `json:"name" db:"name"`
+	Type       string      `json:"type" db:"type"` // см. PlaceTypes; пусто - неизвестно
+	Latitude   *float64    `json:"latitude" db:"latitude"`
+	Longitude  *float64    `json:"longitude" db:"longitude"`
+	GeoNamesID *int        `json:"geonames_id" db:"geonames_id"` // запись справочника GeoNames, из которой взято место
+	Names      []PlaceName `json:"names"`                        // другие названия: прежние, на других языках
+	FullName   string      `json:"full_name"`                    // "Горки, Бежецкий уезд, Тверская губерния" - только для чтения
+}
+
+// PlaceName - другое название места
+type PlaceName struct {
+	Name string `json:"name"`
+	Lang string `json:"lang"` // код языка: ru, en, pl, de...; пусто - неизвестен
+}
+
+// Типы мест
+const (
+	PlaceVillage   = "village"   // деревня, село
+	PlaceCity      = "city"      // город
+	PlaceVolost    = "volost"    // волость
+	PlaceUyezd     = "uyezd"     // уезд
+	PlaceGuberniya = "guberniya" // губерния
+	PlaceDistrict  = "district"  // район
+	PlaceRegion    = "region"    // область, край, республика
+	PlaceCountry   = "country"   // страна
+	PlaceParish    = "parish"    // приход
+	PlaceCemetery  = "cemetery"  // кладбище
+)
+
+// PlaceTypes - допустимые типы мест
+var PlaceTypes = []string{PlaceVillage, PlaceCity, PlaceVolost, PlaceUyezd, PlaceGuberniya, PlaceDistrict, PlaceRegion, PlaceCountry, PlaceParish, PlaceCemetery}
+
+// PlaceMergeResult - итог слияния двух мест в одно
+type PlaceMergeResult struct {
+	Place    Place `json:"place"`     // оставшееся место
+	MergedID int   `json:"merged_id"` // ID удалённого места
+	People   int   `json:"people"`    // у скольких людей место заменено
+	Events   int   `json:"events"`    // у скольких событий
+	Children int   `json:"children"`  // сколько вложенных мест перенесено
+}
+
+// GazetteerEntry - запись офлайн-справочника GeoNames (geonames.org), общего для всех деревьев
+type GazetteerEntry struct {
+	GeoNamesID     int      `json:"geonames_id"`
+	Name           string   `json:"name"`
+	ASCIIName      string   `json:"ascii_name"`
+	AlternateNames []string `json:"alternate_names"`
+	Latitude       float64  `json:"latitude"`
+	Longitude      float64  `json:"longitude"`
+	FeatureClass   string   `json:"feature_class"` // P - населённый пункт, A - административная единица...
+	FeatureCode    string   `json:"feature_code"`  // PPL, PPLA, ADM1, PCLI...
+	CountryCode    string   `json:"country_code"`
+	Admin1         string   `json:"admin1"` // код региона первого уровня
+	Population     int64    `json:"population"`
+}
+
+// Source - источник сведений: архивное дело, книга, сайт, интервью. Один
+// источник (например, метрическая книга за год) подтверждает много фактов.
+type Source struct {
+	ID          int    `json:"id" db:"id"`
+	Type        string `json:"type" db:"type"`               // см. SourceTypes; пусто - не указан
+	Title       string `json:"title" db:"title"`             // "Метрическая книга Покровской церкви с. Горки за 1880 г."
+	Author      string `json:"author" db:"author"`           // автор книги, сайта; рассказчик в интервью
+	Publication string `json:"publication" db:"publication"` // издательство и год, дата интервью
+	Archive     string `json:"archive" db:"archive"`         // архив: "ГАТО", "РГИА"
+	Fond        string `json:"fond" db:"fond"`               // фонд
+	Opis        string `json:"opis" db:"opis"`               // опись
+	Delo        string `json:"delo" db:"delo"`               // дело (единица хранения)
+	URL         string `json:"url" db:"url"`
+	Notes       string `json:"notes" db:"notes"`
+}
+
+// Типы источников
+const (
+	SourceArchive   = "archive"   // архивный документ
+	SourceBook      = "book"      // книга, статья
+	SourceWebsite   = "website"   // сайт, база данных в интернете
+	SourceInterview = "interview" // рассказ родственника
+)
+
+// SourceTypes - допустимые типы источников
+var SourceTypes = []string{SourceArchive, SourceBook, SourceWebsite, SourceInterview}
+
+// Citation - ссылка на источник, подтверждающая сведения о человеке, связи
+// или событии (задано одно из PersonID, RelationshipID, EventID)
+type Citation struct {
+	ID             int    `json:"id" db:"id"`
+	SourceID       int    `json:"source_id" db:"source_id"`
+	PersonID       *int   `json:"person_id" db:"person_id"`
+	RelationshipID *int   `json:"relationship_id" db:"relationship_id"`
+	EventID        *int   `json:"event_id" db:"event_id"`
+	Page           string `json:"page" db:"page"`             // где в источнике: "л. 45 об., запись № 12"
+	Text           string `json:"text" db:"text"`             // выписка из источника
+	Confidence     string `json:"confidence" db:"confidence"` // см. Confidences; пусто - не оценена
+	Notes          string `json:"notes" db:"notes"`
+}
+
+// Насколько ссылка подтверждает факт (QUAY в GEDCOM: 3, 2, 1, 0)
+const (
+	ConfidencePrimary      = "primary"      // прямое свидетельство: запись современника события
+	ConfidenceSecondary    = "secondary"    // запись сделана позже, со слов других
+	ConfidenceQuestionable = "questionable" // сомнительно: косвенные сведения, семейное предание
+	ConfidenceUnreliable   = "unreliable"   // ненадёжно или, скорее всего, ошибочно
+)
+
+// Confidences - допустимые оценки, от самой надёжной
+var Confidences = []string{ConfidencePrimary, ConfidenceSecondary, ConfidenceQuestionable, ConfidenceUnreliable}
+
+// Media - загруженный файл дерева: фотография, скан документа, запись
+// рассказа. Содержимое лежит в хранилище файлов под ключом Hash, поэтому
+// одинаковые файлы хранятся один раз. Файл можно связать с любым числом
+// людей, связей и событий.
+type Media struct {
+	ID              int    `json:"id" db:"id"`
+	Hash            string `json:"hash" db:"hash"`                 // SHA-256 содержимого, hex
+	ContentType     string `json:"content_type" db:"content_type"` // определён по содержимому, а не по имени файла
+	Size            int64  `json:"size" db:"size"`
+	FileName        string `json:"file_name" db:"file_name"` // как файл назывался у загрузившего
+	Title           string `json:"title" db:"title"`
+	Description     string `json:"description" db:"description"`
+	Width           int    `json:"width" db:"width"` // у изображений, с учётом поворота из EXIF; 0 - неизвестно
+	Height          int    `json:"height" db:"height"`
+	TakenAt         string `json:"taken_at" db:"taken_at"` // дата съёмки из EXIF (ГГГГ-ММ-ДД) - подсказка для даты события
+	HasGPS          bool   `json:"has_gps" db:"has_gps"`   // в EXIF остались координаты съёмки
+	CreatedAt       string `json:"created_at" db:"created_at"`
+	PersonIDs       []int  `json:"person_ids"` // с кем связан файл (без тех, кто в корзине)
+	RelationshipIDs []int  `json:"relationship_ids"`
+	EventIDs        []int  `json:"event_ids"`
+}
@@ -0,0 +1,177 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"family-tree-app/internal/auth"
+	"family-tree-app/internal/media"
+	"family-tree-app/internal/models"
+)
+
+// upload загружает content в дерево treeID и возвращает код ответа и файл
+func (s *server) upload(userID, treeID int, content []byte) (int, models.Media) {
+	s.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "photo.png")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	part.Write(content)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/trees/%d/media", treeID), &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	token, err := auth.GenerateToken(userID)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+
+	var m models.Media
+	if rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	return rec.Code, m
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pausedStorage после записи ключа pause сообщает об этом в written
+// и ждёт release - так загрузка застывает между записью содержимого
+// и записью файла в базу
+type pausedStorage struct {
+	*media.MemoryStorage
+	pause   string
+	written chan struct{}
+	release chan struct{}
+}
+
+func (s *pausedStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := s.MemoryStorage.Put(ctx, key, r, size, contentType); err != nil {
+		return err
+	}
+	if key == s.pause {
+		close(s.written)
+		<-s.release
+	}
+	return nil
+}
+
+// Удаление файла в одном дереве не стирает то же содержимое, которое
+// другое дерево в это время загружает
+func TestMediaDeleteDuringUpload(t *testing.T) {
+	files := &pausedStorage{MemoryStorage: media.NewMemory(), written: make(chan struct{}), release: make(chan struct{})}
+	s := newServerWithFiles(t, files)
+	ownerID := s.user("owner@example.com")
+	first, err := s.store.CreateTree(context.Background(), ownerID, "Первое")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.store.CreateTree(context.Background(), ownerID, "Второе")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := pngBytes(t)
+	code, old := s.upload(ownerID, first.ID, content)
+	if code != http.StatusCreated {
+		t.Fatalf("загрузка в первое дерево: %d", code)
+	}
+
+	files.pause = old.Hash
+	uploaded := make(chan int)
+	go func() {
+		code, _ := s.upload(ownerID, second.ID, content)
+		uploaded <- code
+	}()
+	<-files.written
+
+	deleted := make(chan int)
+	go func() {
+		deleted <- s.do(ownerID, http.MethodDelete, fmt.Sprintf("/api/trees/%d/media/%d", first.ID, old.ID), nil, nil)
+	}()
+	// Без блокировки удаление успело бы стереть содержимое за это время
+	time.Sleep(50 * time.Millisecond)
+	close(files.release)
+
+	if code := <-uploaded; code != http.StatusCreated {
+		t.Errorf("загрузка во второе дерево: %d, ожидалось 201", code)
+	}
+	if code := <-deleted; code != http.StatusOK {
+		t.Errorf("удаление из первого дерева: %d, ожидалось 200", code)
+	}
+	rc, err := files.Open(context.Background(), old.Hash)
+	if err != nil {
+		t.Fatalf("содержимое файла второго дерева стёрто: %v", err)
+	}
+	rc.Close()
+}
+
+// Портрет - загруженный файл своего дерева; удаление файла снимает портрет
+func TestPersonPortrait(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	ownerID := s.user("owner@example.com")
+	tree, err := s.store.CreateTree(ctx, ownerID, "Петровы")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.store.CreateTree(ctx, ownerID, "Сидоровы")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, photo := s.upload(ownerID, tree.ID, pngBytes(t))
+	if code != http.StatusCreated {
+		t.Fatalf("загрузка портрета: %d", code)
+	}
+	code, foreign := s.upload(ownerID, other.ID, pngBytes(t))
+	if code != http.StatusCreated {
+		t.Fatalf("загрузка в другое дерево: %d", code)
+	}
+
+	base := fmt.Sprintf("/api/trees/%d", tree.ID)
+	if code := s.do(ownerID, http.MethodPost, base+"/people", models.Person{FirstName: "Иван", PhotoMediaID: &foreign.ID}, nil); code != http.StatusBadRequest {
+		t.Errorf("портрет из другого дерева: %d, ожидалось 400", code)
+	}
+	var person models.Person
+	if code := s.do(ownerID, http.MethodPost, base+"/people", models.Person{FirstName: "Иван", PhotoMediaID: &photo.ID}, &person); code != http.StatusCreated {
+		t.Fatalf("человек с портретом: %d", code)
+	}
+
+	if code := s.do(ownerID, http.MethodDelete, fmt.Sprintf("%s/media/%d", base, photo.ID), nil, nil); code != http.StatusOK {
+		t.Fatalf("удаление файла: %d", code)
+	}
+	var people []models.Person
+	if code := s.do(ownerID, http.MethodGet, base+"/people", nil, &people); code != http.StatusOK {
+		t.Fatalf("GET people = %d", code)
+	}
+	if len(people) != 1 || people[0].PhotoMediaID != nil {
+		t.Errorf("люди после удаления портрета %+v, ожидалось без портрета", people)
+	}
+	// Содержимое осталось у файла другого дерева
+	if code := s.do(ownerID, http.MethodGet, fmt.Sprintf("/api/trees/%d/media/%d/file", other.ID, foreign.ID), nil, nil); code != http.StatusOK {
+		t.Errorf("файл другого дерева после удаления такого же: %d, ожидалось 200", code)
+	}
+}
@@ -0,0 +1,91 @@
+package routes_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"family-tree-app/internal/models"
+)
+
+// Место из справочника GeoNames: поиск, заведение в дерево и слияние с уже записанным
+func TestPlacesFromGazetteer(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	ownerID := s.user("owner@example.com")
+	tree, err := s.store.CreateTree(ctx, ownerID, "Петровы")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tver := models.GazetteerEntry{GeoNamesID: 480060, Name: "Tver", ASCIIName: "Tver", AlternateNames: []string{"Тверь", "Калинин", "Twer"},
+		Latitude: 56.86, Longitude: 35.9, CountryCode: "RU", FeatureClass: "P", FeatureCode: "PPLA", Population: 400000}
+	if err := s.store.ImportGazetteer(ctx, []models.GazetteerEntry{tver}); err != nil {
+		t.Fatal(err)
+	}
+	base := fmt.Sprintf("/api/trees/%d/places", tree.ID)
+
+	for _, tt := range []struct {
+		query string
+		want  int
+		found int
+	}{
+		{"?q=Тверь", http.StatusOK, 1},
+		{"?q=Тверь&country=UA", http.StatusOK, 0},
+		{"?q=Тверь&limit=0", http.StatusBadRequest, 0},
+		{"?q=Тверь&limit=101", http.StatusBadRequest, 0},
+	} {
+		var found []models.GazetteerEntry
+		if code := s.do(ownerID, http.MethodGet, base+"/geocode"+tt.query, nil, &found); code != tt.want || len(found) != tt.found {
+			t.Errorf("GET geocode%s = %d, найдено %d; ожидалось %d, %d", tt.query, code, len(found), tt.want, tt.found)
+		}
+	}
+
+	var place models.Place
+	if code := s.do(ownerID, http.MethodPost, base, map[string]int{"geonames_id": tver.GeoNamesID}, &place); code != http.StatusCreated {
+		t.Fatalf("место из справочника: %d", code)
+	}
+	if place.Name != "Tver" || place.Type != models.PlaceCity || place.Latitude == nil || *place.Latitude != tver.Latitude {
+		t.Errorf("место из справочника %+v, ожидался город Tver с координатами", place)
+	}
+	names := map[string]bool{}
+	for _, n := range place.Names {
+		names[n.Name] = true
+	}
+	if !names["Тверь"] || !names["Калинин"] || names["Twer"] {
+		t.Errorf("другие названия %v, ожидались кириллические без Twer", place.Names)
+	}
+	if code := s.do(ownerID, http.MethodPost, base, map[string]int{"geonames_id": 1}, nil); code != http.StatusBadRequest {
+		t.Errorf("неизвестная запись справочника: %d, ожидалось 400", code)
+	}
+
+	// Место, уже записанное под другим названием, не заводится второй раз
+	var same models.Place
+	if code := s.do(ownerID, http.MethodPost, base, map[string]string{"name": "г. Калинин"}, &same); code != http.StatusOK || same.ID != place.ID {
+		t.Errorf("повтор места: %d, место %d; ожидалось 200 и %d", code, same.ID, place.ID)
+	}
+
+	var written models.Place
+	if code := s.do(ownerID, http.MethodPost, base, map[string]string{"name": "Тверской посад"}, &written); code != http.StatusCreated {
+		t.Fatalf("место без справочника: %d", code)
+	}
+	var result models.PlaceMergeResult
+	if code := s.do(ownerID, http.MethodPost, base+"/merge", map[string]int{"keep_id": place.ID, "merge_id": written.ID}, &result); code != http.StatusOK {
+		t.Fatalf("слияние мест: %d", code)
+	}
+	if result.MergedID != written.ID || result.Place.GeoNamesID == nil || *result.Place.GeoNamesID != tver.GeoNamesID {
+		t.Errorf("слияние мест = %+v", result)
+	}
+	for _, tt := range []struct {
+		name string
+		body map[string]int
+		want int
+	}{
+		{"с удалённым местом", map[string]int{"keep_id": place.ID, "merge_id": written.ID}, http.StatusNotFound},
+		{"с самим собой", map[string]int{"keep_id": place.ID, "merge_id": place.ID}, http.StatusUnprocessableEntity},
+	} {
+		if code := s.do(ownerID, http.MethodPost, base+"/merge", tt.body, nil); code != tt.want {
+			t.Errorf("слияние %s: %d, ожидалось %d", tt.name, code, tt.want)
+		}
+	}
+}
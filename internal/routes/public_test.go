@@ -0,0 +1,78 @@
+package routes_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+// По публичной ссылке живые люди скрыты вместе с портретами
+func TestPublicLinkHidesLiving(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	ownerID := s.user("owner@example.com")
+	tree, err := s.store.CreateTree(ctx, ownerID, "Петровы")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, photo := s.upload(ownerID, tree.ID, pngBytes(t))
+	if code != http.StatusCreated {
+		t.Fatalf("загрузка портрета: %d", code)
+	}
+	died := "1960"
+	dead := models.Person{FirstName: "Иван", LastName: "Петров", Gender: "male", BirthDate: "1890", DeathDate: &died, PhotoMediaID: &photo.ID}
+	living := models.Person{FirstName: "Мария", LastName: "Петрова", Gender: "female", BirthDate: "1990", PhotoMediaID: &photo.ID}
+	for _, p := range []*models.Person{&dead, &living} {
+		if err := s.store.CreatePerson(ctx, tree.ID, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var link models.ShareLink
+	if code := s.do(ownerID, http.MethodPost, fmt.Sprintf("/api/trees/%d/share-links", tree.ID), map[string]int{}, &link); code != http.StatusCreated {
+		t.Fatalf("создание публичной ссылки: %d", code)
+	}
+
+	var public struct {
+		People []models.Person `json:"people"`
+	}
+	if code := s.do(0, http.MethodGet, "/api/public/"+link.Token+"/tree", nil, &public); code != http.StatusOK {
+		t.Fatalf("дерево по ссылке: %d", code)
+	}
+	names := map[int]string{}
+	for _, p := range public.People {
+		names[p.ID] = p.FirstName
+		if p.PhotoMediaID != nil {
+			t.Errorf("у человека %d по ссылке виден файл %d", p.ID, *p.PhotoMediaID)
+		}
+		if p.ID == living.ID && (p.LastName != "" || p.BirthDate != "" || p.PhotoURL != "") {
+			t.Errorf("живой человек по ссылке не скрыт: %+v", p)
+		}
+	}
+	if names[dead.ID] != "Иван" || names[living.ID] != genealogy.LivingName {
+		t.Errorf("имена по ссылке %v, ожидалось Иван и %s", names, genealogy.LivingName)
+	}
+
+	tests := []struct {
+		name     string
+		personID int
+		query    string
+		want     int
+	}{
+		{"портрет умершего", dead.ID, "", http.StatusOK},
+		{"портрет живого", living.ID, "", http.StatusNotFound},
+		{"миниатюра портрета живого", living.ID, "?size=256", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fmt.Sprintf("/api/public/%s/people/%d/photo%s", link.Token, tt.personID, tt.query)
+			if code := s.do(0, http.MethodGet, path, nil, nil); code != tt.want {
+				t.Errorf("GET %s = %d, ожидалось %d", path, code, tt.want)
+			}
+		})
+	}
+}
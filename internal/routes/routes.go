@@ -4,21 +4,160 @@ import (
 	"net/http"
 
 	"family-tree-app/internal/auth"
+	"family-tree-app/internal/config"
 	"family-tree-app/internal/handlers"
+	"family-tree-app/internal/media"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
-func NewRouter() http.Handler {
+// NewRouter собирает роутер. Хранилища передаются снаружи, поэтому
+// в тестах можно подставить store.NewMemory() вместо SQLite и
+// media.NewMemory() вместо диска или S3.
+func NewRouter(cfg *config.Config, st store.Store, files media.Storage) http.Handler {
 	r := chi.NewRouter()
 
+	authHandler := handlers.NewAuthHandler(st, cfg.CookieSecure)
+	people := handlers.NewPeopleHandler(st)
+	relationships := handlers.NewRelationshipHandler(st)
+	tree := handlers.NewTreeHandler(st, st, st, st)
+	gedcomHandler := handlers.NewGedcomHandler(st, st, st, st, st, st)
+	trees := handlers.NewTreesHandler(st, st)
+	sharing := handlers.NewSharingHandler(st, st)
+	public := handlers.NewPublicHandler(st, st, st, st)
+	history := handlers.NewHistoryHandler(st)
+	trash := handlers.NewTrashHandler(st)
+	duplicates := handlers.NewDuplicatesHandler(st, st)
+	events := handlers.NewEventsHandler(st)
+	places := handlers.NewPlacesHandler(st, st)
+	sources := handlers.NewSourcesHandler(st)
+	mediaFiles := handlers.NewMediaHandler(st, files, int64(cfg.MediaMaxMB)<<20)
+	trees.Media = mediaFiles
+	public.Media = mediaFiles
+
+	if cfg.StrictTreeChecks {
+		guard := handlers.NewConsistencyGuard(st, st)
+		people.Guard = guard
+		relationships.Guard = guard
+		duplicates.Guard = guard
+		events.Guard = guard
+	}
+
+	// treeRoutes - маршруты внутри одного дерева. ID дерева и роль кладёт в контекст
+	// RequireTree (/api/trees/{treeID}/...) или DefaultTree (старые пути).
+	// Чтение доступно любому участнику, изменения - редактору и владельцу,
+	// окончательное удаление из корзины - только владельцу.
+	treeRoutes := func(r chi.Router) {
+		r.Get("/people", people.GetAllPeople)
+		r.Get("/people/search", people.SearchPeople)
+		r.Get("/people/duplicates", duplicates.Find)
+		r.Get("/people/{a}/kinship/{b}", tree.Kinship)
+		r.Get("/people/{id}/ancestors", tree.Ancestors)
+		r.Get("/people/{id}/descendants", tree.Descendants)
+		r.Get("/relationships", relationships.GetAllRelationships)
+		r.Get("/people/{id}/events", events.ListPersonEvents)
+		r.Get("/relationships/{id}/events", events.ListRelationshipEvents)
+		r.Get("/places", places.List)
+		r.Get("/places/duplicates", places.Duplicates)
+		r.Get("/places/geocode", places.Geocode)
+		r.Get("/places/{id}", places.Get)
+		r.Get("/sources", sources.List)
+		r.Get("/sources/{id}", sources.Get)
+		r.Get("/sources/{id}/citations", sources.SourceCitations)
+		r.Get("/people/{id}/citations", sources.ListPersonCitations)
+		r.Get("/relationships/{id}/citations", sources.ListRelationshipCitations)
+		r.Get("/events/{id}/citations", sources.ListEventCitations)
+		r.Get("/media", mediaFiles.List)
+		r.Get("/media/{id}", mediaFiles.Get)
+		r.Get("/media/{id}/file", mediaFiles.Download)
+		r.Get("/media/{id}/thumbnail", mediaFiles.Thumbnail)
+		r.Get("/people/{id}/media", mediaFiles.ListPersonMedia)
+		r.Get("/relationships/{id}/media", mediaFiles.ListRelationshipMedia)
+		r.Get("/events/{id}/media", mediaFiles.ListEventMedia)
+		r.Get("/export/gedcom", gedcomHandler.Export)
+		r.Get("/people/{id}/history", history.PersonHistory)
+		r.Get("/history", history.TreeHistory)
+		r.Get("/trash", trash.List)
+
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.RequireRole(models.RoleEditor))
+
+			// Люди
+			r.Post("/people", people.CreatePerson)
+			r.Put("/people/{id}", people.UpdatePerson)
+			r.Delete("/people/{id}", people.DeletePerson)
+			r.Put("/people/position", people.SaveNodePosition)
+			r.Post("/people/merge", duplicates.Merge)
+
+			// Связи
+			r.Post("/relationships", relationships.CreateRelationship)
+			r.Put("/relationships/{id}", relationships.UpdateRelationship)
+			r.Delete("/relationships/{id}", relationships.DeleteRelationship)
+
+			// События
+			r.Post("/people/{id}/events", events.CreatePersonEvent)
+			r.Put("/people/{id}/events/{eventID}", events.UpdatePersonEvent)
+			r.Delete("/people/{id}/events/{eventID}", events.DeletePersonEvent)
+			r.Post("/relationships/{id}/events", events.CreateRelationshipEvent)
+			r.Put("/relationships/{id}/events/{eventID}", events.UpdateRelationshipEvent)
+			r.Delete("/relationships/{id}/events/{eventID}", events.DeleteRelationshipEvent)
+
+			// Места
+			r.Post("/places", places.Create)
+			r.Put("/places/{id}", places.Update)
+			r.Delete("/places/{id}", places.Delete)
+			r.Post("/places/merge", places.Merge)
+
+			// Источники
+			r.Post("/sources", sources.Create)
+			r.Put("/sources/{id}", sources.Update)
+			r.Delete("/sources/{id}", sources.Delete)
+			r.Post("/people/{id}/citations", sources.CreatePersonCitation)
+			r.Post("/relationships/{id}/citations", sources.CreateRelationshipCitation)
+			r.Post("/events/{id}/citations", sources.CreateEventCitation)
+			r.Put("/citations/{id}", sources.UpdateCitation)
+			r.Delete("/citations/{id}", sources.DeleteCitation)
+
+			// Файлы
+			r.Post("/media", mediaFiles.Upload)
+			r.Put("/media/{id}", mediaFiles.Update)
+			r.Delete("/media/{id}", mediaFiles.Delete)
+			r.Post("/people/{id}/media", mediaFiles.LinkPersonMedia)
+			r.Delete("/people/{id}/media/{mediaID}", mediaFiles.UnlinkPersonMedia)
+			r.Post("/relationships/{id}/media", mediaFiles.LinkRelationshipMedia)
+			r.Delete("/relationships/{id}/media/{mediaID}", mediaFiles.UnlinkRelationshipMedia)
+			r.Post("/events/{id}/media", mediaFiles.LinkEventMedia)
+			r.Delete("/events/{id}/media/{mediaID}", mediaFiles.UnlinkEventMedia)
+
+			// Импорт
+			r.Post("/import/gedcom", gedcomHandler.Import)
+
+			// Отмена изменений
+			r.Post("/history/{changeID}/revert", history.Revert)
+			r.Post("/people/{id}/restore", history.RestorePerson)
+
+			// Корзина
+			r.Post("/trash/{entity}/{id}/restore", trash.Restore)
+		})
+
+		// Окончательное удаление из корзины - только владелец
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.RequireRole(models.RoleOwner))
+
+			r.Delete("/trash/{entity}/{id}", trash.Purge)
+			r.Delete("/trash", trash.Empty)
+		})
+	}
+
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
+		AllowedOrigins:   cfg.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
@@ -29,29 +168,56 @@ func NewRouter() http.Handler {
 	r.Route("/api", func(r chi.Router) {
 		
 		// --- ПУБЛИЧНЫЕ ---
-		r.Post("/register", handlers.Register)
-		r.Post("/login", handlers.Login)
-		r.Post("/logout", handlers.Logout)
+		r.Post("/register", authHandler.Register)
+		r.Post("/login", authHandler.Login)
+		r.Post("/logout", authHandler.Logout)
+		r.Get("/public/{token}/tree", public.Tree) // публичная ссылка, живые люди скрыты
+		r.Get("/public/{token}/people/{id}/photo", public.Photo)
 
 		// --- ЗАЩИЩЕННЫЕ ---
 		r.Group(func(r chi.Router) {
 			r.Use(auth.AuthMiddleware)
 
-			r.Get("/me", handlers.Me)
+			r.Get("/me", authHandler.Me)
 
-			// Люди
-			r.Post("/people", handlers.CreatePerson)
-			r.Get("/people", handlers.GetAllPeople)
-			r.Put("/people/{id}", handlers.UpdatePerson)
-			r.Delete("/people/{id}", handlers.DeletePerson)
+			// Деревья пользователя (свои и общие)
+			r.Get("/trees", trees.ListTrees)
+			r.Post("/trees", trees.CreateTree)
+			r.Route("/trees/{treeID}", func(r chi.Router) {
+				r.Use(trees.RequireTree)
 
-			// Связи
-			r.Post("/relationships", handlers.CreateRelationship)
-			r.Get("/relationships", handlers.GetAllRelationships)
-			r.Put("/relationships/{id}", handlers.UpdateRelationship)
-			r.Delete("/relationships/{id}", handlers.DeleteRelationship)
-			
-			r.Put("/people/position", handlers.SaveNodePosition)
+				r.Post("/duplicate", trees.DuplicateTree)
+				r.Get("/lint", tree.Lint)
+				r.Get("/members", sharing.ListMembers)
+				r.Delete("/members/{userID}", sharing.RemoveMember) // владелец или сам участник
+				treeRoutes(r)
+
+				// Управление деревом и доступом - только владелец
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.RequireRole(models.RoleOwner))
+
+					r.Put("/", trees.RenameTree)
+					r.Delete("/", trees.DeleteTree)
+					r.Put("/members/{userID}", sharing.UpdateMember)
+					r.Get("/invites", sharing.ListInvites)
+					r.Post("/invites", sharing.CreateInvite)
+					r.Delete("/invites/{inviteID}", sharing.DeleteInvite)
+					r.Get("/share-links", public.ListShareLinks)
+					r.Post("/share-links", public.CreateShareLink)
+					r.Delete("/share-links/{linkID}", public.RevokeShareLink)
+				})
+			})
+
+			// Приглашения текущего пользователя
+			r.Post("/invites/accept", sharing.AcceptInvite)
+
+			// Старые маршруты без {treeID} работают с деревом по умолчанию
+			r.Group(func(r chi.Router) {
+				r.Use(trees.DefaultTree)
+
+				r.Get("/tree/lint", tree.Lint)
+				treeRoutes(r)
+			})
 		})
 	})
 
@@ -0,0 +1,248 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"family-tree-app/internal/auth"
+	"family-tree-app/internal/config"
+	"family-tree-app/internal/media"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/routes"
+	"family-tree-app/internal/store"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Тесты прав доступа: запросы идут через весь роутер с middleware,
+// как у настоящего сервера, но на MemoryStore и файлах в памяти.
+
+type server struct {
+	t       *testing.T
+	handler http.Handler
+	store   *store.MemoryStore
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	return newServerWithFiles(t, media.NewMemory())
+}
+
+func newServerWithFiles(t *testing.T, files media.Storage) *server {
+	t.Helper()
+	if err := auth.Configure("routes-test-secret-0123456789abcdef", bcrypt.MinCost); err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemory()
+	return &server{t: t, handler: routes.NewRouter(config.Default(), st, files), store: st}
+}
+
+// user заводит пользователя; пароль не нужен - запросы идут с готовым токеном
+func (s *server) user(email string) int {
+	s.t.Helper()
+	id, err := s.store.CreateUser(context.Background(), email, "hash")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return id
+}
+
+// do выполняет запрос от имени userID (0 - без авторизации) и, если out
+// не nil, разбирает JSON ответа
+func (s *server) do(userID int, method, path string, body interface{}, out interface{}) int {
+	s.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		token, err := auth.GenerateToken(userID)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: %v в ответе %q", method, path, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+// invite выписывает приглашение от владельца и возвращает токен
+func (s *server) invite(ownerID, treeID int, email, role string) string {
+	s.t.Helper()
+	var inv models.TreeInvite
+	path := fmt.Sprintf("/api/trees/%d/invites", treeID)
+	if code := s.do(ownerID, http.MethodPost, path, map[string]string{"email": email, "role": role}, &inv); code != http.StatusCreated {
+		s.t.Fatalf("POST %s = %d", path, code)
+	}
+	return inv.Token
+}
+
+func TestInviteAcceptance(t *testing.T) {
+	s := newServer(t)
+	ownerID, guestID, otherID := s.user("owner@example.com"), s.user("guest@example.com"), s.user("other@example.com")
+	tree, err := s.store.CreateTree(context.Background(), ownerID, "Петровы")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := s.invite(ownerID, tree.ID, "", models.RoleViewer)
+	if code := s.do(guestID, http.MethodPost, "/api/invites/accept", map[string]string{"token": open}, nil); code != http.StatusOK {
+		t.Fatalf("принятие приглашения: %d, ожидалось 200", code)
+	}
+	if code := s.do(otherID, http.MethodPost, "/api/invites/accept", map[string]string{"token": open}, nil); code != http.StatusNotFound {
+		t.Errorf("повторное принятие приглашения: %d, ожидалось 404", code)
+	}
+
+	// Приглашение на email: без токена его не принять, с токеном - только этому адресу
+	scoped := s.invite(ownerID, tree.ID, "other@example.com", models.RoleEditor)
+	if code := s.do(otherID, http.MethodPost, "/api/invites/accept", map[string]string{}, nil); code != http.StatusBadRequest {
+		t.Errorf("принятие без токена: %d, ожидалось 400", code)
+	}
+	if code := s.do(guestID, http.MethodPost, "/api/invites/accept", map[string]string{"token": scoped}, nil); code != http.StatusNotFound {
+		t.Errorf("приглашение на чужой email: %d, ожидалось 404", code)
+	}
+	if code := s.do(otherID, http.MethodPost, "/api/invites/accept", map[string]string{"token": scoped}, nil); code != http.StatusOK {
+		t.Errorf("приглашение на свой email: %d, ожидалось 200", code)
+	}
+
+	for userID, want := range map[int]string{guestID: models.RoleViewer, otherID: models.RoleEditor} {
+		if role, err := s.store.GetMemberRole(context.Background(), tree.ID, userID); err != nil || role != want {
+			t.Errorf("роль участника %d: %q, %v; ожидалось %q", userID, role, err, want)
+		}
+	}
+}
+
+func TestTreeRoles(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	ownerID := s.user("owner@example.com")
+	editorID, viewerID, outsiderID := s.user("editor@example.com"), s.user("viewer@example.com"), s.user("outsider@example.com")
+	tree, err := s.store.CreateTree(ctx, ownerID, "Петровы")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for userID, role := range map[int]string{editorID: models.RoleEditor, viewerID: models.RoleViewer} {
+		token := s.invite(ownerID, tree.ID, "", role)
+		if code := s.do(userID, http.MethodPost, "/api/invites/accept", map[string]string{"token": token}, nil); code != http.StatusOK {
+			t.Fatalf("принятие приглашения %s: %d", role, code)
+		}
+	}
+	person := models.Person{FirstName: "Иван", LastName: "Петров", Gender: "male"}
+	if err := s.store.CreatePerson(ctx, tree.ID, &person); err != nil {
+		t.Fatal(err)
+	}
+
+	base := fmt.Sprintf("/api/trees/%d", tree.ID)
+	newPerson := map[string]string{"first_name": "Пётр", "last_name": "Петров", "gender": "male"}
+	tests := []struct {
+		name   string
+		userID int
+		method string
+		path   string
+		body   interface{}
+		want   int
+	}{
+		{"зритель читает людей", viewerID, http.MethodGet, "/people", nil, http.StatusOK},
+		{"зритель читает журнал", viewerID, http.MethodGet, "/history", nil, http.StatusOK},
+		{"зритель не добавляет человека", viewerID, http.MethodPost, "/people", newPerson, http.StatusForbidden},
+		{"зритель не правит человека", viewerID, http.MethodPut, fmt.Sprintf("/people/%d", person.ID), newPerson, http.StatusForbidden},
+		{"зритель не удаляет человека", viewerID, http.MethodDelete, fmt.Sprintf("/people/%d", person.ID), nil, http.StatusForbidden},
+		{"зритель не добавляет связь", viewerID, http.MethodPost, "/relationships", map[string]interface{}{"from_person_id": person.ID, "to_person_id": person.ID, "type": "parent"}, http.StatusForbidden},
+		{"зритель не удаляет связь", viewerID, http.MethodDelete, "/relationships/1", nil, http.StatusForbidden},
+		{"зритель не отменяет изменения", viewerID, http.MethodPost, "/history/1/revert", nil, http.StatusForbidden},
+		{"зритель не приглашает", viewerID, http.MethodPost, "/invites", map[string]string{"role": "viewer"}, http.StatusForbidden},
+		{"редактор добавляет человека", editorID, http.MethodPost, "/people", newPerson, http.StatusCreated},
+		{"редактор не чистит корзину", editorID, http.MethodDelete, "/trash", nil, http.StatusForbidden},
+		{"редактор не стирает из корзины", editorID, http.MethodDelete, fmt.Sprintf("/trash/person/%d", person.ID), nil, http.StatusForbidden},
+		{"редактор не видит публичные ссылки", editorID, http.MethodGet, "/share-links", nil, http.StatusForbidden},
+		{"редактор не создаёт публичную ссылку", editorID, http.MethodPost, "/share-links", map[string]int{}, http.StatusForbidden},
+		{"редактор не видит приглашения", editorID, http.MethodGet, "/invites", nil, http.StatusForbidden},
+		{"редактор не меняет роли", editorID, http.MethodPut, fmt.Sprintf("/members/%d", viewerID), map[string]string{"role": "editor"}, http.StatusForbidden},
+		{"редактор не исключает других", editorID, http.MethodDelete, fmt.Sprintf("/members/%d", viewerID), nil, http.StatusForbidden},
+		{"редактор не переименовывает дерево", editorID, http.MethodPut, "/", map[string]string{"name": "Чужое"}, http.StatusForbidden},
+		{"редактор не удаляет дерево", editorID, http.MethodDelete, "/", nil, http.StatusForbidden},
+		{"владелец видит публичные ссылки", ownerID, http.MethodGet, "/share-links", nil, http.StatusOK},
+		{"владелец видит участников", ownerID, http.MethodGet, "/members", nil, http.StatusOK},
+		{"владелец чистит корзину", ownerID, http.MethodDelete, "/trash", nil, http.StatusOK},
+		{"чужой не видит дерево", outsiderID, http.MethodGet, "/people", nil, http.StatusNotFound},
+		{"чужой не пишет в дерево", outsiderID, http.MethodPost, "/people", newPerson, http.StatusNotFound},
+		{"без входа", 0, http.MethodGet, "/people", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := base + tt.path
+			if code := s.do(tt.userID, tt.method, path, tt.body, nil); code != tt.want {
+				t.Errorf("%s %s = %d, ожидалось %d", tt.method, path, code, tt.want)
+			}
+		})
+	}
+
+	// Старые пути без {treeID} ведут в собственное дерево, а не в общее
+	var people []models.Person
+	if code := s.do(viewerID, http.MethodGet, "/api/people", nil, &people); code != http.StatusOK {
+		t.Fatalf("GET /api/people = %d", code)
+	}
+	if len(people) != 0 {
+		t.Errorf("в дереве по умолчанию зрителя люди чужого дерева: %+v", people)
+	}
+	if code := s.do(viewerID, http.MethodPost, "/api/people", newPerson, nil); code != http.StatusCreated {
+		t.Errorf("POST /api/people в своё дерево = %d, ожидалось 201", code)
+	}
+}
+
+// Старые даты, которые не разобрать, не мешают править человека, пока их не трогают
+func TestUpdatePersonLegacyDates(t *testing.T) {
+	s := newServer(t)
+	ownerID := s.user("owner@example.com")
+	tree, err := s.store.CreateTree(context.Background(), ownerID, "Петровы")
+	if err != nil {
+		t.Fatal(err)
+	}
+	death := "ок. 1950"
+	person := models.Person{FirstName: "Иван", LastName: "Петров", Gender: "male", BirthDate: "1890?", DeathDate: &death}
+	if err := s.store.CreatePerson(context.Background(), tree.ID, &person); err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/api/trees/%d/people/%d", tree.ID, person.ID)
+	otherDeath := "1950?"
+	tests := []struct {
+		name  string
+		birth string
+		death *string
+		want  int
+	}{
+		{"даты не менялись", "1890?", &death, http.StatusOK},
+		{"дата смерти стёрта", "1890?", nil, http.StatusOK},
+		{"новая дата рождения", "1891?", nil, http.StatusBadRequest},
+		{"новая дата смерти", "1890?", &otherDeath, http.StatusBadRequest},
+		{"понятная дата рождения", "1890", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := models.Person{FirstName: "Иоанн", LastName: "Петров", Gender: "male", BirthDate: tt.birth, DeathDate: tt.death}
+			if code := s.do(ownerID, http.MethodPut, path, body, nil); code != tt.want {
+				t.Errorf("PUT %s = %d, ожидалось %d", path, code, tt.want)
+			}
+		})
+	}
+
+	if code := s.do(ownerID, http.MethodPost, fmt.Sprintf("/api/trees/%d/people", tree.ID), models.Person{FirstName: "Пётр", BirthDate: "1890?"}, nil); code != http.StatusBadRequest {
+		t.Errorf("новый человек с непонятной датой: %d, ожидалось 400", code)
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"sort"
+
+	"family-tree-app/internal/models"
+)
+
+// isLifeEvent - дата и место события хранятся и у самого человека (BirthDate, BirthPlaceID...)
+func isLifeEvent(eventType string) bool {
+	return eventType == models.EventBirth || eventType == models.EventDeath
+}
+
+// lifeFacts - дата и место человека для события eventType
+func lifeFacts(p models.Person, eventType string) (date string, placeID *int) {
+	if eventType == models.EventBirth {
+		return p.BirthDate, p.BirthPlaceID
+	}
+	if p.DeathDate != nil {
+		date = *p.DeathDate
+	}
+	return date, p.DeathPlaceID
+}
+
+// setLifeFacts записывает человеку дату и место события eventType; пустая дата смерти - nil
+func setLifeFacts(p *models.Person, eventType, date string, placeID *int) {
+	switch {
+	case eventType == models.EventBirth:
+		p.BirthDate, p.BirthPlaceID = date, placeID
+		return
+	case date == "":
+		p.DeathDate = nil
+	default:
+		p.DeathDate = &date
+	}
+	p.DeathPlaceID = placeID
+}
+
+// mergeLifeFacts - дата и место человека после изменения события: заполненное
+// в событии главнее, пустое берётся у человека
+func mergeLifeFacts(p models.Person, eventType, date string, placeID *int) (string, *int) {
+	personDate, personPlace := lifeFacts(p, eventType)
+	if date == "" {
+		date = personDate
+	}
+	if placeID == nil {
+		placeID = personPlace
+	}
+	return date, placeID
+}
+
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// sortEvents - по началу диапазона даты, события без даты - в конце, при равенстве - по ID
+func sortEvents(events []models.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		a, _ := models.ParseDate(events[i].Date)
+		b, _ := models.ParseDate(events[j].Date)
+		ka, kb := a.SortKey(), b.SortKey()
+		if ka != kb {
+			return kb == "" || (ka != "" && ka < kb)
+		}
+		return events[i].ID < events[j].ID
+	})
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+
+	"family-tree-app/internal/models"
+)
+
+// DefaultChangeLimit - сколько записей журнала отдавать, если лимит не задан
+const DefaultChangeLimit = 100
+
+// ChangeFilter - отбор записей журнала. Нулевые поля не ограничивают.
+type ChangeFilter struct {
+	PersonID   int    // изменения человека и его связей
+	EntityType string // вместе с EntityID - изменения одной сущности
+	EntityID   int
+	Action     string
+	BeforeID   int // для постраничного просмотра: записи старше этой
+	Limit      int
+}
+
+type actorKey struct{}
+
+// WithActor запоминает в контексте, кто выполняет изменения, - это
+// попадёт в журнал. Без него изменения пишутся как системные (user_id = NULL).
+func WithActor(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// actorFrom возвращает автора изменений или nil
+func actorFrom(ctx context.Context) *int {
+	if userID, ok := ctx.Value(actorKey{}).(int); ok && userID != 0 {
+		return &userID
+	}
+	return nil
+}
+
+// change - запись журнала до сохранения
+type change struct {
+	treeID       int
+	entityType   string
+	entityID     int
+	action       string
+	before       interface{} // nil - нет состояния (create)
+	after        interface{} // nil - нет состояния (delete)
+	fromID, toID int         // концы связи
+	causeID      int64       // 0 - нет
+	revertOf     int64       // 0 - нет
+	moved        *mergeMoves // перенесённое при слиянии, nil - ничего
+}
+
+func personChange(treeID int, action string, before, after *models.Person) change {
+	c := change{treeID: treeID, entityType: models.EntityPerson, action: action}
+	if before != nil {
+		c.before, c.entityID = before, before.ID
+	}
+	if after != nil {
+		c.after, c.entityID = after, after.ID
+	}
+	return c
+}
+
+func relationshipChange(treeID int, action string, before, after *models.Relationship) change {
+	c := change{treeID: treeID, entityType: models.EntityRelationship, action: action}
+	if before != nil {
+		c.before, c.entityID, c.fromID, c.toID = before, before.ID, before.FromPersonID, before.ToPersonID
+	}
+	if after != nil {
+		c.after, c.entityID, c.fromID, c.toID = after, after.ID, after.FromPersonID, after.ToPersonID
+	}
+	return c
+}
+
+func (c change) beforeJSON() (interface{}, error) { return marshalState(c.before) }
+func (c change) afterJSON() (interface{}, error)  { return marshalState(c.after) }
+
+func (c change) movedJSON() (interface{}, error) {
+	if c.moved == nil {
+		return nil, nil
+	}
+	return marshalState(c.moved)
+}
+
+// marshalState - JSON состояния или NULL
+func marshalState(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// personUnchanged сообщает, совпадает ли человек с состоянием после изменения c.
+// Координаты не сравниваются: перетаскивание на графе в журнал не пишется.
+// Отменять правку, поверх которой уже что-то поменяли, нельзя - это ErrRevertConflict.
+func personUnchanged(c models.Change, current models.Person) bool {
+	var after models.Person
+	if err := json.Unmarshal(c.After, &after); err != nil {
+		return false
+	}
+	after.PositionX, after.PositionY = 0, 0
+	current.PositionX, current.PositionY = 0, 0
+	return sameJSON(after, current)
+}
+
+// relationshipUnchanged - то же для связи
+func relationshipUnchanged(c models.Change, current models.Relationship) bool {
+	var after models.Relationship
+	if err := json.Unmarshal(c.After, &after); err != nil {
+		return false
+	}
+	return sameJSON(after, current)
+}
+
+func sameJSON(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,28 @@
+package store
+
+import "family-tree-app/internal/models"
+
+// addMediaLink дописывает владельца к связям файла
+func addMediaLink(m *models.Media, personID, relID, eventID *int) {
+	switch {
+	case personID != nil:
+		m.PersonIDs = append(m.PersonIDs, *personID)
+	case relID != nil:
+		m.RelationshipIDs = append(m.RelationshipIDs, *relID)
+	case eventID != nil:
+		m.EventIDs = append(m.EventIDs, *eventID)
+	}
+}
+
+// emptyMediaLinks - связи без владельцев приходят в JSON пустыми списками, а не null
+func emptyMediaLinks(m *models.Media) {
+	if m.PersonIDs == nil {
+		m.PersonIDs = []int{}
+	}
+	if m.RelationshipIDs == nil {
+		m.RelationshipIDs = []int{}
+	}
+	if m.EventIDs == nil {
+		m.EventIDs = []int{}
+	}
+}
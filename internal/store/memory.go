@@ -0,0 +1,592 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore - реализация Store в памяти. Нужна для быстрых тестов обработчиков.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	people        map[int]memPerson
+	relationships map[int]memRelationship
+	users         map[int]models.User
+	trees         map[int]models.Tree
+	members       map[memberKey]models.TreeMember
+	invites       map[int]memInvite
+	shareLinks    map[int]models.ShareLink
+	events        map[int]memEvent
+	places        map[int]memPlace
+	gazetteer     map[int]models.GazetteerEntry
+	sources       map[int]memSource
+	citations     map[int]memCitation
+	media         map[int]memMedia
+	mediaLinks    map[int]memMediaLink
+	changes       []memChange // журнал, по возрастанию ID
+
+	nextPersonID int
+	nextRelID    int
+	nextUserID   int
+	nextTreeID   int
+	nextInviteID int
+
+	nextShareLinkID int
+	nextEventID     int
+	nextPlaceID     int
+	nextSourceID    int
+	nextCitationID  int
+	nextMediaID     int
+	nextMediaLinkID int
+}
+
+type memPerson struct {
+	treeID    int
+	person    models.Person
+	deletedAt time.Time // не нулевое - в корзине
+}
+
+type memRelationship struct {
+	treeID    int
+	rel       models.Relationship
+	deletedAt time.Time
+}
+
+// live - запись в дереве treeID и не в корзине
+func (mp memPerson) live(treeID int) bool {
+	return mp.treeID == treeID && mp.deletedAt.IsZero()
+}
+
+func (mr memRelationship) live(treeID int) bool {
+	return mr.treeID == treeID && mr.deletedAt.IsZero()
+}
+
+// NewMemory создаёт пустое хранилище в памяти
+func NewMemory() *MemoryStore {
+	return &MemoryStore{
+		people:        map[int]memPerson{},
+		relationships: map[int]memRelationship{},
+		users:         map[int]models.User{},
+		trees:         map[int]models.Tree{},
+		members:       map[memberKey]models.TreeMember{},
+		invites:       map[int]memInvite{},
+		shareLinks:    map[int]models.ShareLink{},
+		events:        map[int]memEvent{},
+		places:        map[int]memPlace{},
+		gazetteer:     map[int]models.GazetteerEntry{},
+		sources:       map[int]memSource{},
+		citations:     map[int]memCitation{},
+		media:         map[int]memMedia{},
+		mediaLinks:    map[int]memMediaLink{},
+	}
+}
+
+// --- Люди ---
+
+func (s *MemoryStore) CreatePerson(ctx context.Context, treeID int, p *models.Person) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkPlaces(treeID, p.BirthPlaceID, p.DeathPlaceID); err != nil {
+		return err
+	}
+	if err := s.checkMedia(treeID, p.PhotoMediaID); err != nil {
+		return err
+	}
+	s.nextPersonID++
+	p.ID = s.nextPersonID
+	p.PositionX, p.PositionY = 0, 0
+	s.people[p.ID] = memPerson{treeID: treeID, person: *p}
+	s.logChange(ctx, personChange(treeID, models.ActionCreate, nil, p))
+	return s.syncPersonEvents(ctx, treeID, p.ID)
+}
+
+func (s *MemoryStore) ListPeople(ctx context.Context, treeID int) ([]models.Person, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	people := []models.Person{}
+	for _, mp := range s.people {
+		if mp.live(treeID) {
+			people = append(people, mp.person)
+		}
+	}
+	sort.Slice(people, func(i, j int) bool { return people[i].ID < people[j].ID })
+	return people, nil
+}
+
+func (s *MemoryStore) UpdatePerson(ctx context.Context, treeID int, p models.Person) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updatePerson(ctx, treeID, p, 0)
+}
+
+func (s *MemoryStore) updatePerson(ctx context.Context, treeID int, p models.Person, revertOf int64) error {
+	mp, ok := s.people[p.ID]
+	if !ok || !mp.live(treeID) {
+		return ErrNotFound
+	}
+	if err := s.checkPlaces(treeID, p.BirthPlaceID, p.DeathPlaceID); err != nil {
+		return err
+	}
+	if err := s.checkMedia(treeID, p.PhotoMediaID); err != nil {
+		return err
+	}
+	before := mp.person
+	// Координаты меняются только через UpdatePersonPosition
+	p.PositionX, p.PositionY = mp.person.PositionX, mp.person.PositionY
+	mp.person = p
+	s.people[p.ID] = mp
+
+	entry := personChange(treeID, models.ActionUpdate, &before, &p)
+	entry.revertOf = revertOf
+	s.logChange(ctx, entry)
+	return s.syncPersonEvents(ctx, treeID, p.ID)
+}
+
+// UpdatePersonPosition в журнал не попадает: это раскладка графа, а не данные о человеке
+func (s *MemoryStore) UpdatePersonPosition(ctx context.Context, treeID, personID int, x, y float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mp, ok := s.people[personID]
+	if !ok || !mp.live(treeID) {
+		return nil // как и UPDATE в SQLite: нет строки - нет изменений
+	}
+	mp.person.PositionX, mp.person.PositionY = x, y
+	s.people[personID] = mp
+	return nil
+}
+
+func (s *MemoryStore) DeletePerson(ctx context.Context, treeID, personID int) (*models.Removed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deletePerson(ctx, treeID, personID, 0)
+}
+
+func (s *MemoryStore) deletePerson(ctx context.Context, treeID, personID int, revertOf int64) (*models.Removed, error) {
+	return s.deleteMovedPerson(ctx, treeID, personID, revertOf, nil)
+}
+
+// deleteMovedPerson - см. deleteMovedPerson для SQLite
+func (s *MemoryStore) deleteMovedPerson(ctx context.Context, treeID, personID int, revertOf int64, moved *mergeMoves) (*models.Removed, error) {
+	mp, ok := s.people[personID]
+	if !ok || !mp.live(treeID) {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	rels := []models.Relationship{}
+	for id, mr := range s.relationships {
+		if mr.live(treeID) && (mr.rel.FromPersonID == personID || mr.rel.ToPersonID == personID) {
+			rels = append(rels, mr.rel)
+			mr.deletedAt = now
+			s.relationships[id] = mr
+		}
+	}
+	sort.Slice(rels, func(i, j int) bool { return rels[i].ID < rels[j].ID })
+	mp.deletedAt = now
+	s.people[personID] = mp
+
+	entry := personChange(treeID, models.ActionDelete, &mp.person, nil)
+	entry.revertOf, entry.moved = revertOf, moved
+	causeID := s.logChange(ctx, entry)
+	for i := range rels {
+		entry := relationshipChange(treeID, models.ActionDelete, &rels[i], nil)
+		entry.causeID = causeID
+		s.logChange(ctx, entry)
+	}
+	return &models.Removed{People: []models.Person{mp.person}, Relationships: rels}, nil
+}
+
+// --- Связи ---
+
+func (s *MemoryStore) CreateRelationship(ctx context.Context, treeID int, rel *models.Relationship) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insertRelationship(ctx, treeID, rel, false, 0, 0)
+}
+
+// insertRelationship добавляет связь; keepID - с прежним rel.ID (восстановление)
+func (s *MemoryStore) insertRelationship(ctx context.Context, treeID int, rel *models.Relationship, keepID bool, causeID, revertOf int64) error {
+	for _, personID := range []int{rel.FromPersonID, rel.ToPersonID} {
+		if mp, ok := s.people[personID]; !ok || !mp.live(treeID) {
+			return ErrInvalidReference
+		}
+	}
+
+	if !keepID {
+		s.nextRelID++
+		rel.ID = s.nextRelID
+	}
+	s.relationships[rel.ID] = memRelationship{treeID: treeID, rel: *rel}
+
+	entry := relationshipChange(treeID, models.ActionCreate, nil, rel)
+	entry.causeID, entry.revertOf = causeID, revertOf
+	s.logChange(ctx, entry)
+	return nil
+}
+
+func (s *MemoryStore) ListRelationships(ctx context.Context, treeID int) ([]models.Relationship, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	relationships := []models.Relationship{}
+	for _, mr := range s.relationships {
+		if mr.live(treeID) {
+			relationships = append(relationships, mr.rel)
+		}
+	}
+	sort.Slice(relationships, func(i, j int) bool { return relationships[i].ID < relationships[j].ID })
+	return relationships, nil
+}
+
+func (s *MemoryStore) UpdateRelationshipDescription(ctx context.Context, treeID, relID int, description string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateRelationshipDescription(ctx, treeID, relID, description, 0)
+}
+
+func (s *MemoryStore) updateRelationshipDescription(ctx context.Context, treeID, relID int, description string, revertOf int64) error {
+	mr, ok := s.relationships[relID]
+	if !ok || !mr.live(treeID) {
+		return ErrNotFound
+	}
+	rel := mr.rel
+	rel.Description = description
+	_, err := s.updateRelationship(ctx, treeID, rel, revertOf)
+	return err
+}
+
+// updateRelationship переписывает связь целиком: концы, тип и описание
+func (s *MemoryStore) updateRelationship(ctx context.Context, treeID int, rel models.Relationship, revertOf int64) (*models.Relationship, error) {
+	mr, ok := s.relationships[rel.ID]
+	if !ok || !mr.live(treeID) {
+		return nil, ErrNotFound
+	}
+	for _, personID := range []int{rel.FromPersonID, rel.ToPersonID} {
+		if mp, ok := s.people[personID]; !ok || !mp.live(treeID) {
+			return nil, ErrInvalidReference
+		}
+	}
+	before := mr.rel
+	mr.rel = rel
+	s.relationships[rel.ID] = mr
+
+	entry := relationshipChange(treeID, models.ActionUpdate, &before, &rel)
+	entry.revertOf = revertOf
+	s.logChange(ctx, entry)
+	return &before, nil
+}
+
+func (s *MemoryStore) DeleteRelationship(ctx context.Context, treeID, relID int) (*models.Removed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rel, err := s.deleteRelationship(ctx, treeID, relID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Removed{People: []models.Person{}, Relationships: []models.Relationship{*rel}}, nil
+}
+
+func (s *MemoryStore) deleteRelationship(ctx context.Context, treeID, relID int, revertOf int64) (*models.Relationship, error) {
+	return s.deleteMovedRelationship(ctx, treeID, relID, revertOf, nil)
+}
+
+// deleteMovedRelationship - см. deleteMovedRelationship для SQLite
+func (s *MemoryStore) deleteMovedRelationship(ctx context.Context, treeID, relID int, revertOf int64, moved *mergeMoves) (*models.Relationship, error) {
+	mr, ok := s.relationships[relID]
+	if !ok || !mr.live(treeID) {
+		return nil, ErrNotFound
+	}
+	mr.deletedAt = time.Now().UTC()
+	s.relationships[relID] = mr
+
+	entry := relationshipChange(treeID, models.ActionDelete, &mr.rel, nil)
+	entry.revertOf, entry.moved = revertOf, moved
+	s.logChange(ctx, entry)
+	return &mr.rel, nil
+}
+
+// --- Импорт ---
+
+func (s *MemoryStore) ImportTree(ctx context.Context, treeID int, people []models.Person, relationships []models.Relationship) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.trees[treeID]; !ok {
+		return ErrNotFound
+	}
+
+	// Сначала проверяем ссылки, чтобы не записать дерево частично
+	local := make(map[int]bool, len(people))
+	for _, p := range people {
+		local[p.ID] = true
+	}
+	for _, rel := range relationships {
+		if !local[rel.FromPersonID] || !local[rel.ToPersonID] {
+			return ErrInvalidReference
+		}
+	}
+
+	realID := make(map[int]int, len(people))
+	for i := range people {
+		s.nextPersonID++
+		realID[people[i].ID] = s.nextPersonID
+		people[i].ID = s.nextPersonID
+		s.people[people[i].ID] = memPerson{treeID: treeID, person: people[i]}
+		s.logChange(ctx, personChange(treeID, models.ActionCreate, nil, &people[i]))
+		if err := s.syncPersonEvents(ctx, treeID, people[i].ID); err != nil {
+			return err
+		}
+	}
+	for i := range relationships {
+		rel := &relationships[i]
+		rel.FromPersonID, rel.ToPersonID = realID[rel.FromPersonID], realID[rel.ToPersonID]
+		if err := s.insertRelationship(ctx, treeID, rel, false, 0, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Деревья ---
+
+func (s *MemoryStore) CreateTree(ctx context.Context, userID int, name string) (*models.Tree, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.createTree(userID, name)
+	return &t, nil
+}
+
+func (s *MemoryStore) createTree(userID int, name string) models.Tree {
+	s.nextTreeID++
+	t := models.Tree{ID: s.nextTreeID, UserID: userID, Name: name, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	s.trees[t.ID] = t
+	s.members[memberKey{t.ID, userID}] = models.TreeMember{TreeID: t.ID, UserID: userID, Role: models.RoleOwner, CreatedAt: t.CreatedAt}
+	return t
+}
+
+func (s *MemoryStore) ListTrees(ctx context.Context, userID int) ([]models.Tree, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trees := []models.Tree{}
+	for key, m := range s.members {
+		if key.userID == userID {
+			t := s.trees[key.treeID]
+			t.Role = m.Role
+			trees = append(trees, t)
+		}
+	}
+	sort.Slice(trees, func(i, j int) bool { return trees[i].ID < trees[j].ID })
+	return trees, nil
+}
+
+func (s *MemoryStore) GetTree(ctx context.Context, treeID int) (*models.Tree, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.trees[treeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
+func (s *MemoryStore) RenameTree(ctx context.Context, treeID int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.trees[treeID]
+	if !ok {
+		return ErrNotFound
+	}
+	t.Name = name
+	s.trees[treeID] = t
+	return nil
+}
+
+func (s *MemoryStore) DeleteTree(ctx context.Context, treeID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.trees[treeID]; !ok {
+		return ErrNotFound
+	}
+	for id, mr := range s.relationships {
+		if mr.treeID == treeID {
+			delete(s.relationships, id)
+		}
+	}
+	for id, mp := range s.people {
+		if mp.treeID == treeID {
+			delete(s.people, id)
+		}
+	}
+	for key := range s.members {
+		if key.treeID == treeID {
+			delete(s.members, key)
+		}
+	}
+	for id, mi := range s.invites {
+		if mi.invite.TreeID == treeID {
+			delete(s.invites, id)
+		}
+	}
+	for id, link := range s.shareLinks {
+		if link.TreeID == treeID {
+			delete(s.shareLinks, id)
+		}
+	}
+	s.dropOrphanEvents()
+	for id, mp := range s.places {
+		if mp.treeID == treeID {
+			delete(s.places, id)
+		}
+	}
+	for id, ms := range s.sources {
+		if ms.treeID == treeID {
+			delete(s.sources, id)
+		}
+	}
+	for id, mm := range s.media {
+		if mm.treeID == treeID {
+			delete(s.media, id)
+		}
+	}
+	s.dropOrphanMediaLinks()
+	delete(s.trees, treeID)
+	return nil
+}
+
+func (s *MemoryStore) DuplicateTree(ctx context.Context, treeID, userID int, name string) (*models.Tree, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.trees[treeID]; !ok {
+		return nil, ErrNotFound
+	}
+	t := s.createTree(userID, name)
+	placeIDs := s.copyPlaces(treeID, t.ID)
+
+	// Обходим в порядке ID, чтобы копии шли в том же порядке, что и оригиналы.
+	// Корзина не копируется.
+	var personIDs, relIDs []int
+	for id, mp := range s.people {
+		if mp.live(treeID) {
+			personIDs = append(personIDs, id)
+		}
+	}
+	for id, mr := range s.relationships {
+		if mr.live(treeID) {
+			relIDs = append(relIDs, id)
+		}
+	}
+	sort.Ints(personIDs)
+	sort.Ints(relIDs)
+
+	realID := make(map[int]int, len(personIDs))
+	for _, id := range personIDs {
+		p := s.people[id].person
+		s.nextPersonID++
+		p.ID = s.nextPersonID
+		p.BirthPlaceID, p.DeathPlaceID = mapID(p.BirthPlaceID, placeIDs), mapID(p.DeathPlaceID, placeIDs)
+		realID[id] = p.ID
+		s.people[p.ID] = memPerson{treeID: t.ID, person: p}
+	}
+	realRelID := make(map[int]int, len(relIDs))
+	for _, id := range relIDs {
+		rel := s.relationships[id].rel
+		s.nextRelID++
+		rel.ID, rel.FromPersonID, rel.ToPersonID = s.nextRelID, realID[rel.FromPersonID], realID[rel.ToPersonID]
+		realRelID[id] = rel.ID
+		s.relationships[rel.ID] = memRelationship{treeID: t.ID, rel: rel}
+	}
+	eventIDs := s.copyEvents(treeID, t.ID, realID, realRelID, placeIDs)
+	s.copySources(treeID, t.ID, realID, realRelID, eventIDs)
+	s.copyMedia(treeID, t.ID, realID, realRelID, eventIDs)
+	return &t, nil
+}
+
+func (s *MemoryStore) EnsureDefaultTree(ctx context.Context, userID int) (*models.Tree, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var oldest *models.Tree
+	for _, t := range s.trees {
+		if t.UserID == userID && (oldest == nil || t.ID < oldest.ID) {
+			t := t
+			oldest = &t
+		}
+	}
+	if oldest != nil {
+		return oldest, nil
+	}
+	t := s.createTree(userID, DefaultTreeName)
+	return &t, nil
+}
+
+// --- Пользователи ---
+
+func (s *MemoryStore) CreateUser(ctx context.Context, email, passwordHash string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return 0, ErrEmailTaken
+		}
+	}
+	s.nextUserID++
+	s.users[s.nextUserID] = models.User{ID: s.nextUserID, Email: email, PasswordHash: passwordHash}
+	return s.nextUserID, nil
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (s *MemoryStore) SetPasswordHash(ctx context.Context, id int, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	u.PasswordHash = passwordHash
+	s.users[id] = u
+	return nil
+}
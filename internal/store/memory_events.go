@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+type memEvent struct {
+	treeID int
+	event  models.Event
+}
+
+// owns - событие принадлежит owner в дереве treeID
+func (me memEvent) owns(treeID int, owner EventOwner) bool {
+	e := me.event
+	if me.treeID != treeID {
+		return false
+	}
+	if owner.PersonID != 0 {
+		return e.PersonID != nil && *e.PersonID == owner.PersonID
+	}
+	return e.RelationshipID != nil && *e.RelationshipID == owner.RelationshipID
+}
+
+// checkEventOwner - см. checkEventOwner для SQLite
+func (s *MemoryStore) checkEventOwner(treeID int, owner EventOwner, spouses bool) error {
+	if owner.PersonID != 0 {
+		if mp, ok := s.people[owner.PersonID]; !ok || !mp.live(treeID) {
+			return ErrNotFound
+		}
+		return nil
+	}
+	mr, ok := s.relationships[owner.RelationshipID]
+	if !ok || !mr.live(treeID) {
+		return ErrNotFound
+	}
+	if spouses && genealogy.KindOf(mr.rel.Type) != genealogy.KindSpouse {
+		return ErrNotSpouses
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListEvents(ctx context.Context, treeID int, owner EventOwner) ([]models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkEventOwner(treeID, owner, false); err != nil {
+		return nil, err
+	}
+	events := []models.Event{}
+	for _, me := range s.events {
+		if me.owns(treeID, owner) {
+			events = append(events, me.event)
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+func (s *MemoryStore) ListTreeEvents(ctx context.Context, treeID int) ([]models.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := []models.Event{}
+	for _, me := range s.events {
+		if me.treeID == treeID {
+			events = append(events, me.event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (s *MemoryStore) CreateEvent(ctx context.Context, treeID int, owner EventOwner, e *models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkEventOwner(treeID, owner, true); err != nil {
+		return err
+	}
+	if err := s.checkPlaces(treeID, e.PlaceID); err != nil {
+		return err
+	}
+	s.nextEventID++
+	e.ID = s.nextEventID
+	e.PersonID, e.RelationshipID = eventOwnerIDs(owner)
+	s.events[e.ID] = memEvent{treeID: treeID, event: *e}
+	if err := s.syncEventDates(ctx, treeID, owner, *e, e.Type); err != nil {
+		return err
+	}
+	// пустые дату и место событие могло взять у человека
+	*e = s.events[e.ID].event
+	return nil
+}
+
+func (s *MemoryStore) UpdateEvent(ctx context.Context, treeID int, owner EventOwner, e models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkEventOwner(treeID, owner, true); err != nil {
+		return err
+	}
+	if err := s.checkPlaces(treeID, e.PlaceID); err != nil {
+		return err
+	}
+	me, ok := s.events[e.ID]
+	if !ok || !me.owns(treeID, owner) {
+		return ErrNotFound
+	}
+	previousType := me.event.Type
+	e.PersonID, e.RelationshipID = eventOwnerIDs(owner)
+	me.event = e
+	s.events[e.ID] = me
+	return s.syncEventDates(ctx, treeID, owner, e, previousType)
+}
+
+func (s *MemoryStore) DeleteEvent(ctx context.Context, treeID int, owner EventOwner, eventID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkEventOwner(treeID, owner, false); err != nil {
+		return err
+	}
+	me, ok := s.events[eventID]
+	if !ok || !me.owns(treeID, owner) {
+		return ErrNotFound
+	}
+	delete(s.events, eventID)
+	s.dropOrphanCitations()
+	s.dropOrphanMediaLinks()
+	if owner.PersonID == 0 {
+		return nil
+	}
+	return s.syncLifeEvent(ctx, treeID, owner.PersonID, me.event.Type, true)
+}
+
+// syncEventDates - см. syncEventDates для SQLite
+func (s *MemoryStore) syncEventDates(ctx context.Context, treeID int, owner EventOwner, e models.Event, previousType string) error {
+	if owner.PersonID == 0 {
+		return nil
+	}
+	if previousType != e.Type {
+		if err := s.syncLifeEvent(ctx, treeID, owner.PersonID, previousType, true); err != nil {
+			return err
+		}
+	}
+	return s.syncLifeEvent(ctx, treeID, owner.PersonID, e.Type, true)
+}
+
+// syncLifeEvent - см. syncLifeEvent для SQLite
+func (s *MemoryStore) syncLifeEvent(ctx context.Context, treeID, personID int, eventType string, fromEvent bool) error {
+	if !isLifeEvent(eventType) {
+		return nil
+	}
+	first := 0
+	for id, me := range s.events {
+		if me.owns(treeID, EventOwner{PersonID: personID}) && me.event.Type == eventType && (first == 0 || id < first) {
+			first = id
+		}
+	}
+	mp, ok := s.people[personID]
+	if !ok || !mp.live(treeID) {
+		return nil
+	}
+	if first == 0 {
+		return s.syncMissingLifeEvent(ctx, treeID, mp.person, eventType, fromEvent)
+	}
+	me := s.events[first]
+
+	if fromEvent {
+		newDate, newPlace := mergeLifeFacts(mp.person, eventType, me.event.Date, me.event.PlaceID)
+		if personDate, personPlace := lifeFacts(mp.person, eventType); newDate != personDate || !sameID(newPlace, personPlace) {
+			p := mp.person
+			setLifeFacts(&p, eventType, newDate, newPlace)
+			return s.updatePerson(ctx, treeID, p, 0)
+		}
+	}
+
+	current, currentPlace := lifeFacts(mp.person, eventType)
+	if current == me.event.Date && sameID(currentPlace, me.event.PlaceID) {
+		return nil
+	}
+	me.event.Date, me.event.PlaceID = current, currentPlace
+	s.events[first] = me
+	return nil
+}
+
+// syncMissingLifeEvent - см. syncMissingLifeEvent для SQLite
+func (s *MemoryStore) syncMissingLifeEvent(ctx context.Context, treeID int, p models.Person, eventType string, fromEvent bool) error {
+	date, placeID := lifeFacts(p, eventType)
+	if date == "" && placeID == nil {
+		return nil
+	}
+	if fromEvent {
+		setLifeFacts(&p, eventType, "", nil)
+		return s.updatePerson(ctx, treeID, p, 0)
+	}
+	s.nextEventID++
+	s.events[s.nextEventID] = memEvent{treeID: treeID, event: models.Event{
+		ID: s.nextEventID, PersonID: &p.ID, Type: eventType, Date: date, PlaceID: placeID,
+	}}
+	return nil
+}
+
+// syncPersonEvents - см. syncPersonEvents для SQLite
+func (s *MemoryStore) syncPersonEvents(ctx context.Context, treeID, personID int) error {
+	for _, eventType := range []string{models.EventBirth, models.EventDeath} {
+		if err := s.syncLifeEvent(ctx, treeID, personID, eventType, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropOrphanEvents стирает события стёртых людей и связей - как внешний ключ
+// в SQLite, а с ними ссылки на источники и связи файлов
+func (s *MemoryStore) dropOrphanEvents() {
+	for id, me := range s.events {
+		e := me.event
+		if e.PersonID != nil {
+			if _, ok := s.people[*e.PersonID]; !ok {
+				delete(s.events, id)
+			}
+		} else if _, ok := s.relationships[*e.RelationshipID]; !ok {
+			delete(s.events, id)
+		}
+	}
+	s.dropOrphanCitations()
+	s.dropOrphanMediaLinks()
+}
+
+// copyEvents - см. copyEvents для SQLite
+func (s *MemoryStore) copyEvents(treeID, newTreeID int, personIDs, relIDs, placeIDs map[int]int) map[int]int {
+	var ids []int
+	for id, me := range s.events {
+		if me.treeID == treeID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	eventIDs := make(map[int]int, len(ids))
+	for _, id := range ids {
+		e := s.events[id].event
+		if e.PersonID != nil {
+			newID, ok := personIDs[*e.PersonID]
+			if !ok {
+				continue
+			}
+			e.PersonID = &newID
+		} else {
+			newID, ok := relIDs[*e.RelationshipID]
+			if !ok {
+				continue
+			}
+			e.RelationshipID = &newID
+		}
+		e.PlaceID = mapID(e.PlaceID, placeIDs)
+		s.nextEventID++
+		eventIDs[id] = s.nextEventID
+		e.ID = s.nextEventID
+		s.events[e.ID] = memEvent{treeID: newTreeID, event: e}
+	}
+	return eventIDs
+}
+
+// moveEvents переносит события одного владельца другому (при слиянии)
+func (s *MemoryStore) moveEvents(treeID int, from, to EventOwner) {
+	for id, me := range s.events {
+		if !me.owns(treeID, from) {
+			continue
+		}
+		me.event.PersonID, me.event.RelationshipID = eventOwnerIDs(to)
+		s.events[id] = me
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+func (s *MemoryStore) ImportGazetteer(ctx context.Context, entries []models.GazetteerEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range entries {
+		s.gazetteer[e.GeoNamesID] = e
+	}
+	return nil
+}
+
+// SearchGazetteer - см. SQLiteStore.SearchGazetteer
+func (s *MemoryStore) SearchGazetteer(ctx context.Context, query, country string, limit int) ([]models.GazetteerEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []models.GazetteerEntry{}
+	terms := genealogy.ParseSearchQuery(query)
+	if len(terms) == 0 {
+		return entries, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	for _, e := range s.gazetteer {
+		if country != "" && !strings.EqualFold(e.CountryCode, country) {
+			continue
+		}
+		if genealogy.MatchPlaceNames(gazetteerNames(e), terms) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Population != entries[j].Population {
+			return entries[i].Population > entries[j].Population
+		}
+		return entries[i].GeoNamesID < entries[j].GeoNamesID
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+func (s *MemoryStore) GetGazetteerEntry(ctx context.Context, geonamesID int) (*models.GazetteerEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.gazetteer[geonamesID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+// memChange - запись журнала и концы связи для отбора по человеку
+type memChange struct {
+	change       models.Change
+	fromID, toID int
+}
+
+// logChange дописывает запись в журнал. Вызывается под s.mu.
+func (s *MemoryStore) logChange(ctx context.Context, c change) int64 {
+	before, _ := c.beforeJSON()
+	after, _ := c.afterJSON()
+	moved, _ := c.movedJSON()
+
+	id := len(s.changes) + 1
+	mc := memChange{
+		change: models.Change{
+			ID:         id,
+			TreeID:     c.treeID,
+			UserID:     actorFrom(ctx),
+			EntityType: c.entityType,
+			EntityID:   c.entityID,
+			Action:     c.action,
+			Before:     memJSON(before),
+			After:      memJSON(after),
+			CauseID:    memID(c.causeID),
+			RevertOf:   memID(c.revertOf),
+			CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		},
+		fromID: c.fromID,
+		toID:   c.toID,
+	}
+	if moved != nil {
+		mc.change.Moved = memJSON(moved)
+	}
+	s.changes = append(s.changes, mc)
+	return int64(id)
+}
+
+func memJSON(v interface{}) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(v.(string))
+}
+
+func memID(id int64) *int {
+	if id == 0 {
+		return nil
+	}
+	i := int(id)
+	return &i
+}
+
+// withEmail дополняет запись email автора. Вызывается под s.mu.
+func (s *MemoryStore) withEmail(c models.Change) models.Change {
+	if c.UserID != nil {
+		c.UserEmail = s.users[*c.UserID].Email
+	}
+	return c
+}
+
+func (s *MemoryStore) ListChanges(ctx context.Context, treeID int, f ChangeFilter) ([]models.Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultChangeLimit
+	}
+
+	changes := []models.Change{}
+	for i := len(s.changes) - 1; i >= 0 && len(changes) < limit; i-- {
+		mc := s.changes[i]
+		c := mc.change
+		switch {
+		case c.TreeID != treeID:
+			continue
+		case f.PersonID != 0 && !(c.EntityType == models.EntityPerson && c.EntityID == f.PersonID) && mc.fromID != f.PersonID && mc.toID != f.PersonID:
+			continue
+		case f.EntityType != "" && (c.EntityType != f.EntityType || c.EntityID != f.EntityID):
+			continue
+		case f.Action != "" && c.Action != f.Action:
+			continue
+		case f.BeforeID != 0 && c.ID >= f.BeforeID:
+			continue
+		}
+		changes = append(changes, s.withEmail(c))
+	}
+	return changes, nil
+}
+
+func (s *MemoryStore) GetChange(ctx context.Context, treeID, changeID int) (*models.Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if changeID < 1 || changeID > len(s.changes) || s.changes[changeID-1].change.TreeID != treeID {
+		return nil, ErrNotFound
+	}
+	c := s.withEmail(s.changes[changeID-1].change)
+	return &c, nil
+}
+
+func (s *MemoryStore) RevertChange(ctx context.Context, treeID, changeID int) ([]models.Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if changeID < 1 || changeID > len(s.changes) || s.changes[changeID-1].change.TreeID != treeID {
+		return nil, ErrNotFound
+	}
+	c := s.changes[changeID-1].change
+	revertOf := int64(c.ID)
+	lastID := len(s.changes)
+
+	// Транзакции в памяти нет, но каждая операция сначала проверяет
+	// условия и только потом меняет данные, так что конфликт ничего не портит
+	var err error
+	switch c.EntityType + "/" + c.Action {
+	case "person/create":
+		_, err = s.deletePerson(ctx, treeID, c.EntityID, revertOf)
+
+	case "person/update":
+		var before models.Person
+		if err := json.Unmarshal(c.Before, &before); err != nil {
+			return nil, err
+		}
+		if mp, ok := s.people[before.ID]; ok && mp.live(treeID) && !personUnchanged(c, mp.person) {
+			return nil, ErrRevertConflict
+		}
+		err = s.updatePerson(ctx, treeID, before, revertOf)
+
+	case "person/delete":
+		var before models.Person
+		if err := json.Unmarshal(c.Before, &before); err != nil {
+			return nil, err
+		}
+		err = s.restorePerson(ctx, treeID, before, c.ID, revertOf)
+		if err == nil {
+			err = s.unmergeMoves(ctx, treeID, EventOwner{PersonID: c.EntityID}, c.Moved)
+		}
+
+	case "relationship/create":
+		_, err = s.deleteRelationship(ctx, treeID, c.EntityID, revertOf)
+
+	case "relationship/update":
+		var before models.Relationship
+		if err := json.Unmarshal(c.Before, &before); err != nil {
+			return nil, err
+		}
+		if mr, ok := s.relationships[c.EntityID]; ok && mr.live(treeID) && !relationshipUnchanged(c, mr.rel) {
+			return nil, ErrRevertConflict
+		}
+		_, err = s.updateRelationship(ctx, treeID, before, revertOf)
+
+	case "relationship/delete":
+		var before models.Relationship
+		if err := json.Unmarshal(c.Before, &before); err != nil {
+			return nil, err
+		}
+		err = s.restoreRelationship(ctx, treeID, before, 0, revertOf)
+		if err == nil {
+			err = s.unmergeMoves(ctx, treeID, EventOwner{RelationshipID: c.EntityID}, c.Moved)
+		}
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidReference) {
+		return nil, ErrRevertConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.Change{}
+	for _, mc := range s.changes[lastID:] {
+		result = append(result, s.withEmail(mc.change))
+	}
+	return result, nil
+}
+
+// restorePerson - см. SQLiteStore.restorePerson
+func (s *MemoryStore) restorePerson(ctx context.Context, treeID int, p models.Person, deleteID int, revertOf int64) error {
+	if mp, ok := s.people[p.ID]; ok {
+		if mp.deletedAt.IsZero() || mp.treeID != treeID {
+			return ErrRevertConflict
+		}
+		p = mp.person // из корзины возвращается как есть
+	}
+	if err := s.checkPlaces(treeID, p.BirthPlaceID, p.DeathPlaceID); err != nil {
+		return err
+	}
+	if err := s.checkMedia(treeID, p.PhotoMediaID); err != nil {
+		return err
+	}
+	s.people[p.ID] = memPerson{treeID: treeID, person: p}
+	entry := personChange(treeID, models.ActionCreate, nil, &p)
+	entry.revertOf = revertOf
+	causeID := s.logChange(ctx, entry)
+	if err := s.syncPersonEvents(ctx, treeID, p.ID); err != nil {
+		return err
+	}
+
+	for _, mc := range s.changes {
+		c := mc.change
+		if c.CauseID == nil || *c.CauseID != deleteID || c.EntityType != models.EntityRelationship || c.Action != models.ActionDelete {
+			continue
+		}
+		var rel models.Relationship
+		if err := json.Unmarshal(c.Before, &rel); err != nil {
+			return err
+		}
+		err := s.restoreRelationship(ctx, treeID, rel, causeID, 0)
+		if err != nil && !errors.Is(err, ErrInvalidReference) && !errors.Is(err, ErrRevertConflict) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) restoreRelationship(ctx context.Context, treeID int, rel models.Relationship, causeID, revertOf int64) error {
+	if mr, ok := s.relationships[rel.ID]; ok {
+		if mr.deletedAt.IsZero() || mr.treeID != treeID {
+			return ErrRevertConflict
+		}
+		rel = mr.rel
+	}
+	// insertRelationship перезаписывает запись из корзины той же связью
+	return s.insertRelationship(ctx, treeID, &rel, true, causeID, revertOf)
+}
@@ -0,0 +1,291 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+type memMedia struct {
+	treeID int
+	media  models.Media // без связей: они в mediaLinks
+}
+
+type memMediaLink struct {
+	treeID  int
+	mediaID int
+	owner   MediaOwner
+}
+
+// liveMediaLink - владелец связи и, для события, владелец события не в корзине
+// (см. liveMediaLinks для SQLite)
+func (s *MemoryStore) liveMediaLink(ml memMediaLink) bool {
+	personID, relID := ml.owner.PersonID, ml.owner.RelationshipID
+	if ml.owner.EventID != 0 {
+		e := s.events[ml.owner.EventID].event
+		personID, relID = derefID(e.PersonID), derefID(e.RelationshipID)
+	}
+	if personID != 0 && !s.people[personID].deletedAt.IsZero() {
+		return false
+	}
+	return relID == 0 || s.relationships[relID].deletedAt.IsZero()
+}
+
+// listMedia - файлы дерева, подходящие под match, новые первыми, со связями
+func (s *MemoryStore) listMedia(treeID int, match func(models.Media) bool) []models.Media {
+	files := []models.Media{}
+	for _, mm := range s.media {
+		if mm.treeID == treeID && match(mm.media) {
+			files = append(files, mm.media)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ID > files[j].ID })
+
+	index := make(map[int]int, len(files))
+	for i := range files {
+		index[files[i].ID] = i
+	}
+	var linkIDs []int
+	for id, ml := range s.mediaLinks {
+		if _, ok := index[ml.mediaID]; ok && s.liveMediaLink(ml) {
+			linkIDs = append(linkIDs, id)
+		}
+	}
+	sort.Ints(linkIDs)
+	for _, id := range linkIDs {
+		ml := s.mediaLinks[id]
+		personID, relID, eventID := citationOwnerIDs(CitationOwner(ml.owner))
+		addMediaLink(&files[index[ml.mediaID]], personID, relID, eventID)
+	}
+	for i := range files {
+		emptyMediaLinks(&files[i])
+	}
+	return files
+}
+
+func (s *MemoryStore) getMedia(treeID, mediaID int) (*models.Media, error) {
+	files := s.listMedia(treeID, func(m models.Media) bool { return m.ID == mediaID })
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+	return &files[0], nil
+}
+
+// findMediaLink - ID связи файла с владельцем, 0 - связи нет
+func (s *MemoryStore) findMediaLink(mediaID int, owner MediaOwner) int {
+	for id, ml := range s.mediaLinks {
+		if ml.mediaID == mediaID && ml.owner == owner {
+			return id
+		}
+	}
+	return 0
+}
+
+func (s *MemoryStore) ListMedia(ctx context.Context, treeID int) ([]models.Media, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listMedia(treeID, func(models.Media) bool { return true }), nil
+}
+
+func (s *MemoryStore) GetMedia(ctx context.Context, treeID, mediaID int) (*models.Media, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getMedia(treeID, mediaID)
+}
+
+func (s *MemoryStore) FindMedia(ctx context.Context, treeID int, hash string) (*models.Media, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files := s.listMedia(treeID, func(m models.Media) bool { return m.Hash == hash })
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+	return &files[0], nil
+}
+
+func (s *MemoryStore) CreateMedia(ctx context.Context, treeID int, m *models.Media) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextMediaID++
+	m.ID = s.nextMediaID
+	m.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	m.PersonIDs, m.RelationshipIDs, m.EventIDs = nil, nil, nil
+	s.media[m.ID] = memMedia{treeID: treeID, media: *m}
+	emptyMediaLinks(m)
+	return nil
+}
+
+func (s *MemoryStore) UpdateMedia(ctx context.Context, treeID int, m models.Media) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mm, ok := s.media[m.ID]
+	if !ok || mm.treeID != treeID {
+		return ErrNotFound
+	}
+	mm.media.Title, mm.media.Description = m.Title, m.Description
+	s.media[m.ID] = mm
+	return nil
+}
+
+func (s *MemoryStore) DeleteMedia(ctx context.Context, treeID, mediaID int) (*models.Media, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.getMedia(treeID, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.media, mediaID)
+	s.dropOrphanMediaLinks()
+	// Портрет обнуляется и у людей в корзине - как ON DELETE SET NULL в SQLite
+	for id, mp := range s.people {
+		if mp.person.PhotoMediaID != nil && *mp.person.PhotoMediaID == mediaID {
+			mp.person.PhotoMediaID = nil
+			s.people[id] = mp
+		}
+	}
+	return m, nil
+}
+
+func (s *MemoryStore) MediaInUse(ctx context.Context, hash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, mm := range s.media {
+		if mm.media.Hash == hash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) ListOwnerMedia(ctx context.Context, treeID int, owner MediaOwner) ([]models.Media, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCitationOwner(treeID, CitationOwner(owner)); err != nil {
+		return nil, err
+	}
+	return s.listMedia(treeID, func(m models.Media) bool { return s.findMediaLink(m.ID, owner) != 0 }), nil
+}
+
+// checkMedia - см. checkMedia для SQLite
+func (s *MemoryStore) checkMedia(treeID int, mediaID *int) error {
+	if mediaID == nil {
+		return nil
+	}
+	if mm, ok := s.media[*mediaID]; !ok || mm.treeID != treeID {
+		return ErrInvalidMedia
+	}
+	return nil
+}
+
+func (s *MemoryStore) LinkMedia(ctx context.Context, treeID int, owner MediaOwner, mediaID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCitationOwner(treeID, CitationOwner(owner)); err != nil {
+		return err
+	}
+	if err := s.checkMedia(treeID, &mediaID); err != nil {
+		return err
+	}
+	if s.findMediaLink(mediaID, owner) == 0 {
+		s.nextMediaLinkID++
+		s.mediaLinks[s.nextMediaLinkID] = memMediaLink{treeID: treeID, mediaID: mediaID, owner: owner}
+	}
+	return nil
+}
+
+func (s *MemoryStore) UnlinkMedia(ctx context.Context, treeID int, owner MediaOwner, mediaID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.findMediaLink(mediaID, owner)
+	if id == 0 || s.mediaLinks[id].treeID != treeID {
+		return ErrNotFound
+	}
+	delete(s.mediaLinks, id)
+	return nil
+}
+
+// dropOrphanMediaLinks стирает связи стёртых файлов, людей, связей и событий - как внешний ключ в SQLite
+func (s *MemoryStore) dropOrphanMediaLinks() {
+	for id, ml := range s.mediaLinks {
+		_, media := s.media[ml.mediaID]
+		_, person := s.people[ml.owner.PersonID]
+		_, rel := s.relationships[ml.owner.RelationshipID]
+		_, event := s.events[ml.owner.EventID]
+		if !media || (!person && !rel && !event) {
+			delete(s.mediaLinks, id)
+		}
+	}
+}
+
+// moveMediaLinks переносит связи файлов с from на to (при слиянии) без повторов
+func (s *MemoryStore) moveMediaLinks(from, to MediaOwner) {
+	for id, ml := range s.mediaLinks {
+		if ml.owner != from {
+			continue
+		}
+		if s.findMediaLink(ml.mediaID, to) != 0 {
+			delete(s.mediaLinks, id)
+			continue
+		}
+		ml.owner = to
+		s.mediaLinks[id] = ml
+	}
+}
+
+// copyMedia - см. copyMedia для SQLite
+func (s *MemoryStore) copyMedia(treeID, newTreeID int, personIDs, relIDs, eventIDs map[int]int) {
+	var ids []int
+	for id, mm := range s.media {
+		if mm.treeID == treeID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	mediaIDs := make(map[int]int, len(ids))
+	for _, id := range ids {
+		m := s.media[id].media
+		s.nextMediaID++
+		m.ID = s.nextMediaID
+		mediaIDs[id] = m.ID
+		s.media[m.ID] = memMedia{treeID: newTreeID, media: m}
+	}
+	for _, newPersonID := range personIDs {
+		mp := s.people[newPersonID]
+		mp.person.PhotoMediaID = mapID(mp.person.PhotoMediaID, mediaIDs)
+		s.people[newPersonID] = mp
+	}
+
+	var linkIDs []int
+	for id, ml := range s.mediaLinks {
+		if ml.treeID == treeID {
+			linkIDs = append(linkIDs, id)
+		}
+	}
+	sort.Ints(linkIDs)
+	for _, id := range linkIDs {
+		ml := s.mediaLinks[id]
+		personID, relID, eventID := citationOwnerIDs(CitationOwner(ml.owner))
+		owner := MediaOwner{
+			PersonID:       derefID(mapID(personID, personIDs)),
+			RelationshipID: derefID(mapID(relID, relIDs)),
+			EventID:        derefID(mapID(eventID, eventIDs)),
+		}
+		if owner == (MediaOwner{}) {
+			continue
+		}
+		s.nextMediaLinkID++
+		s.mediaLinks[s.nextMediaLinkID] = memMediaLink{treeID: newTreeID, mediaID: mediaIDs[ml.mediaID], owner: owner}
+	}
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"family-tree-app/internal/models"
+)
+
+// MergePeople - см. SQLiteStore.MergePeople. Все проверки идут до первого изменения.
+func (s *MemoryStore) MergePeople(ctx context.Context, treeID int, merged models.Person, otherID int) (*models.MergeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if merged.ID == otherID {
+		return nil, ErrInvalidReference
+	}
+	for _, personID := range []int{merged.ID, otherID} {
+		if mp, ok := s.people[personID]; !ok || !mp.live(treeID) {
+			return nil, ErrNotFound
+		}
+	}
+
+	result := &models.MergeResult{MergedID: otherID, Repointed: []models.Relationship{}, Removed: []models.Relationship{}}
+	if err := s.updatePerson(ctx, treeID, merged, 0); err != nil {
+		return nil, err
+	}
+	result.Person = s.people[merged.ID].person
+
+	plan := planMerge(merged.ID, otherID, s.liveRelationshipsOf(treeID, merged.ID), s.liveRelationshipsOf(treeID, otherID))
+	for _, relID := range plan.drop {
+		var moved *mergeMoves
+		if to, ok := plan.events[relID]; ok {
+			moved = s.mergeMove(treeID, EventOwner{RelationshipID: relID}, EventOwner{RelationshipID: to})
+		}
+		rel, err := s.deleteMovedRelationship(ctx, treeID, relID, 0, moved.orNil())
+		if err != nil {
+			return nil, err
+		}
+		result.Removed = append(result.Removed, *rel)
+	}
+	for relID, description := range plan.describe {
+		if err := s.updateRelationshipDescription(ctx, treeID, relID, description, 0); err != nil {
+			return nil, err
+		}
+	}
+	for _, rel := range plan.repoint {
+		if _, err := s.updateRelationship(ctx, treeID, rel, 0); err != nil {
+			return nil, err
+		}
+		result.Repointed = append(result.Repointed, rel)
+	}
+
+	moved := s.mergeMove(treeID, EventOwner{PersonID: otherID}, EventOwner{PersonID: merged.ID})
+	if err := s.syncPersonEvents(ctx, treeID, merged.ID); err != nil {
+		return nil, err
+	}
+	if _, err := s.deleteMovedPerson(ctx, treeID, otherID, 0, moved.orNil()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeMove - см. mergeMove для SQLite
+func (s *MemoryStore) mergeMove(treeID int, from, to EventOwner) *mergeMoves {
+	fromOwner := CitationOwner{PersonID: from.PersonID, RelationshipID: from.RelationshipID}
+	toOwner := CitationOwner{PersonID: to.PersonID, RelationshipID: to.RelationshipID}
+	moved := &mergeMoves{Into: to.PersonID + to.RelationshipID}
+	for id, me := range s.events {
+		if me.owns(treeID, from) {
+			moved.Events = append(moved.Events, id)
+		}
+	}
+	for id, mc := range s.citations {
+		if citationOwnedBy(mc.citation, fromOwner) {
+			moved.Citations = append(moved.Citations, id)
+		}
+	}
+	for _, ml := range s.mediaLinks {
+		if ml.owner != MediaOwner(fromOwner) {
+			continue
+		}
+		if s.findMediaLink(ml.mediaID, MediaOwner(toOwner)) != 0 {
+			moved.Shared = append(moved.Shared, ml.mediaID)
+		} else {
+			moved.Media = append(moved.Media, ml.mediaID)
+		}
+	}
+	if from.PersonID != 0 {
+		for id, u := range s.users {
+			if u.LinkToPersonID != nil && *u.LinkToPersonID == from.PersonID {
+				moved.Users = append(moved.Users, id)
+			}
+		}
+	}
+	for _, ids := range [][]int{moved.Events, moved.Citations, moved.Media, moved.Shared, moved.Users} {
+		sort.Ints(ids)
+	}
+
+	s.moveEvents(treeID, from, to)
+	s.moveCitations(fromOwner, toOwner)
+	s.moveMediaLinks(MediaOwner(fromOwner), MediaOwner(toOwner))
+	for _, id := range moved.Users {
+		u := s.users[id]
+		u.LinkToPersonID = &to.PersonID
+		s.users[id] = u
+	}
+	return moved
+}
+
+// unmergeMoves - см. unmergeMoves для SQLite; back - восстановленный владелец
+func (s *MemoryStore) unmergeMoves(ctx context.Context, treeID int, back EventOwner, movedJSON json.RawMessage) error {
+	if len(movedJSON) == 0 {
+		return nil
+	}
+	var moved mergeMoves
+	if err := json.Unmarshal(movedJSON, &moved); err != nil {
+		return err
+	}
+	into := EventOwner{PersonID: moved.Into}
+	if back.PersonID == 0 {
+		into = EventOwner{RelationshipID: moved.Into}
+	}
+	backOwner := CitationOwner{PersonID: back.PersonID, RelationshipID: back.RelationshipID}
+	intoOwner := CitationOwner{PersonID: into.PersonID, RelationshipID: into.RelationshipID}
+
+	for _, id := range moved.Events {
+		if me, ok := s.events[id]; ok && me.owns(treeID, into) {
+			me.event.PersonID, me.event.RelationshipID = eventOwnerIDs(back)
+			s.events[id] = me
+		}
+	}
+	for _, id := range moved.Citations {
+		if mc, ok := s.citations[id]; ok && citationOwnedBy(mc.citation, intoOwner) {
+			mc.citation.PersonID, mc.citation.RelationshipID, _ = citationOwnerIDs(backOwner)
+			s.citations[id] = mc
+		}
+	}
+	for _, mediaID := range moved.Media {
+		id := s.findMediaLink(mediaID, MediaOwner(intoOwner))
+		if id != 0 && s.findMediaLink(mediaID, MediaOwner(backOwner)) == 0 {
+			ml := s.mediaLinks[id]
+			ml.owner = MediaOwner(backOwner)
+			s.mediaLinks[id] = ml
+		}
+	}
+	for _, mediaID := range moved.Shared {
+		if s.checkMedia(treeID, &mediaID) == nil && s.findMediaLink(mediaID, MediaOwner(backOwner)) == 0 {
+			s.nextMediaLinkID++
+			s.mediaLinks[s.nextMediaLinkID] = memMediaLink{treeID: treeID, mediaID: mediaID, owner: MediaOwner(backOwner)}
+		}
+	}
+	for _, id := range moved.Users {
+		if u, ok := s.users[id]; ok && u.LinkToPersonID != nil && *u.LinkToPersonID == into.PersonID {
+			u.LinkToPersonID = &back.PersonID
+			s.users[id] = u
+		}
+	}
+
+	if back.PersonID == 0 {
+		return nil
+	}
+	if err := s.syncPersonEvents(ctx, treeID, back.PersonID); err != nil {
+		return err
+	}
+	return s.syncPersonEvents(ctx, treeID, into.PersonID)
+}
+
+// citationOwnedBy - ссылка подтверждает сведения именно о человеке или связи owner
+func citationOwnedBy(c models.Citation, owner CitationOwner) bool {
+	if owner.PersonID != 0 {
+		return sameID(c.PersonID, &owner.PersonID)
+	}
+	return sameID(c.RelationshipID, &owner.RelationshipID)
+}
+
+// liveRelationshipsOf - связи человека не из корзины, по возрастанию ID
+func (s *MemoryStore) liveRelationshipsOf(treeID, personID int) []models.Relationship {
+	var rels []models.Relationship
+	for _, mr := range s.relationships {
+		if mr.live(treeID) && (mr.rel.FromPersonID == personID || mr.rel.ToPersonID == personID) {
+			rels = append(rels, mr.rel)
+		}
+	}
+	sort.Slice(rels, func(i, j int) bool { return rels[i].ID < rels[j].ID })
+	return rels
+}
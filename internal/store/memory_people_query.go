@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+// QueryPeople - см. SQLiteStore.QueryPeople
+func (s *MemoryStore) QueryPeople(ctx context.Context, treeID int, q PeopleQuery) (*PeoplePage, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(q)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.ListPeople(ctx, treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	surname := genealogy.SurnameKey(q.Surname)
+	people := []models.Person{}
+	for _, p := range all {
+		year, hasYear := genealogy.DateYear(p.BirthDate)
+		switch {
+		case q.Surname != "" && genealogy.SurnameKey(p.LastName) != surname,
+			q.Gender != "" && p.Gender != q.Gender,
+			q.BornFrom != 0 && (!hasYear || year < q.BornFrom),
+			q.BornTo != 0 && (!hasYear || year > q.BornTo),
+			q.Living != nil && genealogy.IsLiving(p, now) != *q.Living,
+			q.HasPhoto != nil && (p.PhotoURL != "" || p.PhotoMediaID != nil) != *q.HasPhoto:
+			continue
+		}
+		people = append(people, p)
+	}
+
+	// compare: <0 - a раньше b в порядке возрастания
+	compare := func(a models.Person, value string, id int) int {
+		if c := strings.Compare(sortValue(a, q.Sort), value); c != 0 {
+			return c
+		}
+		return a.ID - id
+	}
+	sort.Slice(people, func(i, j int) bool {
+		c := compare(people[i], sortValue(people[j], q.Sort), people[j].ID)
+		return (c < 0) != q.Desc
+	})
+	if cursor != nil {
+		start := len(people)
+		for i, p := range people {
+			if c := compare(p, cursor.Value, cursor.ID); (c > 0) != q.Desc && c != 0 {
+				start = i
+				break
+			}
+		}
+		people = people[start:]
+	}
+	if q.Limit > 0 && len(people) > q.Limit+1 {
+		people = people[:q.Limit+1]
+	}
+	return pageOf(people, q), nil
+}
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+type memPlace struct {
+	treeID int
+	place  models.Place // без FullName, его считает listPlaces
+}
+
+// checkPlaces - см. checkPlaces для SQLite
+func (s *MemoryStore) checkPlaces(treeID int, placeIDs ...*int) error {
+	for _, id := range placeIDs {
+		if id == nil {
+			continue
+		}
+		if mp, ok := s.places[*id]; !ok || mp.treeID != treeID {
+			return ErrInvalidPlace
+		}
+	}
+	return nil
+}
+
+// listPlaces - копии мест дерева с полными названиями, по полному названию
+func (s *MemoryStore) listPlaces(treeID int) []models.Place {
+	places := []models.Place{}
+	for _, mp := range s.places {
+		if mp.treeID == treeID {
+			p := mp.place
+			p.Names = append([]models.PlaceName{}, p.Names...)
+			places = append(places, p)
+		}
+	}
+	genealogy.FillFullNames(places)
+	genealogy.SortPlaces(places)
+	return places
+}
+
+func (s *MemoryStore) getPlace(treeID, placeID int) (*models.Place, error) {
+	for _, p := range s.listPlaces(treeID) {
+		if p.ID == placeID {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// placeWithin - см. placeWithin для SQLite
+func (s *MemoryStore) placeWithin(placeID, ancestorID int) bool {
+	seen := map[int]bool{}
+	for id := placeID; !seen[id]; {
+		if id == ancestorID {
+			return true
+		}
+		seen[id] = true
+		mp, ok := s.places[id]
+		if !ok || mp.place.ParentID == nil {
+			return false
+		}
+		id = *mp.place.ParentID
+	}
+	return false
+}
+
+func (s *MemoryStore) ListPlaces(ctx context.Context, treeID int) ([]models.Place, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listPlaces(treeID), nil
+}
+
+func (s *MemoryStore) GetPlace(ctx context.Context, treeID, placeID int) (*models.Place, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getPlace(treeID, placeID)
+}
+
+func (s *MemoryStore) CreatePlace(ctx context.Context, treeID int, p *models.Place) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkPlaces(treeID, p.ParentID); err != nil {
+		return err
+	}
+	cleanPlaceNames(p)
+	s.nextPlaceID++
+	p.ID = s.nextPlaceID
+	p.FullName = ""
+	s.places[p.ID] = memPlace{treeID: treeID, place: *p}
+	created, err := s.getPlace(treeID, p.ID)
+	if err != nil {
+		return err
+	}
+	*p = *created
+	return nil
+}
+
+func (s *MemoryStore) UpdatePlace(ctx context.Context, treeID int, p models.Place) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updatePlace(treeID, p)
+}
+
+func (s *MemoryStore) updatePlace(treeID int, p models.Place) error {
+	if mp, ok := s.places[p.ID]; !ok || mp.treeID != treeID {
+		return ErrNotFound
+	}
+	if err := s.checkPlaces(treeID, p.ParentID); err != nil {
+		return err
+	}
+	if p.ParentID != nil && s.placeWithin(*p.ParentID, p.ID) {
+		return ErrPlaceCycle
+	}
+	cleanPlaceNames(&p)
+	p.FullName = ""
+	s.places[p.ID] = memPlace{treeID: treeID, place: p}
+	return nil
+}
+
+func (s *MemoryStore) DeletePlace(ctx context.Context, treeID, placeID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mp, ok := s.places[placeID]; !ok || mp.treeID != treeID {
+		return ErrNotFound
+	}
+	for _, mp := range s.people {
+		if mp.deletedAt.IsZero() && (sameID(mp.person.BirthPlaceID, &placeID) || sameID(mp.person.DeathPlaceID, &placeID)) {
+			return ErrPlaceInUse
+		}
+	}
+	for _, me := range s.events {
+		if sameID(me.event.PlaceID, &placeID) {
+			return ErrPlaceInUse
+		}
+	}
+	for _, mp := range s.places {
+		if sameID(mp.place.ParentID, &placeID) {
+			return ErrPlaceInUse
+		}
+	}
+	// У людей в корзине ссылка обнуляется - как внешний ключ в SQLite
+	s.replacePlace(placeID, nil)
+	delete(s.places, placeID)
+	return nil
+}
+
+// replacePlace меняет ссылки людей (без журнала) и событий с места from на to
+func (s *MemoryStore) replacePlace(from int, to *int) int {
+	events := 0
+	for id, mp := range s.people {
+		if sameID(mp.person.BirthPlaceID, &from) {
+			mp.person.BirthPlaceID = to
+		}
+		if sameID(mp.person.DeathPlaceID, &from) {
+			mp.person.DeathPlaceID = to
+		}
+		s.people[id] = mp
+	}
+	for id, me := range s.events {
+		if sameID(me.event.PlaceID, &from) {
+			me.event.PlaceID = to
+			s.events[id] = me
+			events++
+		}
+	}
+	return events
+}
+
+func (s *MemoryStore) MergePlaces(ctx context.Context, treeID, keepID, otherID int) (*models.PlaceMergeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keepID == otherID {
+		return nil, ErrPlaceCycle
+	}
+	keep, err := s.getPlace(treeID, keepID)
+	if err != nil {
+		return nil, err
+	}
+	other, err := s.getPlace(treeID, otherID)
+	if err != nil {
+		return nil, err
+	}
+	if s.placeWithin(keepID, otherID) {
+		return nil, ErrPlaceCycle
+	}
+	result := &models.PlaceMergeResult{MergedID: otherID}
+
+	for id, mp := range s.places {
+		if sameID(mp.place.ParentID, &otherID) {
+			mp.place.ParentID = &keepID
+			s.places[id] = mp
+			result.Children++
+		}
+	}
+	merged := mergePlace(*keep, *other, func(placeID int) bool { return s.placeWithin(placeID, keepID) })
+	if err := s.updatePlace(treeID, merged); err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for id, mp := range s.people {
+		if mp.live(treeID) && (sameID(mp.person.BirthPlaceID, &otherID) || sameID(mp.person.DeathPlaceID, &otherID)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	for _, me := range s.events {
+		if sameID(me.event.PlaceID, &otherID) {
+			result.Events++
+		}
+	}
+	for _, id := range ids {
+		p := s.people[id].person
+		if sameID(p.BirthPlaceID, &otherID) {
+			p.BirthPlaceID = &keepID
+		}
+		if sameID(p.DeathPlaceID, &otherID) {
+			p.DeathPlaceID = &keepID
+		}
+		if err := s.updatePerson(ctx, treeID, p, 0); err != nil {
+			return nil, err
+		}
+	}
+	result.People = len(ids)
+	s.replacePlace(otherID, &keepID)
+	delete(s.places, otherID)
+
+	place, err := s.getPlace(treeID, keepID)
+	if err != nil {
+		return nil, err
+	}
+	result.Place = *place
+	return result, nil
+}
+
+// copyPlaces - см. copyPlaces для SQLite
+func (s *MemoryStore) copyPlaces(treeID, newTreeID int) map[int]int {
+	places := s.listPlaces(treeID)
+	placeIDs := make(map[int]int, len(places))
+	for _, p := range places {
+		s.nextPlaceID++
+		placeIDs[p.ID] = s.nextPlaceID
+	}
+	for _, p := range places {
+		p.ID, p.ParentID, p.FullName = placeIDs[p.ID], mapID(p.ParentID, placeIDs), ""
+		s.places[p.ID] = memPlace{treeID: newTreeID, place: p}
+	}
+	return placeIDs
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+// SearchPeople - см. SQLiteStore.SearchPeople. Индекса нет: каждый человек
+// дерева проверяется genealogy.MatchPerson.
+func (s *MemoryStore) SearchPeople(ctx context.Context, treeID int, query string, limit int) ([]models.Person, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	people := []models.Person{}
+	terms := genealogy.ParseSearchQuery(query)
+	if len(terms) == 0 {
+		return people, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	score := map[int]float64{}
+	for id, mp := range s.people {
+		if !mp.live(treeID) {
+			continue
+		}
+		if v := genealogy.MatchPerson(mp.person, terms); v > 0 {
+			score[id] = v
+			people = append(people, mp.person)
+		}
+	}
+	sort.Slice(people, func(i, j int) bool {
+		if score[people[i].ID] != score[people[j].ID] {
+			return score[people[i].ID] > score[people[j].ID]
+		}
+		return people[i].ID < people[j].ID
+	})
+	if len(people) > limit {
+		people = people[:limit]
+	}
+	return people, nil
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+func (s *MemoryStore) CreateShareLink(ctx context.Context, link *models.ShareLink, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextShareLinkID++
+	link.ID = s.nextShareLinkID
+	link.Token = ""
+	link.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	link.ExpiresAt, link.RevokedAt = nil, nil
+	if !expiresAt.IsZero() {
+		v := expiresAt.UTC().Format(time.RFC3339)
+		link.ExpiresAt = &v
+	}
+	s.shareLinks[link.ID] = *link
+	return nil
+}
+
+func (s *MemoryStore) ListShareLinks(ctx context.Context, treeID int) ([]models.ShareLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links := []models.ShareLink{}
+	for _, link := range s.shareLinks {
+		if link.TreeID == treeID {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links, nil
+}
+
+func (s *MemoryStore) GetShareLink(ctx context.Context, linkID int) (*models.ShareLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.shareLinks[linkID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &link, nil
+}
+
+func (s *MemoryStore) RevokeShareLink(ctx context.Context, treeID, linkID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.shareLinks[linkID]
+	if !ok || link.TreeID != treeID {
+		return ErrNotFound
+	}
+	if link.RevokedAt == nil {
+		v := time.Now().UTC().Format(time.RFC3339)
+		link.RevokedAt = &v
+		s.shareLinks[linkID] = link
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+type memberKey struct {
+	treeID int
+	userID int
+}
+
+type memInvite struct {
+	invite    models.TreeInvite
+	tokenHash string
+	expiresAt time.Time // нулевое - без срока
+}
+
+// active - приглашение ещё можно принять
+func (mi memInvite) active(now time.Time) bool {
+	return mi.invite.UsedAt == nil && (mi.expiresAt.IsZero() || mi.expiresAt.After(now))
+}
+
+// --- Участники ---
+
+func (s *MemoryStore) GetMemberRole(ctx context.Context, treeID, userID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.members[memberKey{treeID, userID}]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return m.Role, nil
+}
+
+func (s *MemoryStore) ListMembers(ctx context.Context, treeID int) ([]models.TreeMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := []models.TreeMember{}
+	for key, m := range s.members {
+		if key.treeID == treeID {
+			m.Email = s.users[key.userID].Email
+			members = append(members, m)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].CreatedAt != members[j].CreatedAt {
+			return members[i].CreatedAt < members[j].CreatedAt
+		}
+		return members[i].UserID < members[j].UserID
+	})
+	return members, nil
+}
+
+func (s *MemoryStore) SetMemberRole(ctx context.Context, treeID, userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memberKey{treeID, userID}
+	m, ok := s.members[key]
+	if !ok {
+		return ErrNotFound
+	}
+	m.Role = role
+	s.members[key] = m
+	return nil
+}
+
+func (s *MemoryStore) RemoveMember(ctx context.Context, treeID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memberKey{treeID, userID}
+	if _, ok := s.members[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.members, key)
+	return nil
+}
+
+// --- Приглашения ---
+
+func (s *MemoryStore) CreateInvite(ctx context.Context, inv *models.TreeInvite, tokenHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextInviteID++
+	inv.ID = s.nextInviteID
+	inv.TreeName = s.trees[inv.TreeID].Name
+	inv.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	inv.ExpiresAt, inv.UsedAt = nil, nil
+	if !expiresAt.IsZero() {
+		v := expiresAt.UTC().Format(time.RFC3339)
+		inv.ExpiresAt = &v
+	}
+
+	saved := *inv
+	saved.Token = "" // как и в SQLite, сам токен не хранится
+	s.invites[inv.ID] = memInvite{invite: saved, tokenHash: tokenHash, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *MemoryStore) ListInvites(ctx context.Context, treeID int) ([]models.TreeInvite, error) {
+	return s.listInvites(func(mi memInvite) bool {
+		return mi.invite.TreeID == treeID && mi.invite.UsedAt == nil
+	}), nil
+}
+
+func (s *MemoryStore) listInvites(match func(memInvite) bool) []models.TreeInvite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invites := []models.TreeInvite{}
+	for _, mi := range s.invites {
+		if match(mi) {
+			invites = append(invites, mi.invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].ID < invites[j].ID })
+	return invites
+}
+
+func (s *MemoryStore) DeleteInvite(ctx context.Context, treeID, inviteID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mi, ok := s.invites[inviteID]
+	if !ok || mi.invite.TreeID != treeID {
+		return ErrNotFound
+	}
+	delete(s.invites, inviteID)
+	return nil
+}
+
+func (s *MemoryStore) AcceptInvite(ctx context.Context, tokenHash string, userID int, email string) (*models.TreeMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, mi := range s.invites {
+		if mi.tokenHash != tokenHash {
+			continue
+		}
+		if !mi.active(now) || (mi.invite.Email != "" && !strings.EqualFold(mi.invite.Email, email)) {
+			return nil, ErrInviteInvalid
+		}
+
+		usedAt := now.UTC().Format(time.RFC3339)
+		mi.invite.UsedAt = &usedAt
+		s.invites[id] = mi
+
+		key := memberKey{mi.invite.TreeID, userID}
+		m, ok := s.members[key]
+		if !ok {
+			m = models.TreeMember{TreeID: key.treeID, UserID: userID, Role: mi.invite.Role, CreatedAt: usedAt}
+		} else if !models.RoleAtLeast(m.Role, mi.invite.Role) {
+			m.Role = mi.invite.Role
+		}
+		s.members[key] = m
+		m.Email = s.users[userID].Email
+		return &m, nil
+	}
+	return nil, ErrInviteInvalid
+}
@@ -0,0 +1,281 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"family-tree-app/internal/models"
+)
+
+type memSource struct {
+	treeID int
+	source models.Source
+}
+
+type memCitation struct {
+	treeID   int
+	citation models.Citation
+}
+
+// checkCitationOwner - см. checkCitationOwner для SQLite
+func (s *MemoryStore) checkCitationOwner(treeID int, owner CitationOwner) error {
+	switch {
+	case owner.PersonID != 0:
+		return s.checkEventOwner(treeID, EventOwner{PersonID: owner.PersonID}, false)
+	case owner.RelationshipID != 0:
+		return s.checkEventOwner(treeID, EventOwner{RelationshipID: owner.RelationshipID}, false)
+	}
+	me, ok := s.events[owner.EventID]
+	if !ok || me.treeID != treeID {
+		return ErrNotFound
+	}
+	var eventOwner EventOwner
+	if me.event.PersonID != nil {
+		eventOwner.PersonID = *me.event.PersonID
+	} else {
+		eventOwner.RelationshipID = *me.event.RelationshipID
+	}
+	return s.checkEventOwner(treeID, eventOwner, false)
+}
+
+// cites - ссылка подтверждает сведения о владельце (см. ListCitations)
+func (s *MemoryStore) cites(treeID int, c models.Citation, owner CitationOwner) bool {
+	switch {
+	case owner.PersonID != 0:
+		if sameID(c.PersonID, &owner.PersonID) {
+			return true
+		}
+		if c.RelationshipID != nil {
+			return s.personRelationship(treeID, owner.PersonID, *c.RelationshipID)
+		}
+		if c.EventID != nil {
+			e := s.events[*c.EventID].event
+			return sameID(e.PersonID, &owner.PersonID) ||
+				(e.RelationshipID != nil && s.personRelationship(treeID, owner.PersonID, *e.RelationshipID))
+		}
+	case owner.RelationshipID != 0:
+		if sameID(c.RelationshipID, &owner.RelationshipID) {
+			return true
+		}
+		return c.EventID != nil && sameID(s.events[*c.EventID].event.RelationshipID, &owner.RelationshipID)
+	default:
+		return sameID(c.EventID, &owner.EventID)
+	}
+	return false
+}
+
+// personRelationship - связь relID не в корзине и ведёт от человека или к нему
+func (s *MemoryStore) personRelationship(treeID, personID, relID int) bool {
+	mr, ok := s.relationships[relID]
+	return ok && mr.live(treeID) && (mr.rel.FromPersonID == personID || mr.rel.ToPersonID == personID)
+}
+
+// listCitations - ссылки дерева, подходящие под match, по ID
+func (s *MemoryStore) listCitations(treeID int, match func(models.Citation) bool) []models.Citation {
+	citations := []models.Citation{}
+	for _, mc := range s.citations {
+		if mc.treeID == treeID && match(mc.citation) {
+			citations = append(citations, mc.citation)
+		}
+	}
+	sort.Slice(citations, func(i, j int) bool { return citations[i].ID < citations[j].ID })
+	return citations
+}
+
+func (s *MemoryStore) ListSources(ctx context.Context, treeID int) ([]models.Source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sources := []models.Source{}
+	for _, ms := range s.sources {
+		if ms.treeID == treeID {
+			sources = append(sources, ms.source)
+		}
+	}
+	sortSources(sources)
+	return sources, nil
+}
+
+func (s *MemoryStore) GetSource(ctx context.Context, treeID, sourceID int) (*models.Source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms, ok := s.sources[sourceID]
+	if !ok || ms.treeID != treeID {
+		return nil, ErrNotFound
+	}
+	src := ms.source
+	return &src, nil
+}
+
+func (s *MemoryStore) CreateSource(ctx context.Context, treeID int, src *models.Source) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextSourceID++
+	src.ID = s.nextSourceID
+	s.sources[src.ID] = memSource{treeID: treeID, source: *src}
+	return nil
+}
+
+func (s *MemoryStore) UpdateSource(ctx context.Context, treeID int, src models.Source) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ms, ok := s.sources[src.ID]; !ok || ms.treeID != treeID {
+		return ErrNotFound
+	}
+	s.sources[src.ID] = memSource{treeID: treeID, source: src}
+	return nil
+}
+
+func (s *MemoryStore) DeleteSource(ctx context.Context, treeID, sourceID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ms, ok := s.sources[sourceID]; !ok || ms.treeID != treeID {
+		return ErrNotFound
+	}
+	for _, mc := range s.citations {
+		if mc.citation.SourceID == sourceID {
+			return ErrSourceInUse
+		}
+	}
+	delete(s.sources, sourceID)
+	return nil
+}
+
+func (s *MemoryStore) ListCitations(ctx context.Context, treeID int, owner CitationOwner) ([]models.Citation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCitationOwner(treeID, owner); err != nil {
+		return nil, err
+	}
+	return s.listCitations(treeID, func(c models.Citation) bool { return s.cites(treeID, c, owner) }), nil
+}
+
+func (s *MemoryStore) ListSourceCitations(ctx context.Context, treeID, sourceID int) ([]models.Citation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ms, ok := s.sources[sourceID]; !ok || ms.treeID != treeID {
+		return nil, ErrNotFound
+	}
+	return s.listCitations(treeID, func(c models.Citation) bool { return c.SourceID == sourceID }), nil
+}
+
+func (s *MemoryStore) ListTreeCitations(ctx context.Context, treeID int) ([]models.Citation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.listCitations(treeID, func(models.Citation) bool { return true }), nil
+}
+
+func (s *MemoryStore) CreateCitation(ctx context.Context, treeID int, owner CitationOwner, c *models.Citation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCitationOwner(treeID, owner); err != nil {
+		return err
+	}
+	if ms, ok := s.sources[c.SourceID]; !ok || ms.treeID != treeID {
+		return ErrInvalidSource
+	}
+	s.nextCitationID++
+	c.ID = s.nextCitationID
+	c.PersonID, c.RelationshipID, c.EventID = citationOwnerIDs(owner)
+	s.citations[c.ID] = memCitation{treeID: treeID, citation: *c}
+	return nil
+}
+
+func (s *MemoryStore) UpdateCitation(ctx context.Context, treeID int, c models.Citation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ms, ok := s.sources[c.SourceID]; !ok || ms.treeID != treeID {
+		return ErrInvalidSource
+	}
+	mc, ok := s.citations[c.ID]
+	if !ok || mc.treeID != treeID {
+		return ErrNotFound
+	}
+	c.PersonID, c.RelationshipID, c.EventID = mc.citation.PersonID, mc.citation.RelationshipID, mc.citation.EventID
+	mc.citation = c
+	s.citations[c.ID] = mc
+	return nil
+}
+
+func (s *MemoryStore) DeleteCitation(ctx context.Context, treeID, citationID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mc, ok := s.citations[citationID]; !ok || mc.treeID != treeID {
+		return ErrNotFound
+	}
+	delete(s.citations, citationID)
+	return nil
+}
+
+// dropOrphanCitations стирает ссылки стёртых людей, связей и событий - как внешний ключ в SQLite
+func (s *MemoryStore) dropOrphanCitations() {
+	for id, mc := range s.citations {
+		c := mc.citation
+		_, person := s.people[derefID(c.PersonID)]
+		_, rel := s.relationships[derefID(c.RelationshipID)]
+		_, event := s.events[derefID(c.EventID)]
+		if !person && !rel && !event {
+			delete(s.citations, id)
+		}
+	}
+}
+
+// derefID - ID или 0 (такого ID не бывает), если не задан
+func derefID(id *int) int {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// moveCitations переносит ссылки человека или связи from на to (при слиянии)
+func (s *MemoryStore) moveCitations(from, to CitationOwner) {
+	fromPerson, fromRel, _ := citationOwnerIDs(from)
+	toPerson, toRel, _ := citationOwnerIDs(to)
+	for id, mc := range s.citations {
+		if (fromPerson != nil && sameID(mc.citation.PersonID, fromPerson)) || (fromRel != nil && sameID(mc.citation.RelationshipID, fromRel)) {
+			mc.citation.PersonID, mc.citation.RelationshipID = toPerson, toRel
+			s.citations[id] = mc
+		}
+	}
+}
+
+// copySources - см. copySources для SQLite
+func (s *MemoryStore) copySources(treeID, newTreeID int, personIDs, relIDs, eventIDs map[int]int) {
+	var ids []int
+	for id, ms := range s.sources {
+		if ms.treeID == treeID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	sourceIDs := make(map[int]int, len(ids))
+	for _, id := range ids {
+		src := s.sources[id].source
+		s.nextSourceID++
+		src.ID = s.nextSourceID
+		sourceIDs[id] = src.ID
+		s.sources[src.ID] = memSource{treeID: newTreeID, source: src}
+	}
+
+	for _, c := range s.listCitations(treeID, func(models.Citation) bool { return true }) {
+		c.PersonID, c.RelationshipID, c.EventID = mapID(c.PersonID, personIDs), mapID(c.RelationshipID, relIDs), mapID(c.EventID, eventIDs)
+		if c.PersonID == nil && c.RelationshipID == nil && c.EventID == nil {
+			continue
+		}
+		c.SourceID = sourceIDs[c.SourceID]
+		s.nextCitationID++
+		c.ID = s.nextCitationID
+		s.citations[c.ID] = memCitation{treeID: newTreeID, citation: c}
+	}
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+func (s *MemoryStore) ListTrash(ctx context.Context, treeID int) (*models.Trash, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trash := &models.Trash{People: []models.TrashedPerson{}, Relationships: []models.TrashedRelationship{}}
+	for _, mp := range s.people {
+		if mp.treeID == treeID && !mp.deletedAt.IsZero() {
+			trash.People = append(trash.People, models.TrashedPerson{Person: mp.person, DeletedAt: mp.deletedAt.Format(time.RFC3339)})
+		}
+	}
+	for _, mr := range s.relationships {
+		if mr.treeID == treeID && !mr.deletedAt.IsZero() {
+			trash.Relationships = append(trash.Relationships, models.TrashedRelationship{Relationship: mr.rel, DeletedAt: mr.deletedAt.Format(time.RFC3339)})
+		}
+	}
+	// Как в SQLite: недавно удалённые первыми, при равном времени - больший ID
+	sort.Slice(trash.People, func(i, j int) bool {
+		a, b := s.people[trash.People[i].ID].deletedAt, s.people[trash.People[j].ID].deletedAt
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return trash.People[i].ID > trash.People[j].ID
+	})
+	sort.Slice(trash.Relationships, func(i, j int) bool {
+		a, b := s.relationships[trash.Relationships[i].ID].deletedAt, s.relationships[trash.Relationships[j].ID].deletedAt
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return trash.Relationships[i].ID > trash.Relationships[j].ID
+	})
+	return trash, nil
+}
+
+func (s *MemoryStore) RestoreFromTrash(ctx context.Context, treeID int, entityType string, id int) ([]models.Change, error) {
+	s.mu.Lock()
+	var trashed bool
+	switch entityType {
+	case models.EntityPerson:
+		mp, ok := s.people[id]
+		trashed = ok && mp.treeID == treeID && !mp.deletedAt.IsZero()
+	case models.EntityRelationship:
+		mr, ok := s.relationships[id]
+		trashed = ok && mr.treeID == treeID && !mr.deletedAt.IsZero()
+	}
+	s.mu.Unlock()
+
+	if !trashed {
+		return nil, ErrNotFound
+	}
+	return revertLastDelete(ctx, s, treeID, entityType, id)
+}
+
+func (s *MemoryStore) PurgeFromTrash(ctx context.Context, treeID int, entityType string, id int) (models.PurgeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result models.PurgeResult
+	switch entityType {
+	case models.EntityPerson:
+		if mp, ok := s.people[id]; ok && mp.treeID == treeID && !mp.deletedAt.IsZero() {
+			result = s.purgePeople(func(mp memPerson) bool { return mp.person.ID == id })
+		}
+	case models.EntityRelationship:
+		if mr, ok := s.relationships[id]; ok && mr.treeID == treeID && !mr.deletedAt.IsZero() {
+			delete(s.relationships, id)
+			s.dropOrphanEvents()
+			result.Relationships = 1
+		}
+	}
+	if result.People+result.Relationships == 0 {
+		return result, ErrNotFound
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) EmptyTrash(ctx context.Context, treeID int) (models.PurgeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rels := s.purgeRelationships(func(mr memRelationship) bool { return mr.treeID == treeID })
+	result := s.purgePeople(func(mp memPerson) bool { return mp.treeID == treeID })
+	result.Relationships += rels
+	return result, nil
+}
+
+func (s *MemoryStore) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (models.PurgeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rels := s.purgeRelationships(func(mr memRelationship) bool { return mr.deletedAt.Before(cutoff) })
+	result := s.purgePeople(func(mp memPerson) bool { return mp.deletedAt.Before(cutoff) })
+	result.Relationships += rels
+	return result, nil
+}
+
+// purgePeople стирает из корзины подходящих людей вместе со всеми их связями
+func (s *MemoryStore) purgePeople(match func(memPerson) bool) models.PurgeResult {
+	var result models.PurgeResult
+	purged := map[int]bool{}
+	for id, mp := range s.people {
+		if !mp.deletedAt.IsZero() && match(mp) {
+			purged[id] = true
+			delete(s.people, id)
+			result.People++
+		}
+	}
+	for id, mr := range s.relationships {
+		if purged[mr.rel.FromPersonID] || purged[mr.rel.ToPersonID] {
+			delete(s.relationships, id)
+			result.Relationships++
+		}
+	}
+	for id, u := range s.users {
+		if u.LinkToPersonID != nil && purged[*u.LinkToPersonID] {
+			u.LinkToPersonID = nil
+			s.users[id] = u
+		}
+	}
+	s.dropOrphanEvents()
+	return result
+}
+
+func (s *MemoryStore) purgeRelationships(match func(memRelationship) bool) int {
+	n := 0
+	for id, mr := range s.relationships {
+		if !mr.deletedAt.IsZero() && match(mr) {
+			delete(s.relationships, id)
+			n++
+		}
+	}
+	s.dropOrphanEvents()
+	return n
+}
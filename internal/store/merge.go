@@ -0,0 +1,94 @@
+package store
+
+import (
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+// mergePlan - что сделать со связями при слиянии человека other в keep
+type mergePlan struct {
+	repoint  []models.Relationship // связи other с концом, перенесённым на keep
+	drop     []int                 // связи, которые после переноса повторили бы существующие или замкнулись бы на keep
+	describe map[int]string        // пустые описания связей keep, которые заполняются из выброшенных повторов
+	events   map[int]int           // выброшенный повтор -> связь, к которой переходят его события
+}
+
+// mergeMoves - что слияние перенесло с удалённого (второго человека или
+// выброшенного повтора связи) на оставшегося Into. Пишется в запись журнала
+// об удалении; отмена удаления переносит это обратно, кроме того, что
+// с тех пор уже ушло от Into.
+type mergeMoves struct {
+	Into      int   `json:"into"`
+	Events    []int `json:"events,omitempty"`
+	Citations []int `json:"citations,omitempty"`
+	Media     []int `json:"media,omitempty"`        // файлы, чья связь перенесена
+	Shared    []int `json:"shared_media,omitempty"` // файлы, уже связанные с Into: связь удалённого стёрта
+	Users     []int `json:"users,omitempty"`        // аккаунты, привязанные к человеку
+}
+
+// orNil - nil, если переносить было нечего: запись журнала обходится без moved
+func (m *mergeMoves) orNil() *mergeMoves {
+	if m == nil || len(m.Events)+len(m.Citations)+len(m.Media)+len(m.Shared)+len(m.Users) == 0 {
+		return nil
+	}
+	return m
+}
+
+// planMerge раскладывает связи other: каждая либо переносится на keep,
+// либо выбрасывается как повтор (см. genealogy.SameEdge) или связь между
+// самими сливаемыми людьми. Описание выброшенного повтора не теряется,
+// если у оставшейся связи его нет, а его события (брак, развод) переходят к ней.
+func planMerge(keepID, otherID int, keepRels, otherRels []models.Relationship) mergePlan {
+	plan := mergePlan{describe: map[int]string{}, events: map[int]int{}}
+
+	type kept struct {
+		rel     models.Relationship
+		repoint int // индекс в plan.repoint, -1 - связь keep
+	}
+	var existing []kept
+	for _, rel := range keepRels {
+		if rel.FromPersonID != otherID && rel.ToPersonID != otherID {
+			existing = append(existing, kept{rel, -1})
+		}
+	}
+
+	for _, rel := range otherRels {
+		moved := rel
+		if moved.FromPersonID == otherID {
+			moved.FromPersonID = keepID
+		}
+		if moved.ToPersonID == otherID {
+			moved.ToPersonID = keepID
+		}
+		if moved.FromPersonID == moved.ToPersonID {
+			plan.drop = append(plan.drop, rel.ID)
+			continue
+		}
+
+		duplicate := -1
+		for i, e := range existing {
+			if genealogy.SameEdge(e.rel, moved) {
+				duplicate = i
+				break
+			}
+		}
+		if duplicate < 0 {
+			existing = append(existing, kept{moved, len(plan.repoint)})
+			plan.repoint = append(plan.repoint, moved)
+			continue
+		}
+
+		plan.drop = append(plan.drop, rel.ID)
+		e := &existing[duplicate]
+		plan.events[rel.ID] = e.rel.ID
+		if e.rel.Description == "" && rel.Description != "" {
+			e.rel.Description = rel.Description
+			if e.repoint >= 0 {
+				plan.repoint[e.repoint].Description = rel.Description
+			} else {
+				plan.describe[e.rel.ID] = rel.Description
+			}
+		}
+	}
+	return plan
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"family-tree-app/internal/models"
+)
+
+// Ошибки разбора PeopleQuery
+var (
+	// ErrInvalidCursor - курсор страницы повреждён или выдан для другой сортировки
+	ErrInvalidCursor = errors.New("неверный курсор страницы")
+	ErrInvalidSort   = errors.New("неизвестное поле сортировки")
+)
+
+// Поля сортировки списка людей
+const (
+	SortByID        = "id"
+	SortByLastName  = "last_name"
+	SortByFirstName = "first_name"
+	SortByBirthDate = "birth_date"
+)
+
+// PeopleQuery - отбор, сортировка и страница списка людей. Нулевые поля не ограничивают.
+type PeopleQuery struct {
+	Surname  string // фамилия в любой форме и записи (см. genealogy.SurnameKey)
+	Gender   string
+	BornFrom int   // год рождения не раньше; люди без года рождения при этом отбрасываются
+	BornTo   int   // год рождения не позже
+	Living   *bool // живые или умершие в смысле genealogy.IsLiving
+	HasPhoto *bool
+
+	Sort   string // одно из SortBy*, по умолчанию SortByID; при равенстве - по ID
+	Desc   bool
+	Cursor string // NextCursor предыдущей страницы
+	Limit  int    // 0 - все
+}
+
+// PeoplePage - страница списка людей. NextCursor пустой на последней странице.
+type PeoplePage struct {
+	People     []models.Person
+	NextCursor string
+}
+
+// peopleCursor - позиция после последнего человека страницы:
+// значение поля сортировки и ID. Сортировка и направление зашиты
+// в курсор, чтобы его нельзя было применить к другому порядку.
+type peopleCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c peopleCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor разбирает курсор запроса q; nil - курсора нет
+func decodeCursor(q PeopleQuery) (*peopleCursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c peopleCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != q.Sort || c.Desc != q.Desc {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// sortValue - значение поля сортировки у человека
+func sortValue(p models.Person, field string) string {
+	switch field {
+	case SortByLastName:
+		return p.LastName
+	case SortByFirstName:
+		return p.FirstName
+	case SortByBirthDate:
+		// по диапазону даты, как столбцы birth_from и birth_to в SQLite
+		d, _ := models.ParseDate(p.BirthDate)
+		return d.SortKey()
+	}
+	return ""
+}
+
+// normalize подставляет сортировку по умолчанию и проверяет поле:
+// оно попадает в текст SQL, поэтому допустимы только PeopleSortFields
+func (q *PeopleQuery) normalize() error {
+	if q.Sort == "" {
+		q.Sort = SortByID
+	}
+	for _, field := range PeopleSortFields {
+		if q.Sort == field {
+			return nil
+		}
+	}
+	return ErrInvalidSort
+}
+
+// PeopleSortFields - допустимые значения PeopleQuery.Sort
+var PeopleSortFields = []string{SortByID, SortByLastName, SortByFirstName, SortByBirthDate}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"strings"
+
+	"family-tree-app/internal/models"
+)
+
+// mapID - ID из другого дерева по соответствию ID (при копировании дерева);
+// nil, если ID не задан или не скопирован
+func mapID(id *int, ids map[int]int) *int {
+	if id == nil {
+		return nil
+	}
+	newID, ok := ids[*id]
+	if !ok {
+		return nil
+	}
+	return &newID
+}
+
+// cleanPlaceNames убирает пустые и повторяющиеся названия, а также совпадающие
+// с основным названием места
+func cleanPlaceNames(p *models.Place) {
+	names := make([]models.PlaceName, 0, len(p.Names))
+	seen := map[models.PlaceName]bool{}
+	for _, n := range p.Names {
+		n.Name, n.Lang = strings.TrimSpace(n.Name), strings.ToLower(strings.TrimSpace(n.Lang))
+		if n.Name == "" || seen[n] || (n.Name == p.Name && n.Lang == "") {
+			continue
+		}
+		seen[n] = true
+		names = append(names, n)
+	}
+	p.Names = names
+}
+
+// mergePlace - место keep после слияния с other: названия other становятся
+// другими названиями keep, пустые поля keep заполняются из other.
+// Вышестоящее место берётся у other, только если keep не входит в него
+// (within - входит ли место в keep).
+func mergePlace(keep, other models.Place, within func(placeID int) bool) models.Place {
+	merged := keep
+	merged.Names = append(append([]models.PlaceName(nil), keep.Names...), models.PlaceName{Name: other.Name})
+	merged.Names = append(merged.Names, other.Names...)
+	cleanPlaceNames(&merged)
+	if merged.Type == "" {
+		merged.Type = other.Type
+	}
+	if merged.Latitude == nil || merged.Longitude == nil {
+		merged.Latitude, merged.Longitude = other.Latitude, other.Longitude
+	}
+	if merged.GeoNamesID == nil {
+		merged.GeoNamesID = other.GeoNamesID
+	}
+	if merged.ParentID == nil && other.ParentID != nil && *other.ParentID != keep.ID && !within(*other.ParentID) {
+		merged.ParentID = other.ParentID
+	}
+	return merged
+}
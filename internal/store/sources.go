@@ -0,0 +1,27 @@
+package store
+
+import (
+	"sort"
+
+	"family-tree-app/internal/models"
+)
+
+func citationOwnerIDs(owner CitationOwner) (personID, relID, eventID *int) {
+	switch {
+	case owner.PersonID != 0:
+		return &owner.PersonID, nil, nil
+	case owner.RelationshipID != 0:
+		return nil, &owner.RelationshipID, nil
+	}
+	return nil, nil, &owner.EventID
+}
+
+// sortSources - по названию, при равенстве - по ID
+func sortSources(sources []models.Source) {
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Title != sources[j].Title {
+			return sources[i].Title < sources[j].Title
+		}
+		return sources[i].ID < sources[j].ID
+	})
+}
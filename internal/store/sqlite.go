@@ -0,0 +1,503 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"family-tree-app/internal/models"
+)
+
+var _ Store = (*SQLiteStore)(nil)
+
+// SQLiteStore - реализация Store поверх SQLite
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLite создаёт хранилище поверх уже открытой и смигрированной базы
+func NewSQLite(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+// treeOwner - подзапрос, заполняющий user_id людей и связей владельцем дерева
+const treeOwner = `(SELECT user_id FROM trees WHERE id = ?)`
+
+// inTx выполняет fn в одной транзакции: коммит, если fn вернула nil, иначе откат.
+// Через него идут все изменения из нескольких запросов, чтобы сбой посередине
+// не оставлял базу в промежуточном состоянии.
+func (s *SQLiteStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// --- Люди ---
+
+func (s *SQLiteStore) CreatePerson(ctx context.Context, treeID int, p *models.Person) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// При создании position_x/y по умолчанию 0
+		p.PositionX, p.PositionY = 0, 0
+		if err := insertPerson(ctx, tx, treeID, p, false); err != nil {
+			return err
+		}
+		_, err := logChange(ctx, tx, personChange(treeID, models.ActionCreate, nil, p))
+		return err
+	})
+}
+
+func (s *SQLiteStore) ListPeople(ctx context.Context, treeID int) ([]models.Person, error) {
+	query := `SELECT ` + personColumns + ` FROM people WHERE tree_id = ? AND deleted_at IS NULL`
+	rows, err := s.db.QueryContext(ctx, query, treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	people := []models.Person{}
+	for rows.Next() {
+		p, err := scanPerson(rows)
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, *p)
+	}
+	return people, rows.Err()
+}
+
+func (s *SQLiteStore) UpdatePerson(ctx context.Context, treeID int, p models.Person) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := getPerson(ctx, tx, treeID, p.ID)
+		if err != nil {
+			return err
+		}
+		if err := updatePerson(ctx, tx, treeID, p); err != nil {
+			return err
+		}
+		// Координаты меняются только через UpdatePersonPosition
+		p.PositionX, p.PositionY = before.PositionX, before.PositionY
+		_, err = logChange(ctx, tx, personChange(treeID, models.ActionUpdate, before, &p))
+		return err
+	})
+}
+
+// UpdatePersonPosition в журнал не попадает: это раскладка графа, а не данные о человеке
+func (s *SQLiteStore) UpdatePersonPosition(ctx context.Context, treeID, personID int, x, y float64) error {
+	query := `UPDATE people SET position_x=?, position_y=? WHERE id=? AND tree_id=? AND deleted_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, x, y, personID, treeID)
+	return err
+}
+
+func (s *SQLiteStore) DeletePerson(ctx context.Context, treeID, personID int) (*models.Removed, error) {
+	var removed *models.Removed
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		removed, err = deletePerson(ctx, tx, treeID, personID, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// --- Связи ---
+
+func (s *SQLiteStore) CreateRelationship(ctx context.Context, treeID int, rel *models.Relationship) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := insertRelationship(ctx, tx, treeID, rel, false); err != nil {
+			return err
+		}
+		_, err := logChange(ctx, tx, relationshipChange(treeID, models.ActionCreate, nil, rel))
+		return err
+	})
+}
+
+func (s *SQLiteStore) ListRelationships(ctx context.Context, treeID int) ([]models.Relationship, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, from_person_id, to_person_id, type, description FROM relationships WHERE tree_id = ? AND deleted_at IS NULL", treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	relationships := []models.Relationship{}
+	for rows.Next() {
+		var rel models.Relationship
+		var description sql.NullString
+		if err := rows.Scan(&rel.ID, &rel.FromPersonID, &rel.ToPersonID, &rel.Type, &description); err != nil {
+			return nil, err
+		}
+		rel.Description = description.String
+		relationships = append(relationships, rel)
+	}
+	return relationships, rows.Err()
+}
+
+func (s *SQLiteStore) UpdateRelationshipDescription(ctx context.Context, treeID, relID int, description string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return updateRelationshipDescription(ctx, tx, treeID, relID, description, 0)
+	})
+}
+
+func (s *SQLiteStore) DeleteRelationship(ctx context.Context, treeID, relID int) (*models.Removed, error) {
+	var removed *models.Removed
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		rel, err := deleteRelationship(ctx, tx, treeID, relID, 0)
+		if err != nil {
+			return err
+		}
+		removed = &models.Removed{People: []models.Person{}, Relationships: []models.Relationship{*rel}}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// --- Импорт ---
+
+func (s *SQLiteStore) ImportTree(ctx context.Context, treeID int, people []models.Person, relationships []models.Relationship) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM trees WHERE id = ?", treeID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrNotFound
+		}
+
+		realID := make(map[int]int, len(people))
+		for i := range people {
+			p := &people[i]
+			localID := p.ID
+			if err := insertPerson(ctx, tx, treeID, p, false); err != nil {
+				return err
+			}
+			realID[localID] = p.ID
+			if _, err := logChange(ctx, tx, personChange(treeID, models.ActionCreate, nil, p)); err != nil {
+				return err
+			}
+		}
+
+		for i := range relationships {
+			rel := &relationships[i]
+			from, okFrom := realID[rel.FromPersonID]
+			to, okTo := realID[rel.ToPersonID]
+			if !okFrom || !okTo {
+				return ErrInvalidReference
+			}
+			rel.FromPersonID, rel.ToPersonID = from, to
+			if err := insertRelationship(ctx, tx, treeID, rel, false); err != nil {
+				return err
+			}
+			if _, err := logChange(ctx, tx, relationshipChange(treeID, models.ActionCreate, nil, rel)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// --- Деревья ---
+
+func (s *SQLiteStore) CreateTree(ctx context.Context, userID int, name string) (*models.Tree, error) {
+	var id int
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		id, err = insertTree(ctx, tx, userID, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTree(ctx, id)
+}
+
+// insertTree создаёт дерево и записывает его владельца в участники
+func insertTree(ctx context.Context, tx *sql.Tx, userID int, name string) (int, error) {
+	result, err := tx.ExecContext(ctx, "INSERT INTO trees (user_id, name) VALUES (?, ?)", userID, name)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := result.LastInsertId()
+	if _, err := tx.ExecContext(ctx, "INSERT INTO tree_members (tree_id, user_id, role) VALUES (?, ?, ?)", id, userID, models.RoleOwner); err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *SQLiteStore) ListTrees(ctx context.Context, userID int) ([]models.Tree, error) {
+	// Свои деревья и те, куда пользователя пригласили
+	query := `
+	SELECT t.id, t.user_id, t.name, t.created_at, m.role
+	FROM trees t JOIN tree_members m ON m.tree_id = t.id
+	WHERE m.user_id = ? ORDER BY t.id`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trees := []models.Tree{}
+	for rows.Next() {
+		var t models.Tree
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt, &t.Role); err != nil {
+			return nil, err
+		}
+		trees = append(trees, t)
+	}
+	return trees, rows.Err()
+}
+
+func (s *SQLiteStore) GetTree(ctx context.Context, treeID int) (*models.Tree, error) {
+	var t models.Tree
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, user_id, name, created_at FROM trees WHERE id = ?", treeID,
+	).Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *SQLiteStore) RenameTree(ctx context.Context, treeID int, name string) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE trees SET name = ? WHERE id = ?", name, treeID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+func (s *SQLiteStore) DeleteTree(ctx context.Context, treeID int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// Участники и приглашения удаляются каскадом по внешнему ключу.
+		// Ссылки аккаунтов на людей из удаляемого дерева иначе нарушат внешний ключ
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET link_to_person_id = NULL WHERE link_to_person_id IN (SELECT id FROM people WHERE tree_id = ?)", treeID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM relationships WHERE tree_id = ?", treeID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM people WHERE tree_id = ?", treeID); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, "DELETE FROM trees WHERE id = ?", treeID)
+		if err != nil {
+			return err
+		}
+		return expectAffected(result)
+	})
+}
+
+func (s *SQLiteStore) DuplicateTree(ctx context.Context, treeID, userID int, name string) (*models.Tree, error) {
+	var newID int
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM trees WHERE id = ?", treeID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrNotFound
+		}
+		var err error
+		newID, err = insertTree(ctx, tx, userID, name)
+		if err != nil {
+			return err
+		}
+
+		placeIDs, err := copyPlaces(ctx, tx, treeID, newID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, "SELECT id, birth_place_id, death_place_id FROM people WHERE tree_id = ? AND deleted_at IS NULL ORDER BY id", treeID)
+		if err != nil {
+			return err
+		}
+		var oldIDs []int
+		type personPlaces struct{ birth, death *int }
+		oldPlaces := map[int]personPlaces{}
+		for rows.Next() {
+			var id int
+			var birth, death sql.NullInt64
+			if err := rows.Scan(&id, &birth, &death); err != nil {
+				rows.Close()
+				return err
+			}
+			oldIDs = append(oldIDs, id)
+			oldPlaces[id] = personPlaces{intPtr(birth), intPtr(death)}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Корзина не копируется. Копируем людей по одному, чтобы знать соответствие старых и новых ID
+		realID := make(map[int]int, len(oldIDs))
+		personQuery := `
+		INSERT INTO people (tree_id, user_id, first_name, middle_name, last_name, birth_date, death_date, gender, photo_url, notes, position_x, position_y,
+			birth_from, birth_to, death_from, death_to, birth_place_id, death_place_id)
+		SELECT ?, ?, first_name, middle_name, last_name, birth_date, death_date, gender, photo_url, notes, position_x, position_y,
+			birth_from, birth_to, death_from, death_to, ?, ?
+		FROM people WHERE id = ?`
+		for _, oldID := range oldIDs {
+			places := oldPlaces[oldID]
+			result, err := tx.ExecContext(ctx, personQuery, newID, userID, mapID(places.birth, placeIDs), mapID(places.death, placeIDs), oldID)
+			if err != nil {
+				return err
+			}
+			id, _ := result.LastInsertId()
+			realID[oldID] = int(id)
+			// Поисковая строка у копии та же, что у оригинала
+			if _, err := tx.ExecContext(ctx, "INSERT INTO people_fts (rowid, names, notes, folded, phonetic) SELECT ?, names, notes, folded, phonetic FROM people_fts WHERE rowid = ?", id, oldID); err != nil {
+				return err
+			}
+		}
+
+		rels, err := tx.QueryContext(ctx, "SELECT id, from_person_id, to_person_id, type, description FROM relationships WHERE tree_id = ? AND deleted_at IS NULL ORDER BY id", treeID)
+		if err != nil {
+			return err
+		}
+		type relRow struct {
+			id, from, to int
+			typ          string
+			description  sql.NullString
+		}
+		var copies []relRow
+		for rels.Next() {
+			var r relRow
+			if err := rels.Scan(&r.id, &r.from, &r.to, &r.typ, &r.description); err != nil {
+				rels.Close()
+				return err
+			}
+			copies = append(copies, r)
+		}
+		rels.Close()
+		if err := rels.Err(); err != nil {
+			return err
+		}
+
+		relQuery := `INSERT INTO relationships (tree_id, user_id, from_person_id, to_person_id, type, description) VALUES (?, ?, ?, ?, ?, ?)`
+		realRelID := make(map[int]int, len(copies))
+		for _, r := range copies {
+			from, okFrom := realID[r.from]
+			to, okTo := realID[r.to]
+			if !okFrom || !okTo {
+				continue // связь на человека вне дерева - наследие старых данных, не копируем
+			}
+			result, err := tx.ExecContext(ctx, relQuery, newID, userID, from, to, r.typ, r.description)
+			if err != nil {
+				return err
+			}
+			id, _ := result.LastInsertId()
+			realRelID[r.id] = int(id)
+		}
+		eventIDs, err := copyEvents(ctx, tx, treeID, newID, realID, realRelID, placeIDs)
+		if err != nil {
+			return err
+		}
+		if err := copySources(ctx, tx, treeID, newID, realID, realRelID, eventIDs); err != nil {
+			return err
+		}
+		return copyMedia(ctx, tx, treeID, newID, realID, realRelID, eventIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTree(ctx, newID)
+}
+
+// EnsureDefaultTree создаёт дерево по умолчанию одним условным INSERT внутри
+// транзакции: два одновременных первых запроса не заведут пользователю два дерева.
+func (s *SQLiteStore) EnsureDefaultTree(ctx context.Context, userID int) (*models.Tree, error) {
+	var id int
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO trees (user_id, name) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM trees WHERE user_id = ?)",
+			userID, DefaultTreeName, userID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			newID, _ := result.LastInsertId()
+			id = int(newID)
+			_, err := tx.ExecContext(ctx, "INSERT INTO tree_members (tree_id, user_id, role) VALUES (?, ?, ?)", id, userID, models.RoleOwner)
+			return err
+		}
+		return tx.QueryRowContext(ctx, "SELECT id FROM trees WHERE user_id = ? ORDER BY id LIMIT 1", userID).Scan(&id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTree(ctx, id)
+}
+
+// --- Пользователи ---
+
+func (s *SQLiteStore) CreateUser(ctx context.Context, email, passwordHash string) (int, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO users (email, password_hash) VALUES (?, ?)`, email, passwordHash)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return 0, ErrEmailTaken
+		}
+		return 0, err
+	}
+	id, _ := result.LastInsertId()
+	return int(id), nil
+}
+
+func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.getUser(ctx, "email = ?", email)
+}
+
+func (s *SQLiteStore) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	return s.getUser(ctx, "id = ?", id)
+}
+
+func (s *SQLiteStore) getUser(ctx context.Context, where string, arg interface{}) (*models.User, error) {
+	var u models.User
+	var isVerified sql.NullBool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, email, password_hash, is_verified, link_to_person_id FROM users WHERE "+where, arg,
+	).Scan(&u.ID, &u.Email, &u.PasswordHash, &isVerified, &u.LinkToPersonID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.IsVerified = isVerified.Bool
+	return &u, nil
+}
+
+func (s *SQLiteStore) SetPasswordHash(ctx context.Context, id int, passwordHash string) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+// expectAffected превращает "ни одна строка не изменена" в ErrNotFound
+func expectAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
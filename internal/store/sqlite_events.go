@@ -0,0 +1,347 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+const eventColumns = "id, person_id, relationship_id, type, date, place, place_id, description"
+
+// eventOrder - как sortEvents: по началу диапазона даты, без даты - в конце
+const eventOrder = " ORDER BY COALESCE(date_from, date_to) IS NULL, COALESCE(date_from, date_to), id"
+
+func scanEvent(row rowScanner) (*models.Event, error) {
+	var e models.Event
+	var personID, relID, placeID sql.NullInt64
+	if err := row.Scan(&e.ID, &personID, &relID, &e.Type, &e.Date, &e.Place, &placeID, &e.Description); err != nil {
+		return nil, err
+	}
+	e.PersonID, e.RelationshipID, e.PlaceID = intPtr(personID), intPtr(relID), intPtr(placeID)
+	return &e, nil
+}
+
+// ownerWhere - условие на владельца события и его аргумент
+func ownerWhere(owner EventOwner) (string, interface{}) {
+	if owner.PersonID != 0 {
+		return "person_id = ?", owner.PersonID
+	}
+	return "relationship_id = ?", owner.RelationshipID
+}
+
+// checkEventOwner проверяет, что человек или связь есть в дереве. spouses - события
+// пары можно добавлять только связи супругов.
+func checkEventOwner(ctx context.Context, q querier, treeID int, owner EventOwner, spouses bool) error {
+	if owner.PersonID != 0 {
+		_, err := getPerson(ctx, q, treeID, owner.PersonID)
+		return err
+	}
+	rel, err := getRelationship(ctx, q, treeID, owner.RelationshipID)
+	if err != nil {
+		return err
+	}
+	if spouses && genealogy.KindOf(rel.Type) != genealogy.KindSpouse {
+		return ErrNotSpouses
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListEvents(ctx context.Context, treeID int, owner EventOwner) ([]models.Event, error) {
+	if err := checkEventOwner(ctx, s.db, treeID, owner, false); err != nil {
+		return nil, err
+	}
+	where, arg := ownerWhere(owner)
+	rows, err := s.db.QueryContext(ctx, "SELECT "+eventColumns+" FROM events WHERE tree_id = ? AND "+where+eventOrder, treeID, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLiteStore) ListTreeEvents(ctx context.Context, treeID int) ([]models.Event, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+eventColumns+" FROM events WHERE tree_id = ? ORDER BY id", treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLiteStore) CreateEvent(ctx context.Context, treeID int, owner EventOwner, e *models.Event) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkEventOwner(ctx, tx, treeID, owner, true); err != nil {
+			return err
+		}
+		if err := checkPlaces(ctx, tx, treeID, e.PlaceID); err != nil {
+			return err
+		}
+		var personID, relID interface{}
+		if owner.PersonID != 0 {
+			personID = owner.PersonID
+		} else {
+			relID = owner.RelationshipID
+		}
+		from, to := dateBounds(e.Date)
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO events (tree_id, person_id, relationship_id, type, date, date_from, date_to, place, place_id, description)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			treeID, personID, relID, e.Type, e.Date, from, to, e.Place, e.PlaceID, e.Description)
+		if err != nil {
+			return err
+		}
+		id, _ := result.LastInsertId()
+		e.ID = int(id)
+		e.PersonID, e.RelationshipID = eventOwnerIDs(owner)
+		if err := syncEventDates(ctx, tx, treeID, owner, *e, e.Type); err != nil {
+			return err
+		}
+		// пустые дату и место событие могло взять у человека
+		created, err := getEvent(ctx, tx, treeID, owner, e.ID)
+		if err != nil {
+			return err
+		}
+		*e = *created
+		return nil
+	})
+}
+
+func (s *SQLiteStore) UpdateEvent(ctx context.Context, treeID int, owner EventOwner, e models.Event) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkEventOwner(ctx, tx, treeID, owner, true); err != nil {
+			return err
+		}
+		before, err := getEvent(ctx, tx, treeID, owner, e.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkPlaces(ctx, tx, treeID, e.PlaceID); err != nil {
+			return err
+		}
+		from, to := dateBounds(e.Date)
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE events SET type = ?, date = ?, date_from = ?, date_to = ?, place = ?, place_id = ?, description = ? WHERE id = ?",
+			e.Type, e.Date, from, to, e.Place, e.PlaceID, e.Description, e.ID); err != nil {
+			return err
+		}
+		return syncEventDates(ctx, tx, treeID, owner, e, before.Type)
+	})
+}
+
+func (s *SQLiteStore) DeleteEvent(ctx context.Context, treeID int, owner EventOwner, eventID int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkEventOwner(ctx, tx, treeID, owner, false); err != nil {
+			return err
+		}
+		before, err := getEvent(ctx, tx, treeID, owner, eventID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM events WHERE id = ?", eventID); err != nil {
+			return err
+		}
+		if owner.PersonID == 0 {
+			return nil
+		}
+		return syncLifeEvent(ctx, tx, treeID, owner.PersonID, before.Type, true)
+	})
+}
+
+func getEvent(ctx context.Context, q querier, treeID int, owner EventOwner, eventID int) (*models.Event, error) {
+	where, arg := ownerWhere(owner)
+	e, err := scanEvent(q.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM events WHERE id = ? AND tree_id = ? AND "+where, eventID, treeID, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+func eventOwnerIDs(owner EventOwner) (personID, relID *int) {
+	if owner.PersonID != 0 {
+		return &owner.PersonID, nil
+	}
+	return nil, &owner.RelationshipID
+}
+
+// syncEventDates переносит дату и место записанного события e в человека.
+// previousType - тип события до изменения: если рождение стало крещением,
+// дата и место рождения человека берутся из следующего события birth.
+func syncEventDates(ctx context.Context, tx *sql.Tx, treeID int, owner EventOwner, e models.Event, previousType string) error {
+	if owner.PersonID == 0 {
+		return nil
+	}
+	if previousType != e.Type {
+		if err := syncLifeEvent(ctx, tx, treeID, owner.PersonID, previousType, true); err != nil {
+			return err
+		}
+	}
+	return syncLifeEvent(ctx, tx, treeID, owner.PersonID, e.Type, true)
+}
+
+// syncLifeEvent выравнивает дату и место человека и его первого (по ID) события
+// eventType. fromEvent - главнее событие (его только что изменили или удалили):
+// человек получает его дату и место с записью в журнал, а пустые дату и место
+// событие само берёт у человека (см. mergeLifeFacts); не осталось события -
+// дата и место человека стираются. Иначе событие получает дату и место человека,
+// а если события нет, оно заводится.
+func syncLifeEvent(ctx context.Context, tx *sql.Tx, treeID, personID int, eventType string, fromEvent bool) error {
+	if !isLifeEvent(eventType) {
+		return nil
+	}
+	var eventID int
+	var date string
+	var placeID sql.NullInt64
+	err := tx.QueryRowContext(ctx,
+		"SELECT id, date, place_id FROM events WHERE tree_id = ? AND person_id = ? AND type = ? ORDER BY id LIMIT 1", treeID, personID, eventType,
+	).Scan(&eventID, &date, &placeID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	p, perr := getPerson(ctx, tx, treeID, personID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(perr, ErrNotFound) {
+			return nil // человек в корзине: его даты не трогаем
+		}
+		if perr != nil {
+			return perr
+		}
+		return syncMissingLifeEvent(ctx, tx, treeID, *p, eventType, fromEvent)
+	}
+	if perr != nil {
+		return perr
+	}
+
+	if fromEvent {
+		newDate, newPlace := mergeLifeFacts(*p, eventType, date, intPtr(placeID))
+		if personDate, personPlace := lifeFacts(*p, eventType); newDate != personDate || !sameID(newPlace, personPlace) {
+			// updatePerson сам вернёт событию то, что взято у человека
+			before := *p
+			setLifeFacts(p, eventType, newDate, newPlace)
+			if err := updatePerson(ctx, tx, treeID, *p); err != nil {
+				return err
+			}
+			_, err = logChange(ctx, tx, personChange(treeID, models.ActionUpdate, &before, p))
+			return err
+		}
+	}
+
+	current, currentPlace := lifeFacts(*p, eventType)
+	if current == date && sameID(currentPlace, intPtr(placeID)) {
+		return nil
+	}
+	from, to := dateBounds(current)
+	_, err = tx.ExecContext(ctx, "UPDATE events SET date = ?, date_from = ?, date_to = ?, place_id = ? WHERE id = ?", current, from, to, currentPlace, eventID)
+	return err
+}
+
+// syncMissingLifeEvent - у человека p нет события eventType. Удалили последнее
+// (fromEvent) - стираются дата и место человека, иначе по ним заводится событие.
+func syncMissingLifeEvent(ctx context.Context, tx *sql.Tx, treeID int, p models.Person, eventType string, fromEvent bool) error {
+	date, placeID := lifeFacts(p, eventType)
+	if date == "" && placeID == nil {
+		return nil
+	}
+	if fromEvent {
+		before := p
+		setLifeFacts(&p, eventType, "", nil)
+		if err := updatePerson(ctx, tx, treeID, p); err != nil {
+			return err
+		}
+		_, err := logChange(ctx, tx, personChange(treeID, models.ActionUpdate, &before, &p))
+		return err
+	}
+	from, to := dateBounds(date)
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO events (tree_id, person_id, type, date, date_from, date_to, place_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		treeID, p.ID, eventType, date, from, to, placeID)
+	return err
+}
+
+// syncPersonEvents - после записи человека его события birth и death получают
+// его даты и места; недостающие события заводятся
+func syncPersonEvents(ctx context.Context, tx *sql.Tx, treeID, personID int) error {
+	for _, eventType := range []string{models.EventBirth, models.EventDeath} {
+		if err := syncLifeEvent(ctx, tx, treeID, personID, eventType, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyEvents копирует события людей и связей в другое дерево по соответствию ID
+// людей, связей и мест и возвращает соответствие ID событий
+func copyEvents(ctx context.Context, tx *sql.Tx, treeID, newTreeID int, personIDs, relIDs, placeIDs map[int]int) (map[int]int, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT "+eventColumns+", date_from, date_to FROM events WHERE tree_id = ? ORDER BY id", treeID)
+	if err != nil {
+		return nil, err
+	}
+	type eventRow struct {
+		e        models.Event
+		from, to sql.NullString
+	}
+	var copies []eventRow
+	for rows.Next() {
+		var r eventRow
+		var personID, relID, placeID sql.NullInt64
+		if err := rows.Scan(&r.e.ID, &personID, &relID, &r.e.Type, &r.e.Date, &r.e.Place, &placeID, &r.e.Description, &r.from, &r.to); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.e.PersonID, r.e.RelationshipID = intPtr(personID), intPtr(relID)
+		r.e.PlaceID = mapID(intPtr(placeID), placeIDs)
+		copies = append(copies, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	eventIDs := make(map[int]int, len(copies))
+	for _, r := range copies {
+		var personID, relID interface{}
+		if r.e.PersonID != nil {
+			id, ok := personIDs[*r.e.PersonID]
+			if !ok {
+				continue // человек в корзине не копируется, его события тоже
+			}
+			personID = id
+		} else {
+			id, ok := relIDs[*r.e.RelationshipID]
+			if !ok {
+				continue
+			}
+			relID = id
+		}
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO events (tree_id, person_id, relationship_id, type, date, date_from, date_to, place, place_id, description)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			newTreeID, personID, relID, r.e.Type, r.e.Date, r.from, r.to, r.e.Place, r.e.PlaceID, r.e.Description)
+		if err != nil {
+			return nil, err
+		}
+		id, _ := result.LastInsertId()
+		eventIDs[r.e.ID] = int(id)
+	}
+	return eventIDs, nil
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+
+	"github.com/glebarez/go-sqlite"
+)
+
+// Функции SQL, которые считает приложение: так отбор людей в SQLite
+// совпадает с правилами пакета genealogy. Регистрируются до открытия базы.
+func init() {
+	// surname_key(last_name) - см. genealogy.SurnameKey
+	sqlite.MustRegisterDeterministicScalarFunction("surname_key", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return genealogy.SurnameKey(textArg(args[0])), nil
+	})
+	// date_year(date) - год даты или NULL
+	sqlite.MustRegisterDeterministicScalarFunction("date_year", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if year, ok := genealogy.DateYear(textArg(args[0])); ok {
+			return int64(year), nil
+		}
+		return nil, nil
+	})
+	// date_from(date), date_to(date) - границы диапазона даты, как в столбцах birth_from и birth_to
+	sqlite.MustRegisterDeterministicScalarFunction("date_from", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		from, _ := dateBounds(textArg(args[0]))
+		return from, nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("date_to", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		_, to := dateBounds(textArg(args[0]))
+		return to, nil
+	})
+	// is_living(birth_date, death_date, today) - см. genealogy.IsLiving; today в формате 2006-01-02
+	sqlite.MustRegisterDeterministicScalarFunction("is_living", 3, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		today, err := time.Parse(time.DateOnly, textArg(args[2]))
+		if err != nil {
+			return nil, err
+		}
+		p := models.Person{BirthDate: textArg(args[0])}
+		if args[1] != nil {
+			death := textArg(args[1])
+			p.DeathDate = &death
+		}
+		return genealogy.IsLiving(p, today), nil
+	})
+}
+
+// textArg - текстовый аргумент функции, NULL - пустая строка
+func textArg(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
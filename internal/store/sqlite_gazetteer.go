@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+// Офлайн-справочник GeoNames: таблица gazetteer и индекс gazetteer_fts (миграция 0012)
+
+const gazetteerColumns = "geonames_id, name, ascii_name, alternate_names, latitude, longitude, feature_class, feature_code, country_code, admin1, population"
+
+func scanGazetteerEntry(row rowScanner) (*models.GazetteerEntry, error) {
+	var e models.GazetteerEntry
+	var alternateNames string
+	if err := row.Scan(&e.GeoNamesID, &e.Name, &e.ASCIIName, &alternateNames, &e.Latitude, &e.Longitude,
+		&e.FeatureClass, &e.FeatureCode, &e.CountryCode, &e.Admin1, &e.Population); err != nil {
+		return nil, err
+	}
+	e.AlternateNames = []string{}
+	if alternateNames != "" {
+		e.AlternateNames = strings.Split(alternateNames, ",")
+	}
+	return &e, nil
+}
+
+// gazetteerNames - все названия записи для поиска
+func gazetteerNames(e models.GazetteerEntry) []string {
+	return append([]string{e.Name, e.ASCIIName}, e.AlternateNames...)
+}
+
+func (s *SQLiteStore) ImportGazetteer(ctx context.Context, entries []models.GazetteerEntry) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		insert, err := tx.PrepareContext(ctx, "INSERT OR REPLACE INTO gazetteer ("+gazetteerColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer insert.Close()
+		index, err := tx.PrepareContext(ctx, "INSERT OR REPLACE INTO gazetteer_fts (rowid, names, folded) VALUES (?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer index.Close()
+
+		for _, e := range entries {
+			if _, err := insert.ExecContext(ctx, e.GeoNamesID, e.Name, e.ASCIIName, strings.Join(e.AlternateNames, ","), e.Latitude, e.Longitude,
+				e.FeatureClass, e.FeatureCode, e.CountryCode, e.Admin1, e.Population); err != nil {
+				return err
+			}
+			names := gazetteerNames(e)
+			if _, err := index.ExecContext(ctx, e.GeoNamesID, strings.Join(names, " "), genealogy.GazetteerKeys(names)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) SearchGazetteer(ctx context.Context, query, country string, limit int) ([]models.GazetteerEntry, error) {
+	entries := []models.GazetteerEntry{}
+	terms := genealogy.ParseSearchQuery(query)
+	if len(terms) == 0 {
+		return entries, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	clauses := make([]string, 0, len(terms))
+	for _, t := range terms {
+		alts := []string{fmt.Sprintf(`names:"%s"*`, t.Text)}
+		if t.Folded != "" {
+			alts = append(alts, fmt.Sprintf(`folded:"%s"*`, t.Folded))
+		}
+		clauses = append(clauses, "("+strings.Join(alts, " OR ")+")")
+	}
+
+	// Крупные места первыми: "Тверь" - скорее город, чем деревня с тем же названием
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+prefixColumns("g.", gazetteerColumns)+`
+	FROM gazetteer_fts f JOIN gazetteer g ON g.geonames_id = f.rowid
+	WHERE gazetteer_fts MATCH ? AND (? = '' OR g.country_code = ?)
+	ORDER BY g.population DESC, bm25(gazetteer_fts), g.geonames_id
+	LIMIT ?`, strings.Join(clauses, " AND "), country, strings.ToUpper(country), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanGazetteerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLiteStore) GetGazetteerEntry(ctx context.Context, geonamesID int) (*models.GazetteerEntry, error) {
+	e, err := scanGazetteerEntry(s.db.QueryRowContext(ctx, "SELECT "+gazetteerColumns+" FROM gazetteer WHERE geonames_id = ?", geonamesID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
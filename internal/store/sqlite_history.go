@@ -0,0 +1,656 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"family-tree-app/internal/models"
+)
+
+// Операции над людьми и связями внутри транзакции. Каждая изменяющая
+// операция сразу пишет запись в audit_log той же транзакцией.
+// Записи с deleted_at лежат в корзине: читающие и изменяющие операции их не видят.
+
+const personColumns = "id, first_name, middle_name, last_name, birth_date, death_date, gender, photo_url, notes, position_x, position_y, birth_place_id, death_place_id, photo_media_id"
+
+// querier - общее у *sql.DB и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// scanPerson читает столбцы personColumns и затем extra. NULL в необязательных
+// столбцах (старые записи) читается как пустое значение.
+func scanPerson(row rowScanner, extra ...interface{}) (*models.Person, error) {
+	var p models.Person
+	var photoUrl *string
+	var middleName *string
+	var birthDate, gender, notes sql.NullString
+	var x, y sql.NullFloat64
+	var birthPlace, deathPlace, photoMedia sql.NullInt64
+	dest := []interface{}{&p.ID, &p.FirstName, &middleName, &p.LastName, &birthDate, &p.DeathDate, &gender, &photoUrl, &notes, &x, &y, &birthPlace, &deathPlace, &photoMedia}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	p.BirthDate, p.Gender, p.Notes = birthDate.String, gender.String, notes.String
+	p.PositionX, p.PositionY = x.Float64, y.Float64
+	p.BirthPlaceID, p.DeathPlaceID = intPtr(birthPlace), intPtr(deathPlace)
+	p.PhotoMediaID = intPtr(photoMedia)
+	if photoUrl != nil {
+		p.PhotoURL = *photoUrl
+	}
+	if middleName != nil {
+		p.MiddleName = *middleName
+	}
+	return &p, nil
+}
+
+func getPerson(ctx context.Context, q querier, treeID, personID int) (*models.Person, error) {
+	p, err := scanPerson(q.QueryRowContext(ctx, "SELECT "+personColumns+" FROM people WHERE id = ? AND tree_id = ? AND deleted_at IS NULL", personID, treeID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return p, err
+}
+
+func getRelationship(ctx context.Context, q querier, treeID, relID int) (*models.Relationship, error) {
+	var rel models.Relationship
+	var description sql.NullString
+	err := q.QueryRowContext(ctx,
+		"SELECT id, from_person_id, to_person_id, type, description FROM relationships WHERE id = ? AND tree_id = ? AND deleted_at IS NULL", relID, treeID,
+	).Scan(&rel.ID, &rel.FromPersonID, &rel.ToPersonID, &rel.Type, &description)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	rel.Description = description.String
+	return &rel, err
+}
+
+// insertPerson добавляет человека в дерево. keepID - вставить с p.ID
+// (восстановление удалённого), иначе p.ID заполняется новым.
+func insertPerson(ctx context.Context, tx *sql.Tx, treeID int, p *models.Person, keepID bool) error {
+	var id interface{}
+	if keepID {
+		id = p.ID
+	}
+	if err := checkPlaces(ctx, tx, treeID, p.BirthPlaceID, p.DeathPlaceID); err != nil {
+		return err
+	}
+	if err := checkMedia(ctx, tx, treeID, p.PhotoMediaID); err != nil {
+		return err
+	}
+	birthFrom, birthTo, deathFrom, deathTo := personDateBounds(*p)
+	query := `INSERT INTO people (id, tree_id, user_id, first_name, middle_name, last_name, birth_date, death_date, gender, photo_url, notes, position_x, position_y,
+		birth_from, birth_to, death_from, death_to, birth_place_id, death_place_id, photo_media_id)
+	VALUES (?, ?, ` + treeOwner + `, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, id, treeID, treeID, p.FirstName, p.MiddleName, p.LastName, p.BirthDate, p.DeathDate, p.Gender, p.PhotoURL, p.Notes, p.PositionX, p.PositionY,
+		birthFrom, birthTo, deathFrom, deathTo, p.BirthPlaceID, p.DeathPlaceID, p.PhotoMediaID)
+	if err != nil {
+		return err
+	}
+	newID, _ := result.LastInsertId()
+	p.ID = int(newID)
+	if err := indexPerson(ctx, tx, *p); err != nil {
+		return err
+	}
+	return syncPersonEvents(ctx, tx, treeID, p.ID)
+}
+
+// dateBounds - границы диапазона даты для столбцов *_from и *_to (см. models.Date);
+// NULL, если границы нет или дату не удалось разобрать
+func dateBounds(s string) (from, to interface{}) {
+	d, err := models.ParseDate(s)
+	if err != nil {
+		return nil, nil
+	}
+	if d.From != "" {
+		from = d.From
+	}
+	if d.To != "" {
+		to = d.To
+	}
+	return from, to
+}
+
+func personDateBounds(p models.Person) (birthFrom, birthTo, deathFrom, deathTo interface{}) {
+	birthFrom, birthTo = dateBounds(p.BirthDate)
+	if p.DeathDate != nil {
+		deathFrom, deathTo = dateBounds(*p.DeathDate)
+	}
+	return birthFrom, birthTo, deathFrom, deathTo
+}
+
+func updatePerson(ctx context.Context, tx *sql.Tx, treeID int, p models.Person) error {
+	if err := checkPlaces(ctx, tx, treeID, p.BirthPlaceID, p.DeathPlaceID); err != nil {
+		return err
+	}
+	if err := checkMedia(ctx, tx, treeID, p.PhotoMediaID); err != nil {
+		return err
+	}
+	birthFrom, birthTo, deathFrom, deathTo := personDateBounds(p)
+	query := `UPDATE people SET first_name=?, middle_name=?, last_name=?, birth_date=?, death_date=?, gender=?, photo_url=?, notes=?,
+		birth_from=?, birth_to=?, death_from=?, death_to=?, birth_place_id=?, death_place_id=?, photo_media_id=? WHERE id=? AND tree_id=? AND deleted_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, p.FirstName, p.MiddleName, p.LastName, p.BirthDate, p.DeathDate, p.Gender, p.PhotoURL, p.Notes,
+		birthFrom, birthTo, deathFrom, deathTo, p.BirthPlaceID, p.DeathPlaceID, p.PhotoMediaID, p.ID, treeID)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result); err != nil {
+		return err
+	}
+	if err := indexPerson(ctx, tx, p); err != nil {
+		return err
+	}
+	return syncPersonEvents(ctx, tx, treeID, p.ID)
+}
+
+// deletePerson переносит человека в корзину вместе со связями и возвращает всё,
+// что удалено. Связи попадают в журнал с cause_id на запись об удалении
+// человека - по ней их потом восстанавливают.
+func deletePerson(ctx context.Context, tx *sql.Tx, treeID, personID int, revertOf int64) (*models.Removed, error) {
+	return deleteMovedPerson(ctx, tx, treeID, personID, revertOf, nil)
+}
+
+// deleteMovedPerson - deletePerson с записью в журнал того, что слияние перенесло с человека
+func deleteMovedPerson(ctx context.Context, tx *sql.Tx, treeID, personID int, revertOf int64, moved *mergeMoves) (*models.Removed, error) {
+	before, err := getPerson(ctx, tx, treeID, personID)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := relationshipsOf(ctx, tx, treeID, personID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE relationships SET deleted_at = CURRENT_TIMESTAMP WHERE (from_person_id=? OR to_person_id=?) AND tree_id=? AND deleted_at IS NULL",
+		personID, personID, treeID); err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, "UPDATE people SET deleted_at = CURRENT_TIMESTAMP WHERE id=? AND tree_id=? AND deleted_at IS NULL", personID, treeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectAffected(result); err != nil {
+		return nil, err
+	}
+
+	entry := personChange(treeID, models.ActionDelete, before, nil)
+	entry.revertOf, entry.moved = revertOf, moved
+	causeID, err := logChange(ctx, tx, entry)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rels {
+		entry := relationshipChange(treeID, models.ActionDelete, &rels[i], nil)
+		entry.causeID = causeID
+		if _, err := logChange(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	}
+	return &models.Removed{People: []models.Person{*before}, Relationships: rels}, nil
+}
+
+func relationshipsOf(ctx context.Context, q querier, treeID, personID int) ([]models.Relationship, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT id, from_person_id, to_person_id, type, description FROM relationships WHERE (from_person_id=? OR to_person_id=?) AND tree_id=? AND deleted_at IS NULL ORDER BY id",
+		personID, personID, treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rels := []models.Relationship{}
+	for rows.Next() {
+		var rel models.Relationship
+		var description sql.NullString
+		if err := rows.Scan(&rel.ID, &rel.FromPersonID, &rel.ToPersonID, &rel.Type, &description); err != nil {
+			return nil, err
+		}
+		rel.Description = description.String
+		rels = append(rels, rel)
+	}
+	return rels, rows.Err()
+}
+
+// insertRelationship добавляет связь, если оба человека есть в дереве, иначе ErrInvalidReference.
+// keepID - вставить с rel.ID (восстановление удалённой связи).
+func insertRelationship(ctx context.Context, tx *sql.Tx, treeID int, rel *models.Relationship, keepID bool) error {
+	var id interface{}
+	if keepID {
+		id = rel.ID
+	}
+	// Вставка и проверка принадлежности дереву - одним запросом, чтобы человека не удалили между ними
+	query := `
+	INSERT INTO relationships (id, tree_id, user_id, from_person_id, to_person_id, type, description)
+	SELECT ?, ?, ` + treeOwner + `, ?, ?, ?, ?
+	WHERE (SELECT COUNT(*) FROM people WHERE id IN (?, ?) AND tree_id = ? AND deleted_at IS NULL) = 2`
+	result, err := tx.ExecContext(ctx, query,
+		id, treeID, treeID, rel.FromPersonID, rel.ToPersonID, rel.Type, rel.Description,
+		rel.FromPersonID, rel.ToPersonID, treeID,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return ErrInvalidReference
+		}
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInvalidReference
+	}
+
+	newID, _ := result.LastInsertId()
+	rel.ID = int(newID)
+	return nil
+}
+
+func updateRelationshipDescription(ctx context.Context, tx *sql.Tx, treeID, relID int, description string, revertOf int64) error {
+	rel, err := getRelationship(ctx, tx, treeID, relID)
+	if err != nil {
+		return err
+	}
+	rel.Description = description
+	_, err = updateRelationship(ctx, tx, treeID, *rel, revertOf)
+	return err
+}
+
+// updateRelationship переписывает связь целиком: концы, тип и описание.
+// Оба человека должны быть в дереве, иначе ErrInvalidReference. Возвращает прежнее состояние.
+func updateRelationship(ctx context.Context, tx *sql.Tx, treeID int, rel models.Relationship, revertOf int64) (*models.Relationship, error) {
+	before, err := getRelationship(ctx, tx, treeID, rel.ID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, `
+	UPDATE relationships SET from_person_id=?, to_person_id=?, type=?, description=?
+	WHERE id=? AND tree_id=? AND deleted_at IS NULL
+	AND (SELECT COUNT(*) FROM people WHERE id IN (?, ?) AND tree_id = ? AND deleted_at IS NULL) = 2`,
+		rel.FromPersonID, rel.ToPersonID, rel.Type, rel.Description, rel.ID, treeID,
+		rel.FromPersonID, rel.ToPersonID, treeID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrInvalidReference
+	}
+
+	entry := relationshipChange(treeID, models.ActionUpdate, before, &rel)
+	entry.revertOf = revertOf
+	if _, err := logChange(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+func deleteRelationship(ctx context.Context, tx *sql.Tx, treeID, relID int, revertOf int64) (*models.Relationship, error) {
+	return deleteMovedRelationship(ctx, tx, treeID, relID, revertOf, nil)
+}
+
+// deleteMovedRelationship - deleteRelationship с записью в журнал того, что слияние перенесло со связи
+func deleteMovedRelationship(ctx context.Context, tx *sql.Tx, treeID, relID int, revertOf int64, moved *mergeMoves) (*models.Relationship, error) {
+	before, err := getRelationship(ctx, tx, treeID, relID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE relationships SET deleted_at = CURRENT_TIMESTAMP WHERE id=? AND tree_id=? AND deleted_at IS NULL", relID, treeID); err != nil {
+		return nil, err
+	}
+
+	entry := relationshipChange(treeID, models.ActionDelete, before, nil)
+	entry.revertOf, entry.moved = revertOf, moved
+	if _, err := logChange(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+// --- Журнал ---
+
+func logChange(ctx context.Context, tx *sql.Tx, c change) (int64, error) {
+	before, err := c.beforeJSON()
+	if err != nil {
+		return 0, err
+	}
+	after, err := c.afterJSON()
+	if err != nil {
+		return 0, err
+	}
+	moved, err := c.movedJSON()
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+	INSERT INTO audit_log (tree_id, user_id, entity_type, entity_id, action, before_json, after_json, from_person_id, to_person_id, cause_id, revert_of, moved_json)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query,
+		c.treeID, actorFrom(ctx), c.entityType, c.entityID, c.action, before, after,
+		nullID(int64(c.fromID)), nullID(int64(c.toID)), nullID(c.causeID), nullID(c.revertOf), moved,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// nullID превращает 0 в NULL
+func nullID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func (s *SQLiteStore) ListChanges(ctx context.Context, treeID int, f ChangeFilter) ([]models.Change, error) {
+	where := []string{"a.tree_id = ?"}
+	args := []interface{}{treeID}
+	if f.PersonID != 0 {
+		where = append(where, "((a.entity_type = 'person' AND a.entity_id = ?) OR a.from_person_id = ? OR a.to_person_id = ?)")
+		args = append(args, f.PersonID, f.PersonID, f.PersonID)
+	}
+	if f.EntityType != "" {
+		where = append(where, "a.entity_type = ? AND a.entity_id = ?")
+		args = append(args, f.EntityType, f.EntityID)
+	}
+	if f.Action != "" {
+		where = append(where, "a.action = ?")
+		args = append(args, f.Action)
+	}
+	if f.BeforeID != 0 {
+		where = append(where, "a.id < ?")
+		args = append(args, f.BeforeID)
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultChangeLimit
+	}
+	args = append(args, limit)
+
+	return s.queryChanges(ctx, s.db, strings.Join(where, " AND ")+" ORDER BY a.id DESC LIMIT ?", args...)
+}
+
+func (s *SQLiteStore) GetChange(ctx context.Context, treeID, changeID int) (*models.Change, error) {
+	changes, err := s.queryChanges(ctx, s.db, "a.tree_id = ? AND a.id = ?", treeID, changeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, ErrNotFound
+	}
+	return &changes[0], nil
+}
+
+func (s *SQLiteStore) queryChanges(ctx context.Context, q querier, where string, args ...interface{}) ([]models.Change, error) {
+	query := `
+	SELECT a.id, a.tree_id, a.user_id, COALESCE(u.email, ''), a.entity_type, a.entity_id, a.action,
+		a.before_json, a.after_json, a.cause_id, a.revert_of, a.moved_json, a.created_at
+	FROM audit_log a LEFT JOIN users u ON u.id = a.user_id
+	WHERE ` + where
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.Change{}
+	for rows.Next() {
+		var c models.Change
+		var userID, causeID, revertOf sql.NullInt64
+		var before, after, moved sql.NullString
+		if err := rows.Scan(&c.ID, &c.TreeID, &userID, &c.UserEmail, &c.EntityType, &c.EntityID, &c.Action,
+			&before, &after, &causeID, &revertOf, &moved, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.UserID = intPtr(userID)
+		c.CauseID = intPtr(causeID)
+		c.RevertOf = intPtr(revertOf)
+		c.Before = rawJSON(before)
+		c.After = rawJSON(after)
+		if moved.Valid {
+			c.Moved = json.RawMessage(moved.String)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func intPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+func rawJSON(v sql.NullString) json.RawMessage {
+	if !v.Valid {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(v.String)
+}
+
+func (s *SQLiteStore) RevertChange(ctx context.Context, treeID, changeID int) ([]models.Change, error) {
+	var result []models.Change
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		// Последний ID журнала до отмены: всё, что записано после, - результат отмены
+		var lastID int64
+		if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM audit_log").Scan(&lastID); err != nil {
+			return err
+		}
+
+		changes, err := s.queryChanges(ctx, tx, "a.tree_id = ? AND a.id = ?", treeID, changeID)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return ErrNotFound
+		}
+
+		err = s.revertChange(ctx, tx, treeID, changes[0])
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidReference) {
+			return ErrRevertConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err = s.queryChanges(ctx, tx, "a.id > ? ORDER BY a.id", lastID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// revertChange выполняет действие, обратное изменению c
+func (s *SQLiteStore) revertChange(ctx context.Context, tx *sql.Tx, treeID int, c models.Change) error {
+	revertOf := int64(c.ID)
+
+	switch c.EntityType + "/" + c.Action {
+	case "person/create":
+		_, err := deletePerson(ctx, tx, treeID, c.EntityID, revertOf)
+		return err
+
+	case "person/update":
+		var before models.Person
+		if err := json.Unmarshal(c.Before, &before); err != nil {
+			return err
+		}
+		return s.revertPersonUpdate(ctx, tx, treeID, c, before, revertOf)
+
+	case "person/delete":
+		var before models.Person
+		if err := json.Unmarshal(c.Before, &before); err != nil {
+			return err
+		}
+		if err := s.restorePerson(ctx, tx, treeID, before, c.ID, revertOf); err != nil {
+			return err
+		}
+		return unmergeMoves(ctx, tx, treeID, "person_id", c.EntityID, c.Moved)
+
+	case "relationship/create":
+		_, err := deleteRelationship(ctx, tx, treeID, c.EntityID, revertOf)
+		return err
+
+	case "relationship/update":
+		var before models.Relationship
+		if err := json.Unmarshal(c.Before, &before); err != nil {
+			return err
+		}
+		current, err := getRelationship(ctx, tx, treeID, c.EntityID)
+		if err != nil || !relationshipUnchanged(c, *current) {
+			return ErrRevertConflict
+		}
+		_, err = updateRelationship(ctx, tx, treeID, before, revertOf)
+		return err
+
+	case "relationship/delete":
+		var before models.Relationship
+		if err := json.Unmarshal(c.Before, &before); err != nil {
+			return err
+		}
+		if err := restoreRelationship(ctx, tx, treeID, before, 0, revertOf); err != nil {
+			return err
+		}
+		return unmergeMoves(ctx, tx, treeID, "relationship_id", c.EntityID, c.Moved)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) revertPersonUpdate(ctx context.Context, tx *sql.Tx, treeID int, c models.Change, before models.Person, revertOf int64) error {
+	current, err := getPerson(ctx, tx, treeID, before.ID)
+	if err != nil {
+		return err
+	}
+	if !personUnchanged(c, *current) {
+		return ErrRevertConflict
+	}
+	if err := updatePerson(ctx, tx, treeID, before); err != nil {
+		return err
+	}
+	before.PositionX, before.PositionY = current.PositionX, current.PositionY
+	entry := personChange(treeID, models.ActionUpdate, current, &before)
+	entry.revertOf = revertOf
+	_, err = logChange(ctx, tx, entry)
+	return err
+}
+
+// restorePerson возвращает удалённого человека с прежним ID и связи,
+// удалённые вместе с ним (записи журнала с cause_id = deleteID).
+// Связь, второй конец которой тоже удалён, пропускается.
+// Из корзины человек просто возвращается, а стёртый из неё - вставляется заново по журналу.
+func (s *SQLiteStore) restorePerson(ctx context.Context, tx *sql.Tx, treeID int, p models.Person, deleteID int, revertOf int64) error {
+	state, err := trashState(ctx, tx, "people", treeID, p.ID)
+	if err != nil {
+		return err
+	}
+	switch state {
+	case rowLive:
+		return ErrRevertConflict
+	case rowTrashed:
+		if _, err := tx.ExecContext(ctx, "UPDATE people SET deleted_at = NULL WHERE id = ?", p.ID); err != nil {
+			return err
+		}
+		restored, err := getPerson(ctx, tx, treeID, p.ID)
+		if err != nil {
+			return err
+		}
+		p = *restored
+	case rowMissing:
+		if err := insertPerson(ctx, tx, treeID, &p, true); err != nil {
+			return err
+		}
+	}
+	entry := personChange(treeID, models.ActionCreate, nil, &p)
+	entry.revertOf = revertOf
+	causeID, err := logChange(ctx, tx, entry)
+	if err != nil {
+		return err
+	}
+
+	cascaded, err := s.queryChanges(ctx, tx, "a.cause_id = ? AND a.entity_type = 'relationship' AND a.action = 'delete' ORDER BY a.id", deleteID)
+	if err != nil {
+		return err
+	}
+	for _, c := range cascaded {
+		var rel models.Relationship
+		if err := json.Unmarshal(c.Before, &rel); err != nil {
+			return err
+		}
+		err := restoreRelationship(ctx, tx, treeID, rel, causeID, 0)
+		if errors.Is(err, ErrInvalidReference) || errors.Is(err, ErrRevertConflict) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreRelationship возвращает связь из корзины или, если её уже стёрли, по журналу.
+// Оба человека должны быть в дереве, иначе ErrInvalidReference.
+func restoreRelationship(ctx context.Context, tx *sql.Tx, treeID int, rel models.Relationship, causeID, revertOf int64) error {
+	state, err := trashState(ctx, tx, "relationships", treeID, rel.ID)
+	if err != nil {
+		return err
+	}
+	switch state {
+	case rowLive:
+		return ErrRevertConflict
+	case rowTrashed:
+		result, err := tx.ExecContext(ctx, `
+		UPDATE relationships SET deleted_at = NULL
+		WHERE id = ? AND (SELECT COUNT(*) FROM people WHERE id IN (from_person_id, to_person_id) AND tree_id = ? AND deleted_at IS NULL) = 2`,
+			rel.ID, treeID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrInvalidReference
+		}
+		restored, err := getRelationship(ctx, tx, treeID, rel.ID)
+		if err != nil {
+			return err
+		}
+		rel = *restored
+	case rowMissing:
+		if err := insertRelationship(ctx, tx, treeID, &rel, true); err != nil {
+			return err
+		}
+	}
+	entry := relationshipChange(treeID, models.ActionCreate, nil, &rel)
+	entry.causeID, entry.revertOf = causeID, revertOf
+	_, err = logChange(ctx, tx, entry)
+	return err
+}
+
+// Состояние записи для восстановления
+const (
+	rowMissing = iota // нет ни в дереве, ни в корзине
+	rowLive
+	rowTrashed
+)
+
+// trashState - где запись id таблицы table. Запись с тем же ID в чужом
+// дереве считается живой: вставить её заново нельзя.
+func trashState(ctx context.Context, q querier, table string, treeID, id int) (int, error) {
+	var rowTree int
+	var deletedAt sql.NullString
+	err := q.QueryRowContext(ctx, "SELECT tree_id, deleted_at FROM "+table+" WHERE id = ?", id).Scan(&rowTree, &deletedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return rowMissing, nil
+	case err != nil:
+		return 0, err
+	case deletedAt.Valid && rowTree == treeID:
+		return rowTrashed, nil
+	default:
+		return rowLive, nil
+	}
+}
@@ -0,0 +1,293 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"family-tree-app/internal/models"
+)
+
+const mediaColumns = "id, hash, content_type, size, file_name, title, description, width, height, taken_at, has_gps, created_at"
+
+func scanMedia(row rowScanner) (*models.Media, error) {
+	var m models.Media
+	if err := row.Scan(&m.ID, &m.Hash, &m.ContentType, &m.Size, &m.FileName, &m.Title, &m.Description,
+		&m.Width, &m.Height, &m.TakenAt, &m.HasGPS, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// liveMediaLinks - связи файлов дерева ?, кроме связей с людьми и связями
+// из корзины и с их событиями
+const liveMediaLinks = `SELECT l.media_id, l.person_id, l.relationship_id, l.event_id FROM media_links l
+	LEFT JOIN people p ON p.id = l.person_id
+	LEFT JOIN relationships r ON r.id = l.relationship_id
+	LEFT JOIN events e ON e.id = l.event_id
+	LEFT JOIN people ep ON ep.id = e.person_id
+	LEFT JOIN relationships er ON er.id = e.relationship_id
+	WHERE l.tree_id = ? AND p.deleted_at IS NULL AND r.deleted_at IS NULL
+		AND ep.deleted_at IS NULL AND er.deleted_at IS NULL`
+
+// listMedia читает файлы дерева, подходящие под cond (начинается с " AND"),
+// новые первыми, и дописывает к ним связи
+func listMedia(ctx context.Context, q querier, treeID int, cond string, args ...interface{}) ([]models.Media, error) {
+	treeArgs := append([]interface{}{treeID}, args...)
+	rows, err := q.QueryContext(ctx, "SELECT "+mediaColumns+" FROM media WHERE tree_id = ?"+cond+" ORDER BY id DESC", treeArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []models.Media{}
+	index := map[int]int{}
+	for rows.Next() {
+		m, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[m.ID] = len(files)
+		files = append(files, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	links, err := q.QueryContext(ctx, liveMediaLinks+" AND l.media_id IN (SELECT id FROM media WHERE tree_id = ?"+cond+") ORDER BY l.id",
+		append([]interface{}{treeID}, treeArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer links.Close()
+	for links.Next() {
+		var mediaID int
+		var personID, relID, eventID sql.NullInt64
+		if err := links.Scan(&mediaID, &personID, &relID, &eventID); err != nil {
+			return nil, err
+		}
+		if i, ok := index[mediaID]; ok {
+			addMediaLink(&files[i], intPtr(personID), intPtr(relID), intPtr(eventID))
+		}
+	}
+	for i := range files {
+		emptyMediaLinks(&files[i])
+	}
+	return files, links.Err()
+}
+
+func getMedia(ctx context.Context, q querier, treeID, mediaID int) (*models.Media, error) {
+	files, err := listMedia(ctx, q, treeID, " AND id = ?", mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+	return &files[0], nil
+}
+
+// mediaOwnerColumn - столбец media_links, в котором записан владелец, и его ID
+func mediaOwnerColumn(owner MediaOwner) (string, int) {
+	switch {
+	case owner.PersonID != 0:
+		return "person_id", owner.PersonID
+	case owner.RelationshipID != 0:
+		return "relationship_id", owner.RelationshipID
+	}
+	return "event_id", owner.EventID
+}
+
+func (s *SQLiteStore) ListMedia(ctx context.Context, treeID int) ([]models.Media, error) {
+	return listMedia(ctx, s.db, treeID, "")
+}
+
+func (s *SQLiteStore) GetMedia(ctx context.Context, treeID, mediaID int) (*models.Media, error) {
+	return getMedia(ctx, s.db, treeID, mediaID)
+}
+
+func (s *SQLiteStore) FindMedia(ctx context.Context, treeID int, hash string) (*models.Media, error) {
+	files, err := listMedia(ctx, s.db, treeID, " AND hash = ?", hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+	return &files[0], nil
+}
+
+func (s *SQLiteStore) CreateMedia(ctx context.Context, treeID int, m *models.Media) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO media (tree_id, hash, content_type, size, file_name, title, description, width, height, taken_at, has_gps)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			treeID, m.Hash, m.ContentType, m.Size, m.FileName, m.Title, m.Description, m.Width, m.Height, m.TakenAt, m.HasGPS)
+		if err != nil {
+			return err
+		}
+		id, _ := result.LastInsertId()
+		m.ID = int(id)
+		m.PersonIDs, m.RelationshipIDs, m.EventIDs = nil, nil, nil
+		emptyMediaLinks(m)
+		return tx.QueryRowContext(ctx, "SELECT created_at FROM media WHERE id = ?", m.ID).Scan(&m.CreatedAt)
+	})
+}
+
+func (s *SQLiteStore) UpdateMedia(ctx context.Context, treeID int, m models.Media) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE media SET title = ?, description = ? WHERE id = ? AND tree_id = ?",
+		m.Title, m.Description, m.ID, treeID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+func (s *SQLiteStore) DeleteMedia(ctx context.Context, treeID, mediaID int) (*models.Media, error) {
+	var m *models.Media
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if m, err = getMedia(ctx, tx, treeID, mediaID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM media WHERE id = ?", mediaID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *SQLiteStore) MediaInUse(ctx context.Context, hash string) (bool, error) {
+	var used bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM media WHERE hash = ?)", hash).Scan(&used)
+	return used, err
+}
+
+func (s *SQLiteStore) ListOwnerMedia(ctx context.Context, treeID int, owner MediaOwner) ([]models.Media, error) {
+	if err := checkCitationOwner(ctx, s.db, treeID, CitationOwner(owner)); err != nil {
+		return nil, err
+	}
+	column, id := mediaOwnerColumn(owner)
+	return listMedia(ctx, s.db, treeID, " AND id IN (SELECT media_id FROM media_links WHERE "+column+" = ?)", id)
+}
+
+// checkMedia проверяет, что файл есть в дереве, иначе ErrInvalidMedia. nil - файла не задано.
+func checkMedia(ctx context.Context, q querier, treeID int, mediaID *int) error {
+	if mediaID == nil {
+		return nil
+	}
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM media WHERE id = ? AND tree_id = ?)", *mediaID, treeID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrInvalidMedia
+	}
+	return nil
+}
+
+func (s *SQLiteStore) LinkMedia(ctx context.Context, treeID int, owner MediaOwner, mediaID int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkCitationOwner(ctx, tx, treeID, CitationOwner(owner)); err != nil {
+			return err
+		}
+		if err := checkMedia(ctx, tx, treeID, &mediaID); err != nil {
+			return err
+		}
+		personID, relID, eventID := citationOwnerIDs(CitationOwner(owner))
+		_, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO media_links (tree_id, media_id, person_id, relationship_id, event_id)
+			VALUES (?, ?, ?, ?, ?)`, treeID, mediaID, personID, relID, eventID)
+		return err
+	})
+}
+
+func (s *SQLiteStore) UnlinkMedia(ctx context.Context, treeID int, owner MediaOwner, mediaID int) error {
+	column, id := mediaOwnerColumn(owner)
+	result, err := s.db.ExecContext(ctx, "DELETE FROM media_links WHERE tree_id = ? AND media_id = ? AND "+column+" = ?", treeID, mediaID, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+// moveMediaLinks переносит связи файлов с владельца from (столбец column) на to
+// (при слиянии). Связи, которые у to уже есть, просто удаляются.
+func moveMediaLinks(ctx context.Context, tx *sql.Tx, column string, from, to int) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE OR IGNORE media_links SET "+column+" = ? WHERE "+column+" = ?", to, from); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM media_links WHERE "+column+" = ?", from)
+	return err
+}
+
+// copyMedia копирует файлы (содержимое в хранилище общее), их связи и портреты
+// людей в другое дерево по соответствию ID людей, связей и событий. Связи с тем, что не
+// скопировано (корзина), пропускаются.
+func copyMedia(ctx context.Context, tx *sql.Tx, treeID, newTreeID int, personIDs, relIDs, eventIDs map[int]int) error {
+	files, err := listMedia(ctx, tx, treeID, "")
+	if err != nil {
+		return err
+	}
+	mediaIDs := make(map[int]int, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO media (tree_id, hash, content_type, size, file_name, title, description, width, height, taken_at, has_gps, created_at)
+			SELECT ?, hash, content_type, size, file_name, title, description, width, height, taken_at, has_gps, created_at FROM media WHERE id = ?`, newTreeID, files[i].ID)
+		if err != nil {
+			return err
+		}
+		id, _ := result.LastInsertId()
+		mediaIDs[files[i].ID] = int(id)
+	}
+
+	// Портреты копий людей - на копии файлов
+	for oldID, newPersonID := range personIDs {
+		var photo sql.NullInt64
+		if err := tx.QueryRowContext(ctx, "SELECT photo_media_id FROM people WHERE id = ?", oldID).Scan(&photo); err != nil {
+			return err
+		}
+		if photo.Valid {
+			if _, err := tx.ExecContext(ctx, "UPDATE people SET photo_media_id = ? WHERE id = ?", mapID(intPtr(photo), mediaIDs), newPersonID); err != nil {
+				return err
+			}
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT media_id, person_id, relationship_id, event_id FROM media_links WHERE tree_id = ? ORDER BY id", treeID)
+	if err != nil {
+		return err
+	}
+	type link struct {
+		mediaID                  int
+		personID, relID, eventID *int
+	}
+	var links []link
+	for rows.Next() {
+		var l link
+		var personID, relID, eventID sql.NullInt64
+		if err := rows.Scan(&l.mediaID, &personID, &relID, &eventID); err != nil {
+			rows.Close()
+			return err
+		}
+		l.personID, l.relID, l.eventID = mapID(intPtr(personID), personIDs), mapID(intPtr(relID), relIDs), mapID(intPtr(eventID), eventIDs)
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, l := range links {
+		if l.personID == nil && l.relID == nil && l.eventID == nil {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO media_links (tree_id, media_id, person_id, relationship_id, event_id) VALUES (?, ?, ?, ?, ?)`,
+			newTreeID, mediaIDs[l.mediaID], l.personID, l.relID, l.eventID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
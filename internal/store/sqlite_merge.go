@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"family-tree-app/internal/models"
+)
+
+func (s *SQLiteStore) MergePeople(ctx context.Context, treeID int, merged models.Person, otherID int) (*models.MergeResult, error) {
+	if merged.ID == otherID {
+		return nil, ErrInvalidReference
+	}
+	result := &models.MergeResult{MergedID: otherID, Repointed: []models.Relationship{}, Removed: []models.Relationship{}}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := getPerson(ctx, tx, treeID, otherID); err != nil {
+			return err
+		}
+		before, err := getPerson(ctx, tx, treeID, merged.ID)
+		if err != nil {
+			return err
+		}
+		if err := updatePerson(ctx, tx, treeID, merged); err != nil {
+			return err
+		}
+		merged.PositionX, merged.PositionY = before.PositionX, before.PositionY
+		if _, err := logChange(ctx, tx, personChange(treeID, models.ActionUpdate, before, &merged)); err != nil {
+			return err
+		}
+		result.Person = merged
+
+		keepRels, err := relationshipsOf(ctx, tx, treeID, merged.ID)
+		if err != nil {
+			return err
+		}
+		otherRels, err := relationshipsOf(ctx, tx, treeID, otherID)
+		if err != nil {
+			return err
+		}
+		plan := planMerge(merged.ID, otherID, keepRels, otherRels)
+
+		// События, ссылки на источники и файлы выброшенного повтора переходят
+		// к оставшейся связи, а что перенесено - в журнал вместе с удалением
+		for _, relID := range plan.drop {
+			var moved *mergeMoves
+			if to, ok := plan.events[relID]; ok {
+				if moved, err = mergeMove(ctx, tx, "relationship_id", relID, to); err != nil {
+					return err
+				}
+			}
+			rel, err := deleteMovedRelationship(ctx, tx, treeID, relID, 0, moved.orNil())
+			if err != nil {
+				return err
+			}
+			result.Removed = append(result.Removed, *rel)
+		}
+		for relID, description := range plan.describe {
+			if err := updateRelationshipDescription(ctx, tx, treeID, relID, description, 0); err != nil {
+				return err
+			}
+		}
+		for _, rel := range plan.repoint {
+			if _, err := updateRelationship(ctx, tx, treeID, rel, 0); err != nil {
+				return err
+			}
+			result.Repointed = append(result.Repointed, rel)
+		}
+
+		// События, ссылки на источники, файлы и аккаунт второго переходят
+		// к оставшемуся. Даты рождения и смерти остаются выбранными при слиянии.
+		moved, err := mergeMove(ctx, tx, "person_id", otherID, merged.ID)
+		if err != nil {
+			return err
+		}
+		if err := syncPersonEvents(ctx, tx, treeID, merged.ID); err != nil {
+			return err
+		}
+		_, err = deleteMovedPerson(ctx, tx, treeID, otherID, 0, moved.orNil())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeMove переносит события, ссылки на источники и связи файлов (без
+// повторов) владельца from на to; column - person_id или relationship_id.
+// У человека переносится и привязанный к нему аккаунт.
+func mergeMove(ctx context.Context, tx *sql.Tx, column string, from, to int) (*mergeMoves, error) {
+	moved := &mergeMoves{Into: to}
+	var err error
+	if moved.Events, err = queryIDs(ctx, tx, "SELECT id FROM events WHERE "+column+" = ? ORDER BY id", from); err != nil {
+		return nil, err
+	}
+	if moved.Citations, err = queryIDs(ctx, tx, "SELECT id FROM citations WHERE "+column+" = ? ORDER BY id", from); err != nil {
+		return nil, err
+	}
+	if moved.Media, err = queryIDs(ctx, tx, "SELECT media_id FROM media_links WHERE "+column+" = ?1 AND media_id NOT IN (SELECT media_id FROM media_links WHERE "+column+" = ?2) ORDER BY media_id", from, to); err != nil {
+		return nil, err
+	}
+	if moved.Shared, err = queryIDs(ctx, tx, "SELECT media_id FROM media_links WHERE "+column+" = ?1 AND media_id IN (SELECT media_id FROM media_links WHERE "+column+" = ?2) ORDER BY media_id", from, to); err != nil {
+		return nil, err
+	}
+	if column == "person_id" {
+		if moved.Users, err = queryIDs(ctx, tx, "SELECT id FROM users WHERE link_to_person_id = ? ORDER BY id", from); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE events SET "+column+" = ? WHERE "+column+" = ?", to, from); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE citations SET "+column+" = ? WHERE "+column+" = ?", to, from); err != nil {
+		return nil, err
+	}
+	if err := moveMediaLinks(ctx, tx, column, from, to); err != nil {
+		return nil, err
+	}
+	if column == "person_id" {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET link_to_person_id = ? WHERE link_to_person_id = ?", to, from); err != nil {
+			return nil, err
+		}
+	}
+	return moved, nil
+}
+
+// unmergeMoves возвращает восстановленному владельцу back (column - person_id
+// или relationship_id) то, что слияние перенесло с него (moved из журнала).
+// Что с тех пор удалено или перенесено дальше, остаётся как есть.
+func unmergeMoves(ctx context.Context, tx *sql.Tx, treeID int, column string, back int, movedJSON json.RawMessage) error {
+	if len(movedJSON) == 0 {
+		return nil
+	}
+	var moved mergeMoves
+	if err := json.Unmarshal(movedJSON, &moved); err != nil {
+		return err
+	}
+	into := moved.Into
+
+	for _, id := range moved.Events {
+		if _, err := tx.ExecContext(ctx, "UPDATE events SET "+column+" = ? WHERE id = ? AND "+column+" = ?", back, id, into); err != nil {
+			return err
+		}
+	}
+	for _, id := range moved.Citations {
+		if _, err := tx.ExecContext(ctx, "UPDATE citations SET "+column+" = ? WHERE id = ? AND "+column+" = ?", back, id, into); err != nil {
+			return err
+		}
+	}
+	for _, mediaID := range moved.Media {
+		if _, err := tx.ExecContext(ctx, "UPDATE OR IGNORE media_links SET "+column+" = ? WHERE media_id = ? AND "+column+" = ?", back, mediaID, into); err != nil {
+			return err
+		}
+	}
+	for _, mediaID := range moved.Shared {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO media_links (tree_id, media_id, "+column+") SELECT tree_id, id, ? FROM media WHERE id = ? AND tree_id = ?",
+			back, mediaID, treeID); err != nil {
+			return err
+		}
+	}
+	for _, userID := range moved.Users {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET link_to_person_id = ? WHERE id = ? AND link_to_person_id = ?", back, userID, into); err != nil {
+			return err
+		}
+	}
+
+	if column != "person_id" {
+		return nil
+	}
+	// Даты рождения и смерти вернулись с человеком, события подстраиваются под них
+	if err := syncPersonEvents(ctx, tx, treeID, back); err != nil {
+		return err
+	}
+	if err := syncPersonEvents(ctx, tx, treeID, into); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// queryIDs - первый столбец всех строк запроса
+func queryIDs(ctx context.Context, q querier, query string, args ...interface{}) ([]int, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
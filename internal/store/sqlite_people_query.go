@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+func (s *SQLiteStore) QueryPeople(ctx context.Context, treeID int, q PeopleQuery) (*PeoplePage, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(q)
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{"tree_id = ?", "deleted_at IS NULL"}
+	args := []interface{}{treeID}
+	if q.Surname != "" {
+		where = append(where, "surname_key(last_name) = ?")
+		args = append(args, genealogy.SurnameKey(q.Surname))
+	}
+	if q.Gender != "" {
+		where = append(where, "gender = ?")
+		args = append(args, q.Gender)
+	}
+	if q.BornFrom != 0 {
+		where = append(where, "date_year(birth_date) >= ?")
+		args = append(args, q.BornFrom)
+	}
+	if q.BornTo != 0 {
+		where = append(where, "date_year(birth_date) <= ?")
+		args = append(args, q.BornTo)
+	}
+	if q.Living != nil {
+		where = append(where, "is_living(birth_date, death_date, ?) = ?")
+		args = append(args, time.Now().Format(time.DateOnly), *q.Living)
+	}
+	if q.HasPhoto != nil {
+		where = append(where, "(COALESCE(photo_url, '') != '' OR photo_media_id IS NOT NULL) = ?")
+		args = append(args, *q.HasPhoto)
+	}
+
+	// Порядок по полю сортировки, при равенстве - по ID; курсор - строго после последней записи
+	key, cmp, dir := "COALESCE("+q.Sort+", '')", ">", "ASC"
+	if q.Sort == SortByBirthDate {
+		// Не по тексту: "около 1890" и "BEF 1900" сортируются по диапазону даты
+		key = "COALESCE(birth_from, birth_to, '')"
+	}
+	if q.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	if q.Sort == SortByID {
+		if cursor != nil {
+			where = append(where, "id "+cmp+" ?")
+			args = append(args, cursor.ID)
+		}
+	} else if cursor != nil {
+		where = append(where, "("+key+" "+cmp+" ? OR ("+key+" = ? AND id "+cmp+" ?))")
+		args = append(args, cursor.Value, cursor.Value, cursor.ID)
+	}
+	query := "SELECT " + personColumns + " FROM people WHERE " + strings.Join(where, " AND ") + " ORDER BY "
+	if q.Sort != SortByID {
+		query += key + " " + dir + ", "
+	}
+	query += "id " + dir
+	if q.Limit > 0 {
+		// Лишняя запись показывает, что есть следующая страница
+		query += " LIMIT ?"
+		args = append(args, q.Limit+1)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	people := []models.Person{}
+	for rows.Next() {
+		p, err := scanPerson(rows)
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pageOf(people, q), nil
+}
+
+// pageOf обрезает выборку до q.Limit и выписывает курсор следующей страницы.
+// people должна содержать на одну запись больше лимита, если следующая страница есть.
+func pageOf(people []models.Person, q PeopleQuery) *PeoplePage {
+	page := &PeoplePage{People: people}
+	if q.Limit > 0 && len(people) > q.Limit {
+		page.People = people[:q.Limit]
+		last := page.People[q.Limit-1]
+		page.NextCursor = encodeCursor(peopleCursor{Sort: q.Sort, Desc: q.Desc, Value: sortValue(last, q.Sort), ID: last.ID})
+	}
+	return page
+}
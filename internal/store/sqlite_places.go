@@ -0,0 +1,377 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+const placeColumns = "id, parent_id, name, type, latitude, longitude, geonames_id"
+
+func scanPlace(row rowScanner) (*models.Place, error) {
+	var p models.Place
+	var parentID, geonamesID sql.NullInt64
+	var lat, lon sql.NullFloat64
+	if err := row.Scan(&p.ID, &parentID, &p.Name, &p.Type, &lat, &lon, &geonamesID); err != nil {
+		return nil, err
+	}
+	p.ParentID, p.GeoNamesID = intPtr(parentID), intPtr(geonamesID)
+	if lat.Valid && lon.Valid {
+		p.Latitude, p.Longitude = &lat.Float64, &lon.Float64
+	}
+	p.Names = []models.PlaceName{}
+	return &p, nil
+}
+
+// checkPlaces проверяет, что заданные места есть в дереве, иначе ErrInvalidPlace
+func checkPlaces(ctx context.Context, q querier, treeID int, placeIDs ...*int) error {
+	for _, id := range placeIDs {
+		if id == nil {
+			continue
+		}
+		var n int
+		if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM places WHERE id = ? AND tree_id = ?", *id, treeID).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrInvalidPlace
+		}
+	}
+	return nil
+}
+
+// requirePlace - как checkPlaces для одного места, но ErrNotFound: места нет в самом запросе
+func requirePlace(ctx context.Context, q querier, treeID, placeID int) error {
+	err := checkPlaces(ctx, q, treeID, &placeID)
+	if errors.Is(err, ErrInvalidPlace) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// listPlaces читает места дерева с другими названиями и полными названиями
+func listPlaces(ctx context.Context, q querier, treeID int) ([]models.Place, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+placeColumns+" FROM places WHERE tree_id = ?", treeID)
+	if err != nil {
+		return nil, err
+	}
+	places := []models.Place{}
+	index := map[int]int{}
+	for rows.Next() {
+		p, err := scanPlace(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[p.ID] = len(places)
+		places = append(places, *p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	names, err := q.QueryContext(ctx,
+		"SELECT n.place_id, n.name, n.lang FROM place_names n JOIN places p ON p.id = n.place_id WHERE p.tree_id = ? ORDER BY n.place_id, n.rowid", treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer names.Close()
+	for names.Next() {
+		var placeID int
+		var n models.PlaceName
+		if err := names.Scan(&placeID, &n.Name, &n.Lang); err != nil {
+			return nil, err
+		}
+		i := index[placeID]
+		places[i].Names = append(places[i].Names, n)
+	}
+	if err := names.Err(); err != nil {
+		return nil, err
+	}
+
+	genealogy.FillFullNames(places)
+	genealogy.SortPlaces(places)
+	return places, nil
+}
+
+func getPlace(ctx context.Context, q querier, treeID, placeID int) (*models.Place, error) {
+	places, err := listPlaces(ctx, q, treeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range places {
+		if p.ID == placeID {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// placeWithin - место placeID входит в ancestorID (напрямую или через другие места)
+func placeWithin(ctx context.Context, q querier, placeID, ancestorID int) (bool, error) {
+	seen := map[int]bool{}
+	for id := placeID; !seen[id]; {
+		if id == ancestorID {
+			return true, nil
+		}
+		seen[id] = true
+		var parentID sql.NullInt64
+		err := q.QueryRowContext(ctx, "SELECT parent_id FROM places WHERE id = ?", id).Scan(&parentID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && !parentID.Valid) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		id = int(parentID.Int64)
+	}
+	return false, nil
+}
+
+func writePlaceNames(ctx context.Context, tx *sql.Tx, p models.Place) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM place_names WHERE place_id = ?", p.ID); err != nil {
+		return err
+	}
+	for _, n := range p.Names {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO place_names (place_id, name, lang) VALUES (?, ?, ?)", p.ID, n.Name, n.Lang); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListPlaces(ctx context.Context, treeID int) ([]models.Place, error) {
+	return listPlaces(ctx, s.db, treeID)
+}
+
+func (s *SQLiteStore) GetPlace(ctx context.Context, treeID, placeID int) (*models.Place, error) {
+	return getPlace(ctx, s.db, treeID, placeID)
+}
+
+func (s *SQLiteStore) CreatePlace(ctx context.Context, treeID int, p *models.Place) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkPlaces(ctx, tx, treeID, p.ParentID); err != nil {
+			return err
+		}
+		cleanPlaceNames(p)
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO places (tree_id, parent_id, name, type, latitude, longitude, geonames_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			treeID, p.ParentID, p.Name, p.Type, p.Latitude, p.Longitude, p.GeoNamesID)
+		if err != nil {
+			return err
+		}
+		id, _ := result.LastInsertId()
+		p.ID = int(id)
+		if err := writePlaceNames(ctx, tx, *p); err != nil {
+			return err
+		}
+		created, err := getPlace(ctx, tx, treeID, p.ID)
+		if err != nil {
+			return err
+		}
+		*p = *created
+		return nil
+	})
+}
+
+func (s *SQLiteStore) UpdatePlace(ctx context.Context, treeID int, p models.Place) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return updatePlace(ctx, tx, treeID, p)
+	})
+}
+
+func updatePlace(ctx context.Context, tx *sql.Tx, treeID int, p models.Place) error {
+	if err := requirePlace(ctx, tx, treeID, p.ID); err != nil {
+		return err
+	}
+	if err := checkPlaces(ctx, tx, treeID, p.ParentID); err != nil {
+		return err
+	}
+	if p.ParentID != nil {
+		within, err := placeWithin(ctx, tx, *p.ParentID, p.ID)
+		if err != nil {
+			return err
+		}
+		if within {
+			return ErrPlaceCycle
+		}
+	}
+	cleanPlaceNames(&p)
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE places SET parent_id = ?, name = ?, type = ?, latitude = ?, longitude = ?, geonames_id = ? WHERE id = ?",
+		p.ParentID, p.Name, p.Type, p.Latitude, p.Longitude, p.GeoNamesID, p.ID); err != nil {
+		return err
+	}
+	return writePlaceNames(ctx, tx, p)
+}
+
+func (s *SQLiteStore) DeletePlace(ctx context.Context, treeID, placeID int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := requirePlace(ctx, tx, treeID, placeID); err != nil {
+			return err
+		}
+		var used int
+		err := tx.QueryRowContext(ctx, `SELECT
+			(SELECT COUNT(*) FROM people WHERE deleted_at IS NULL AND (birth_place_id = ?1 OR death_place_id = ?1)) +
+			(SELECT COUNT(*) FROM events WHERE place_id = ?1) +
+			(SELECT COUNT(*) FROM places WHERE parent_id = ?1)`, placeID).Scan(&used)
+		if err != nil {
+			return err
+		}
+		if used > 0 {
+			return ErrPlaceInUse
+		}
+		// У людей в корзине ссылка обнулится внешним ключом
+		_, err = tx.ExecContext(ctx, "DELETE FROM places WHERE id = ?", placeID)
+		return err
+	})
+}
+
+func (s *SQLiteStore) MergePlaces(ctx context.Context, treeID, keepID, otherID int) (*models.PlaceMergeResult, error) {
+	result := &models.PlaceMergeResult{MergedID: otherID}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if keepID == otherID {
+			return ErrPlaceCycle
+		}
+		keep, err := getPlace(ctx, tx, treeID, keepID)
+		if err != nil {
+			return err
+		}
+		other, err := getPlace(ctx, tx, treeID, otherID)
+		if err != nil {
+			return err
+		}
+		within, err := placeWithin(ctx, tx, keepID, otherID)
+		if err != nil {
+			return err
+		}
+		if within {
+			return ErrPlaceCycle
+		}
+
+		// Вложенные места переходят к keep до записи самого keep: иначе
+		// вышестоящее место other могло бы оказаться внутри keep
+		moved, err := tx.ExecContext(ctx, "UPDATE places SET parent_id = ? WHERE parent_id = ?", keepID, otherID)
+		if err != nil {
+			return err
+		}
+		children, _ := moved.RowsAffected()
+		result.Children = int(children)
+
+		var withinErr error
+		merged := mergePlace(*keep, *other, func(placeID int) bool {
+			in, err := placeWithin(ctx, tx, placeID, keepID)
+			if err != nil {
+				withinErr = err
+			}
+			return in
+		})
+		if withinErr != nil {
+			return withinErr
+		}
+		if err := updatePlace(ctx, tx, treeID, merged); err != nil {
+			return err
+		}
+
+		// События - раньше людей: updatePerson выровнял бы события рождения и смерти сам
+		events, err := tx.ExecContext(ctx, "UPDATE events SET place_id = ? WHERE place_id = ?", keepID, otherID)
+		if err != nil {
+			return err
+		}
+		n, _ := events.RowsAffected()
+		result.Events = int(n)
+
+		rows, err := tx.QueryContext(ctx,
+			"SELECT "+personColumns+" FROM people WHERE tree_id = ? AND deleted_at IS NULL AND (birth_place_id = ?2 OR death_place_id = ?2) ORDER BY id", treeID, otherID)
+		if err != nil {
+			return err
+		}
+		var people []models.Person
+		for rows.Next() {
+			p, err := scanPerson(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			people = append(people, *p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, before := range people {
+			after := before
+			if sameID(after.BirthPlaceID, &otherID) {
+				after.BirthPlaceID = &keepID
+			}
+			if sameID(after.DeathPlaceID, &otherID) {
+				after.DeathPlaceID = &keepID
+			}
+			if err := updatePerson(ctx, tx, treeID, after); err != nil {
+				return err
+			}
+			if _, err := logChange(ctx, tx, personChange(treeID, models.ActionUpdate, &before, &after)); err != nil {
+				return err
+			}
+		}
+		result.People = len(people)
+
+		// Люди в корзине - без журнала, как и при удалении места
+		if _, err := tx.ExecContext(ctx, "UPDATE people SET birth_place_id = ? WHERE birth_place_id = ?", keepID, otherID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE people SET death_place_id = ? WHERE death_place_id = ?", keepID, otherID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM places WHERE id = ?", otherID); err != nil {
+			return err
+		}
+		place, err := getPlace(ctx, tx, treeID, keepID)
+		if err != nil {
+			return err
+		}
+		result.Place = *place
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// copyPlaces копирует места дерева в другое и возвращает соответствие старых и новых ID
+func copyPlaces(ctx context.Context, tx *sql.Tx, treeID, newTreeID int) (map[int]int, error) {
+	places, err := listPlaces(ctx, tx, treeID)
+	if err != nil {
+		return nil, err
+	}
+	placeIDs := make(map[int]int, len(places))
+	for _, p := range places {
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO places (tree_id, name, type, latitude, longitude, geonames_id) VALUES (?, ?, ?, ?, ?, ?)",
+			newTreeID, p.Name, p.Type, p.Latitude, p.Longitude, p.GeoNamesID)
+		if err != nil {
+			return nil, err
+		}
+		id, _ := result.LastInsertId()
+		placeIDs[p.ID] = int(id)
+		p.ID = int(id)
+		if err := writePlaceNames(ctx, tx, p); err != nil {
+			return nil, err
+		}
+	}
+	// Вышестоящие места - когда скопированы все
+	for _, p := range places {
+		if p.ParentID == nil {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE places SET parent_id = ? WHERE id = ?", mapID(p.ParentID, placeIDs), placeIDs[p.ID]); err != nil {
+			return nil, err
+		}
+	}
+	return placeIDs, nil
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"family-tree-app/internal/genealogy"
+	"family-tree-app/internal/models"
+)
+
+// Поиск людей по индексу people_fts (миграция 0009)
+
+// indexPerson записывает или обновляет поисковую строку человека
+func indexPerson(ctx context.Context, q querier, p models.Person) error {
+	folded, phonetic := genealogy.SearchKeys(p)
+	names := strings.Join([]string{p.FirstName, p.MiddleName, p.LastName}, " ")
+	_, err := q.ExecContext(ctx,
+		"INSERT OR REPLACE INTO people_fts (rowid, names, notes, folded, phonetic) VALUES (?, ?, ?, ?, ?)",
+		p.ID, names, p.Notes, folded, phonetic)
+	return err
+}
+
+// IndexMissingPeople добавляет в поисковый индекс людей, которых в нём нет
+// (записанных до миграции 0009), и возвращает их число. Вызывается при старте.
+func (s *SQLiteStore) IndexMissingPeople(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+personColumns+" FROM people WHERE id NOT IN (SELECT rowid FROM people_fts)")
+	if err != nil {
+		return 0, err
+	}
+	var missing []models.Person
+	for rows.Next() {
+		p, err := scanPerson(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		missing = append(missing, *p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, p := range missing {
+			if err := indexPerson(ctx, tx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
+
+// ftsQuery переводит слова запроса в выражение FTS5. Слова состоят только
+// из букв и цифр (см. genealogy.ParseSearchQuery), поэтому кавычки в них не попадут.
+func ftsQuery(terms []genealogy.SearchTerm) string {
+	clauses := make([]string, 0, len(terms))
+	for _, t := range terms {
+		alts := []string{fmt.Sprintf(`names:"%s"*`, t.Text), fmt.Sprintf(`notes:"%s"*`, t.Text)}
+		if t.Folded != "" {
+			alts = append(alts, fmt.Sprintf(`folded:"%s"*`, t.Folded))
+		}
+		for _, code := range t.Phonetic {
+			alts = append(alts, fmt.Sprintf(`phonetic:"%s"`, code))
+		}
+		clauses = append(clauses, "("+strings.Join(alts, " OR ")+")")
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+func (s *SQLiteStore) SearchPeople(ctx context.Context, treeID int, query string, limit int) ([]models.Person, error) {
+	people := []models.Person{}
+	terms := genealogy.ParseSearchQuery(query)
+	if len(terms) == 0 {
+		return people, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	// bm25 тем меньше, чем лучше совпадение; веса столбцов - как в genealogy.MatchPerson
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+prefixColumns("p.", personColumns)+`
+	FROM people_fts f JOIN people p ON p.id = f.rowid
+	WHERE people_fts MATCH ? AND p.tree_id = ? AND p.deleted_at IS NULL
+	ORDER BY bm25(people_fts, 10.0, 1.0, 5.0, 2.0), p.id
+	LIMIT ?`, ftsQuery(terms), treeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanPerson(rows)
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, *p)
+	}
+	return people, rows.Err()
+}
+
+// prefixColumns добавляет псевдоним таблицы к списку столбцов
+func prefixColumns(prefix, columns string) string {
+	return prefix + strings.ReplaceAll(columns, ", ", ", "+prefix)
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+func (s *SQLiteStore) CreateShareLink(ctx context.Context, link *models.ShareLink, expiresAt time.Time) error {
+	var expires *string
+	if !expiresAt.IsZero() {
+		v := expiresAt.UTC().Format(sqliteTime)
+		expires = &v
+	}
+
+	query := `INSERT INTO share_links (tree_id, label, created_by, expires_at) VALUES (?, ?, ?, ?)`
+	result, err := s.db.ExecContext(ctx, query, link.TreeID, link.Label, link.CreatedBy, expires)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+
+	saved, err := s.GetShareLink(ctx, int(id))
+	if err != nil {
+		return err
+	}
+	*link = *saved
+	return nil
+}
+
+func (s *SQLiteStore) ListShareLinks(ctx context.Context, treeID int) ([]models.ShareLink, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+shareLinkColumns+" FROM share_links WHERE tree_id = ? ORDER BY id", treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+func (s *SQLiteStore) GetShareLink(ctx context.Context, linkID int) (*models.ShareLink, error) {
+	link, err := scanShareLink(s.db.QueryRowContext(ctx, "SELECT "+shareLinkColumns+" FROM share_links WHERE id = ?", linkID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return link, err
+}
+
+func (s *SQLiteStore) RevokeShareLink(ctx context.Context, treeID, linkID int) error {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE share_links SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = ? AND tree_id = ?", linkID, treeID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+const shareLinkColumns = "id, tree_id, label, created_by, created_at, expires_at, revoked_at"
+
+// rowScanner - общее у *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanShareLink(row rowScanner) (*models.ShareLink, error) {
+	var link models.ShareLink
+	var expiresAt, revokedAt sql.NullString
+	if err := row.Scan(&link.ID, &link.TreeID, &link.Label, &link.CreatedBy, &link.CreatedAt, &expiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.String
+	}
+	if revokedAt.Valid {
+		link.RevokedAt = &revokedAt.String
+	}
+	return &link, nil
+}
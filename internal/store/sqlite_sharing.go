@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+// sqliteTime - формат DATETIME, совместимый со сравнением с CURRENT_TIMESTAMP
+const sqliteTime = "2006-01-02 15:04:05"
+
+// --- Участники ---
+
+func (s *SQLiteStore) GetMemberRole(ctx context.Context, treeID, userID int) (string, error) {
+	var role string
+	err := s.db.QueryRowContext(ctx, "SELECT role FROM tree_members WHERE tree_id = ? AND user_id = ?", treeID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return role, err
+}
+
+func (s *SQLiteStore) ListMembers(ctx context.Context, treeID int) ([]models.TreeMember, error) {
+	query := `
+	SELECT m.tree_id, m.user_id, u.email, m.role, m.created_at
+	FROM tree_members m JOIN users u ON u.id = m.user_id
+	WHERE m.tree_id = ? ORDER BY m.created_at, m.user_id`
+	rows, err := s.db.QueryContext(ctx, query, treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.TreeMember{}
+	for rows.Next() {
+		var m models.TreeMember
+		if err := rows.Scan(&m.TreeID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (s *SQLiteStore) SetMemberRole(ctx context.Context, treeID, userID int, role string) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE tree_members SET role = ? WHERE tree_id = ? AND user_id = ?", role, treeID, userID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+func (s *SQLiteStore) RemoveMember(ctx context.Context, treeID, userID int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM tree_members WHERE tree_id = ? AND user_id = ?", treeID, userID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+// --- Приглашения ---
+
+func (s *SQLiteStore) CreateInvite(ctx context.Context, inv *models.TreeInvite, tokenHash string, expiresAt time.Time) error {
+	var expires *string
+	if !expiresAt.IsZero() {
+		v := expiresAt.UTC().Format(sqliteTime)
+		expires = &v
+	}
+	var email *string
+	if inv.Email != "" {
+		email = &inv.Email
+	}
+
+	query := `INSERT INTO tree_invites (tree_id, token_hash, email, role, created_by, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := s.db.ExecContext(ctx, query, inv.TreeID, tokenHash, email, inv.Role, inv.CreatedBy, expires)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+
+	saved, err := s.listInvites(ctx, "i.id = ?", id)
+	if err != nil {
+		return err
+	}
+	token := inv.Token
+	*inv = saved[0]
+	inv.Token = token
+	return nil
+}
+
+func (s *SQLiteStore) ListInvites(ctx context.Context, treeID int) ([]models.TreeInvite, error) {
+	return s.listInvites(ctx, "i.tree_id = ? AND i.used_at IS NULL", treeID)
+}
+
+func (s *SQLiteStore) listInvites(ctx context.Context, where string, args ...interface{}) ([]models.TreeInvite, error) {
+	query := `
+	SELECT i.id, i.tree_id, t.name, COALESCE(i.email, ''), i.role, i.created_by, i.created_at, i.expires_at, i.used_at
+	FROM tree_invites i JOIN trees t ON t.id = i.tree_id
+	WHERE ` + where + ` ORDER BY i.id`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []models.TreeInvite{}
+	for rows.Next() {
+		var inv models.TreeInvite
+		var expiresAt, usedAt sql.NullString
+		if err := rows.Scan(&inv.ID, &inv.TreeID, &inv.TreeName, &inv.Email, &inv.Role, &inv.CreatedBy, &inv.CreatedAt, &expiresAt, &usedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			inv.ExpiresAt = &expiresAt.String
+		}
+		if usedAt.Valid {
+			inv.UsedAt = &usedAt.String
+		}
+		invites = append(invites, inv)
+	}
+	return invites, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteInvite(ctx context.Context, treeID, inviteID int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM tree_invites WHERE id = ? AND tree_id = ?", inviteID, treeID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+func (s *SQLiteStore) AcceptInvite(ctx context.Context, tokenHash string, userID int, email string) (*models.TreeMember, error) {
+	var m models.TreeMember
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var inviteID, treeID int
+		var role string
+		var inviteEmail sql.NullString
+		err := tx.QueryRowContext(ctx,
+			"SELECT id, tree_id, role, email FROM tree_invites WHERE token_hash = ? AND used_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)", tokenHash,
+		).Scan(&inviteID, &treeID, &role, &inviteEmail)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInviteInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if inviteEmail.Valid && !strings.EqualFold(inviteEmail.String, email) {
+			return ErrInviteInvalid
+		}
+
+		// Гасим приглашение условным UPDATE: из двух одновременных принятий пройдёт одно
+		result, err := tx.ExecContext(ctx, "UPDATE tree_invites SET used_by = ?, used_at = CURRENT_TIMESTAMP WHERE id = ? AND used_at IS NULL", userID, inviteID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrInviteInvalid
+		}
+
+		var current string
+		err = tx.QueryRowContext(ctx, "SELECT role FROM tree_members WHERE tree_id = ? AND user_id = ?", treeID, userID).Scan(&current)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if _, err := tx.ExecContext(ctx, "INSERT INTO tree_members (tree_id, user_id, role) VALUES (?, ?, ?)", treeID, userID, role); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !models.RoleAtLeast(current, role):
+			if _, err := tx.ExecContext(ctx, "UPDATE tree_members SET role = ? WHERE tree_id = ? AND user_id = ?", role, treeID, userID); err != nil {
+				return err
+			}
+		}
+
+		return tx.QueryRowContext(ctx, `
+		SELECT m.tree_id, m.user_id, u.email, m.role, m.created_at
+		FROM tree_members m JOIN users u ON u.id = m.user_id
+		WHERE m.tree_id = ? AND m.user_id = ?`, treeID, userID,
+		).Scan(&m.TreeID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
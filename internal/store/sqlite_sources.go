@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"family-tree-app/internal/models"
+)
+
+const sourceColumns = "id, type, title, author, publication, archive, fond, opis, delo, url, notes"
+
+func scanSource(row rowScanner) (*models.Source, error) {
+	var src models.Source
+	if err := row.Scan(&src.ID, &src.Type, &src.Title, &src.Author, &src.Publication,
+		&src.Archive, &src.Fond, &src.Opis, &src.Delo, &src.URL, &src.Notes); err != nil {
+		return nil, err
+	}
+	return &src, nil
+}
+
+const citationColumns = "id, source_id, person_id, relationship_id, event_id, page, text, confidence, notes"
+
+func scanCitation(row rowScanner) (*models.Citation, error) {
+	var c models.Citation
+	var personID, relID, eventID sql.NullInt64
+	if err := row.Scan(&c.ID, &c.SourceID, &personID, &relID, &eventID, &c.Page, &c.Text, &c.Confidence, &c.Notes); err != nil {
+		return nil, err
+	}
+	c.PersonID, c.RelationshipID, c.EventID = intPtr(personID), intPtr(relID), intPtr(eventID)
+	return &c, nil
+}
+
+func getSource(ctx context.Context, q querier, treeID, sourceID int) (*models.Source, error) {
+	src, err := scanSource(q.QueryRowContext(ctx, "SELECT "+sourceColumns+" FROM sources WHERE id = ? AND tree_id = ?", sourceID, treeID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return src, err
+}
+
+// checkSource проверяет, что источник есть в дереве, иначе ErrInvalidSource
+func checkSource(ctx context.Context, q querier, treeID, sourceID int) error {
+	_, err := getSource(ctx, q, treeID, sourceID)
+	if errors.Is(err, ErrNotFound) {
+		return ErrInvalidSource
+	}
+	return err
+}
+
+// checkCitationOwner - человек, связь или событие есть в дереве, а человек
+// и связь (в том числе владелец события) не в корзине; иначе ErrNotFound
+func checkCitationOwner(ctx context.Context, q querier, treeID int, owner CitationOwner) error {
+	switch {
+	case owner.PersonID != 0:
+		return checkEventOwner(ctx, q, treeID, EventOwner{PersonID: owner.PersonID}, false)
+	case owner.RelationshipID != 0:
+		return checkEventOwner(ctx, q, treeID, EventOwner{RelationshipID: owner.RelationshipID}, false)
+	}
+	var personID, relID sql.NullInt64
+	err := q.QueryRowContext(ctx, "SELECT person_id, relationship_id FROM events WHERE id = ? AND tree_id = ?", owner.EventID, treeID).Scan(&personID, &relID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return checkEventOwner(ctx, q, treeID, EventOwner{PersonID: int(personID.Int64), RelationshipID: int(relID.Int64)}, false)
+}
+
+// citationWhere - условие на ссылки владельца (см. ListCitations); ID владельца - параметр ?2
+func citationWhere(owner CitationOwner) (string, int) {
+	switch {
+	case owner.PersonID != 0:
+		rels := "SELECT id FROM relationships WHERE deleted_at IS NULL AND (from_person_id = ?2 OR to_person_id = ?2)"
+		return `(person_id = ?2 OR relationship_id IN (` + rels + `)
+			OR event_id IN (SELECT id FROM events WHERE person_id = ?2 OR relationship_id IN (` + rels + `)))`, owner.PersonID
+	case owner.RelationshipID != 0:
+		return "(relationship_id = ?2 OR event_id IN (SELECT id FROM events WHERE relationship_id = ?2))", owner.RelationshipID
+	}
+	return "event_id = ?2", owner.EventID
+}
+
+func listCitations(ctx context.Context, q querier, query string, args ...interface{}) ([]models.Citation, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	citations := []models.Citation{}
+	for rows.Next() {
+		c, err := scanCitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		citations = append(citations, *c)
+	}
+	return citations, rows.Err()
+}
+
+func (s *SQLiteStore) ListSources(ctx context.Context, treeID int) ([]models.Source, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+sourceColumns+" FROM sources WHERE tree_id = ? ORDER BY title, id", treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []models.Source{}
+	for rows.Next() {
+		src, err := scanSource(rows)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, *src)
+	}
+	return sources, rows.Err()
+}
+
+func (s *SQLiteStore) GetSource(ctx context.Context, treeID, sourceID int) (*models.Source, error) {
+	return getSource(ctx, s.db, treeID, sourceID)
+}
+
+func (s *SQLiteStore) CreateSource(ctx context.Context, treeID int, src *models.Source) error {
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO sources (tree_id, type, title, author, publication, archive, fond, opis, delo, url, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		treeID, src.Type, src.Title, src.Author, src.Publication, src.Archive, src.Fond, src.Opis, src.Delo, src.URL, src.Notes)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+	src.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) UpdateSource(ctx context.Context, treeID int, src models.Source) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE sources SET type = ?, title = ?, author = ?, publication = ?, archive = ?, fond = ?, opis = ?, delo = ?, url = ?, notes = ?
+		WHERE id = ? AND tree_id = ?`,
+		src.Type, src.Title, src.Author, src.Publication, src.Archive, src.Fond, src.Opis, src.Delo, src.URL, src.Notes, src.ID, treeID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+func (s *SQLiteStore) DeleteSource(ctx context.Context, treeID, sourceID int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := getSource(ctx, tx, treeID, sourceID); err != nil {
+			return err
+		}
+		var used int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM citations WHERE source_id = ?", sourceID).Scan(&used); err != nil {
+			return err
+		}
+		if used > 0 {
+			return ErrSourceInUse
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM sources WHERE id = ?", sourceID)
+		return err
+	})
+}
+
+func (s *SQLiteStore) ListCitations(ctx context.Context, treeID int, owner CitationOwner) ([]models.Citation, error) {
+	if err := checkCitationOwner(ctx, s.db, treeID, owner); err != nil {
+		return nil, err
+	}
+	where, id := citationWhere(owner)
+	return listCitations(ctx, s.db, "SELECT "+citationColumns+" FROM citations WHERE tree_id = ?1 AND "+where+" ORDER BY id", treeID, id)
+}
+
+func (s *SQLiteStore) ListSourceCitations(ctx context.Context, treeID, sourceID int) ([]models.Citation, error) {
+	if _, err := getSource(ctx, s.db, treeID, sourceID); err != nil {
+		return nil, err
+	}
+	return listCitations(ctx, s.db, "SELECT "+citationColumns+" FROM citations WHERE tree_id = ? AND source_id = ? ORDER BY id", treeID, sourceID)
+}
+
+func (s *SQLiteStore) ListTreeCitations(ctx context.Context, treeID int) ([]models.Citation, error) {
+	return listCitations(ctx, s.db, "SELECT "+citationColumns+" FROM citations WHERE tree_id = ? ORDER BY id", treeID)
+}
+
+func (s *SQLiteStore) CreateCitation(ctx context.Context, treeID int, owner CitationOwner, c *models.Citation) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkCitationOwner(ctx, tx, treeID, owner); err != nil {
+			return err
+		}
+		if err := checkSource(ctx, tx, treeID, c.SourceID); err != nil {
+			return err
+		}
+		c.PersonID, c.RelationshipID, c.EventID = citationOwnerIDs(owner)
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO citations (tree_id, source_id, person_id, relationship_id, event_id, page, text, confidence, notes)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			treeID, c.SourceID, c.PersonID, c.RelationshipID, c.EventID, c.Page, c.Text, c.Confidence, c.Notes)
+		if err != nil {
+			return err
+		}
+		id, _ := result.LastInsertId()
+		c.ID = int(id)
+		return nil
+	})
+}
+
+func (s *SQLiteStore) UpdateCitation(ctx context.Context, treeID int, c models.Citation) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkSource(ctx, tx, treeID, c.SourceID); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx,
+			"UPDATE citations SET source_id = ?, page = ?, text = ?, confidence = ?, notes = ? WHERE id = ? AND tree_id = ?",
+			c.SourceID, c.Page, c.Text, c.Confidence, c.Notes, c.ID, treeID)
+		if err != nil {
+			return err
+		}
+		return expectAffected(result)
+	})
+}
+
+func (s *SQLiteStore) DeleteCitation(ctx context.Context, treeID, citationID int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM citations WHERE id = ? AND tree_id = ?", citationID, treeID)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
+
+// copySources копирует источники и ссылки на них в другое дерево по
+// соответствию ID людей, связей и событий. Ссылки на то, что не скопировано
+// (корзина), пропускаются.
+func copySources(ctx context.Context, tx *sql.Tx, treeID, newTreeID int, personIDs, relIDs, eventIDs map[int]int) error {
+	sourceIDs := map[int]int{}
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM sources WHERE tree_id = ? ORDER BY id", treeID)
+	if err != nil {
+		return err
+	}
+	var oldIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		oldIDs = append(oldIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, oldID := range oldIDs {
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO sources (tree_id, type, title, author, publication, archive, fond, opis, delo, url, notes)
+			SELECT ?, type, title, author, publication, archive, fond, opis, delo, url, notes FROM sources WHERE id = ?`, newTreeID, oldID)
+		if err != nil {
+			return err
+		}
+		id, _ := result.LastInsertId()
+		sourceIDs[oldID] = int(id)
+	}
+
+	citations, err := listCitations(ctx, tx, "SELECT "+citationColumns+" FROM citations WHERE tree_id = ? ORDER BY id", treeID)
+	if err != nil {
+		return err
+	}
+	for _, c := range citations {
+		personID, relID, eventID := mapID(c.PersonID, personIDs), mapID(c.RelationshipID, relIDs), mapID(c.EventID, eventIDs)
+		if personID == nil && relID == nil && eventID == nil {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO citations (tree_id, source_id, person_id, relationship_id, event_id, page, text, confidence, notes)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			newTreeID, sourceIDs[c.SourceID], personID, relID, eventID, c.Page, c.Text, c.Confidence, c.Notes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+// trashTables - таблица корзины для каждого типа записи журнала
+var trashTables = map[string]string{
+	models.EntityPerson:       "people",
+	models.EntityRelationship: "relationships",
+}
+
+func (s *SQLiteStore) ListTrash(ctx context.Context, treeID int) (*models.Trash, error) {
+	trash := &models.Trash{People: []models.TrashedPerson{}, Relationships: []models.TrashedRelationship{}}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+personColumns+", deleted_at FROM people WHERE tree_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC", treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tp models.TrashedPerson
+		p, err := scanPerson(rows, &tp.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		tp.Person = *p
+		trash.People = append(trash.People, tp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rels, err := s.db.QueryContext(ctx,
+		"SELECT id, from_person_id, to_person_id, type, description, deleted_at FROM relationships WHERE tree_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC", treeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rels.Close()
+	for rels.Next() {
+		var tr models.TrashedRelationship
+		var description sql.NullString
+		if err := rels.Scan(&tr.ID, &tr.FromPersonID, &tr.ToPersonID, &tr.Type, &description, &tr.DeletedAt); err != nil {
+			return nil, err
+		}
+		tr.Description = description.String
+		trash.Relationships = append(trash.Relationships, tr)
+	}
+	return trash, rels.Err()
+}
+
+func (s *SQLiteStore) RestoreFromTrash(ctx context.Context, treeID int, entityType string, id int) ([]models.Change, error) {
+	table, ok := trashTables[entityType]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	state, err := trashState(ctx, s.db, table, treeID, id)
+	if err != nil {
+		return nil, err
+	}
+	if state != rowTrashed {
+		return nil, ErrNotFound
+	}
+	return revertLastDelete(ctx, s, treeID, entityType, id)
+}
+
+func (s *SQLiteStore) PurgeFromTrash(ctx context.Context, treeID int, entityType string, id int) (models.PurgeResult, error) {
+	return s.purge(ctx, func(tx *sql.Tx) (models.PurgeResult, error) {
+		var result models.PurgeResult
+		var err error
+		switch entityType {
+		case models.EntityPerson:
+			result, err = purgePeople(ctx, tx, "tree_id = ? AND id = ?", treeID, id)
+		case models.EntityRelationship:
+			result.Relationships, err = purgeRelationships(ctx, tx, "tree_id = ? AND id = ?", treeID, id)
+		}
+		if err != nil {
+			return result, err
+		}
+		if result.People+result.Relationships == 0 {
+			return result, ErrNotFound
+		}
+		return result, nil
+	})
+}
+
+func (s *SQLiteStore) EmptyTrash(ctx context.Context, treeID int) (models.PurgeResult, error) {
+	return s.purge(ctx, func(tx *sql.Tx) (models.PurgeResult, error) {
+		return purgeAll(ctx, tx, "tree_id = ?", treeID)
+	})
+}
+
+func (s *SQLiteStore) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (models.PurgeResult, error) {
+	return s.purge(ctx, func(tx *sql.Tx) (models.PurgeResult, error) {
+		return purgeAll(ctx, tx, "deleted_at < ?", cutoff.UTC().Format(sqliteTime))
+	})
+}
+
+// purge выполняет стирание одной транзакцией
+func (s *SQLiteStore) purge(ctx context.Context, fn func(tx *sql.Tx) (models.PurgeResult, error)) (models.PurgeResult, error) {
+	var result models.PurgeResult
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		result, err = fn(tx)
+		return err
+	})
+	if err != nil {
+		return models.PurgeResult{}, err
+	}
+	return result, nil
+}
+
+// purgeAll стирает людей и связи из корзины по условию where,
+// которое одинаково применимо к обеим таблицам
+func purgeAll(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) (models.PurgeResult, error) {
+	rels, err := purgeRelationships(ctx, tx, where, args...)
+	if err != nil {
+		return models.PurgeResult{}, err
+	}
+	result, err := purgePeople(ctx, tx, where, args...)
+	result.Relationships += rels
+	return result, err
+}
+
+// purgePeople стирает людей из корзины вместе со всеми их связями.
+// Записи журнала остаются: по ним стёртого можно вернуть через историю.
+func purgePeople(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) (models.PurgeResult, error) {
+	var result models.PurgeResult
+	trashed := "SELECT id FROM people WHERE deleted_at IS NOT NULL AND " + where
+
+	res, err := tx.ExecContext(ctx,
+		"DELETE FROM relationships WHERE from_person_id IN ("+trashed+") OR to_person_id IN ("+trashed+")",
+		append(append([]interface{}{}, args...), args...)...)
+	if err != nil {
+		return result, err
+	}
+	n, _ := res.RowsAffected()
+	result.Relationships = int(n)
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET link_to_person_id = NULL WHERE link_to_person_id IN ("+trashed+")", args...); err != nil {
+		return result, err
+	}
+	res, err = tx.ExecContext(ctx, "DELETE FROM people WHERE deleted_at IS NOT NULL AND "+where, args...)
+	if err != nil {
+		return result, err
+	}
+	n, _ = res.RowsAffected()
+	result.People = int(n)
+	return result, nil
+}
+
+func purgeRelationships(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) (int, error) {
+	res, err := tx.ExecContext(ctx, "DELETE FROM relationships WHERE deleted_at IS NOT NULL AND "+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
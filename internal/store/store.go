@@ -0,0 +1,319 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"family-tree-app/internal/models"
+)
+
+// Общие ошибки хранилищ. Обработчики переводят их в HTTP-статусы.
+var (
+	ErrNotFound   = errors.New("запись не найдена")
+	ErrEmailTaken = errors.New("пользователь с таким email уже существует")
+	// ErrInvalidReference - связь ссылается на человека, которого нет в этом дереве
+	ErrInvalidReference = errors.New("человек не найден в дереве")
+	// ErrInviteInvalid - приглашение не найдено, уже использовано, просрочено или выписано на другой email
+	ErrInviteInvalid = errors.New("приглашение недействительно")
+	// ErrRevertConflict - изменение нельзя отменить: данные с тех пор изменились
+	// (человек уже удалён или, наоборот, уже восстановлен)
+	ErrRevertConflict = errors.New("изменение нельзя отменить: данные с тех пор изменились")
+	// ErrNotSpouses - события пары (брак, развод) бывают только у связи супругов
+	ErrNotSpouses = errors.New("события пары бывают только у супругов")
+	// ErrInvalidPlace - ссылка на место, которого нет в этом дереве
+	ErrInvalidPlace = errors.New("место не найдено в дереве")
+	// ErrPlaceCycle - место оказалось бы вложено само в себя
+	ErrPlaceCycle = errors.New("место не может входить само в себя")
+	// ErrPlaceInUse - на место ссылаются люди, события или вложенные места
+	ErrPlaceInUse = errors.New("место используется")
+	// ErrInvalidSource - ссылка на источник, которого нет в этом дереве
+	ErrInvalidSource = errors.New("источник не найден в дереве")
+	// ErrSourceInUse - на источник ссылаются сведения дерева
+	ErrSourceInUse = errors.New("источник используется")
+	// ErrInvalidMedia - ссылка на файл, которого нет в этом дереве
+	ErrInvalidMedia = errors.New("файл не найден в дереве")
+)
+
+// PeopleStore - хранилище людей (узлов графа). Все операции ограничены деревом treeID.
+type PeopleStore interface {
+	CreatePerson(ctx context.Context, treeID int, p *models.Person) error
+	ListPeople(ctx context.Context, treeID int) ([]models.Person, error)
+	// QueryPeople - список людей с отбором, сортировкой и постраничной выдачей.
+	// Возвращает ErrInvalidSort и ErrInvalidCursor при неверных параметрах.
+	QueryPeople(ctx context.Context, treeID int, q PeopleQuery) (*PeoplePage, error)
+	UpdatePerson(ctx context.Context, treeID int, p models.Person) error
+	UpdatePersonPosition(ctx context.Context, treeID, personID int, x, y float64) error
+	// DeletePerson одной транзакцией переносит человека в корзину вместе
+	// со всеми его связями и возвращает всё, что удалено
+	DeletePerson(ctx context.Context, treeID, personID int) (*models.Removed, error)
+	// MergePeople одной транзакцией сливает человека otherID в merged.ID:
+	// записывает merged, переносит связи otherID без повторов и отправляет
+	// otherID в корзину. Каждый шаг попадает в журнал и отменяется по отдельности:
+	// события, ссылки на источники, файлы и аккаунты, перешедшие к merged.ID,
+	// записаны в удаление otherID (и выброшенных повторов связей) и возвращаются
+	// при его отмене.
+	MergePeople(ctx context.Context, treeID int, merged models.Person, otherID int) (*models.MergeResult, error)
+	// SearchPeople ищет людей по словам запроса в именах (как есть, в любой
+	// транслитерации и по звучанию) и заметках, лучшие совпадения первыми
+	SearchPeople(ctx context.Context, treeID int, query string, limit int) ([]models.Person, error)
+}
+
+// DefaultSearchLimit - сколько людей возвращает поиск, если лимит не задан
+const DefaultSearchLimit = 50
+
+// RelationshipStore - хранилище связей (рёбер графа)
+type RelationshipStore interface {
+	// CreateRelationship возвращает ErrInvalidReference, если хотя бы один
+	// из людей не существует или принадлежит другому дереву
+	CreateRelationship(ctx context.Context, treeID int, rel *models.Relationship) error
+	ListRelationships(ctx context.Context, treeID int) ([]models.Relationship, error)
+	UpdateRelationshipDescription(ctx context.Context, treeID, relID int, description string) error
+	// DeleteRelationship переносит связь в корзину и возвращает её
+	DeleteRelationship(ctx context.Context, treeID, relID int) (*models.Removed, error)
+}
+
+// EventOwner - чьи события: человека или связи (задано одно из полей)
+type EventOwner struct {
+	PersonID       int
+	RelationshipID int
+}
+
+// EventStore - события жизни людей и пар. Даты рождения и смерти человека
+// совпадают с датами его первого события birth и death: изменение события
+// меняет человека (с записью в журнал), а изменение человека - событие.
+// У человека с датой или местом рождения (смерти) такое событие есть всегда:
+// запись человека его заводит, а удаление последнего стирает дату и место.
+type EventStore interface {
+	// ListEvents возвращает события по дате, без даты - в конце.
+	// ErrNotFound - человека или связи нет (или они в корзине).
+	ListEvents(ctx context.Context, treeID int, owner EventOwner) ([]models.Event, error)
+	// ListTreeEvents возвращает все события дерева по ID (для экспорта), в том
+	// числе людей и связей из корзины
+	ListTreeEvents(ctx context.Context, treeID int) ([]models.Event, error)
+	// CreateEvent заполняет e.ID и владельца. ErrNotSpouses - событие пары у связи не супругов.
+	CreateEvent(ctx context.Context, treeID int, owner EventOwner, e *models.Event) error
+	// UpdateEvent меняет тип, дату, место и описание события e.ID владельца owner
+	UpdateEvent(ctx context.Context, treeID int, owner EventOwner, e models.Event) error
+	DeleteEvent(ctx context.Context, treeID int, owner EventOwner, eventID int) error
+}
+
+// PlaceStore - справочник мест дерева. Места приходят с другими названиями
+// и полным названием через вышестоящие места (FullName).
+type PlaceStore interface {
+	// ListPlaces возвращает все места дерева по полному названию
+	ListPlaces(ctx context.Context, treeID int) ([]models.Place, error)
+	GetPlace(ctx context.Context, treeID, placeID int) (*models.Place, error)
+	// CreatePlace заполняет p.ID и p.FullName. ErrInvalidPlace - нет вышестоящего места.
+	CreatePlace(ctx context.Context, treeID int, p *models.Place) error
+	// UpdatePlace меняет место целиком, вместе с другими названиями.
+	// ErrPlaceCycle - вышестоящим указано само место или вложенное в него.
+	UpdatePlace(ctx context.Context, treeID int, p models.Place) error
+	// DeletePlace возвращает ErrPlaceInUse, если на место ссылаются люди
+	// (не из корзины), события или вложенные места
+	DeletePlace(ctx context.Context, treeID, placeID int) error
+	// MergePlaces одной транзакцией переносит на keepID людей (с записью
+	// в журнал), события, вложенные места и названия места otherID и удаляет
+	// его. Пустые координаты и ссылка на GeoNames берутся у otherID.
+	// ErrPlaceCycle - otherID входит в keepID.
+	MergePlaces(ctx context.Context, treeID, keepID, otherID int) (*models.PlaceMergeResult, error)
+}
+
+// GazetteerStore - офлайн-справочник GeoNames для поиска координат мест
+type GazetteerStore interface {
+	// ImportGazetteer добавляет записи, а уже известные (по geonames_id) заменяет
+	ImportGazetteer(ctx context.Context, entries []models.GazetteerEntry) error
+	// SearchGazetteer ищет записи по началу слов названия на любом языке
+	// и в любой транслитерации, крупные места первыми. country - код страны
+	// ISO (RU, UA...), пусто - любая.
+	SearchGazetteer(ctx context.Context, query, country string, limit int) ([]models.GazetteerEntry, error)
+	GetGazetteerEntry(ctx context.Context, geonamesID int) (*models.GazetteerEntry, error)
+}
+
+// CitationOwner - что подтверждает ссылка на источник: человек, связь или
+// событие (задано одно из полей)
+type CitationOwner struct {
+	PersonID       int
+	RelationshipID int
+	EventID        int
+}
+
+// SourceStore - источники дерева и ссылки на них из сведений о людях,
+// связях и событиях. Ссылки стираются вместе с тем, что они подтверждают.
+type SourceStore interface {
+	// ListSources возвращает источники дерева по названию
+	ListSources(ctx context.Context, treeID int) ([]models.Source, error)
+	GetSource(ctx context.Context, treeID, sourceID int) (*models.Source, error)
+	CreateSource(ctx context.Context, treeID int, src *models.Source) error
+	UpdateSource(ctx context.Context, treeID int, src models.Source) error
+	// DeleteSource возвращает ErrSourceInUse, если на источник есть ссылки
+	DeleteSource(ctx context.Context, treeID, sourceID int) error
+
+	// ListCitations возвращает ссылки, подтверждающие сведения о владельце:
+	// для человека - и о его событиях, связях и событиях этих связей, для
+	// связи - и о её событиях. ErrNotFound - владельца нет (или он в корзине).
+	ListCitations(ctx context.Context, treeID int, owner CitationOwner) ([]models.Citation, error)
+	// ListSourceCitations возвращает все ссылки на источник. ErrNotFound - источника нет.
+	ListSourceCitations(ctx context.Context, treeID, sourceID int) ([]models.Citation, error)
+	// ListTreeCitations возвращает все ссылки дерева по ID (для экспорта)
+	ListTreeCitations(ctx context.Context, treeID int) ([]models.Citation, error)
+	// CreateCitation заполняет c.ID и владельца. ErrNotFound - владельца нет,
+	// ErrInvalidSource - источника нет в дереве.
+	CreateCitation(ctx context.Context, treeID int, owner CitationOwner, c *models.Citation) error
+	// UpdateCitation меняет источник, страницу, выписку, оценку и заметки
+	// ссылки c.ID; владелец остаётся прежним
+	UpdateCitation(ctx context.Context, treeID int, c models.Citation) error
+	DeleteCitation(ctx context.Context, treeID, citationID int) error
+}
+
+// MediaOwner - с чем связан файл: человек, связь или событие (задано одно из полей)
+type MediaOwner struct {
+	PersonID       int
+	RelationshipID int
+	EventID        int
+}
+
+// MediaStore - загруженные файлы дерева и их связи с людьми, связями
+// и событиями. Здесь только описание файлов: содержимое хранит media.Storage
+// под ключом Hash.
+type MediaStore interface {
+	// ListMedia возвращает файлы дерева со связями, новые первыми
+	ListMedia(ctx context.Context, treeID int) ([]models.Media, error)
+	GetMedia(ctx context.Context, treeID, mediaID int) (*models.Media, error)
+	// FindMedia возвращает файл дерева с таким содержимым или ErrNotFound
+	FindMedia(ctx context.Context, treeID int, hash string) (*models.Media, error)
+	// CreateMedia заполняет m.ID и m.CreatedAt
+	CreateMedia(ctx context.Context, treeID int, m *models.Media) error
+	// UpdateMedia меняет название и описание файла m.ID
+	UpdateMedia(ctx context.Context, treeID int, m models.Media) error
+	// DeleteMedia удаляет файл вместе со связями и возвращает его
+	DeleteMedia(ctx context.Context, treeID, mediaID int) (*models.Media, error)
+	// MediaInUse - есть ли хоть в одном дереве файл с таким содержимым.
+	// Если нет, содержимое можно стереть из хранилища.
+	MediaInUse(ctx context.Context, hash string) (bool, error)
+
+	// ListOwnerMedia возвращает файлы, связанные с владельцем, новые первыми.
+	// ErrNotFound - владельца нет (или он в корзине).
+	ListOwnerMedia(ctx context.Context, treeID int, owner MediaOwner) ([]models.Media, error)
+	// LinkMedia связывает файл с владельцем, повторная связь ничего не меняет.
+	// ErrNotFound - владельца нет, ErrInvalidMedia - файла нет в дереве.
+	LinkMedia(ctx context.Context, treeID int, owner MediaOwner, mediaID int) error
+	// UnlinkMedia убирает связь, сам файл остаётся. ErrNotFound - связи нет.
+	UnlinkMedia(ctx context.Context, treeID int, owner MediaOwner, mediaID int) error
+}
+
+// UserStore - хранилище аккаунтов
+type UserStore interface {
+	// CreateUser возвращает ErrEmailTaken, если email уже занят
+	CreateUser(ctx context.Context, email, passwordHash string) (int, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	SetPasswordHash(ctx context.Context, id int, passwordHash string) error
+}
+
+// TreeImporter - массовая запись людей и связей (импорт GEDCOM)
+type TreeImporter interface {
+	// ImportTree атомарно добавляет людей и связи в дерево treeID.
+	// ID людей на входе - локальные, связи ссылаются на них. После записи
+	// в people и relationships проставляются настоящие ID.
+	ImportTree(ctx context.Context, treeID int, people []models.Person, relationships []models.Relationship) error
+}
+
+// TreeStore - хранилище деревьев. У одного аккаунта их может быть несколько.
+type TreeStore interface {
+	// CreateTree создаёт дерево, userID становится его владельцем
+	CreateTree(ctx context.Context, userID int, name string) (*models.Tree, error)
+	// ListTrees возвращает деревья, где пользователь - участник, с его ролью
+	ListTrees(ctx context.Context, userID int) ([]models.Tree, error)
+	// GetTree возвращает ErrNotFound, если дерева нет. Права доступа проверяет вызывающий.
+	GetTree(ctx context.Context, treeID int) (*models.Tree, error)
+	RenameTree(ctx context.Context, treeID int, name string) error
+	// DeleteTree удаляет дерево вместе со всеми людьми и связями в нём
+	DeleteTree(ctx context.Context, treeID int) error
+	// DuplicateTree копирует людей, связи, события, места, источники и файлы в новое дерево, владельцем которого становится userID
+	DuplicateTree(ctx context.Context, treeID, userID int, name string) (*models.Tree, error)
+	// EnsureDefaultTree возвращает самое старое собственное дерево пользователя,
+	// а если таких нет - создаёт пустое
+	EnsureDefaultTree(ctx context.Context, userID int) (*models.Tree, error)
+}
+
+// MembershipStore - участники деревьев и приглашения
+type MembershipStore interface {
+	// GetMemberRole возвращает роль пользователя в дереве или ErrNotFound
+	GetMemberRole(ctx context.Context, treeID, userID int) (string, error)
+	ListMembers(ctx context.Context, treeID int) ([]models.TreeMember, error)
+	SetMemberRole(ctx context.Context, treeID, userID int, role string) error
+	RemoveMember(ctx context.Context, treeID, userID int) error
+
+	// CreateInvite сохраняет приглашение с хешем токена. Нулевой expiresAt - без срока.
+	CreateInvite(ctx context.Context, inv *models.TreeInvite, tokenHash string, expiresAt time.Time) error
+	// ListInvites возвращает неиспользованные приглашения в дерево
+	ListInvites(ctx context.Context, treeID int) ([]models.TreeInvite, error)
+	DeleteInvite(ctx context.Context, treeID, inviteID int) error
+	// AcceptInvite по токену атомарно гасит приглашение и добавляет участника.
+	// Уже участнику роль только повышается. Возвращает ErrInviteInvalid, если
+	// приглашение не найдено, использовано, просрочено или выписано на другой email.
+	// Без токена приглашение не принять: адрес при регистрации не подтверждается.
+	AcceptInvite(ctx context.Context, tokenHash string, userID int, email string) (*models.TreeMember, error)
+}
+
+// ShareLinkStore - публичные ссылки на деревья
+type ShareLinkStore interface {
+	// CreateShareLink сохраняет ссылку. Нулевой expiresAt - бессрочно.
+	CreateShareLink(ctx context.Context, link *models.ShareLink, expiresAt time.Time) error
+	// ListShareLinks возвращает все ссылки дерева, включая отозванные
+	ListShareLinks(ctx context.Context, treeID int) ([]models.ShareLink, error)
+	GetShareLink(ctx context.Context, linkID int) (*models.ShareLink, error)
+	// RevokeShareLink отзывает ссылку. Повторный отзыв ничего не меняет.
+	RevokeShareLink(ctx context.Context, treeID, linkID int) error
+}
+
+// HistoryStore - журнал изменений людей и связей. Записи в него добавляют
+// сами изменяющие методы PeopleStore, RelationshipStore и TreeImporter.
+type HistoryStore interface {
+	// ListChanges возвращает записи журнала дерева, новые первыми
+	ListChanges(ctx context.Context, treeID int, f ChangeFilter) ([]models.Change, error)
+	GetChange(ctx context.Context, treeID, changeID int) (*models.Change, error)
+	// RevertChange одной транзакцией отменяет изменение и возвращает новые
+	// записи журнала. Отмена удаления человека возвращает и его связи.
+	// ErrRevertConflict - если отменять уже нечего.
+	RevertChange(ctx context.Context, treeID, changeID int) ([]models.Change, error)
+}
+
+// TrashStore - корзина. Удалённые люди и связи хранятся с отметкой
+// deleted_at и не видны остальным методам, пока их не восстановят.
+type TrashStore interface {
+	ListTrash(ctx context.Context, treeID int) (*models.Trash, error)
+	// RestoreFromTrash отменяет удаление человека (вместе со связями, удалёнными
+	// с ним) или связи и возвращает новые записи журнала. ErrNotFound - записи
+	// нет в корзине, ErrRevertConflict - связь ведёт к человеку из корзины.
+	RestoreFromTrash(ctx context.Context, treeID int, entityType string, id int) ([]models.Change, error)
+	// PurgeFromTrash окончательно стирает запись из корзины, человека - вместе
+	// с его связями. ErrNotFound - записи нет в корзине.
+	PurgeFromTrash(ctx context.Context, treeID int, entityType string, id int) (models.PurgeResult, error)
+	// EmptyTrash окончательно стирает всё содержимое корзины дерева
+	EmptyTrash(ctx context.Context, treeID int) (models.PurgeResult, error)
+	// PurgeDeletedBefore стирает во всех деревьях то, что лежит в корзине с момента раньше cutoff
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (models.PurgeResult, error)
+}
+
+// DefaultTreeName - имя дерева, которое создаётся автоматически
+const DefaultTreeName = "Моё дерево"
+
+// Store объединяет все хранилища одного бэкенда
+type Store interface {
+	PeopleStore
+	RelationshipStore
+	EventStore
+	PlaceStore
+	GazetteerStore
+	SourceStore
+	MediaStore
+	UserStore
+	TreeImporter
+	TreeStore
+	MembershipStore
+	ShareLinkStore
+	HistoryStore
+	TrashStore
+}
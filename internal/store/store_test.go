@@ -0,0 +1,1261 @@
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"family-tree-app/internal/database"
+	"family-tree-app/internal/models"
+	"family-tree-app/internal/store"
+)
+
+// Контрактные тесты: одно и то же поведение требуется от MemoryStore
+// и от SQLiteStore на настоящей схеме после всех миграций.
+
+// forEachStore запускает test для каждого бэкенда на пустом хранилище
+func forEachStore(t *testing.T, test func(t *testing.T, s store.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, store.NewMemory())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if _, err := database.MigrateUp(db); err != nil {
+			t.Fatal(err)
+		}
+		test(t, store.NewSQLite(db))
+	})
+}
+
+// newTree заводит пользователя и его дерево
+func newTree(t *testing.T, s store.Store, email string) (userID, treeID int) {
+	t.Helper()
+	ctx := context.Background()
+	userID, err := s.CreateUser(ctx, email, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := s.CreateTree(ctx, userID, "Дерево")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userID, tree.ID
+}
+
+func addPerson(t *testing.T, s store.Store, treeID int, first, last string) int {
+	t.Helper()
+	p := models.Person{FirstName: first, LastName: last, Gender: "male"}
+	if err := s.CreatePerson(context.Background(), treeID, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p.ID
+}
+
+func addRelationship(t *testing.T, s store.Store, treeID, from, to int, typ string) int {
+	t.Helper()
+	rel := models.Relationship{FromPersonID: from, ToPersonID: to, Type: typ}
+	if err := s.CreateRelationship(context.Background(), treeID, &rel); err != nil {
+		t.Fatal(err)
+	}
+	return rel.ID
+}
+
+func personIDs(t *testing.T, s store.Store, treeID int) []int {
+	t.Helper()
+	people, err := s.ListPeople(context.Background(), treeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, p := range people {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func relationshipIDs(t *testing.T, s store.Store, treeID int) []int {
+	t.Helper()
+	rels, err := s.ListRelationships(context.Background(), treeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, rel := range rels {
+		ids = append(ids, rel.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// addMedia заводит файл без содержимого: хранилищу достаточно хеша
+func addMedia(t *testing.T, s store.Store, treeID int, hash string) int {
+	t.Helper()
+	m := models.Media{Hash: hash, ContentType: "image/jpeg", Size: 1}
+	if err := s.CreateMedia(context.Background(), treeID, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m.ID
+}
+
+func eventIDs(t *testing.T, s store.Store, treeID, personID int) []int {
+	t.Helper()
+	events, err := s.ListEvents(context.Background(), treeID, store.EventOwner{PersonID: personID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func mediaIDs(t *testing.T, s store.Store, treeID, personID int) []int {
+	t.Helper()
+	media, err := s.ListOwnerMedia(context.Background(), treeID, store.MediaOwner{PersonID: personID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, m := range media {
+		ids = append(ids, m.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTrashAndRevert(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		father := addPerson(t, s, treeID, "Иван", "Петров")
+		son := addPerson(t, s, treeID, "Пётр", "Петров")
+		rel := addRelationship(t, s, treeID, father, son, "parent")
+
+		removed, err := s.DeletePerson(ctx, treeID, father)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(removed.People) != 1 || len(removed.Relationships) != 1 || removed.Relationships[0].ID != rel {
+			t.Fatalf("DeletePerson = %+v, ожидался человек со связью %d", removed, rel)
+		}
+		if got := personIDs(t, s, treeID); !equalIDs(got, []int{son}) {
+			t.Errorf("люди после удаления %v, ожидалось [%d]", got, son)
+		}
+		trash, err := s.ListTrash(ctx, treeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trash.People) != 1 || trash.People[0].ID != father || len(trash.Relationships) != 1 {
+			t.Fatalf("корзина %+v, ожидался человек %d и одна связь", trash, father)
+		}
+		// Связь с человеком из корзины сама по себе не восстанавливается
+		if _, err := s.RestoreFromTrash(ctx, treeID, models.EntityRelationship, rel); !errors.Is(err, store.ErrRevertConflict) {
+			t.Errorf("восстановление связи с человеком из корзины: %v, ожидалось ErrRevertConflict", err)
+		}
+
+		if _, err := s.RestoreFromTrash(ctx, treeID, models.EntityPerson, father); err != nil {
+			t.Fatal(err)
+		}
+		if got := relationshipIDs(t, s, treeID); !equalIDs(got, []int{rel}) {
+			t.Errorf("связи после восстановления %v, ожидалось [%d]", got, rel)
+		}
+		if _, err := s.RestoreFromTrash(ctx, treeID, models.EntityPerson, father); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("повторное восстановление: %v, ожидалось ErrNotFound", err)
+		}
+
+		// Отмена удаления через журнал возвращает человека вместе со связями
+		if _, err := s.DeletePerson(ctx, treeID, father); err != nil {
+			t.Fatal(err)
+		}
+		deletions, err := s.ListChanges(ctx, treeID, store.ChangeFilter{EntityType: models.EntityPerson, EntityID: father, Action: models.ActionDelete})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deletions) != 2 {
+			t.Fatalf("записей об удалении %d, ожидалось 2", len(deletions))
+		}
+		if _, err := s.RevertChange(ctx, treeID, deletions[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := personIDs(t, s, treeID); !equalIDs(got, []int{father, son}) {
+			t.Errorf("люди после отмены %v, ожидалось [%d %d]", got, father, son)
+		}
+		if got := relationshipIDs(t, s, treeID); !equalIDs(got, []int{rel}) {
+			t.Errorf("связи после отмены %v, ожидалось [%d]", got, rel)
+		}
+		if _, err := s.RevertChange(ctx, treeID, deletions[0].ID); !errors.Is(err, store.ErrRevertConflict) {
+			t.Errorf("повторная отмена: %v, ожидалось ErrRevertConflict", err)
+		}
+
+		// Окончательное удаление
+		if _, err := s.DeletePerson(ctx, treeID, son); err != nil {
+			t.Fatal(err)
+		}
+		purged, err := s.EmptyTrash(ctx, treeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if purged.People != 1 || purged.Relationships != 1 {
+			t.Errorf("EmptyTrash = %+v, ожидались 1 человек и 1 связь", purged)
+		}
+		if _, err := s.RestoreFromTrash(ctx, treeID, models.EntityPerson, son); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("восстановление после стирания: %v, ожидалось ErrNotFound", err)
+		}
+	})
+}
+
+func TestPurgeFromTrash(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		_, otherTreeID := newTree(t, s, "other@example.com")
+		father := addPerson(t, s, treeID, "Иван", "Петров")
+		son := addPerson(t, s, treeID, "Пётр", "Петров")
+		daughter := addPerson(t, s, treeID, "Мария", "Петрова")
+		toSon := addRelationship(t, s, treeID, father, son, "parent")
+		siblings := addRelationship(t, s, treeID, son, daughter, "sibling")
+		stranger := addPerson(t, s, otherTreeID, "Сидор", "Сидоров")
+
+		if _, err := s.DeleteRelationship(ctx, treeID, siblings); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeletePerson(ctx, treeID, father); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeletePerson(ctx, otherTreeID, stranger); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name       string
+			treeID     int
+			entityType string
+			id         int
+			want       models.PurgeResult
+			wantErr    error
+		}{
+			{"живой человек", treeID, models.EntityPerson, son, models.PurgeResult{}, store.ErrNotFound},
+			{"человек из корзины чужого дерева", treeID, models.EntityPerson, stranger, models.PurgeResult{}, store.ErrNotFound},
+			{"неизвестный тип", treeID, "event", father, models.PurgeResult{}, store.ErrNotFound},
+			{"связь из корзины", treeID, models.EntityRelationship, siblings, models.PurgeResult{Relationships: 1}, nil},
+			{"человек со связью", treeID, models.EntityPerson, father, models.PurgeResult{People: 1, Relationships: 1}, nil},
+			{"стёртый человек", treeID, models.EntityPerson, father, models.PurgeResult{}, store.ErrNotFound},
+			{"связь стёртого человека", treeID, models.EntityRelationship, toSon, models.PurgeResult{}, store.ErrNotFound},
+		}
+		for _, tt := range tests {
+			got, err := s.PurgeFromTrash(ctx, tt.treeID, tt.entityType, tt.id)
+			if !errors.Is(err, tt.wantErr) || (err == nil && got != tt.want) {
+				t.Errorf("%s: PurgeFromTrash = %+v, %v; ожидалось %+v, %v", tt.name, got, err, tt.want, tt.wantErr)
+			}
+		}
+
+		if got := personIDs(t, s, treeID); !equalIDs(got, []int{son, daughter}) {
+			t.Errorf("люди после стирания %v, ожидалось [%d %d]", got, son, daughter)
+		}
+		trash, err := s.ListTrash(ctx, treeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trash.People)+len(trash.Relationships) != 0 {
+			t.Errorf("корзина после стирания %+v, ожидалось пусто", trash)
+		}
+		// Корзина другого дерева не тронута
+		if _, err := s.RestoreFromTrash(ctx, otherTreeID, models.EntityPerson, stranger); err != nil {
+			t.Errorf("восстановление в другом дереве: %v", err)
+		}
+	})
+}
+
+// lastChange - последняя запись журнала об action над сущностью
+func lastChange(t *testing.T, s store.Store, treeID int, entityType string, entityID int, action string) models.Change {
+	t.Helper()
+	changes, err := s.ListChanges(context.Background(), treeID, store.ChangeFilter{EntityType: entityType, EntityID: entityID, Action: action})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) == 0 {
+		t.Fatalf("в журнале нет записи %s %s %d", action, entityType, entityID)
+	}
+	return changes[0]
+}
+
+func TestRevertChange(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		_, otherTreeID := newTree(t, s, "other@example.com")
+		father := addPerson(t, s, treeID, "Иван", "Петров")
+		son := addPerson(t, s, treeID, "Пётр", "Петров")
+		rel := addRelationship(t, s, treeID, father, son, "parent")
+
+		rename := func(name string) models.Change {
+			t.Helper()
+			p := getPerson(t, s, treeID, father)
+			p.FirstName = name
+			if err := s.UpdatePerson(ctx, treeID, p); err != nil {
+				t.Fatal(err)
+			}
+			return lastChange(t, s, treeID, models.EntityPerson, father, models.ActionUpdate)
+		}
+		first := rename("Иоанн")
+		second := rename("Ян")
+
+		// Правку, поверх которой уже есть другая, не отменить
+		if _, err := s.RevertChange(ctx, treeID, first.ID); !errors.Is(err, store.ErrRevertConflict) {
+			t.Errorf("отмена перекрытой правки: %v, ожидалось ErrRevertConflict", err)
+		}
+		reverted, err := s.RevertChange(ctx, treeID, second.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reverted) != 1 || reverted[0].RevertOf == nil || *reverted[0].RevertOf != second.ID {
+			t.Errorf("записи отмены %+v, ожидалась одна с revert_of = %d", reverted, second.ID)
+		}
+		if got := getPerson(t, s, treeID, father).FirstName; got != "Иоанн" {
+			t.Errorf("имя после отмены %q, ожидалось Иоанн", got)
+		}
+		// Теперь перекрытая правка снова последняя
+		if _, err := s.RevertChange(ctx, treeID, first.ID); err != nil {
+			t.Errorf("отмена первой правки: %v", err)
+		}
+		if got := getPerson(t, s, treeID, father).FirstName; got != "Иван" {
+			t.Errorf("имя после второй отмены %q, ожидалось Иван", got)
+		}
+
+		if err := s.UpdateRelationshipDescription(ctx, treeID, rel, "усыновлён в 1920"); err != nil {
+			t.Fatal(err)
+		}
+		described := lastChange(t, s, treeID, models.EntityRelationship, rel, models.ActionUpdate)
+		if _, err := s.RevertChange(ctx, treeID, described.ID); err != nil {
+			t.Fatal(err)
+		}
+		rels, err := s.ListRelationships(ctx, treeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rels) != 1 || rels[0].Description != "" {
+			t.Errorf("связи после отмены описания %+v, ожидалась связь без описания", rels)
+		}
+
+		created := lastChange(t, s, treeID, models.EntityRelationship, rel, models.ActionCreate)
+		if _, err := s.RevertChange(ctx, treeID, created.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := relationshipIDs(t, s, treeID); len(got) != 0 {
+			t.Errorf("связи после отмены создания %v, ожидалось пусто", got)
+		}
+		if _, err := s.RevertChange(ctx, treeID, created.ID); !errors.Is(err, store.ErrRevertConflict) {
+			t.Errorf("повторная отмена создания связи: %v, ожидалось ErrRevertConflict", err)
+		}
+
+		born := lastChange(t, s, treeID, models.EntityPerson, son, models.ActionCreate)
+		if _, err := s.RevertChange(ctx, treeID, born.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := personIDs(t, s, treeID); !equalIDs(got, []int{father}) {
+			t.Errorf("люди после отмены создания %v, ожидалось [%d]", got, father)
+		}
+
+		// Запись чужого дерева и несуществующая запись не находятся
+		for _, id := range []int{born.ID, 1 << 20} {
+			if _, err := s.RevertChange(ctx, otherTreeID, id); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("отмена записи %d из другого дерева: %v, ожидалось ErrNotFound", id, err)
+			}
+		}
+	})
+}
+
+// nextSecond ждёт начала следующей секунды и возвращает текущее время:
+// SQLite хранит момент удаления с точностью до секунды
+func nextSecond() time.Time {
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	return time.Now()
+}
+
+func TestPurgeDeletedBefore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		_, otherTreeID := newTree(t, s, "other@example.com")
+		father := addPerson(t, s, treeID, "Иван", "Петров")
+		son := addPerson(t, s, treeID, "Пётр", "Петров")
+		daughter := addPerson(t, s, treeID, "Мария", "Петрова")
+		toSon := addRelationship(t, s, treeID, father, son, "parent")
+		toDaughter := addRelationship(t, s, treeID, father, daughter, "parent")
+		siblings := addRelationship(t, s, treeID, son, daughter, "sibling")
+		stranger := addPerson(t, s, otherTreeID, "Сидор", "Сидоров")
+
+		// Удалены до cutoff: отец со своими связями и человек из другого дерева
+		if _, err := s.DeletePerson(ctx, treeID, father); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeletePerson(ctx, otherTreeID, stranger); err != nil {
+			t.Fatal(err)
+		}
+		cutoff := nextSecond()
+		// Удалена в момент cutoff - срок хранения ещё не вышел
+		if _, err := s.DeleteRelationship(ctx, treeID, siblings); err != nil {
+			t.Fatal(err)
+		}
+
+		purged, err := s.PurgeDeletedBefore(ctx, cutoff)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if purged.People != 2 || purged.Relationships != 2 {
+			t.Errorf("PurgeDeletedBefore = %+v, ожидались 2 человека и 2 связи", purged)
+		}
+		trash, err := s.ListTrash(ctx, treeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trash.People) != 0 || len(trash.Relationships) != 1 || trash.Relationships[0].ID != siblings {
+			t.Errorf("корзина после очистки %+v, ожидалась только связь %d", trash, siblings)
+		}
+		for _, rel := range []int{toSon, toDaughter} {
+			if _, err := s.RestoreFromTrash(ctx, treeID, models.EntityRelationship, rel); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("восстановление связи %d стёртого человека: %v, ожидалось ErrNotFound", rel, err)
+			}
+		}
+		if _, err := s.RestoreFromTrash(ctx, otherTreeID, models.EntityPerson, stranger); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("восстановление стёртого в другом дереве: %v, ожидалось ErrNotFound", err)
+		}
+		if got := personIDs(t, s, treeID); !equalIDs(got, []int{son, daughter}) {
+			t.Errorf("люди после очистки %v, ожидалось [%d %d]", got, son, daughter)
+		}
+
+		// Повторный проход с тем же cutoff ничего не стирает
+		if purged, err := s.PurgeDeletedBefore(ctx, cutoff); err != nil || purged != (models.PurgeResult{}) {
+			t.Errorf("повторная очистка = %+v, %v; ожидалось ничего", purged, err)
+		}
+		if _, err := s.RestoreFromTrash(ctx, treeID, models.EntityRelationship, siblings); err != nil {
+			t.Errorf("восстановление связи, удалённой в момент cutoff: %v", err)
+		}
+	})
+}
+
+func TestMergePeople(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		keep := addPerson(t, s, treeID, "Иван", "Петров")
+		other := addPerson(t, s, treeID, "Ваня", "Петров")
+		child := addPerson(t, s, treeID, "Пётр", "Петров")
+		wife := addPerson(t, s, treeID, "Мария", "Петрова")
+		kept := addRelationship(t, s, treeID, keep, child, "parent")
+		duplicate := addRelationship(t, s, treeID, other, child, "parent")
+		marriage := addRelationship(t, s, treeID, other, wife, "spouse")
+		between := addRelationship(t, s, treeID, keep, other, "spouse")
+		event := models.Event{Type: "residence", Place: "Тверь"}
+		if err := s.CreateEvent(ctx, treeID, store.EventOwner{PersonID: other}, &event); err != nil {
+			t.Fatal(err)
+		}
+		own, shared := addMedia(t, s, treeID, "own"), addMedia(t, s, treeID, "shared")
+		for _, link := range []struct{ person, media int }{{other, own}, {other, shared}, {keep, shared}} {
+			if err := s.LinkMedia(ctx, treeID, store.MediaOwner{PersonID: link.person}, link.media); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		merged := models.Person{ID: keep, FirstName: "Иван", LastName: "Петров", Gender: "male", BirthDate: "1850"}
+		result, err := s.MergePeople(ctx, treeID, merged, other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Person.BirthDate != "1850" || result.MergedID != other {
+			t.Errorf("MergePeople: человек %+v, слит %d", result.Person, result.MergedID)
+		}
+		if len(result.Repointed) != 1 || result.Repointed[0].ID != marriage || result.Repointed[0].FromPersonID != keep {
+			t.Errorf("перенесены %+v, ожидался брак %d у %d", result.Repointed, marriage, keep)
+		}
+		var removed []int
+		for _, rel := range result.Removed {
+			removed = append(removed, rel.ID)
+		}
+		sort.Ints(removed)
+		if !equalIDs(removed, []int{duplicate, between}) {
+			t.Errorf("в корзине %v, ожидалось [%d %d]", removed, duplicate, between)
+		}
+		if got := personIDs(t, s, treeID); !equalIDs(got, []int{keep, child, wife}) {
+			t.Errorf("люди после слияния %v", got)
+		}
+		if got := relationshipIDs(t, s, treeID); !equalIDs(got, []int{kept, marriage}) {
+			t.Errorf("связи после слияния %v, ожидалось [%d %d]", got, kept, marriage)
+		}
+		// Кроме перенесённого, у первого теперь событие рождения по дате из merged
+		if got := eventIDs(t, s, treeID, keep); len(got) != 2 || !slices.Contains(got, event.ID) {
+			t.Errorf("события после слияния %v, ожидалось %d и рождение", got, event.ID)
+		}
+
+		// Удаление второго в журнале помнит, что слияние у него забрало,
+		// и восстановление из корзины возвращает это обратно
+		deleted, err := s.ListChanges(ctx, treeID, store.ChangeFilter{EntityType: models.EntityPerson, EntityID: other, Action: models.ActionDelete})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deleted) != 1 || len(deleted[0].Moved) == 0 {
+			t.Fatalf("удаление второго в журнале: %+v", deleted)
+		}
+		if _, err := s.RestoreFromTrash(ctx, treeID, models.EntityPerson, other); err != nil {
+			t.Fatal(err)
+		}
+		if got := eventIDs(t, s, treeID, other); !equalIDs(got, []int{event.ID}) {
+			t.Errorf("события второго после восстановления %v, ожидалось [%d]", got, event.ID)
+		}
+		if got := eventIDs(t, s, treeID, keep); len(got) != 1 || slices.Contains(got, event.ID) {
+			t.Errorf("события первого после восстановления %v, ожидалось одно рождение", got)
+		}
+		if got := mediaIDs(t, s, treeID, other); !equalIDs(got, []int{own, shared}) {
+			t.Errorf("файлы второго после восстановления %v, ожидалось [%d %d]", got, own, shared)
+		}
+		if got := mediaIDs(t, s, treeID, keep); !equalIDs(got, []int{shared}) {
+			t.Errorf("файлы первого после восстановления %v, ожидалось [%d]", got, shared)
+		}
+		if _, err := s.MergePeople(ctx, treeID, merged, other); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.MergePeople(ctx, treeID, merged, keep); !errors.Is(err, store.ErrInvalidReference) {
+			t.Errorf("слияние с самим собой: %v, ожидалось ErrInvalidReference", err)
+		}
+		if _, err := s.MergePeople(ctx, treeID, merged, other); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("слияние человека из корзины: %v, ожидалось ErrNotFound", err)
+		}
+	})
+}
+
+// getPerson - человек из ListPeople; в тестах нет отдельного чтения одного человека
+func getPerson(t *testing.T, s store.Store, treeID, personID int) models.Person {
+	t.Helper()
+	people, err := s.ListPeople(context.Background(), treeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range people {
+		if p.ID == personID {
+			return p
+		}
+	}
+	t.Fatalf("человек %d не найден", personID)
+	return models.Person{}
+}
+
+// lifeEvents - даты событий рождения и смерти человека по типу
+func lifeEvents(t *testing.T, s store.Store, treeID, personID int) map[string][]string {
+	t.Helper()
+	events, err := s.ListEvents(context.Background(), treeID, store.EventOwner{PersonID: personID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dates := map[string][]string{}
+	for _, e := range events {
+		if e.Type == models.EventBirth || e.Type == models.EventDeath {
+			dates[e.Type] = append(dates[e.Type], e.Date)
+		}
+	}
+	return dates
+}
+
+func TestLifeEvents(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		death := "1920"
+		p := models.Person{FirstName: "Иван", LastName: "Петров", Gender: "male", BirthDate: "1850", DeathDate: &death}
+		if err := s.CreatePerson(ctx, treeID, &p); err != nil {
+			t.Fatal(err)
+		}
+		owner := store.EventOwner{PersonID: p.ID}
+
+		// Человек с датами получает события рождения и смерти
+		got := lifeEvents(t, s, treeID, p.ID)
+		if !slices.Equal(got[models.EventBirth], []string{"1850"}) || !slices.Equal(got[models.EventDeath], []string{"1920"}) {
+			t.Fatalf("события после создания %v, ожидалось рождение 1850 и смерть 1920", got)
+		}
+
+		p.BirthDate = "1851"
+		if err := s.UpdatePerson(ctx, treeID, p); err != nil {
+			t.Fatal(err)
+		}
+		if got := lifeEvents(t, s, treeID, p.ID); !slices.Equal(got[models.EventBirth], []string{"1851"}) {
+			t.Errorf("рождение после правки человека %v, ожидалось [1851]", got[models.EventBirth])
+		}
+
+		events, err := s.ListEvents(ctx, treeID, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			if err := s.DeleteEvent(ctx, treeID, owner, e.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := getPerson(t, s, treeID, p.ID); got.BirthDate != "" || got.DeathDate != nil {
+			t.Errorf("после удаления событий рождение %q, смерть %v; ожидалось пусто", got.BirthDate, got.DeathDate)
+		}
+
+		birth := models.Event{Type: models.EventBirth, Date: "1849"}
+		if err := s.CreateEvent(ctx, treeID, owner, &birth); err != nil {
+			t.Fatal(err)
+		}
+		if got := getPerson(t, s, treeID, p.ID); got.BirthDate != "1849" {
+			t.Errorf("рождение после нового события %q, ожидалось 1849", got.BirthDate)
+		}
+		birth.Type = models.EventBaptism
+		if err := s.UpdateEvent(ctx, treeID, owner, birth); err != nil {
+			t.Fatal(err)
+		}
+		if got := getPerson(t, s, treeID, p.ID); got.BirthDate != "" {
+			t.Errorf("рождение после смены типа события %q, ожидалось пусто", got.BirthDate)
+		}
+		if got := lifeEvents(t, s, treeID, p.ID); len(got) != 0 {
+			t.Errorf("события рождения и смерти %v, ожидалось пусто", got)
+		}
+	})
+}
+
+func TestQueryPeopleCursor(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		// Одинаковые фамилии проверяют дочитывание по ID внутри равных значений
+		for _, last := range []string{"Сидоров", "Иванов", "Петров", "Иванов", "Абрамов", "Иванов", "Яковлев"} {
+			addPerson(t, s, treeID, "Имя", last)
+		}
+
+		for _, desc := range []bool{false, true} {
+			all, err := s.QueryPeople(ctx, treeID, store.PeopleQuery{Sort: store.SortByLastName, Desc: desc})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if all.NextCursor != "" {
+				t.Errorf("desc=%v: курсор без лимита %q", desc, all.NextCursor)
+			}
+			var want []int
+			for _, p := range all.People {
+				want = append(want, p.ID)
+			}
+
+			var got []int
+			q := store.PeopleQuery{Sort: store.SortByLastName, Desc: desc, Limit: 2}
+			for pages := 0; ; pages++ {
+				if pages > len(want) {
+					t.Fatalf("desc=%v: страницы не кончаются", desc)
+				}
+				page, err := s.QueryPeople(ctx, treeID, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, p := range page.People {
+					got = append(got, p.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				q.Cursor = page.NextCursor
+			}
+			if !equalIDs(got, want) {
+				t.Errorf("desc=%v: по страницам %v, ожидалось %v", desc, got, want)
+			}
+		}
+
+		first, err := s.QueryPeople(ctx, treeID, store.PeopleQuery{Sort: store.SortByLastName, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Курсор другой сортировки или направления не подходит
+		for _, q := range []store.PeopleQuery{
+			{Sort: store.SortByFirstName, Cursor: first.NextCursor},
+			{Sort: store.SortByLastName, Desc: true, Cursor: first.NextCursor},
+			{Sort: store.SortByLastName, Cursor: "not a cursor"},
+		} {
+			if _, err := s.QueryPeople(ctx, treeID, q); !errors.Is(err, store.ErrInvalidCursor) {
+				t.Errorf("QueryPeople(%+v): %v, ожидалось ErrInvalidCursor", q, err)
+			}
+		}
+		if _, err := s.QueryPeople(ctx, treeID, store.PeopleQuery{Sort: "photo_url"}); !errors.Is(err, store.ErrInvalidSort) {
+			t.Errorf("неизвестная сортировка: %v, ожидалось ErrInvalidSort", err)
+		}
+	})
+}
+
+// queryAllPages проходит все страницы запроса q и возвращает ID людей по порядку
+func queryAllPages(t *testing.T, s store.Store, treeID int, q store.PeopleQuery) []int {
+	t.Helper()
+	ids := []int{}
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatalf("QueryPeople(%+v): страницы не кончаются", q)
+		}
+		page, err := s.QueryPeople(context.Background(), treeID, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range page.People {
+			ids = append(ids, p.ID)
+		}
+		if page.NextCursor == "" {
+			return ids
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func TestQueryPeopleFilters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		_, otherTreeID := newTree(t, s, "other@example.com")
+		died1950, died1940 := "1950", "1940"
+		add := func(p models.Person) int {
+			t.Helper()
+			if err := s.CreatePerson(ctx, treeID, &p); err != nil {
+				t.Fatal(err)
+			}
+			return p.ID
+		}
+		ivan := add(models.Person{FirstName: "Иван", LastName: "Петров", Gender: "male", BirthDate: "1890", DeathDate: &died1950, PhotoURL: "/ivan.jpg"})
+		maria := add(models.Person{FirstName: "Мария", LastName: "Петрова", Gender: "female", BirthDate: "ABT 1880", DeathDate: &died1940})
+		petrov := add(models.Person{FirstName: "Ivan", LastName: "Petrov", Gender: "male", BirthDate: "1995"})
+		anna := add(models.Person{FirstName: "Анна", LastName: "Сидорова", Gender: "female", BirthDate: "1990-05-01", PhotoURL: "/anna.jpg"})
+		oleg := add(models.Person{FirstName: "Олег", LastName: "Иванов", Gender: "male"})
+		petr := add(models.Person{FirstName: "Пётр", LastName: "Петров", Gender: "male", BirthDate: "1900-12-31"})
+		addPerson(t, s, otherTreeID, "Иван", "Петров")
+
+		yes, no := true, false
+		tests := []struct {
+			name string
+			q    store.PeopleQuery
+			want []int
+		}{
+			{"все", store.PeopleQuery{}, []int{ivan, maria, petrov, anna, oleg, petr}},
+			{"фамилия в любой форме и записи", store.PeopleQuery{Surname: "Петрова"}, []int{ivan, maria, petrov, petr}},
+			{"пол", store.PeopleQuery{Gender: "female"}, []int{maria, anna}},
+			{"фамилия и пол", store.PeopleQuery{Surname: "Petrov", Gender: "female"}, []int{maria}},
+			{"родились не раньше", store.PeopleQuery{BornFrom: 1890}, []int{ivan, petrov, anna, petr}},
+			{"родились не позже", store.PeopleQuery{BornTo: 1890}, []int{ivan, maria}},
+			{"годы рождения", store.PeopleQuery{BornFrom: 1890, BornTo: 1900}, []int{ivan, petr}},
+			{"живые", store.PeopleQuery{Living: &yes}, []int{petrov, anna, oleg}},
+			{"умершие", store.PeopleQuery{Living: &no}, []int{ivan, maria, petr}},
+			{"с фото", store.PeopleQuery{HasPhoto: &yes}, []int{ivan, anna}},
+			{"без фото", store.PeopleQuery{HasPhoto: &no}, []int{maria, petrov, oleg, petr}},
+			{"по дате рождения", store.PeopleQuery{Sort: store.SortByBirthDate}, []int{oleg, maria, ivan, petr, anna, petrov}},
+			{"умершие по дате рождения с конца", store.PeopleQuery{Living: &no, Sort: store.SortByBirthDate, Desc: true}, []int{petr, ivan, maria}},
+			{"по имени", store.PeopleQuery{Surname: "Петров", Sort: store.SortByFirstName}, []int{petrov, ivan, maria, petr}},
+			{"никого", store.PeopleQuery{Surname: "Сидоров", Gender: "male"}, []int{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// Без лимита и по страницам из двух человек - одно и то же
+				for _, limit := range []int{0, 2} {
+					q := tt.q
+					q.Limit = limit
+					if got := queryAllPages(t, s, treeID, q); !equalIDs(got, tt.want) {
+						t.Errorf("limit=%d: %v, ожидалось %v", limit, got, tt.want)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestInvites(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		ownerID, treeID := newTree(t, s, "owner@example.com")
+		guestID, err := s.CreateUser(ctx, "guest@example.com", "hash")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		inv := models.TreeInvite{TreeID: treeID, Role: models.RoleViewer, CreatedBy: ownerID}
+		if err := s.CreateInvite(ctx, &inv, "hash-1", time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AcceptInvite(ctx, "hash-2", guestID, "guest@example.com"); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("неизвестный токен: %v, ожидалось ErrInviteInvalid", err)
+		}
+		member, err := s.AcceptInvite(ctx, "hash-1", guestID, "guest@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if member.TreeID != treeID || member.UserID != guestID || member.Role != models.RoleViewer {
+			t.Errorf("AcceptInvite = %+v", member)
+		}
+		if _, err := s.AcceptInvite(ctx, "hash-1", guestID, "guest@example.com"); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("использованное приглашение: %v, ожидалось ErrInviteInvalid", err)
+		}
+		if invites, err := s.ListInvites(ctx, treeID); err != nil || len(invites) != 0 {
+			t.Errorf("ListInvites после принятия: %v, %v; ожидалось пусто", invites, err)
+		}
+
+		// Приглашение на email: чужой email не подходит, роль только повышается
+		editor := models.TreeInvite{TreeID: treeID, Email: "guest@example.com", Role: models.RoleEditor, CreatedBy: ownerID}
+		if err := s.CreateInvite(ctx, &editor, "hash-3", time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AcceptInvite(ctx, "hash-3", ownerID, "owner@example.com"); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("приглашение на другой email: %v, ожидалось ErrInviteInvalid", err)
+		}
+		if member, err = s.AcceptInvite(ctx, "hash-3", guestID, "GUEST@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if role, _ := s.GetMemberRole(ctx, treeID, guestID); member.Role != models.RoleEditor || role != models.RoleEditor {
+			t.Errorf("роль после приглашения на email %q (участник %q), ожидалось editor", role, member.Role)
+		}
+
+		viewer := models.TreeInvite{TreeID: treeID, Role: models.RoleViewer, CreatedBy: ownerID}
+		if err := s.CreateInvite(ctx, &viewer, "hash-4", time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if member, err = s.AcceptInvite(ctx, "hash-4", guestID, "guest@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if member.Role != models.RoleEditor {
+			t.Errorf("приглашение зрителя понизило роль до %q", member.Role)
+		}
+
+		expired := models.TreeInvite{TreeID: treeID, Role: models.RoleViewer, CreatedBy: ownerID}
+		if err := s.CreateInvite(ctx, &expired, "hash-5", time.Now().Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AcceptInvite(ctx, "hash-5", guestID, "guest@example.com"); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("просроченное приглашение: %v, ожидалось ErrInviteInvalid", err)
+		}
+	})
+}
+
+func addPlace(t *testing.T, s store.Store, treeID int, p models.Place) int {
+	t.Helper()
+	if err := s.CreatePlace(context.Background(), treeID, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p.ID
+}
+
+// placeEvents - ID событий людей, которые ссылаются на место placeID
+func placeEvents(t *testing.T, s store.Store, treeID, placeID int, people ...int) []int {
+	t.Helper()
+	ids := []int{}
+	for _, personID := range people {
+		events, err := s.ListEvents(context.Background(), treeID, store.EventOwner{PersonID: personID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			if e.PlaceID != nil && *e.PlaceID == placeID {
+				ids = append(ids, e.ID)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestMergePlaces(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		_, otherTreeID := newTree(t, s, "other@example.com")
+		lat, lon, geonamesID := 56.86, 35.9, 480060
+		region := addPlace(t, s, treeID, models.Place{Name: "Тверская губерния", Type: models.PlaceGuberniya})
+		keep := addPlace(t, s, treeID, models.Place{Name: "Тверь", Names: []models.PlaceName{{Name: "Tver", Lang: "en"}}})
+		other := addPlace(t, s, treeID, models.Place{Name: "Калинин", ParentID: &region, Type: models.PlaceCity,
+			Latitude: &lat, Longitude: &lon, GeoNamesID: &geonamesID, Names: []models.PlaceName{{Name: "Tver", Lang: "en"}}})
+		suburb := addPlace(t, s, treeID, models.Place{Name: "Заволжье", ParentID: &other})
+		foreign := addPlace(t, s, otherTreeID, models.Place{Name: "Тверь"})
+
+		born := models.Person{FirstName: "Иван", LastName: "Петров", BirthDate: "1890", BirthPlaceID: &other}
+		died := models.Person{FirstName: "Мария", LastName: "Петрова", BirthPlaceID: &keep, DeathPlaceID: &other}
+		for _, p := range []*models.Person{&born, &died} {
+			if err := s.CreatePerson(ctx, treeID, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		residence := models.Event{Type: "residence", PlaceID: &other}
+		if err := s.CreateEvent(ctx, treeID, store.EventOwner{PersonID: died.ID}, &residence); err != nil {
+			t.Fatal(err)
+		}
+		events := placeEvents(t, s, treeID, other, born.ID, died.ID)
+		if !slices.Contains(events, residence.ID) {
+			t.Fatalf("события места до слияния %v, ожидалось и %d", events, residence.ID)
+		}
+		keepEvents := append(placeEvents(t, s, treeID, keep, born.ID, died.ID), events...)
+		sort.Ints(keepEvents)
+
+		for _, tt := range []struct {
+			name        string
+			keep, other int
+			want        error
+		}{
+			{"само с собой", keep, keep, store.ErrPlaceCycle},
+			{"в место, куда оно входит", suburb, other, store.ErrPlaceCycle},
+			{"с местом другого дерева", keep, foreign, store.ErrNotFound},
+		} {
+			if _, err := s.MergePlaces(ctx, treeID, tt.keep, tt.other); !errors.Is(err, tt.want) {
+				t.Errorf("слияние %s: %v, ожидалось %v", tt.name, err, tt.want)
+			}
+		}
+
+		result, err := s.MergePlaces(ctx, treeID, keep, other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.MergedID != other || result.People != 2 || result.Events != len(events) || result.Children != 1 {
+			t.Errorf("MergePlaces = %+v, ожидалось людей 2, событий %d, вложенных 1", result, len(events))
+		}
+		merged := result.Place
+		if merged.Name != "Тверь" || merged.Type != models.PlaceCity || merged.ParentID == nil || *merged.ParentID != region {
+			t.Errorf("место после слияния %+v, ожидалась Тверь (город) в губернии %d", merged, region)
+		}
+		if merged.Latitude == nil || *merged.Latitude != lat || merged.GeoNamesID == nil || *merged.GeoNamesID != geonamesID {
+			t.Errorf("координаты после слияния не взяты у второго места: %+v", merged)
+		}
+		wantNames := []models.PlaceName{{Name: "Tver", Lang: "en"}, {Name: "Калинин"}}
+		if !slices.Equal(merged.Names, wantNames) {
+			t.Errorf("названия после слияния %v, ожидалось %v", merged.Names, wantNames)
+		}
+
+		if _, err := s.GetPlace(ctx, treeID, other); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("второе место после слияния: %v, ожидалось ErrNotFound", err)
+		}
+		if p, err := s.GetPlace(ctx, treeID, suburb); err != nil || p.ParentID == nil || *p.ParentID != keep {
+			t.Errorf("вложенное место после слияния %+v, %v; ожидалось внутри %d", p, err, keep)
+		}
+		if p := getPerson(t, s, treeID, born.ID); p.BirthPlaceID == nil || *p.BirthPlaceID != keep {
+			t.Errorf("место рождения после слияния %v, ожидалось %d", p.BirthPlaceID, keep)
+		}
+		if p := getPerson(t, s, treeID, died.ID); p.DeathPlaceID == nil || *p.DeathPlaceID != keep {
+			t.Errorf("место смерти после слияния %v, ожидалось %d", p.DeathPlaceID, keep)
+		}
+		if got := placeEvents(t, s, treeID, keep, born.ID, died.ID); !slices.Equal(got, keepEvents) {
+			t.Errorf("события оставшегося места %v, ожидалось %v", got, keepEvents)
+		}
+		if got := placeEvents(t, s, treeID, other, born.ID, died.ID); len(got) != 0 {
+			t.Errorf("события удалённого места %v, ожидалось пусто", got)
+		}
+		// Замена места у людей попадает в журнал
+		var after models.Person
+		if err := json.Unmarshal(lastChange(t, s, treeID, models.EntityPerson, born.ID, models.ActionUpdate).After, &after); err != nil {
+			t.Fatal(err)
+		}
+		if after.BirthPlaceID == nil || *after.BirthPlaceID != keep {
+			t.Errorf("место рождения в журнале %v, ожидалось %d", after.BirthPlaceID, keep)
+		}
+	})
+}
+
+func TestSearchGazetteer(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		entries := []models.GazetteerEntry{
+			{GeoNamesID: 480060, Name: "Tver", ASCIIName: "Tver", AlternateNames: []string{"Тверь", "Калинин"}, CountryCode: "RU", FeatureClass: "P", FeatureCode: "PPLA", Population: 400000},
+			{GeoNamesID: 480061, Name: "Tverskaya", ASCIIName: "Tverskaya", AlternateNames: []string{"Тверская"}, CountryCode: "RU", FeatureClass: "P", FeatureCode: "PPL", Population: 500},
+			{GeoNamesID: 700000, Name: "Tvertsy", ASCIIName: "Tvertsy", AlternateNames: []string{"Тверцы"}, CountryCode: "UA", FeatureClass: "P", FeatureCode: "PPL", Population: 2000},
+			{GeoNamesID: 524901, Name: "Moscow", ASCIIName: "Moskva", AlternateNames: []string{"Москва"}, CountryCode: "RU", FeatureClass: "P", FeatureCode: "PPLC", Population: 12000000},
+		}
+		if err := s.ImportGazetteer(ctx, entries); err != nil {
+			t.Fatal(err)
+		}
+
+		search := func(query, country string, limit int) []int {
+			t.Helper()
+			found, err := s.SearchGazetteer(ctx, query, country, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []int{}
+			for _, e := range found {
+				ids = append(ids, e.GeoNamesID)
+			}
+			return ids
+		}
+		tests := []struct {
+			query, country string
+			limit          int
+			want           []int
+		}{
+			{"Тверцы", "", 0, []int{700000}},
+			{"Тверь", "", 0, []int{480060, 700000, 480061}}, // по началу слова в любой записи
+			{"твер", "", 0, []int{480060, 700000, 480061}},  // крупные первыми
+			{"Tver", "RU", 0, []int{480060, 480061}},
+			{"твер", "ru", 1, []int{480060}},
+			{"Калинин", "", 0, []int{480060}},
+			{"Moskva", "", 0, []int{524901}},
+			{"Ленинград", "", 0, []int{}},
+			{"  ", "", 0, []int{}},
+		}
+		for _, tt := range tests {
+			if got := search(tt.query, tt.country, tt.limit); !equalIDs(got, tt.want) {
+				t.Errorf("SearchGazetteer(%q, %q, %d) = %v, ожидалось %v", tt.query, tt.country, tt.limit, got, tt.want)
+			}
+		}
+
+		// Повторный импорт заменяет запись, а не добавляет вторую
+		renamed := entries[0]
+		renamed.AlternateNames = []string{"Тверь"}
+		if err := s.ImportGazetteer(ctx, []models.GazetteerEntry{renamed}); err != nil {
+			t.Fatal(err)
+		}
+		if got := search("Калинин", "", 0); len(got) != 0 {
+			t.Errorf("старое название после повторного импорта: %v", got)
+		}
+		if e, err := s.GetGazetteerEntry(ctx, 480060); err != nil || !slices.Equal(e.AlternateNames, renamed.AlternateNames) {
+			t.Errorf("запись после повторного импорта %+v, %v; ожидались названия %v", e, err, renamed.AlternateNames)
+		}
+		if _, err := s.GetGazetteerEntry(ctx, 1); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("неизвестная запись: %v, ожидалось ErrNotFound", err)
+		}
+	})
+}
+
+func citationIDs(t *testing.T, s store.Store, treeID int, owner store.CitationOwner) []int {
+	t.Helper()
+	citations, err := s.ListCitations(context.Background(), treeID, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, c := range citations {
+		ids = append(ids, c.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestSourcesAndCitations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		_, treeID := newTree(t, s, "owner@example.com")
+		_, otherTreeID := newTree(t, s, "other@example.com")
+		addSource := func(treeID int, title string) int {
+			t.Helper()
+			src := models.Source{Type: models.SourceArchive, Title: title, Archive: "ГАТО", Fond: "160"}
+			if err := s.CreateSource(ctx, treeID, &src); err != nil {
+				t.Fatal(err)
+			}
+			return src.ID
+		}
+		register := addSource(treeID, "Метрическая книга с. Горки, 1880")
+		census := addSource(treeID, "Исповедная ведомость с. Горки, 1885")
+		foreign := addSource(otherTreeID, "Чужой источник")
+
+		sources, err := s.ListSources(ctx, treeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sources) != 2 || sources[0].ID != census || sources[1].ID != register {
+			t.Errorf("источники %+v, ожидались %d и %d по названию", sources, census, register)
+		}
+
+		father := addPerson(t, s, treeID, "Иван", "Петров")
+		mother := addPerson(t, s, treeID, "Мария", "Петрова")
+		son := addPerson(t, s, treeID, "Пётр", "Петров")
+		marriage := addRelationship(t, s, treeID, father, mother, "spouse")
+		baptism := models.Event{Type: models.EventBaptism, Date: "1881"}
+		if err := s.CreateEvent(ctx, treeID, store.EventOwner{PersonID: son}, &baptism); err != nil {
+			t.Fatal(err)
+		}
+		wedding := models.Event{Type: models.EventMarriage, Date: "1879"}
+		if err := s.CreateEvent(ctx, treeID, store.EventOwner{RelationshipID: marriage}, &wedding); err != nil {
+			t.Fatal(err)
+		}
+
+		cite := func(owner store.CitationOwner, sourceID int) int {
+			t.Helper()
+			c := models.Citation{SourceID: sourceID, Page: "л. 45 об.", Confidence: models.ConfidencePrimary}
+			if err := s.CreateCitation(ctx, treeID, owner, &c); err != nil {
+				t.Fatal(err)
+			}
+			return c.ID
+		}
+		ofFather := cite(store.CitationOwner{PersonID: father}, register)
+		ofBaptism := cite(store.CitationOwner{EventID: baptism.ID}, register)
+		ofMarriage := cite(store.CitationOwner{RelationshipID: marriage}, census)
+		ofWedding := cite(store.CitationOwner{EventID: wedding.ID}, census)
+
+		for _, tt := range []struct {
+			name  string
+			owner store.CitationOwner
+			want  []int
+		}{
+			{"человека - с его связями и их событиями", store.CitationOwner{PersonID: father}, []int{ofFather, ofMarriage, ofWedding}},
+			{"человека - с его событиями", store.CitationOwner{PersonID: son}, []int{ofBaptism}},
+			{"связи - с её событиями", store.CitationOwner{RelationshipID: marriage}, []int{ofMarriage, ofWedding}},
+			{"события", store.CitationOwner{EventID: wedding.ID}, []int{ofWedding}},
+		} {
+			if got := citationIDs(t, s, treeID, tt.owner); !equalIDs(got, tt.want) {
+				t.Errorf("ссылки %s: %v, ожидалось %v", tt.name, got, tt.want)
+			}
+		}
+
+		for _, tt := range []struct {
+			name     string
+			owner    store.CitationOwner
+			sourceID int
+			want     error
+		}{
+			{"на источник другого дерева", store.CitationOwner{PersonID: father}, foreign, store.ErrInvalidSource},
+			{"у несуществующего человека", store.CitationOwner{PersonID: 1 << 20}, register, store.ErrNotFound},
+			{"у несуществующего события", store.CitationOwner{EventID: 1 << 20}, register, store.ErrNotFound},
+		} {
+			c := models.Citation{SourceID: tt.sourceID}
+			if err := s.CreateCitation(ctx, treeID, tt.owner, &c); !errors.Is(err, tt.want) {
+				t.Errorf("ссылка %s: %v, ожидалось %v", tt.name, err, tt.want)
+			}
+		}
+
+		// Ссылку можно перевести на другой источник, владелец остаётся прежним
+		if err := s.UpdateCitation(ctx, treeID, models.Citation{ID: ofBaptism, SourceID: census, Page: "л. 3"}); err != nil {
+			t.Fatal(err)
+		}
+		citations, err := s.ListSourceCitations(ctx, treeID, census)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var moved *models.Citation
+		for i := range citations {
+			if citations[i].ID == ofBaptism {
+				moved = &citations[i]
+			}
+		}
+		if moved == nil || moved.Page != "л. 3" || moved.EventID == nil || *moved.EventID != baptism.ID {
+			t.Errorf("ссылка после правки %+v, ожидалась у события %d на л. 3", moved, baptism.ID)
+		}
+		if _, err := s.ListSourceCitations(ctx, treeID, foreign); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("ссылки на источник другого дерева: %v, ожидалось ErrNotFound", err)
+		}
+
+		if err := s.DeleteSource(ctx, treeID, register); !errors.Is(err, store.ErrSourceInUse) {
+			t.Errorf("удаление источника со ссылками: %v, ожидалось ErrSourceInUse", err)
+		}
+
+		// Человек в корзине: ссылки не видны, но возвращаются вместе с ним
+		if _, err := s.DeletePerson(ctx, treeID, father); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ListCitations(ctx, treeID, store.CitationOwner{PersonID: father}); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("ссылки человека из корзины: %v, ожидалось ErrNotFound", err)
+		}
+		if _, err := s.RestoreFromTrash(ctx, treeID, models.EntityPerson, father); err != nil {
+			t.Fatal(err)
+		}
+		if got := citationIDs(t, s, treeID, store.CitationOwner{PersonID: father}); !equalIDs(got, []int{ofFather, ofMarriage, ofWedding}) {
+			t.Errorf("ссылки после восстановления %v, ожидалось [%d %d %d]", got, ofFather, ofMarriage, ofWedding)
+		}
+
+		// Стёртый из корзины человек уносит ссылки на себя, свои связи и их события
+		if _, err := s.DeletePerson(ctx, treeID, father); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.EmptyTrash(ctx, treeID); err != nil {
+			t.Fatal(err)
+		}
+		all, err := s.ListTreeCitations(ctx, treeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 || all[0].ID != ofBaptism {
+			t.Errorf("ссылки дерева после стирания %+v, ожидалась только %d", all, ofBaptism)
+		}
+
+		if err := s.DeleteSource(ctx, treeID, register); err != nil {
+			t.Errorf("удаление источника без ссылок: %v", err)
+		}
+		if err := s.DeleteCitation(ctx, treeID, ofBaptism); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.DeleteSource(ctx, treeID, census); err != nil {
+			t.Errorf("удаление источника после удаления ссылки: %v", err)
+		}
+		if _, err := s.GetSource(ctx, treeID, census); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("удалённый источник: %v, ожидалось ErrNotFound", err)
+		}
+	})
+}
+
+func TestPersonPhotoMedia(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s store.Store) {
+		ctx := context.Background()
+		ownerID, treeID := newTree(t, s, "owner@example.com")
+		_, otherTreeID := newTree(t, s, "other@example.com")
+		portrait := addMedia(t, s, treeID, "portrait")
+		foreign := addMedia(t, s, otherTreeID, "foreign")
+
+		bad := models.Person{FirstName: "Иван", LastName: "Петров", PhotoMediaID: &foreign}
+		if err := s.CreatePerson(ctx, treeID, &bad); !errors.Is(err, store.ErrInvalidMedia) {
+			t.Errorf("портрет из другого дерева: %v, ожидалось ErrInvalidMedia", err)
+		}
+		person := models.Person{FirstName: "Иван", LastName: "Петров", PhotoMediaID: &portrait}
+		if err := s.CreatePerson(ctx, treeID, &person); err != nil {
+			t.Fatal(err)
+		}
+		if p := getPerson(t, s, treeID, person.ID); p.PhotoMediaID == nil || *p.PhotoMediaID != portrait {
+			t.Errorf("портрет после создания %v, ожидалось %d", p.PhotoMediaID, portrait)
+		}
+		person.PhotoMediaID = &foreign
+		if err := s.UpdatePerson(ctx, treeID, person); !errors.Is(err, store.ErrInvalidMedia) {
+			t.Errorf("смена портрета на файл другого дерева: %v, ожидалось ErrInvalidMedia", err)
+		}
+		withPhoto := true
+		if got := queryAllPages(t, s, treeID, store.PeopleQuery{HasPhoto: &withPhoto}); !equalIDs(got, []int{person.ID}) {
+			t.Errorf("люди с фото %v, ожидалось [%d]", got, person.ID)
+		}
+
+		// В копии дерева портрет - копия файла в новом дереве
+		copied, err := s.DuplicateTree(ctx, treeID, ownerID, "Копия")
+		if err != nil {
+			t.Fatal(err)
+		}
+		people, err := s.ListPeople(ctx, copied.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(people) != 1 || people[0].PhotoMediaID == nil || *people[0].PhotoMediaID == portrait {
+			t.Fatalf("люди копии %+v, ожидался человек с новым файлом портрета", people)
+		}
+		if m, err := s.GetMedia(ctx, copied.ID, *people[0].PhotoMediaID); err != nil || m.Hash != "portrait" {
+			t.Errorf("портрет в копии %+v, %v; ожидался файл с тем же содержимым", m, err)
+		}
+
+		// Удалённый файл перестаёт быть портретом, копия дерева его не теряет
+		if _, err := s.DeleteMedia(ctx, treeID, portrait); err != nil {
+			t.Fatal(err)
+		}
+		if p := getPerson(t, s, treeID, person.ID); p.PhotoMediaID != nil {
+			t.Errorf("портрет после удаления файла %d, ожидалось пусто", *p.PhotoMediaID)
+		}
+		if inUse, err := s.MediaInUse(ctx, "portrait"); err != nil || !inUse {
+			t.Errorf("MediaInUse после удаления в одном дереве = %v, %v; ожидалось true", inUse, err)
+		}
+		if err := s.DeleteTree(ctx, copied.ID); err != nil {
+			t.Fatal(err)
+		}
+		if inUse, err := s.MediaInUse(ctx, "portrait"); err != nil || inUse {
+			t.Errorf("MediaInUse после удаления копии = %v, %v; ожидалось false", inUse, err)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+
+	"family-tree-app/internal/models"
+)
+
+// revertLastDelete отменяет последнее удаление записи по журналу: так
+// восстановление из корзины и отмена удаления в истории - одно и то же действие
+func revertLastDelete(ctx context.Context, h HistoryStore, treeID int, entityType string, id int) ([]models.Change, error) {
+	deletions, err := h.ListChanges(ctx, treeID, ChangeFilter{
+		EntityType: entityType,
+		EntityID:   id,
+		Action:     models.ActionDelete,
+		Limit:      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(deletions) == 0 {
+		return nil, ErrNotFound
+	}
+	return h.RevertChange(ctx, treeID, deletions[0].ID)
+}
@@ -1,27 +1,120 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"family-tree-app/internal/auth"
+	"family-tree-app/internal/config"
 	"family-tree-app/internal/database"
+	"family-tree-app/internal/media"
 	"family-tree-app/internal/routes" // Импортируем наш новый пакет
+	"family-tree-app/internal/store"
 )
 
 func main() {
+	// Подкоманды: family-tree-app migrate up|down|status, family-tree-app import <email> <файл.ged>,
+	// family-tree-app geonames <выгрузка geonames.org>
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "geonames":
+			runGeonames(os.Args[2:])
+			return
+		}
+	}
+
+	// 0. Загружаем и проверяем конфигурацию (файл, окружение, флаги)
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal("Ошибка конфигурации: ", err)
+	}
+	if err := auth.Configure(cfg.JWTSecret, cfg.BcryptCost); err != nil {
+		log.Fatal("Ошибка конфигурации: ", err)
+	}
+	if !cfg.IsProduction() && cfg.JWTSecret == config.DefaultJWTSecret {
+		log.Println("ВНИМАНИЕ: используется JWT-ключ по умолчанию, задайте JWT_SECRET")
+	}
+
 	// 1. Инициализация БД
-	database.InitDB()
+	database.InitDB(cfg.DBPath, cfg.AutoMigrate)
 	defer database.DB.Close()
 
+	st := store.NewSQLite(database.DB)
+	if n, err := st.IndexMissingPeople(context.Background()); err != nil {
+		log.Fatal("Ошибка построения поискового индекса: ", err)
+	} else if n > 0 {
+		log.Printf("Поисковый индекс: добавлено людей %d", n)
+	}
+	// ctx отменяется по Ctrl+C или SIGTERM: сервер и фоновые задачи
+	// останавливаются до закрытия базы
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	purgeDone := make(chan struct{})
+	go func() {
+		defer close(purgeDone)
+		if cfg.TrashRetentionDays > 0 {
+			runTrashPurge(ctx, st, time.Duration(cfg.TrashRetentionDays)*24*time.Hour)
+		}
+	}()
+
+	files, err := newMediaStorage(cfg)
+	if err != nil {
+		log.Fatal("Ошибка хранилища файлов: ", err)
+	}
+
 	// 2. Получаем настроенный роутер из пакета routes
-	r := routes.NewRouter()
+	r := routes.NewRouter(cfg, st, files)
 
 	// 3. Запуск сервера
-	port := "8080"
-	log.Printf("Сервер запущен: http://localhost:%s", port)
-	
-	err := http.ListenAndServe(":"+port, r)
-	if err != nil {
+	log.Printf("Сервер запущен: http://localhost:%s (режим: %s)", cfg.Port, cfg.Env)
+
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Println("Ошибка остановки сервера:", err)
+		}
+	}()
+
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+	// Shutdown дожидается начатых запросов уже после выхода из ListenAndServe
+	<-stopped
+	<-purgeDone
+	log.Println("Сервер остановлен")
+}
+
+// shutdownTimeout - сколько ждать завершения начатых запросов при остановке
+const shutdownTimeout = 10 * time.Second
+
+// newMediaStorage подключает хранилище загруженных файлов по настройкам
+func newMediaStorage(cfg *config.Config) (media.Storage, error) {
+	if cfg.MediaStorage == config.MediaS3 {
+		return media.NewS3(media.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+		})
+	}
+	return media.NewLocal(cfg.MediaDir)
+}
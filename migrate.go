@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"family-tree-app/internal/config"
+	"family-tree-app/internal/database"
+)
+
+const migrateUsage = `Использование: family-tree-app migrate <up|down [N]|status> [флаги конфигурации]`
+
+// runMigrate выполняет подкоманду migrate: up, down [N] (по умолчанию 1) или status
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+	action, rest := args[0], args[1:]
+
+	steps := 1
+	if action == "down" && len(rest) > 0 {
+		if n, err := strconv.Atoi(rest[0]); err == nil {
+			if n < 1 {
+				log.Fatal("Число откатываемых миграций должно быть положительным")
+			}
+			steps, rest = n, rest[1:]
+		}
+	}
+
+	cfg, err := config.Load(rest)
+	if err != nil {
+		log.Fatal("Ошибка конфигурации: ", err)
+	}
+
+	db, err := database.Open(cfg.DBPath)
+	if err != nil {
+		log.Fatal("Ошибка соединения с БД: ", err)
+	}
+	defer db.Close()
+
+	switch action {
+	case "up":
+		applied, err := database.MigrateUp(db)
+		for _, m := range applied {
+			fmt.Printf("применена %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("схема актуальна")
+		}
+	case "down":
+		reverted, err := database.MigrateDown(db, steps)
+		for _, m := range reverted {
+			fmt.Printf("откачена %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("нечего откатывать")
+		}
+	case "status":
+		states, err := database.Status(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		latest, err := database.LatestVersion()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, st := range states {
+			switch {
+			case st.Applied && st.Version > latest:
+				fmt.Printf("%04d  %-30s неизвестна бинарнику (применена %s)\n", st.Version, st.Name, st.AppliedAt)
+			case st.Applied:
+				fmt.Printf("%04d  %-30s применена %s\n", st.Version, st.Name, st.AppliedAt)
+			default:
+				fmt.Printf("%04d  %-30s ожидает\n", st.Version, st.Name)
+			}
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
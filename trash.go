@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"family-tree-app/internal/store"
+)
+
+// trashPurgeInterval - как часто проверять корзину на просроченные записи
+const trashPurgeInterval = time.Hour
+
+// runTrashPurge раз в trashPurgeInterval окончательно стирает из корзины
+// то, что лежит там дольше retention. Первый проход - сразу при запуске.
+// Возвращается, когда ctx отменён; прерванный проход откатывается целиком.
+func runTrashPurge(ctx context.Context, ts store.TrashStore, retention time.Duration) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		result, err := ts.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("Ошибка очистки корзины:", err)
+		} else if result.People+result.Relationships > 0 {
+			log.Printf("Корзина: стёрто людей %d, связей %d", result.People, result.Relationships)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
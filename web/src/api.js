@@ -44,6 +44,14 @@ export const fetchPeople = async () => {
   return response.data;
 };
 
+// Страница списка людей. params: { limit, cursor, sort, surname, gender, born_from, born_to, living, has_photo, fields }
+// nextCursor — курсор следующей страницы из заголовка Link, null на последней
+export const fetchPeoplePage = async (params = {}) => {
+  const response = await api.get('/people', { params });
+  const next = /[?&]cursor=([^&>]+)[^>]*>;\s*rel="next"/.exec(response.headers.link || '');
+  return { people: response.data, nextCursor: next ? decodeURIComponent(next[1]) : null };
+};
+
 export const createPerson = async (person) => {
   const response = await api.post('/people', person);
   return response.data;
@@ -56,9 +64,44 @@ export const updatePerson = async (id, person) => {
 
 export const deletePerson = async (id) => {
   const response = await api.delete(`/people/${id}`);
+  return response.data; // { status, removed: { people, relationships } } — что ушло в корзину
+};
+
+// Поиск по именам и заметкам на сервере, лучшие совпадения первыми
+export const searchPeople = async (q, limit) => {
+  const response = await api.get('/people/search', { params: { q, limit } });
+  return response.data;
+};
+
+// Возможные дубли: [{ people: [a, b], score, reasons }], самые вероятные первыми
+export const findDuplicates = async (minScore) => {
+  const response = await api.get('/people/duplicates', { params: { min_score: minScore } });
+  return response.data;
+};
+
+// prefer — откуда брать поле: { birth_date: 'merge' }; по умолчанию непустое значение keepId
+export const mergePeople = async (keepId, mergeId, prefer = {}) => {
+  const response = await api.post('/people/merge', { keep_id: keepId, merge_id: mergeId, prefer });
+  return response.data; // { person, merged_id, repointed, removed }
+};
+
+// Кем человек b приходится человеку a: { kind, en, ru, path, common_ancestors, ... }
+export const fetchKinship = async (a, b) => {
+  const response = await api.get(`/people/${a}/kinship/${b}`);
   return response.data;
 };
 
+// Предки с номерами Ahnentafel и потомки с номерами д'Абовиля
+export const fetchAncestors = async (id, depth = 10) => {
+  const response = await api.get(`/people/${id}/ancestors`, { params: { depth } });
+  return response.data; // { root, depth, people: [{ person, generation, ahnentafel }], relationships }
+};
+
+export const fetchDescendants = async (id, depth = 10) => {
+  const response = await api.get(`/people/${id}/descendants`, { params: { depth } });
+  return response.data; // { root, depth, people: [{ person, generation, daboville }], relationships }
+};
+
 // Связи
 export const fetchRelationships = async () => {
   const response = await api.get('/relationships');
@@ -77,9 +120,343 @@ export const updateRelationship = async (id, description) => {
 
 export const deleteRelationship = async (id) => {
   const response = await api.delete(`/relationships/${id}`);
+  return response.data; // { status, removed: { people: [], relationships: [связь] } }
+};
+
+// Деревья пользователя. Пути без /trees/{id} работают с деревом по умолчанию.
+export const fetchTrees = async () => {
+  const response = await api.get('/trees');
+  return response.data;
+};
+
+export const createTree = async (name) => {
+  const response = await api.post('/trees', { name });
+  return response.data;
+};
+
+export const renameTree = async (id, name) => {
+  const response = await api.put(`/trees/${id}`, { name });
+  return response.data;
+};
+
+export const deleteTree = async (id) => {
+  const response = await api.delete(`/trees/${id}`);
+  return response.data;
+};
+
+export const duplicateTree = async (id, name) => {
+  const response = await api.post(`/trees/${id}/duplicate`, name ? { name } : undefined);
+  return response.data;
+};
+
+// Совместный доступ: участники и приглашения
+export const fetchTreeMembers = async (treeId) => {
+  const response = await api.get(`/trees/${treeId}/members`);
+  return response.data;
+};
+
+export const updateTreeMember = async (treeId, userId, role) => {
+  const response = await api.put(`/trees/${treeId}/members/${userId}`, { role });
+  return response.data;
+};
+
+export const removeTreeMember = async (treeId, userId) => {
+  const response = await api.delete(`/trees/${treeId}/members/${userId}`);
+  return response.data;
+};
+
+// email пустой — приглашение по ссылке, в ответе будет token
+export const createInvite = async (treeId, { email = '', role = 'viewer', expiresInDays = 0 } = {}) => {
+  const response = await api.post(`/trees/${treeId}/invites`, { email, role, expires_in_days: expiresInDays });
+  return response.data;
+};
+
+export const fetchTreeInvites = async (treeId) => {
+  const response = await api.get(`/trees/${treeId}/invites`);
+  return response.data;
+};
+
+export const revokeInvite = async (treeId, inviteId) => {
+  const response = await api.delete(`/trees/${treeId}/invites/${inviteId}`);
+  return response.data;
+};
+
+export const acceptInvite = async (token) => {
+  const response = await api.post('/invites/accept', { token });
+  return response.data;
+};
+
+// Публичные ссылки только для чтения (живые люди скрыты)
+export const fetchShareLinks = async (treeId) => {
+  const response = await api.get(`/trees/${treeId}/share-links`);
+  return response.data;
+};
+
+export const createShareLink = async (treeId, { label = '', expiresInDays = 0 } = {}) => {
+  const response = await api.post(`/trees/${treeId}/share-links`, { label, expires_in_days: expiresInDays });
+  return response.data; // { id, token, expires_at, ... }
+};
+
+export const revokeShareLink = async (treeId, linkId) => {
+  const response = await api.delete(`/trees/${treeId}/share-links/${linkId}`);
+  return response.data;
+};
+
+export const fetchPublicTree = async (token) => {
+  const response = await api.get(`/public/${encodeURIComponent(token)}/tree`);
+  return response.data; // { name, people, relationships }
+};
+
+// Журнал изменений и отмена (дерево по умолчанию)
+export const fetchPersonHistory = async (personId, { limit, before } = {}) => {
+  const response = await api.get(`/people/${personId}/history`, { params: { limit, before } });
+  return response.data; // [{ id, user_email, entity_type, entity_id, action, before, after, created_at }]
+};
+
+export const fetchTreeHistory = async ({ limit, before } = {}) => {
+  const response = await api.get('/history', { params: { limit, before } });
+  return response.data;
+};
+
+export const revertChange = async (changeId) => {
+  const response = await api.post(`/history/${changeId}/revert`);
+  return response.data; // новые записи журнала, 409 — данные с тех пор изменились
+};
+
+export const restorePerson = async (personId) => {
+  const response = await api.post(`/people/${personId}/restore`);
+  return response.data;
+};
+
+// Корзина: entity — 'people' или 'relationships'
+export const fetchTrash = async () => {
+  const response = await api.get('/trash');
+  return response.data; // { people: [...], relationships: [...] } с deleted_at
+};
+
+export const restoreFromTrash = async (entity, id) => {
+  const response = await api.post(`/trash/${entity}/${id}/restore`);
+  return response.data;
+};
+
+export const purgeFromTrash = async (entity, id) => {
+  const response = await api.delete(`/trash/${entity}/${id}`);
+  return response.data; // { people, relationships } — сколько стёрто
+};
+
+export const emptyTrash = async () => {
+  const response = await api.delete('/trash');
+  return response.data;
+};
+
+// События: owner — 'people' или 'relationships'. Тип birth или death меняет и дату у человека,
+// события пары (engagement, marriage, divorce) бывают только у связи супругов
+export const fetchEvents = async (owner, id) => {
+  const response = await api.get(`/${owner}/${id}/events`);
+  return response.data; // [{ id, type, date, place, description }] по дате
+};
+
+export const createEvent = async (owner, id, event) => {
+  const response = await api.post(`/${owner}/${id}/events`, event);
   return response.data;
 };
 
+export const updateEvent = async (owner, id, eventId, event) => {
+  const response = await api.put(`/${owner}/${id}/events/${eventId}`, event);
+  return response.data;
+};
+
+export const deleteEvent = async (owner, id, eventId) => {
+  const response = await api.delete(`/${owner}/${id}/events/${eventId}`);
+  return response.data;
+};
+
+// Места: иерархия «деревня → уезд → губерния», у места бывают другие названия
+// (старые, на других языках). birth_place_id, death_place_id и place_id событий ссылаются сюда.
+export const fetchPlaces = async (q = '') => {
+  const response = await api.get('/places', { params: q ? { q } : {} });
+  return response.data; // [{ id, parent_id, name, type, latitude, longitude, geonames_id, names, full_name }]
+};
+
+export const fetchPlace = async (id) => {
+  const response = await api.get(`/places/${id}`);
+  return response.data;
+};
+
+// Если такое место уже есть, сервер вернёт его (200), а не создаст второе (201)
+export const createPlace = async (place) => {
+  const response = await api.post('/places', place);
+  return response.data;
+};
+
+export const updatePlace = async (id, place) => {
+  const response = await api.put(`/places/${id}`, place);
+  return response.data;
+};
+
+export const deletePlace = async (id) => {
+  const response = await api.delete(`/places/${id}`);
+  return response.data;
+};
+
+export const fetchPlaceDuplicates = async () => {
+  const response = await api.get('/places/duplicates');
+  return response.data; // [{ places: [a, b], reasons }]
+};
+
+export const mergePlaces = async (keepId, mergeId) => {
+  const response = await api.post('/places/merge', { keep_id: keepId, merge_id: mergeId });
+  return response.data; // { place, merged_id, people, events, children }
+};
+
+// Поиск по офлайн-справочнику GeoNames; найденное заводится через createPlace({ geonames_id })
+export const geocodePlace = async (q, { country = '', limit = 10 } = {}) => {
+  const response = await api.get('/places/geocode', { params: { q, country, limit } });
+  return response.data; // [{ geonames_id, name, alternate_names, latitude, longitude, country_code, population }]
+};
+
+// Источники: type — archive, book, website или interview; у архивного дела — archive, fond, opis, delo
+export const fetchSources = async () => {
+  const response = await api.get('/sources');
+  return response.data; // [{ id, type, title, author, publication, archive, fond, opis, delo, url, notes }]
+};
+
+export const fetchSource = async (id) => {
+  const response = await api.get(`/sources/${id}`);
+  return response.data;
+};
+
+export const createSource = async (source) => {
+  const response = await api.post('/sources', source);
+  return response.data;
+};
+
+export const updateSource = async (id, source) => {
+  const response = await api.put(`/sources/${id}`, source);
+  return response.data;
+};
+
+// Источник, на который есть ссылки, не удаляется (409)
+export const deleteSource = async (id) => {
+  const response = await api.delete(`/sources/${id}`);
+  return response.data;
+};
+
+export const fetchSourceCitations = async (id) => {
+  const response = await api.get(`/sources/${id}/citations`);
+  return response.data;
+};
+
+// Ссылки на источники: owner — 'people', 'relationships' или 'events'. У человека приходят
+// и ссылки его событий и связей, у связи — её событий.
+// confidence — primary, secondary, questionable или unreliable
+export const fetchCitations = async (owner, id) => {
+  const response = await api.get(`/${owner}/${id}/citations`);
+  return response.data; // [{ id, source_id, person_id, relationship_id, event_id, page, text, confidence, notes }]
+};
+
+export const createCitation = async (owner, id, citation) => {
+  const response = await api.post(`/${owner}/${id}/citations`, citation);
+  return response.data;
+};
+
+export const updateCitation = async (id, citation) => {
+  const response = await api.put(`/citations/${id}`, citation);
+  return response.data;
+};
+
+export const deleteCitation = async (id) => {
+  const response = await api.delete(`/citations/${id}`);
+  return response.data;
+};
+
+// Файлы: фотографии, сканы документов, записи рассказов. Такой же файл
+// повторно не заводится — сервер вернёт уже загруженный (200 вместо 201).
+// stripGps — стереть из фотографии координаты съёмки до сохранения.
+export const uploadMedia = async (file, { title = '', description = '', stripGps = false } = {}) => {
+  const form = new FormData();
+  form.append('file', file);
+  form.append('title', title);
+  form.append('description', description);
+  if (stripGps) form.append('strip_gps', 'true');
+  const response = await api.post('/media', form);
+  // { id, hash, content_type, size, file_name, title, description, width, height,
+  //   taken_at (дата съёмки из EXIF — подсказка для даты события), has_gps,
+  //   person_ids, relationship_ids, event_ids }
+  return response.data;
+};
+
+export const fetchMedia = async () => {
+  const response = await api.get('/media');
+  return response.data;
+};
+
+export const updateMedia = async (id, { title, description }) => {
+  const response = await api.put(`/media/${id}`, { title, description });
+  return response.data;
+};
+
+export const deleteMedia = async (id) => {
+  const response = await api.delete(`/media/${id}`);
+  return response.data;
+};
+
+// Адрес содержимого для <img src> и ссылок: кука уходит вместе с запросом
+export const mediaFileUrl = (id, { download = false } = {}) =>
+  `/api/media/${id}/file${download ? '?download=1' : ''}`;
+
+// Миниатюра фотографии в JPEG: size — 64, 256 или 1024 пикселей по длинной стороне
+export const mediaThumbnailUrl = (id, size = 256) => `/api/media/${id}/thumbnail?size=${size}`;
+
+// Фото человека для показа в мелком размере: если photo_url указывает на
+// загруженный файл (старые записи) или на портрет по публичной ссылке,
+// вместо исходника берётся миниатюра
+export const photoThumbnailUrl = (photoUrl, size = 64) => {
+  const match = /^\/api\/media\/(\d+)\/file$/.exec(photoUrl || '');
+  if (match) return mediaThumbnailUrl(match[1], size);
+  if (/^\/api\/public\/[^/]+\/people\/\d+\/photo$/.test(photoUrl || '')) return `${photoUrl}?size=${size}`;
+  return photoUrl;
+};
+
+// Портрет человека: загруженный файл (photo_media_id) важнее внешней ссылки
+export const personPhotoUrl = (person, size = 64) =>
+  person?.photo_media_id
+    ? mediaThumbnailUrl(person.photo_media_id, size)
+    : photoThumbnailUrl(person?.photo_url, size);
+
+// owner — 'people', 'relationships' или 'events'
+export const fetchOwnerMedia = async (owner, id) => {
+  const response = await api.get(`/${owner}/${id}/media`);
+  return response.data;
+};
+
+export const linkMedia = async (owner, id, mediaId) => {
+  const response = await api.post(`/${owner}/${id}/media`, { media_id: mediaId });
+  return response.data;
+};
+
+export const unlinkMedia = async (owner, id, mediaId) => {
+  const response = await api.delete(`/${owner}/${id}/media/${mediaId}`);
+  return response.data;
+};
+
+// Дерево целиком
+export const lintTree = async () => {
+  const response = await api.get('/tree/lint');
+  return response.data; // { errors, warnings, issues: [{ severity, code, message, person_ids }] }
+};
+
+// Импорт GEDCOM: file — File из <input type="file">
+export const importGedcom = async (file, { dryRun = false } = {}) => {
+  const form = new FormData();
+  form.append('file', file);
+  const response = await api.post('/import/gedcom', form, { params: { dry_run: dryRun } });
+  return response.data; // { charset, people_imported, relationships_imported, skipped, unmapped }
+};
+
+// Экспорт GEDCOM: ссылка для скачивания (кука уходит вместе с переходом по ссылке)
+export const gedcomExportUrl = (version = '5.5.1') => `/api/export/gedcom?version=${version}`;
+
 export const saveNodePosition = async (id, x, y) => {
   return api.put('/people/position', { id: parseInt(id), x, y });
 };
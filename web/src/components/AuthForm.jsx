@@ -42,7 +42,11 @@ export function AuthForm({ onLoginSuccess }) {
         setType('login');
       }
     } catch (err) {
-      const msg = err.response?.data || 'Ошибка соединения';
+      const data = err.response?.data;
+      // Нарушения политики паролей приходят списком: { error, violations: [{ code, message }] }
+      const msg = data?.violations
+        ? data.violations.map((v) => v.message).join('. ')
+        : data || 'Ошибка соединения';
       setError(msg);
     } finally {
       setLoading(false);
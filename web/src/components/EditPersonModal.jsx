@@ -2,6 +2,7 @@ import React, { useEffect, useState, useCallback, useRef } from 'react';
 import {
   Modal,
   TextInput,
+  Textarea,
   Select,
   Button,
   Group,
@@ -12,9 +13,10 @@ import {
   Table,
   Divider,
   Tooltip,
+  FileButton,
 } from '@mantine/core';
 import { DateInput } from '@mantine/dates';
-import { IconTrash, IconInfoCircle, IconCheck, IconX } from '@tabler/icons-react';
+import { IconTrash, IconInfoCircle, IconCheck, IconX, IconUpload } from '@tabler/icons-react';
 import dayjs from 'dayjs';
 import {
   updatePerson,
@@ -23,6 +25,9 @@ import {
   fetchPeople,
   deleteRelationship,
   updateRelationship,
+  uploadMedia,
+  linkMedia,
+  personPhotoUrl,
 } from '../api';
 
 export function EditPersonModal({ opened, onClose, person, onUpdated }) {
@@ -34,6 +39,8 @@ export function EditPersonModal({ opened, onClose, person, onUpdated }) {
     death_date: person?.death_date || '',
     gender: person?.gender || 'male',
     photo_url: person?.photo_url || '',
+    photo_media_id: person?.photo_media_id ?? null,
+    notes: person?.notes || '',
   }));
 
   const [personRelationships, setPersonRelationships] = useState([]);
@@ -136,6 +143,24 @@ export function EditPersonModal({ opened, onClose, person, onUpdated }) {
     }
   };
 
+  // Портрет хранится на сервере: координаты съёмки стираются, файл
+  // привязывается к человеку и становится его портретом (photo_media_id),
+  // а в графе показывается его миниатюра
+  const handleUploadPhoto = async (file) => {
+    if (!file) return;
+    setLoading(true);
+    setError(null);
+    try {
+      const media = await uploadMedia(file, { stripGps: true });
+      await linkMedia('people', person.id, media.id);
+      setFormData((prev) => ({ ...prev, photo_url: '', photo_media_id: media.id }));
+    } catch (err) {
+      setError(err.response?.data || err.message || 'Ошибка при загрузке фото');
+    } finally {
+      setLoading(false);
+    }
+  };
+
   const handleDeleteRel = async (relId) => {
     try {
       await deleteRelationship(relId);
@@ -175,10 +200,10 @@ export function EditPersonModal({ opened, onClose, person, onUpdated }) {
         <Group align="flex-start" grow>
           {/* ЛЕВАЯ КОЛОНКА */}
           <Stack>
-            {formData.photo_url && (
+            {(formData.photo_url || formData.photo_media_id) && (
               <Group justify="center">
                 <Image
-                  src={formData.photo_url}
+                  src={personPhotoUrl(formData, 256)}
                   w={120}
                   h={120}
                   radius="md"
@@ -247,7 +272,30 @@ export function EditPersonModal({ opened, onClose, person, onUpdated }) {
               }
               placeholder="https://..."
               value={formData.photo_url}
-              onChange={(e) => handleChange('photo_url', e.target.value)}
+              onChange={(e) =>
+                setFormData((prev) => ({ ...prev, photo_url: e.target.value, photo_media_id: null }))
+              }
+            />
+            <FileButton onChange={handleUploadPhoto} accept="image/jpeg,image/png,image/gif">
+              {(props) => (
+                <Button
+                  {...props}
+                  variant="light"
+                  leftSection={<IconUpload size={16} />}
+                  loading={loading}
+                >
+                  Загрузить фото
+                </Button>
+              )}
+            </FileButton>
+
+            <Textarea
+              label="Заметки"
+              autosize
+              minRows={2}
+              maxRows={6}
+              value={formData.notes}
+              onChange={(e) => handleChange('notes', e.target.value)}
             />
           </Stack>
 
@@ -15,7 +15,7 @@ import jsPDF from 'jspdf';
 import { Button } from '@mantine/core';
 import { IconDownload, IconX, IconLayoutDashboard } from '@tabler/icons-react';
 import 'reactflow/dist/style.css';
-import { fetchPeople, fetchRelationships, saveNodePosition } from '../api';
+import { fetchPeople, fetchRelationships, saveNodePosition, searchPeople } from '../api';
 import { isVerticalType, isSpouseType, isSiblingType } from '../utils/relationshipTypes';
 import { PersonNode } from './PersonNode';
 
@@ -123,6 +123,8 @@ export function FamilyGraph({ refreshTrigger, onPersonClick, searchQuery }) {
 
   const [rawData, setRawData] = useState({ people: [], rels: [] });
   const [selectedNodeId, setSelectedNodeId] = useState(null);
+  // ID людей, найденных сервером по строке поиска (null — поиск не задан или ещё идёт)
+  const [matchIds, setMatchIds] = useState(null);
   // Кэш позиций на сессию: не пересчитываем dagre при каждом клике
   const nodePositions = useRef({});
 
@@ -139,6 +141,30 @@ export function FamilyGraph({ refreshTrigger, onPersonClick, searchQuery }) {
     loadData();
   }, [refreshTrigger]);
 
+  // Поиск на сервере: транслитерация, формы фамилий, похожее звучание.
+  // Запрос уходит после паузы в наборе; если сервер недоступен — ищем подстроку локально.
+  useEffect(() => {
+    const q = searchQuery ? searchQuery.trim() : '';
+    if (!q) {
+      setMatchIds(null);
+      return;
+    }
+    let cancelled = false;
+    const timer = setTimeout(async () => {
+      try {
+        const found = await searchPeople(q, 200);
+        if (!cancelled) setMatchIds(new Set(found.map((p) => p.id)));
+      } catch (error) {
+        console.error('Ошибка поиска:', error);
+        if (!cancelled) setMatchIds(null);
+      }
+    }, 250);
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+  }, [searchQuery, refreshTrigger]);
+
   // 2. Пересчёт позиций — только при изменении данных, НЕ при клике
   useEffect(() => {
     const { people, rels } = rawData;
@@ -178,7 +204,8 @@ export function FamilyGraph({ refreshTrigger, onPersonClick, searchQuery }) {
       const ageString = getAgeString(age);
       const fullName =
         `${person.first_name} ${person.last_name} ${person.middle_name || ''}`.toLowerCase();
-      const isMatch = !!searchQuery && fullName.includes(searchLower);
+      const isMatch =
+        !!searchQuery && (matchIds ? matchIds.has(person.id) : fullName.includes(searchLower));
       const isDimmed = !!searchQuery && !isMatch;
       const isSelected = selectedNodeId === id;
 
@@ -255,7 +282,7 @@ export function FamilyGraph({ refreshTrigger, onPersonClick, searchQuery }) {
 
     setNodes(finalNodes);
     setEdges(newEdges);
-  }, [rawData, searchQuery, selectedNodeId, setNodes, setEdges]);
+  }, [rawData, searchQuery, matchIds, selectedNodeId, setNodes, setEdges]);
 
   const handleNodeClick = (event, node) => {
     if (selectedNodeId === node.id) {
@@ -1,5 +1,6 @@
 import React from 'react';
 import { Handle, Position } from 'reactflow';
+import { personPhotoUrl } from '../api';
 
 export function PersonNode({ data }) {
   const { person, isDimmed, isMatch, isSelected, age, ageString } = data;
@@ -48,7 +49,10 @@ export function PersonNode({ data }) {
         >
           <div style={{ position: 'relative' }}>
             <img
-              src={person.photo_url || `https://placehold.co/60?text=${person.first_name[0]}`}
+              src={
+                personPhotoUrl(person) ||
+                `https://placehold.co/60?text=${person.first_name[0]}`
+              }
               alt="avatar"
               style={{
                 width: 50,